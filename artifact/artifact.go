@@ -0,0 +1,172 @@
+// Package artifact builds the zipped code of the Lambda functions. Go functions are
+// cross-compiled for Linux and Node.js functions are bundled with esbuild. The zips are
+// reproducible, so their hash only changes if the code changes and unchanged functions
+// do not need to be uploaded and updated again.
+package artifact
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// modified is the modification time of every file in a zip, so the zip does not depend
+// on when it was built.
+var modified = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Artifact is the zipped code of a Lambda function.
+type Artifact struct {
+	// Data is the zip.
+	Data []byte
+	// Hash is the hex encoded SHA-256 hash of the zip.
+	Hash string
+}
+
+// New returns the artifact of the given zip.
+func New(data []byte) *Artifact {
+	hash := sha256.Sum256(data)
+	return &Artifact{Data: data, Hash: hex.EncodeToString(hash[:])}
+}
+
+// Key returns the given bucket key with the first 16 characters of the hash of the
+// artifact before its extension, e.g. `preprocessing-0123456789abcdef.zip` for
+// `preprocessing.zip`.
+func (a *Artifact) Key(key string) string {
+	ext := filepath.Ext(key)
+	return fmt.Sprintf("%s-%s%s", strings.TrimSuffix(key, ext), a.Hash[:16], ext)
+}
+
+// File is a file in a zip.
+type File struct {
+	Name string
+	Data []byte
+	Mode fs.FileMode
+}
+
+// Zip returns a zip of the given files. The files are sorted by name and have a fixed
+// modification time, so the same files always result in the same zip.
+func Zip(files []File) ([]byte, error) {
+	sorted := append([]File(nil), files...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, file := range sorted {
+		header := &zip.FileHeader{
+			Name:     file.Name,
+			Method:   zip.Deflate,
+			Modified: modified,
+		}
+		header.SetMode(file.Mode)
+
+		fw, err := w.CreateHeader(header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fw.Write(file.Data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Read returns the artifact of the zip that was built beforehand at the given path.
+func Read(path string) (*Artifact, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(data), nil
+}
+
+// BuildGo cross-compiles the Go main package in the given directory for the Lambda Go
+// runtime and zips the binary as `main`. The build is reproducible, so the same code
+// results in the same hash.
+func BuildGo(ctx context.Context, dir string) (*Artifact, error) {
+	tmp, err := os.MkdirTemp("", "artifact-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	binary := filepath.Join(tmp, "main")
+	cmd := exec.CommandContext(ctx, "go", "build", "-trimpath", "-buildvcs=false", "-ldflags=-s -w -buildid=", "-o", binary, ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOOS=linux", "GOARCH=amd64", "CGO_ENABLED=0")
+	if err := run(cmd); err != nil {
+		return nil, fmt.Errorf("building %s: %w", dir, err)
+	}
+
+	data, err := os.ReadFile(binary)
+	if err != nil {
+		return nil, err
+	}
+
+	zipped, err := Zip([]File{{Name: "main", Data: data, Mode: 0o755}})
+	if err != nil {
+		return nil, err
+	}
+	return New(zipped), nil
+}
+
+// BuildNode bundles the `index.ts` of the Node.js project in the given directory with the
+// esbuild of the project and zips the bundle and its source map. The dependencies of the
+// project must be installed.
+func BuildNode(ctx context.Context, dir string) (*Artifact, error) {
+	esbuild, err := filepath.Abs(filepath.Join(dir, "node_modules", ".bin", "esbuild"))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(esbuild); err != nil {
+		return nil, fmt.Errorf("building %s: esbuild is not installed, run `pnpm install`: %w", dir, err)
+	}
+
+	// The bundle is written to the `dist` directory of the project, so the paths in the
+	// source map do not depend on a temporary directory.
+	out := filepath.Join("dist", "index.js")
+	cmd := exec.CommandContext(ctx, esbuild, "index.ts", "--bundle", "--minify", "--sourcemap",
+		"--platform=node", "--target=es2020", "--outfile="+out)
+	cmd.Dir = dir
+	if err := run(cmd); err != nil {
+		return nil, fmt.Errorf("building %s: %w", dir, err)
+	}
+
+	var files []File
+	for _, name := range []string{"index.js", "index.js.map"} {
+		data, err := os.ReadFile(filepath.Join(dir, "dist", name))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, File{Name: name, Data: data, Mode: 0o644})
+	}
+
+	zipped, err := Zip(files)
+	if err != nil {
+		return nil, err
+	}
+	return New(zipped), nil
+}
+
+// run runs the given command and adds its output to the error if it fails.
+func run(cmd *exec.Cmd) error {
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, bytes.TrimSpace(output))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package artifact
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestZip(t *testing.T) {
+	files := []File{
+		{Name: "index.js.map", Data: []byte("{}"), Mode: 0o644},
+		{Name: "index.js", Data: []byte("exports.handler = () => {}"), Mode: 0o644},
+	}
+
+	first, err := Zip(files)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := Zip([]File{files[1], files[0]})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Errorf("expected the same zip for the same files")
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(first), int64(len(first)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(r.File) != 2 || r.File[0].Name != "index.js" {
+		t.Errorf("unexpected files: %v", r.File)
+	}
+}
+
+func TestArtifact_Key(t *testing.T) {
+	a := New([]byte("test-code"))
+	if len(a.Hash) != 64 {
+		t.Fatalf("unexpected hash: %s", a.Hash)
+	}
+
+	expected := "preprocessing-" + a.Hash[:16] + ".zip"
+	if key := a.Key("preprocessing.zip"); key != expected {
+		t.Errorf("unexpected key: %s", key)
+	}
+}
+
+func TestBuildGo(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	write("go.mod", "module example.com/handler\n\ngo 1.20\n")
+	write("main.go", "package main\n\nfunc main() {}\n")
+
+	first, err := BuildGo(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, err := BuildGo(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first.Hash != second.Hash {
+		t.Errorf("expected the same hash for the same code")
+	}
+
+	write("main.go", "package main\n\nfunc main() { println() }\n")
+	changed, err := BuildGo(context.Background(), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if changed.Hash == first.Hash {
+		t.Errorf("expected a different hash for changed code")
+	}
+}
@@ -3,6 +3,7 @@ package aws
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/apigatewayv2"
@@ -15,6 +16,12 @@ type apiGatewayAPI interface {
 	CreateDeployment(ctx context.Context, params *apigatewayv2.CreateDeploymentInput, optFns ...func(*apigatewayv2.Options)) (*apigatewayv2.CreateDeploymentOutput, error)
 	CreateRoute(ctx context.Context, params *apigatewayv2.CreateRouteInput, optFns ...func(*apigatewayv2.Options)) (*apigatewayv2.CreateRouteOutput, error)
 	CreateIntegration(ctx context.Context, params *apigatewayv2.CreateIntegrationInput, optFns ...func(*apigatewayv2.Options)) (*apigatewayv2.CreateIntegrationOutput, error)
+	GetApis(ctx context.Context, params *apigatewayv2.GetApisInput, optFns ...func(*apigatewayv2.Options)) (*apigatewayv2.GetApisOutput, error)
+	GetIntegrations(ctx context.Context, params *apigatewayv2.GetIntegrationsInput, optFns ...func(*apigatewayv2.Options)) (*apigatewayv2.GetIntegrationsOutput, error)
+	UpdateIntegration(ctx context.Context, params *apigatewayv2.UpdateIntegrationInput, optFns ...func(*apigatewayv2.Options)) (*apigatewayv2.UpdateIntegrationOutput, error)
+	GetRoutes(ctx context.Context, params *apigatewayv2.GetRoutesInput, optFns ...func(*apigatewayv2.Options)) (*apigatewayv2.GetRoutesOutput, error)
+	UpdateRoute(ctx context.Context, params *apigatewayv2.UpdateRouteInput, optFns ...func(*apigatewayv2.Options)) (*apigatewayv2.UpdateRouteOutput, error)
+	GetDeployment(ctx context.Context, params *apigatewayv2.GetDeploymentInput, optFns ...func(*apigatewayv2.Options)) (*apigatewayv2.GetDeploymentOutput, error)
 }
 
 // APIGateway is a wrapper around the AWS API Gateway client.
@@ -25,14 +32,20 @@ type APIGateway struct {
 // NewAPIGateway creates a new API Gateway client with the given configuration.
 func NewAPIGateway(config aws.Config) *APIGateway {
 	return &APIGateway{
-		client: apigatewayv2.NewFromConfig(config),
+		client: apigatewayv2.NewFromConfig(clientConfig(config)),
 	}
 }
 
+// NewAPIGatewayFromClient creates a new APIGateway wrapper around the given client, e.g. a
+// fake of the `awsfake` package.
+func NewAPIGatewayFromClient(client apiGatewayAPI) *APIGateway {
+	return &APIGateway{client: client}
+}
+
 // Create creates a websocket API Gateway with the given name and returns the ID of the
 // API Gateway that was created.
-func (a *APIGateway) CreateWebSocketApi(name string) (string, error) {
-	createOutput, err := a.client.CreateApi(context.TODO(), &apigatewayv2.CreateApiInput{
+func (a *APIGateway) CreateWebSocketApi(ctx context.Context, name string) (string, error) {
+	createOutput, err := a.client.CreateApi(ctx, &apigatewayv2.CreateApiInput{
 		Name:                     aws.String(name),
 		ProtocolType:             types.ProtocolTypeWebsocket,
 		RouteSelectionExpression: aws.String("$request.body.action"),
@@ -46,8 +59,8 @@ func (a *APIGateway) CreateWebSocketApi(name string) (string, error) {
 
 // Create creates a HTTP API Gateway with the given name and returns the ID of the
 // API Gateway that was created.
-func (a *APIGateway) CreateHTTPApi(name string) (string, error) {
-	createOutput, err := a.client.CreateApi(context.TODO(), &apigatewayv2.CreateApiInput{
+func (a *APIGateway) CreateHTTPApi(ctx context.Context, name string) (string, error) {
+	createOutput, err := a.client.CreateApi(ctx, &apigatewayv2.CreateApiInput{
 		Name:         aws.String(name),
 		ProtocolType: types.ProtocolTypeHttp,
 	})
@@ -58,9 +71,38 @@ func (a *APIGateway) CreateHTTPApi(name string) (string, error) {
 	return aws.ToString(createOutput.ApiId), nil
 }
 
+// GetApiId returns the ID of the API Gateway with the given name. It returns ErrNotFound
+// if there is no such API Gateway.
+func (a *APIGateway) GetApiId(ctx context.Context, name string) (string, error) {
+	api, err := a.findApi(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	if api == nil {
+		return "", fmt.Errorf("api %s: %w", name, ErrNotFound)
+	}
+
+	return aws.ToString(api.ApiId), nil
+}
+
+// GetEndpoints returns the base URL of the API Gateway with the given name and the URL of
+// the given stage.
+func (a *APIGateway) GetEndpoints(ctx context.Context, name, stage string) (string, string, error) {
+	api, err := a.findApi(ctx, name)
+	if err != nil {
+		return "", "", err
+	}
+	if api == nil {
+		return "", "", fmt.Errorf("api %s: %w", name, ErrNotFound)
+	}
+
+	endpoint := aws.ToString(api.ApiEndpoint)
+	return endpoint, fmt.Sprintf("%s/%s", endpoint, stage), nil
+}
+
 // Delete deletes the API Gateway with the given ID.
-func (a *APIGateway) Delete(id string) error {
-	_, err := a.client.DeleteApi(context.TODO(), &apigatewayv2.DeleteApiInput{
+func (a *APIGateway) Delete(ctx context.Context, id string) error {
+	_, err := a.client.DeleteApi(ctx, &apigatewayv2.DeleteApiInput{
 		ApiId: aws.String(id),
 	})
 	if err != nil {
@@ -82,19 +124,43 @@ type EndpointOptions struct {
 	RequestParameters map[string]string
 }
 
-// Deploy deploys the API Gateway with the given ID to the `dev` stage.
-func (a *APIGateway) Deploy(id string) error {
-	_, err := a.client.CreateDeployment(context.TODO(), &apigatewayv2.CreateDeploymentInput{
+// Deploy deploys the API Gateway with the given ID to the given stage and returns the ID
+// of the deployment.
+func (a *APIGateway) Deploy(ctx context.Context, id, stage string) (string, error) {
+	output, err := a.client.CreateDeployment(ctx, &apigatewayv2.CreateDeploymentInput{
 		ApiId:     aws.String(id),
-		StageName: aws.String("dev"),
+		StageName: aws.String(stage),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.ToString(output.DeploymentId), nil
+}
+
+// WaitUntilDeployed waits until the deployment with the given ID of the API Gateway with
+// the given ID is deployed. A deployment that failed is an error.
+func (a *APIGateway) WaitUntilDeployed(ctx context.Context, id, deploymentId string, timeout time.Duration) error {
+	return wait(ctx, fmt.Sprintf("deployment %s of api %s", deploymentId, id), timeout, func(ctx context.Context) (bool, string, error) {
+		deployment, err := a.client.GetDeployment(ctx, &apigatewayv2.GetDeploymentInput{
+			ApiId:        aws.String(id),
+			DeploymentId: aws.String(deploymentId),
+		})
+		if err != nil {
+			return false, "", err
+		}
+
+		if deployment.DeploymentStatus == types.DeploymentStatusFailed {
+			return false, "", fmt.Errorf("deployment failed: %s", aws.ToString(deployment.DeploymentStatusMessage))
+		}
+		return deployment.DeploymentStatus == types.DeploymentStatusDeployed, string(deployment.DeploymentStatus), nil
 	})
-	return err
 }
 
 // CreateWebSocket creates a websocket endpoint for the given API Gateway ID with the given
 // options.
-func (a *APIGateway) CreateWebSocket(id string, options EndpointOptions) error {
-	integrationOutput, err := a.client.CreateIntegration(context.TODO(), &apigatewayv2.CreateIntegrationInput{
+func (a *APIGateway) CreateWebSocket(ctx context.Context, id string, options EndpointOptions) error {
+	integrationOutput, err := a.client.CreateIntegration(ctx, &apigatewayv2.CreateIntegrationInput{
 		ApiId:             aws.String(id),
 		IntegrationType:   types.IntegrationTypeAwsProxy,
 		IntegrationMethod: aws.String(options.Method),
@@ -104,7 +170,7 @@ func (a *APIGateway) CreateWebSocket(id string, options EndpointOptions) error {
 		return err
 	}
 
-	_, err = a.client.CreateRoute(context.TODO(), &apigatewayv2.CreateRouteInput{
+	_, err = a.client.CreateRoute(ctx, &apigatewayv2.CreateRouteInput{
 		ApiId:                            aws.String(id),
 		RouteKey:                         aws.String(options.Path),
 		Target:                           integrationOutput.IntegrationId,
@@ -116,7 +182,7 @@ func (a *APIGateway) CreateWebSocket(id string, options EndpointOptions) error {
 
 	// Create a route for the `$connect` websocket event which is used for opening the
 	// connection.
-	_, err = a.client.CreateRoute(context.TODO(), &apigatewayv2.CreateRouteInput{
+	_, err = a.client.CreateRoute(ctx, &apigatewayv2.CreateRouteInput{
 		ApiId:    aws.String(id),
 		RouteKey: aws.String("$connect"),
 		Target:   integrationOutput.IntegrationId,
@@ -127,7 +193,7 @@ func (a *APIGateway) CreateWebSocket(id string, options EndpointOptions) error {
 
 	// Create a route for the `$disconnect` websocket event which is used for closing the
 	// connection.
-	_, err = a.client.CreateRoute(context.TODO(), &apigatewayv2.CreateRouteInput{
+	_, err = a.client.CreateRoute(ctx, &apigatewayv2.CreateRouteInput{
 		ApiId:    aws.String(id),
 		RouteKey: aws.String("$disconnect"),
 		Target:   integrationOutput.IntegrationId,
@@ -137,7 +203,7 @@ func (a *APIGateway) CreateWebSocket(id string, options EndpointOptions) error {
 	}
 
 	// Create a route for the `$default` websocket event which is used for data transfer.
-	_, err = a.client.CreateRoute(context.TODO(), &apigatewayv2.CreateRouteInput{
+	_, err = a.client.CreateRoute(ctx, &apigatewayv2.CreateRouteInput{
 		ApiId:                            aws.String(id),
 		RouteKey:                         aws.String("$default"),
 		Target:                           integrationOutput.IntegrationId,
@@ -153,8 +219,8 @@ func (a *APIGateway) CreateWebSocket(id string, options EndpointOptions) error {
 // CreateEndpoint creates a REST endpoint for the given API Gateway ID with the given
 // options. It creates a resource with the given path, a method with the given HTTP method,
 // an integration with the given URI, and a deployment.
-func (a *APIGateway) CreateEndpoint(id string, options EndpointOptions) error {
-	integrationOutput, err := a.client.CreateIntegration(context.TODO(), &apigatewayv2.CreateIntegrationInput{
+func (a *APIGateway) CreateEndpoint(ctx context.Context, id string, options EndpointOptions) error {
+	integrationOutput, err := a.client.CreateIntegration(ctx, &apigatewayv2.CreateIntegrationInput{
 		ApiId:             aws.String(id),
 		IntegrationType:   types.IntegrationTypeAwsProxy,
 		IntegrationMethod: aws.String(options.Method),
@@ -165,7 +231,7 @@ func (a *APIGateway) CreateEndpoint(id string, options EndpointOptions) error {
 		return err
 	}
 
-	_, err = a.client.CreateRoute(context.TODO(), &apigatewayv2.CreateRouteInput{
+	_, err = a.client.CreateRoute(ctx, &apigatewayv2.CreateRouteInput{
 		ApiId:    aws.String(id),
 		RouteKey: aws.String(fmt.Sprintf("%s %s", options.Method, options.Path)),
 		Target:   integrationOutput.IntegrationId,
@@ -176,3 +242,379 @@ func (a *APIGateway) CreateEndpoint(id string, options EndpointOptions) error {
 
 	return nil
 }
+
+// EnsureWebSocketApi returns the ID of the websocket API Gateway with the given name and
+// creates it if it does not exist yet.
+func (a *APIGateway) EnsureWebSocketApi(ctx context.Context, name string) (string, error) {
+	return a.ensureApi(ctx, name, types.ProtocolTypeWebsocket, a.CreateWebSocketApi)
+}
+
+// EnsureHTTPApi returns the ID of the HTTP API Gateway with the given name and creates it
+// if it does not exist yet.
+func (a *APIGateway) EnsureHTTPApi(ctx context.Context, name string) (string, error) {
+	return a.ensureApi(ctx, name, types.ProtocolTypeHttp, a.CreateHTTPApi)
+}
+
+// ensureApi returns the ID of the API Gateway with the given name or creates it with the
+// given create function. An existing API Gateway with a different protocol is an error.
+func (a *APIGateway) ensureApi(ctx context.Context, name string, protocol types.ProtocolType, create func(context.Context, string) (string, error)) (string, error) {
+	id, action, err := a.planApi(ctx, name, protocol)
+	if err != nil {
+		return "", err
+	}
+
+	if action == ActionCreate {
+		return create(ctx, name)
+	}
+
+	return id, nil
+}
+
+// PlanWebSocketApi returns the ID of the websocket API Gateway with the given name and
+// the action EnsureWebSocketApi would take for it without changing it. The ID is empty
+// if the API Gateway does not exist yet.
+func (a *APIGateway) PlanWebSocketApi(ctx context.Context, name string) (string, Action, error) {
+	return a.planApi(ctx, name, types.ProtocolTypeWebsocket)
+}
+
+// PlanHTTPApi returns the ID of the HTTP API Gateway with the given name and the action
+// EnsureHTTPApi would take for it without changing it. The ID is empty if the API
+// Gateway does not exist yet.
+func (a *APIGateway) PlanHTTPApi(ctx context.Context, name string) (string, Action, error) {
+	return a.planApi(ctx, name, types.ProtocolTypeHttp)
+}
+
+// planApi looks up the API Gateway with the given name. An existing API Gateway with a
+// different protocol is an error.
+func (a *APIGateway) planApi(ctx context.Context, name string, protocol types.ProtocolType) (string, Action, error) {
+	api, err := a.findApi(ctx, name)
+	if err != nil {
+		return "", "", err
+	}
+
+	if api == nil {
+		return "", ActionCreate, nil
+	}
+
+	if api.ProtocolType != protocol {
+		return "", "", fmt.Errorf("api %s uses protocol %s instead of %s: %w", name, api.ProtocolType, protocol, ErrAlreadyExists)
+	}
+
+	return aws.ToString(api.ApiId), ActionNone, nil
+}
+
+// findApi returns the API Gateway with the given name or `nil` if there is none.
+func (a *APIGateway) findApi(ctx context.Context, name string) (*types.Api, error) {
+	input := &apigatewayv2.GetApisInput{}
+	for {
+		output, err := a.client.GetApis(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range output.Items {
+			if aws.ToString(output.Items[i].Name) == name {
+				return &output.Items[i], nil
+			}
+		}
+
+		if output.NextToken == nil {
+			return nil, nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
+
+// EnsureWebSocket works like CreateWebSocket but reuses the integration and the routes
+// if they already exist and updates them if they differ.
+func (a *APIGateway) EnsureWebSocket(ctx context.Context, id string, options EndpointOptions) error {
+	integrationId, err := a.ensureIntegration(ctx, id, webSocketIntegration(id, options))
+	if err != nil {
+		return err
+	}
+
+	return a.ensureRoutes(ctx, id, webSocketRoutes(id, integrationId, options))
+}
+
+// EnsureEndpoint works like CreateEndpoint but reuses the integration and the route if
+// they already exist and updates them if they differ.
+func (a *APIGateway) EnsureEndpoint(ctx context.Context, id string, options EndpointOptions) error {
+	integrationId, err := a.ensureIntegration(ctx, id, endpointIntegration(id, options))
+	if err != nil {
+		return err
+	}
+
+	return a.ensureRoutes(ctx, id, endpointRoutes(id, integrationId, options))
+}
+
+// PlanWebSocket returns the action EnsureWebSocket would take for the integration and
+// the routes of the given API Gateway without changing them.
+func (a *APIGateway) PlanWebSocket(ctx context.Context, id string, options EndpointOptions) (Action, error) {
+	drift, err := a.DriftWebSocket(ctx, id, options)
+	return drift.Action, err
+}
+
+// PlanEndpoint returns the action EnsureEndpoint would take for the integration and the
+// route of the given API Gateway without changing them.
+func (a *APIGateway) PlanEndpoint(ctx context.Context, id string, options EndpointOptions) (Action, error) {
+	drift, err := a.DriftEndpoint(ctx, id, options)
+	return drift.Action, err
+}
+
+// DriftWebSocket compares the integration and the routes of the given API Gateway with
+// the ones EnsureWebSocket creates without changing them.
+func (a *APIGateway) DriftWebSocket(ctx context.Context, id string, options EndpointOptions) (Drift, error) {
+	return a.driftEndpoint(ctx, id, webSocketIntegration(id, options), func(integrationId string) []*apigatewayv2.CreateRouteInput {
+		return webSocketRoutes(id, integrationId, options)
+	})
+}
+
+// DriftEndpoint compares the integration and the route of the given API Gateway with the
+// ones EnsureEndpoint creates without changing them.
+func (a *APIGateway) DriftEndpoint(ctx context.Context, id string, options EndpointOptions) (Drift, error) {
+	return a.driftEndpoint(ctx, id, endpointIntegration(id, options), func(integrationId string) []*apigatewayv2.CreateRouteInput {
+		return endpointRoutes(id, integrationId, options)
+	})
+}
+
+// driftEndpoint compares the existing integration and routes of the given API Gateway
+// with the given integration and the routes that target it. A missing integration is
+// created, every other difference updates the endpoint.
+func (a *APIGateway) driftEndpoint(ctx context.Context, id string, integration *apigatewayv2.CreateIntegrationInput, routes func(string) []*apigatewayv2.CreateRouteInput) (Drift, error) {
+	existing, err := a.findIntegration(ctx, id, aws.ToString(integration.IntegrationUri))
+	if err != nil {
+		return Drift{}, err
+	}
+
+	if existing == nil {
+		return Drift{Action: ActionCreate}, nil
+	}
+
+	diffs := integrationDifferences(existing, integration)
+
+	existingRoutes, err := a.getRoutes(ctx, id)
+	if err != nil {
+		return Drift{}, err
+	}
+
+	for _, route := range routes(aws.ToString(existing.IntegrationId)) {
+		key := aws.ToString(route.RouteKey)
+		current, ok := existingRoutes[key]
+		if !ok {
+			diffs.compare(fmt.Sprintf("routes[%s]", key), aws.ToString(route.Target), missingValue)
+			continue
+		}
+		diffs = append(diffs, routeDifferences(current, route)...)
+	}
+
+	return drift(diffs), nil
+}
+
+// webSocketIntegration returns the input of the integration of a websocket endpoint.
+func webSocketIntegration(id string, options EndpointOptions) *apigatewayv2.CreateIntegrationInput {
+	return &apigatewayv2.CreateIntegrationInput{
+		ApiId:             aws.String(id),
+		IntegrationType:   types.IntegrationTypeAwsProxy,
+		IntegrationMethod: aws.String(options.Method),
+		IntegrationUri:    aws.String(options.Uri),
+	}
+}
+
+// webSocketRoutes returns the inputs of the routes of a websocket endpoint that target
+// the integration with the given ID.
+func webSocketRoutes(id, integrationId string, options EndpointOptions) []*apigatewayv2.CreateRouteInput {
+	return []*apigatewayv2.CreateRouteInput{
+		{
+			ApiId:                            aws.String(id),
+			RouteKey:                         aws.String(options.Path),
+			Target:                           aws.String(integrationId),
+			RouteResponseSelectionExpression: aws.String("$default"),
+		},
+		{
+			ApiId:    aws.String(id),
+			RouteKey: aws.String("$connect"),
+			Target:   aws.String(integrationId),
+		},
+		{
+			ApiId:    aws.String(id),
+			RouteKey: aws.String("$disconnect"),
+			Target:   aws.String(integrationId),
+		},
+		{
+			ApiId:                            aws.String(id),
+			RouteKey:                         aws.String("$default"),
+			Target:                           aws.String(integrationId),
+			RouteResponseSelectionExpression: aws.String("$default"),
+		},
+	}
+}
+
+// endpointIntegration returns the input of the integration of a HTTP endpoint.
+func endpointIntegration(id string, options EndpointOptions) *apigatewayv2.CreateIntegrationInput {
+	return &apigatewayv2.CreateIntegrationInput{
+		ApiId:             aws.String(id),
+		IntegrationType:   types.IntegrationTypeAwsProxy,
+		IntegrationMethod: aws.String(options.Method),
+		IntegrationUri:    aws.String(options.Uri),
+		RequestParameters: options.RequestParameters,
+	}
+}
+
+// endpointRoutes returns the input of the route of a HTTP endpoint that targets the
+// integration with the given ID.
+func endpointRoutes(id, integrationId string, options EndpointOptions) []*apigatewayv2.CreateRouteInput {
+	return []*apigatewayv2.CreateRouteInput{
+		{
+			ApiId:    aws.String(id),
+			RouteKey: aws.String(fmt.Sprintf("%s %s", options.Method, options.Path)),
+			Target:   aws.String(integrationId),
+		},
+	}
+}
+
+// ensureIntegration returns the ID of the integration of the given API Gateway that has
+// the same URI as the given input. The integration is updated if its method or request
+// parameters differ and it is created if it does not exist yet.
+func (a *APIGateway) ensureIntegration(ctx context.Context, id string, input *apigatewayv2.CreateIntegrationInput) (string, error) {
+	existing, err := a.findIntegration(ctx, id, aws.ToString(input.IntegrationUri))
+	if err != nil {
+		return "", err
+	}
+
+	if existing == nil {
+		output, err := a.client.CreateIntegration(ctx, input)
+		if err != nil {
+			return "", err
+		}
+		return aws.ToString(output.IntegrationId), nil
+	}
+
+	if integrationMatches(existing, input) {
+		return aws.ToString(existing.IntegrationId), nil
+	}
+
+	_, err = a.client.UpdateIntegration(ctx, &apigatewayv2.UpdateIntegrationInput{
+		ApiId:             aws.String(id),
+		IntegrationId:     existing.IntegrationId,
+		IntegrationType:   input.IntegrationType,
+		IntegrationMethod: input.IntegrationMethod,
+		RequestParameters: input.RequestParameters,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.ToString(existing.IntegrationId), nil
+}
+
+// findIntegration returns the integration of the given API Gateway with the given URI or
+// `nil` if there is none.
+func (a *APIGateway) findIntegration(ctx context.Context, id, uri string) (*types.Integration, error) {
+	input := &apigatewayv2.GetIntegrationsInput{ApiId: aws.String(id)}
+	for {
+		output, err := a.client.GetIntegrations(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range output.Items {
+			if aws.ToString(output.Items[i].IntegrationUri) == uri {
+				return &output.Items[i], nil
+			}
+		}
+
+		if output.NextToken == nil {
+			return nil, nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
+
+// integrationMatches reports whether the existing integration has the same method, type
+// and request parameters as the given input.
+func integrationMatches(existing *types.Integration, input *apigatewayv2.CreateIntegrationInput) bool {
+	return len(integrationDifferences(existing, input)) == 0
+}
+
+// integrationDifferences returns the fields of the existing integration that differ from
+// the given input.
+func integrationDifferences(existing *types.Integration, input *apigatewayv2.CreateIntegrationInput) differences {
+	var diffs differences
+	diffs.compare("integration.method", aws.ToString(input.IntegrationMethod), aws.ToString(existing.IntegrationMethod))
+	diffs.compare("integration.type", input.IntegrationType, existing.IntegrationType)
+	diffs.compareMaps("integration.requestParameters", input.RequestParameters, existing.RequestParameters)
+	return diffs
+}
+
+// ensureRoutes creates the given routes of the API Gateway if they do not exist yet.
+// Existing routes with the same route key are updated if their target or route response
+// selection expression differ.
+func (a *APIGateway) ensureRoutes(ctx context.Context, id string, routes []*apigatewayv2.CreateRouteInput) error {
+	existing, err := a.getRoutes(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	for _, route := range routes {
+		current, ok := existing[aws.ToString(route.RouteKey)]
+		if !ok {
+			if _, err := a.client.CreateRoute(ctx, route); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if routeMatches(current, route) {
+			continue
+		}
+
+		_, err := a.client.UpdateRoute(ctx, &apigatewayv2.UpdateRouteInput{
+			ApiId:                            aws.String(id),
+			RouteId:                          current.RouteId,
+			Target:                           route.Target,
+			RouteResponseSelectionExpression: route.RouteResponseSelectionExpression,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getRoutes returns the routes of the given API Gateway by their route key.
+func (a *APIGateway) getRoutes(ctx context.Context, id string) (map[string]types.Route, error) {
+	routes := map[string]types.Route{}
+	input := &apigatewayv2.GetRoutesInput{ApiId: aws.String(id)}
+	for {
+		output, err := a.client.GetRoutes(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, route := range output.Items {
+			routes[aws.ToString(route.RouteKey)] = route
+		}
+
+		if output.NextToken == nil {
+			return routes, nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
+
+// routeMatches reports whether the existing route has the same target and route response
+// selection expression as the given input.
+func routeMatches(existing types.Route, input *apigatewayv2.CreateRouteInput) bool {
+	return len(routeDifferences(existing, input)) == 0
+}
+
+// routeDifferences returns the fields of the existing route that differ from the given
+// input. The fields are prefixed with the route key.
+func routeDifferences(existing types.Route, input *apigatewayv2.CreateRouteInput) differences {
+	var diffs differences
+	prefix := fmt.Sprintf("routes[%s]", aws.ToString(input.RouteKey))
+	diffs.compare(prefix+".target", aws.ToString(input.Target), aws.ToString(existing.Target))
+	diffs.compare(prefix+".routeResponseSelectionExpression", aws.ToString(input.RouteResponseSelectionExpression), aws.ToString(existing.RouteResponseSelectionExpression))
+	return diffs
+}
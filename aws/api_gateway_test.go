@@ -2,10 +2,13 @@ package aws
 
 import (
 	"context"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/apigatewayv2"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewayv2/types"
 )
 
 type mockAPIGatewayClient struct {
@@ -14,6 +17,12 @@ type mockAPIGatewayClient struct {
 	createDeploymentFunc  func(ctx context.Context, input *apigatewayv2.CreateDeploymentInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.CreateDeploymentOutput, error)
 	createRouteFunc       func(ctx context.Context, input *apigatewayv2.CreateRouteInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.CreateRouteOutput, error)
 	createIntegrationFunc func(ctx context.Context, input *apigatewayv2.CreateIntegrationInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.CreateIntegrationOutput, error)
+	getApisFunc           func(ctx context.Context, input *apigatewayv2.GetApisInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.GetApisOutput, error)
+	getIntegrationsFunc   func(ctx context.Context, input *apigatewayv2.GetIntegrationsInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.GetIntegrationsOutput, error)
+	updateIntegrationFunc func(ctx context.Context, input *apigatewayv2.UpdateIntegrationInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.UpdateIntegrationOutput, error)
+	getRoutesFunc         func(ctx context.Context, input *apigatewayv2.GetRoutesInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.GetRoutesOutput, error)
+	updateRouteFunc       func(ctx context.Context, input *apigatewayv2.UpdateRouteInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.UpdateRouteOutput, error)
+	getDeploymentFunc     func(ctx context.Context, input *apigatewayv2.GetDeploymentInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.GetDeploymentOutput, error)
 }
 
 func (m *mockAPIGatewayClient) CreateApi(ctx context.Context, input *apigatewayv2.CreateApiInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.CreateApiOutput, error) {
@@ -36,6 +45,30 @@ func (m *mockAPIGatewayClient) CreateIntegration(ctx context.Context, input *api
 	return m.createIntegrationFunc(ctx, input, opts...)
 }
 
+func (m *mockAPIGatewayClient) GetApis(ctx context.Context, input *apigatewayv2.GetApisInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.GetApisOutput, error) {
+	return m.getApisFunc(ctx, input, opts...)
+}
+
+func (m *mockAPIGatewayClient) GetIntegrations(ctx context.Context, input *apigatewayv2.GetIntegrationsInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.GetIntegrationsOutput, error) {
+	return m.getIntegrationsFunc(ctx, input, opts...)
+}
+
+func (m *mockAPIGatewayClient) UpdateIntegration(ctx context.Context, input *apigatewayv2.UpdateIntegrationInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.UpdateIntegrationOutput, error) {
+	return m.updateIntegrationFunc(ctx, input, opts...)
+}
+
+func (m *mockAPIGatewayClient) GetRoutes(ctx context.Context, input *apigatewayv2.GetRoutesInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.GetRoutesOutput, error) {
+	return m.getRoutesFunc(ctx, input, opts...)
+}
+
+func (m *mockAPIGatewayClient) UpdateRoute(ctx context.Context, input *apigatewayv2.UpdateRouteInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.UpdateRouteOutput, error) {
+	return m.updateRouteFunc(ctx, input, opts...)
+}
+
+func (m *mockAPIGatewayClient) GetDeployment(ctx context.Context, input *apigatewayv2.GetDeploymentInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.GetDeploymentOutput, error) {
+	return m.getDeploymentFunc(ctx, input, opts...)
+}
+
 func TestAPIGateway_CreateWebSocketApi(t *testing.T) {
 	mockClient := &mockAPIGatewayClient{
 		createApiFunc: func(ctx context.Context, input *apigatewayv2.CreateApiInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.CreateApiOutput, error) {
@@ -52,7 +85,7 @@ func TestAPIGateway_CreateWebSocketApi(t *testing.T) {
 		client: mockClient,
 	}
 
-	id, err := apiGatewayClient.CreateWebSocketApi("test-api")
+	id, err := apiGatewayClient.CreateWebSocketApi(context.Background(), "test-api")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -78,7 +111,7 @@ func TestAPIGateway_CreateHTTPApi(t *testing.T) {
 		client: mockClient,
 	}
 
-	id, err := apiGatewayClient.CreateHTTPApi("test-api")
+	id, err := apiGatewayClient.CreateHTTPApi(context.Background(), "test-api")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -88,6 +121,45 @@ func TestAPIGateway_CreateHTTPApi(t *testing.T) {
 	}
 }
 
+func TestAPIGateway_EnsureHTTPApi(t *testing.T) {
+	mockClient := &mockAPIGatewayClient{
+		getApisFunc: func(ctx context.Context, input *apigatewayv2.GetApisInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.GetApisOutput, error) {
+			return &apigatewayv2.GetApisOutput{
+				Items: []types.Api{
+					{
+						ApiId:        aws.String("other-id"),
+						Name:         aws.String("other-api"),
+						ProtocolType: types.ProtocolTypeHttp,
+					},
+					{
+						ApiId:        aws.String("test-id"),
+						Name:         aws.String("test-api"),
+						ProtocolType: types.ProtocolTypeHttp,
+					},
+				},
+			}, nil
+		},
+	}
+
+	apiGatewayClient := &APIGateway{
+		client: mockClient,
+	}
+
+	id, err := apiGatewayClient.EnsureHTTPApi(context.Background(), "test-api")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if id != "test-id" {
+		t.Errorf("unexpected api id: %s", id)
+	}
+
+	_, err = apiGatewayClient.EnsureWebSocketApi(context.Background(), "test-api")
+	if err == nil {
+		t.Errorf("expected error for mismatching protocol")
+	}
+}
+
 func TestAPIGateway_Delete(t *testing.T) {
 	mockClient := &mockAPIGatewayClient{
 		deleteApiFunc: func(ctx context.Context, input *apigatewayv2.DeleteApiInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.DeleteApiOutput, error) {
@@ -102,7 +174,7 @@ func TestAPIGateway_Delete(t *testing.T) {
 		client: mockClient,
 	}
 
-	err := apiGatewayClient.Delete("test-api")
+	err := apiGatewayClient.Delete(context.Background(), "test-api")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -148,7 +220,7 @@ func TestAPIGateway_CreateEndpoint(t *testing.T) {
 		client: mockClient,
 	}
 
-	err := apiGatewayClient.CreateEndpoint("test-api", EndpointOptions{
+	err := apiGatewayClient.CreateEndpoint(context.Background(), "test-api", EndpointOptions{
 		Path:   "/hello",
 		Method: "GET",
 		Uri:    "http://example.com",
@@ -198,7 +270,7 @@ func TestAPIGateway_CreateWebSocket(t *testing.T) {
 		client: mockClient,
 	}
 
-	err := apiGatewayClient.CreateWebSocket("test-api", EndpointOptions{
+	err := apiGatewayClient.CreateWebSocket(context.Background(), "test-api", EndpointOptions{
 		Path:   "hello",
 		Method: "POST",
 		Uri:    "http://example.com",
@@ -214,7 +286,130 @@ func TestAPIGateway_CreateDeployment(t *testing.T) {
 			if aws.ToString(input.ApiId) != "test-api" {
 				t.Errorf("unexpected api id: %s", aws.ToString(input.ApiId))
 			}
-			return &apigatewayv2.CreateDeploymentOutput{}, nil
+			if aws.ToString(input.StageName) != "staging" {
+				t.Errorf("unexpected stage: %s", aws.ToString(input.StageName))
+			}
+			return &apigatewayv2.CreateDeploymentOutput{
+				DeploymentId: aws.String("test-deployment"),
+			}, nil
+		},
+	}
+
+	apiGatewayClient := &APIGateway{
+		client: mockClient,
+	}
+
+	id, err := apiGatewayClient.Deploy(context.Background(), "test-api", "staging")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if id != "test-deployment" {
+		t.Errorf("unexpected deployment id: %s", id)
+	}
+}
+
+func TestAPIGateway_WaitUntilDeployed(t *testing.T) {
+	setWaitDelay(t)
+
+	calls := 0
+	mockClient := &mockAPIGatewayClient{
+		getDeploymentFunc: func(ctx context.Context, input *apigatewayv2.GetDeploymentInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.GetDeploymentOutput, error) {
+			if aws.ToString(input.DeploymentId) != "test-deployment" {
+				t.Errorf("unexpected deployment id: %s", aws.ToString(input.DeploymentId))
+			}
+			calls++
+			if calls < 2 {
+				return &apigatewayv2.GetDeploymentOutput{DeploymentStatus: types.DeploymentStatusPending}, nil
+			}
+			return &apigatewayv2.GetDeploymentOutput{DeploymentStatus: types.DeploymentStatusDeployed}, nil
+		},
+	}
+
+	apiGatewayClient := &APIGateway{
+		client: mockClient,
+	}
+
+	err := apiGatewayClient.WaitUntilDeployed(context.Background(), "test-api", "test-deployment", time.Second)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestAPIGateway_EnsureEndpoint(t *testing.T) {
+	mockClient := &mockAPIGatewayClient{
+		getIntegrationsFunc: func(ctx context.Context, input *apigatewayv2.GetIntegrationsInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.GetIntegrationsOutput, error) {
+			return &apigatewayv2.GetIntegrationsOutput{
+				Items: []types.Integration{
+					{
+						IntegrationId:     aws.String("test-integration"),
+						IntegrationType:   types.IntegrationTypeAwsProxy,
+						IntegrationMethod: aws.String("GET"),
+						IntegrationUri:    aws.String("http://example.com"),
+					},
+				},
+			}, nil
+		},
+		getRoutesFunc: func(ctx context.Context, input *apigatewayv2.GetRoutesInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.GetRoutesOutput, error) {
+			return &apigatewayv2.GetRoutesOutput{
+				Items: []types.Route{
+					{
+						RouteId:  aws.String("test-route"),
+						RouteKey: aws.String("GET /hello"),
+						Target:   aws.String("old-integration"),
+					},
+				},
+			}, nil
+		},
+		updateRouteFunc: func(ctx context.Context, input *apigatewayv2.UpdateRouteInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.UpdateRouteOutput, error) {
+			if aws.ToString(input.Target) != "test-integration" {
+				t.Errorf("unexpected target: %s", aws.ToString(input.Target))
+			}
+			return &apigatewayv2.UpdateRouteOutput{}, nil
+		},
+	}
+
+	apiGatewayClient := &APIGateway{
+		client: mockClient,
+	}
+
+	err := apiGatewayClient.EnsureEndpoint(context.Background(), "test-api", EndpointOptions{
+		Path:   "/hello",
+		Method: "GET",
+		Uri:    "http://example.com",
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestAPIGateway_PlanEndpoint(t *testing.T) {
+	target := "old-integration"
+	mockClient := &mockAPIGatewayClient{
+		getIntegrationsFunc: func(ctx context.Context, input *apigatewayv2.GetIntegrationsInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.GetIntegrationsOutput, error) {
+			return &apigatewayv2.GetIntegrationsOutput{
+				Items: []types.Integration{
+					{
+						IntegrationId:     aws.String("test-integration"),
+						IntegrationType:   types.IntegrationTypeAwsProxy,
+						IntegrationMethod: aws.String("GET"),
+						IntegrationUri:    aws.String("http://example.com"),
+					},
+				},
+			}, nil
+		},
+		getRoutesFunc: func(ctx context.Context, input *apigatewayv2.GetRoutesInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.GetRoutesOutput, error) {
+			return &apigatewayv2.GetRoutesOutput{
+				Items: []types.Route{
+					{
+						RouteId:  aws.String("test-route"),
+						RouteKey: aws.String("GET /hello"),
+						Target:   aws.String(target),
+					},
+				},
+			}, nil
 		},
 	}
 
@@ -222,8 +417,129 @@ func TestAPIGateway_CreateDeployment(t *testing.T) {
 		client: mockClient,
 	}
 
-	err := apiGatewayClient.Deploy("test-api")
+	options := EndpointOptions{
+		Path:   "/hello",
+		Method: "GET",
+		Uri:    "http://example.com",
+	}
+
+	action, err := apiGatewayClient.PlanEndpoint(context.Background(), "test-api", options)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if action != ActionUpdate {
+		t.Errorf("unexpected action for outdated route: %s", action)
+	}
+
+	target = "test-integration"
+	action, err = apiGatewayClient.PlanEndpoint(context.Background(), "test-api", options)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if action != ActionNone {
+		t.Errorf("unexpected action for unchanged endpoint: %s", action)
+	}
+
+	options.Uri = "http://other.example.com"
+	action, err = apiGatewayClient.PlanEndpoint(context.Background(), "test-api", options)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
+	if action != ActionCreate {
+		t.Errorf("unexpected action for missing integration: %s", action)
+	}
+}
+
+func TestAPIGateway_DriftWebSocket(t *testing.T) {
+	mockClient := &mockAPIGatewayClient{
+		getIntegrationsFunc: func(ctx context.Context, input *apigatewayv2.GetIntegrationsInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.GetIntegrationsOutput, error) {
+			return &apigatewayv2.GetIntegrationsOutput{
+				Items: []types.Integration{
+					{
+						IntegrationId:     aws.String("test-integration"),
+						IntegrationType:   types.IntegrationTypeAwsProxy,
+						IntegrationMethod: aws.String("GET"),
+						IntegrationUri:    aws.String("test-arn"),
+					},
+				},
+			}, nil
+		},
+		getRoutesFunc: func(ctx context.Context, input *apigatewayv2.GetRoutesInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.GetRoutesOutput, error) {
+			return &apigatewayv2.GetRoutesOutput{
+				Items: []types.Route{
+					{
+						RouteId:  aws.String("test-route"),
+						RouteKey: aws.String("sendmessage"),
+						Target:   aws.String("test-integration"),
+					},
+					{
+						RouteId:  aws.String("connect-route"),
+						RouteKey: aws.String("$connect"),
+						Target:   aws.String("test-integration"),
+					},
+				},
+			}, nil
+		},
+	}
+
+	apiGatewayClient := &APIGateway{
+		client: mockClient,
+	}
+
+	drift, err := apiGatewayClient.DriftWebSocket(context.Background(), "test-api", EndpointOptions{
+		Path:   "sendmessage",
+		Method: "POST",
+		Uri:    "test-arn",
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if drift.Action != ActionUpdate {
+		t.Errorf("unexpected action for changed endpoint: %s", drift.Action)
+	}
+
+	expected := []Difference{
+		{Field: "integration.method", Expected: "POST", Actual: "GET"},
+		{Field: "routes[sendmessage].routeResponseSelectionExpression", Expected: "$default", Actual: ""},
+		{Field: "routes[$disconnect]", Expected: "test-integration", Actual: "<missing>"},
+		{Field: "routes[$default]", Expected: "test-integration", Actual: "<missing>"},
+	}
+	if !reflect.DeepEqual(drift.Differences, expected) {
+		t.Errorf("unexpected differences: %v", drift.Differences)
+	}
+}
+
+func TestAPIGateway_GetEndpoints(t *testing.T) {
+	mockClient := &mockAPIGatewayClient{
+		getApisFunc: func(ctx context.Context, input *apigatewayv2.GetApisInput, opts ...func(*apigatewayv2.Options)) (*apigatewayv2.GetApisOutput, error) {
+			return &apigatewayv2.GetApisOutput{
+				Items: []types.Api{
+					{
+						ApiId:       aws.String("test-id"),
+						Name:        aws.String("test-api"),
+						ApiEndpoint: aws.String("ws://localhost:4510"),
+					},
+				},
+			}, nil
+		},
+	}
+
+	apiGatewayClient := &APIGateway{
+		client: mockClient,
+	}
+
+	endpoint, invokeURL, err := apiGatewayClient.GetEndpoints(context.Background(), "test-api", "dev")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if endpoint != "ws://localhost:4510" {
+		t.Errorf("unexpected endpoint: %s", endpoint)
+	}
+	if invokeURL != "ws://localhost:4510/dev" {
+		t.Errorf("unexpected invoke url: %s", invokeURL)
+	}
+
+	if _, _, err := apiGatewayClient.GetEndpoints(context.Background(), "missing-api", "dev"); err == nil {
+		t.Errorf("expected error for missing api")
+	}
 }
@@ -2,6 +2,9 @@ package aws
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/rds"
@@ -13,16 +16,26 @@ import (
 type auroraAPI interface {
 	CreateDBCluster(ctx context.Context, params *rds.CreateDBClusterInput, optFns ...func(*rds.Options)) (*rds.CreateDBClusterOutput, error)
 	DescribeDBClusters(ctx context.Context, params *rds.DescribeDBClustersInput, optFns ...func(*rds.Options)) (*rds.DescribeDBClustersOutput, error)
+	DeleteDBCluster(ctx context.Context, params *rds.DeleteDBClusterInput, optFns ...func(*rds.Options)) (*rds.DeleteDBClusterOutput, error)
 }
 
 type rdsDataAPI interface {
 	ExecuteStatement(ctx context.Context, params *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error)
+	BeginTransaction(ctx context.Context, params *rdsdata.BeginTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error)
+	CommitTransaction(ctx context.Context, params *rdsdata.CommitTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error)
+	RollbackTransaction(ctx context.Context, params *rdsdata.RollbackTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.RollbackTransactionOutput, error)
 }
 
 type secretsManagerAPI interface {
 	CreateSecret(ctx context.Context, params *secretsmanager.CreateSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.CreateSecretOutput, error)
+	GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+	PutSecretValue(ctx context.Context, params *secretsmanager.PutSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.PutSecretValueOutput, error)
+	DeleteSecret(ctx context.Context, params *secretsmanager.DeleteSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.DeleteSecretOutput, error)
 }
 
+// auroraEngine is the engine of the created database clusters.
+const auroraEngine = "aurora-postgresql"
+
 // Aurora is a wrapper around the AWS Aurora client.
 type Aurora struct {
 	rdsClient            auroraAPI
@@ -32,7 +45,7 @@ type Aurora struct {
 
 // NewAurora creates a new Aurora client with the given configuration.
 func NewAurora(config aws.Config) *Aurora {
-	cfg := config.Copy()
+	cfg := clientConfig(config)
 	return &Aurora{
 		rdsClient:            rds.NewFromConfig(cfg),
 		rdsDataClient:        rdsdata.NewFromConfig(cfg),
@@ -40,14 +53,24 @@ func NewAurora(config aws.Config) *Aurora {
 	}
 }
 
+// NewAuroraFromClients creates a new Aurora wrapper around the given RDS, RDS Data API and
+// Secrets Manager clients, e.g. fakes of the `awsfake` package.
+func NewAuroraFromClients(rdsClient auroraAPI, rdsDataClient rdsDataAPI, secretsManagerClient secretsManagerAPI) *Aurora {
+	return &Aurora{
+		rdsClient:            rdsClient,
+		rdsDataClient:        rdsDataClient,
+		secretsManagerClient: secretsManagerClient,
+	}
+}
+
 // CreateDBCluster creates a new Aurora database cluster with the given identifier and
 // database name. It also creates a secret for the database cluster with the given
 // username and password.
-func (a *Aurora) CreateDBCluster(identifier, databaseName, username, password string) (*rds.CreateDBClusterOutput, *secretsmanager.CreateSecretOutput, error) {
+func (a *Aurora) CreateDBCluster(ctx context.Context, identifier, databaseName, username, password string) (*rds.CreateDBClusterOutput, *secretsmanager.CreateSecretOutput, error) {
 	// Creates the database cluster.
-	cluster, err := a.rdsClient.CreateDBCluster(context.TODO(), &rds.CreateDBClusterInput{
+	cluster, err := a.rdsClient.CreateDBCluster(ctx, &rds.CreateDBClusterInput{
 		DBClusterIdentifier: aws.String(identifier),
-		Engine:              aws.String("aurora-postgresql"),
+		Engine:              aws.String(auroraEngine),
 		DatabaseName:        aws.String(databaseName),
 		SourceRegion:        aws.String("us-east-1"),
 	})
@@ -56,7 +79,7 @@ func (a *Aurora) CreateDBCluster(identifier, databaseName, username, password st
 	}
 
 	// Creates the secret for the database cluster.
-	secret, err := a.secretsManagerClient.CreateSecret(context.TODO(), &secretsmanager.CreateSecretInput{
+	secret, err := a.secretsManagerClient.CreateSecret(ctx, &secretsmanager.CreateSecretInput{
 		Name:         aws.String(username),
 		SecretString: aws.String(password),
 	})
@@ -67,9 +90,140 @@ func (a *Aurora) CreateDBCluster(identifier, databaseName, username, password st
 	return cluster, secret, nil
 }
 
-// GetDBCluster returns the database cluster with the given identifier.
-func (a *Aurora) GetDBCluster(clusterIdentifier string) (*types.DBCluster, error) {
-	clusters, err := a.rdsClient.DescribeDBClusters(context.TODO(), &rds.DescribeDBClustersInput{
+// EnsureDBCluster creates a new Aurora database cluster with the given identifier and
+// database name if it does not exist yet. The secret for the database cluster is created
+// with the given username and password if it does not exist yet, otherwise its value is
+// updated if it differs. It returns the database cluster and the ARN of the secret.
+func (a *Aurora) EnsureDBCluster(ctx context.Context, identifier, databaseName, username, password string) (*types.DBCluster, string, error) {
+	cluster, err := a.findDBCluster(ctx, identifier)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if cluster == nil {
+		output, err := a.rdsClient.CreateDBCluster(ctx, &rds.CreateDBClusterInput{
+			DBClusterIdentifier: aws.String(identifier),
+			Engine:              aws.String(auroraEngine),
+			DatabaseName:        aws.String(databaseName),
+			SourceRegion:        aws.String("us-east-1"),
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		cluster = output.DBCluster
+	}
+
+	secretArn, err := a.ensureSecret(ctx, username, password)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return cluster, secretArn, nil
+}
+
+// PlanDBCluster returns the action EnsureDBCluster would take for the database cluster
+// with the given identifier and its secret without changing them.
+func (a *Aurora) PlanDBCluster(ctx context.Context, identifier, username, password string) (Action, error) {
+	drift, err := a.DriftDBCluster(ctx, identifier, username, password)
+	return drift.Action, err
+}
+
+// DriftDBCluster compares the database cluster with the given identifier and its secret
+// with the cluster EnsureDBCluster creates without changing them. The password is never
+// part of the differences.
+func (a *Aurora) DriftDBCluster(ctx context.Context, identifier, username, password string) (Drift, error) {
+	cluster, err := a.findDBCluster(ctx, identifier)
+	if err != nil {
+		return Drift{}, err
+	}
+
+	if cluster == nil {
+		return Drift{Action: ActionCreate}, nil
+	}
+
+	var diffs differences
+	secret, err := a.secretsManagerClient.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(username),
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return Drift{}, err
+		}
+		diffs.compare("secret", username, missingValue)
+		return drift(diffs), nil
+	}
+
+	if aws.ToString(secret.SecretString) != password {
+		diffs.compare("secret.value", "<password from manifest>", "<different password>")
+	}
+
+	return drift(diffs), nil
+}
+
+// findDBCluster returns the database cluster with the given identifier or `nil` if there
+// is none. An existing cluster with a different engine is an error.
+func (a *Aurora) findDBCluster(ctx context.Context, identifier string) (*types.DBCluster, error) {
+	clusters, err := a.rdsClient.DescribeDBClusters(ctx, &rds.DescribeDBClustersInput{
+		DBClusterIdentifier: aws.String(identifier),
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	if len(clusters.DBClusters) == 0 {
+		return nil, nil
+	}
+
+	cluster := &clusters.DBClusters[0]
+	if engine := aws.ToString(cluster.Engine); engine != auroraEngine {
+		return nil, fmt.Errorf("cluster %s uses engine %s instead of %s: %w", identifier, engine, auroraEngine, ErrAlreadyExists)
+	}
+
+	return cluster, nil
+}
+
+// ensureSecret creates a secret with the given name and value if it does not exist yet.
+// The value of an existing secret is updated if it differs. It returns the ARN of the
+// secret.
+func (a *Aurora) ensureSecret(ctx context.Context, name, value string) (string, error) {
+	secret, err := a.secretsManagerClient.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return "", err
+		}
+
+		created, err := a.secretsManagerClient.CreateSecret(ctx, &secretsmanager.CreateSecretInput{
+			Name:         aws.String(name),
+			SecretString: aws.String(value),
+		})
+		if err != nil {
+			return "", err
+		}
+		return aws.ToString(created.ARN), nil
+	}
+
+	if aws.ToString(secret.SecretString) != value {
+		_, err := a.secretsManagerClient.PutSecretValue(ctx, &secretsmanager.PutSecretValueInput{
+			SecretId:     secret.ARN,
+			SecretString: aws.String(value),
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return aws.ToString(secret.ARN), nil
+}
+
+// GetDBCluster returns the database cluster with the given identifier. It returns
+// ErrNotFound if there is no such cluster.
+func (a *Aurora) GetDBCluster(ctx context.Context, clusterIdentifier string) (*types.DBCluster, error) {
+	clusters, err := a.rdsClient.DescribeDBClusters(ctx, &rds.DescribeDBClustersInput{
 		DBClusterIdentifier: aws.String(clusterIdentifier),
 	})
 	if err != nil {
@@ -77,15 +231,77 @@ func (a *Aurora) GetDBCluster(clusterIdentifier string) (*types.DBCluster, error
 	}
 
 	if len(clusters.DBClusters) == 0 {
-		return nil, nil
+		return nil, fmt.Errorf("cluster %s: %w", clusterIdentifier, ErrNotFound)
 	}
 
 	return &clusters.DBClusters[0], nil
 }
 
+// WaitUntilAvailable waits until the database cluster with the given identifier is
+// available. A cluster that failed is an error.
+func (a *Aurora) WaitUntilAvailable(ctx context.Context, identifier string, timeout time.Duration) error {
+	return wait(ctx, fmt.Sprintf("aurora cluster %s", identifier), timeout, func(ctx context.Context) (bool, string, error) {
+		clusters, err := a.rdsClient.DescribeDBClusters(ctx, &rds.DescribeDBClustersInput{
+			DBClusterIdentifier: aws.String(identifier),
+		})
+		if err != nil {
+			return false, "", err
+		}
+		if len(clusters.DBClusters) == 0 {
+			return false, "missing", nil
+		}
+
+		status := aws.ToString(clusters.DBClusters[0].Status)
+		if status == "failed" {
+			return false, "", errors.New("cluster failed")
+		}
+		return status == "available", status, nil
+	})
+}
+
+// DeleteDBCluster deletes the database cluster with the given identifier without
+// creating a final snapshot.
+func (a *Aurora) DeleteDBCluster(ctx context.Context, identifier string) error {
+	_, err := a.rdsClient.DeleteDBCluster(ctx, &rds.DeleteDBClusterInput{
+		DBClusterIdentifier: aws.String(identifier),
+		SkipFinalSnapshot:   true,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetSecretARN returns the ARN of the secret with the given name.
+func (a *Aurora) GetSecretARN(ctx context.Context, name string) (string, error) {
+	secret, err := a.secretsManagerClient.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(name),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.ToString(secret.ARN), nil
+}
+
+// DeleteSecret deletes the secret with the given name immediately without a recovery
+// window.
+func (a *Aurora) DeleteSecret(ctx context.Context, name string) error {
+	_, err := a.secretsManagerClient.DeleteSecret(ctx, &secretsmanager.DeleteSecretInput{
+		SecretId:                   aws.String(name),
+		ForceDeleteWithoutRecovery: aws.Bool(true),
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // ExecuteStatement executes the given SQL statement on the given database cluster.
-func (a *Aurora) ExecuteStatement(databaseName, clusterArn, secretArn, sql string) (*rdsdata.ExecuteStatementOutput, error) {
-	executeStatementOutput, err := a.rdsDataClient.ExecuteStatement(context.TODO(), &rdsdata.ExecuteStatementInput{
+func (a *Aurora) ExecuteStatement(ctx context.Context, databaseName, clusterArn, secretArn, sql string) (*rdsdata.ExecuteStatementOutput, error) {
+	executeStatementOutput, err := a.rdsDataClient.ExecuteStatement(ctx, &rdsdata.ExecuteStatementInput{
 		Database:              aws.String(databaseName),
 		ResourceArn:           aws.String(clusterArn),
 		SecretArn:             aws.String(secretArn),
@@ -98,3 +314,49 @@ func (a *Aurora) ExecuteStatement(databaseName, clusterArn, secretArn, sql strin
 
 	return executeStatementOutput, nil
 }
+
+// ExecuteTransaction executes the given SQL statements in order in a single transaction on
+// the given database cluster. If a statement fails, the transaction is rolled back and
+// none of the statements take effect.
+func (a *Aurora) ExecuteTransaction(ctx context.Context, databaseName, clusterArn, secretArn string, statements []string) error {
+	transaction, err := a.rdsDataClient.BeginTransaction(ctx, &rdsdata.BeginTransactionInput{
+		Database:    aws.String(databaseName),
+		ResourceArn: aws.String(clusterArn),
+		SecretArn:   aws.String(secretArn),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, sql := range statements {
+		_, err := a.rdsDataClient.ExecuteStatement(ctx, &rdsdata.ExecuteStatementInput{
+			Database:      aws.String(databaseName),
+			ResourceArn:   aws.String(clusterArn),
+			SecretArn:     aws.String(secretArn),
+			TransactionId: transaction.TransactionId,
+			Sql:           aws.String(sql),
+		})
+		if err != nil {
+			_, rollbackErr := a.rdsDataClient.RollbackTransaction(ctx, &rdsdata.RollbackTransactionInput{
+				ResourceArn:   aws.String(clusterArn),
+				SecretArn:     aws.String(secretArn),
+				TransactionId: transaction.TransactionId,
+			})
+			if rollbackErr != nil {
+				return errors.Join(err, fmt.Errorf("rolling back transaction: %w", rollbackErr))
+			}
+			return err
+		}
+	}
+
+	_, err = a.rdsDataClient.CommitTransaction(ctx, &rdsdata.CommitTransactionInput{
+		ResourceArn:   aws.String(clusterArn),
+		SecretArn:     aws.String(secretArn),
+		TransactionId: transaction.TransactionId,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
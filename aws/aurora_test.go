@@ -2,16 +2,22 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
 	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
 	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+	"github.com/aws/smithy-go"
 )
 
 type mockAurora struct {
 	createDBClusterFn    func(context.Context, *rds.CreateDBClusterInput, ...func(*rds.Options)) (*rds.CreateDBClusterOutput, error)
 	describeDBClustersFn func(context.Context, *rds.DescribeDBClustersInput, ...func(*rds.Options)) (*rds.DescribeDBClustersOutput, error)
+	deleteDBClusterFn    func(context.Context, *rds.DeleteDBClusterInput, ...func(*rds.Options)) (*rds.DeleteDBClusterOutput, error)
 }
 
 func (m *mockAurora) CreateDBCluster(ctx context.Context, params *rds.CreateDBClusterInput, optFns ...func(*rds.Options)) (*rds.CreateDBClusterOutput, error) {
@@ -22,22 +28,56 @@ func (m *mockAurora) DescribeDBClusters(ctx context.Context, params *rds.Describ
 	return m.describeDBClustersFn(ctx, params, optFns...)
 }
 
+func (m *mockAurora) DeleteDBCluster(ctx context.Context, params *rds.DeleteDBClusterInput, optFns ...func(*rds.Options)) (*rds.DeleteDBClusterOutput, error) {
+	return m.deleteDBClusterFn(ctx, params, optFns...)
+}
+
 type mockRDSData struct {
-	executeStatementFn func(context.Context, *rdsdata.ExecuteStatementInput, ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error)
+	executeStatementFn    func(context.Context, *rdsdata.ExecuteStatementInput, ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error)
+	beginTransactionFn    func(context.Context, *rdsdata.BeginTransactionInput, ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error)
+	commitTransactionFn   func(context.Context, *rdsdata.CommitTransactionInput, ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error)
+	rollbackTransactionFn func(context.Context, *rdsdata.RollbackTransactionInput, ...func(*rdsdata.Options)) (*rdsdata.RollbackTransactionOutput, error)
 }
 
 func (m *mockRDSData) ExecuteStatement(ctx context.Context, params *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
 	return m.executeStatementFn(ctx, params, optFns...)
 }
 
+func (m *mockRDSData) BeginTransaction(ctx context.Context, params *rdsdata.BeginTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error) {
+	return m.beginTransactionFn(ctx, params, optFns...)
+}
+
+func (m *mockRDSData) CommitTransaction(ctx context.Context, params *rdsdata.CommitTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error) {
+	return m.commitTransactionFn(ctx, params, optFns...)
+}
+
+func (m *mockRDSData) RollbackTransaction(ctx context.Context, params *rdsdata.RollbackTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.RollbackTransactionOutput, error) {
+	return m.rollbackTransactionFn(ctx, params, optFns...)
+}
+
 type mockSecretsManager struct {
-	createSecretFn func(context.Context, *secretsmanager.CreateSecretInput, ...func(*secretsmanager.Options)) (*secretsmanager.CreateSecretOutput, error)
+	createSecretFn   func(context.Context, *secretsmanager.CreateSecretInput, ...func(*secretsmanager.Options)) (*secretsmanager.CreateSecretOutput, error)
+	getSecretValueFn func(context.Context, *secretsmanager.GetSecretValueInput, ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error)
+	putSecretValueFn func(context.Context, *secretsmanager.PutSecretValueInput, ...func(*secretsmanager.Options)) (*secretsmanager.PutSecretValueOutput, error)
+	deleteSecretFn   func(context.Context, *secretsmanager.DeleteSecretInput, ...func(*secretsmanager.Options)) (*secretsmanager.DeleteSecretOutput, error)
 }
 
 func (m *mockSecretsManager) CreateSecret(ctx context.Context, params *secretsmanager.CreateSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.CreateSecretOutput, error) {
 	return m.createSecretFn(ctx, params, optFns...)
 }
 
+func (m *mockSecretsManager) GetSecretValue(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	return m.getSecretValueFn(ctx, params, optFns...)
+}
+
+func (m *mockSecretsManager) PutSecretValue(ctx context.Context, params *secretsmanager.PutSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.PutSecretValueOutput, error) {
+	return m.putSecretValueFn(ctx, params, optFns...)
+}
+
+func (m *mockSecretsManager) DeleteSecret(ctx context.Context, params *secretsmanager.DeleteSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.DeleteSecretOutput, error) {
+	return m.deleteSecretFn(ctx, params, optFns...)
+}
+
 func TestAurora_CreateDBCluster(t *testing.T) {
 	mockClient := &mockAurora{
 		createDBClusterFn: func(ctx context.Context, params *rds.CreateDBClusterInput, optFns ...func(*rds.Options)) (*rds.CreateDBClusterOutput, error) {
@@ -63,7 +103,7 @@ func TestAurora_CreateDBCluster(t *testing.T) {
 		secretsManagerClient: mockSecretsManager,
 	}
 
-	_, _, err := aurora.CreateDBCluster("identifier", "databaseName", "username", "password")
+	_, _, err := aurora.CreateDBCluster(context.Background(), "identifier", "databaseName", "username", "password")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -94,17 +134,62 @@ func TestAurora_ExecuteStatement(t *testing.T) {
 		secretsManagerClient: mockSecretsManager,
 	}
 
-	_, _, err := aurora.CreateDBCluster("identifier", "databaseName", "username", "password")
+	_, _, err := aurora.CreateDBCluster(context.Background(), "identifier", "databaseName", "username", "password")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
 
-	_, err = aurora.ExecuteStatement("databaseName", "clusterArn", "secretArn", "sql")
+	_, err = aurora.ExecuteStatement(context.Background(), "databaseName", "clusterArn", "secretArn", "sql")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
 }
 
+func TestAurora_ExecuteTransaction(t *testing.T) {
+	var executed []string
+	var committed, rolledBack bool
+	mockRDSDataClient := &mockRDSData{
+		beginTransactionFn: func(ctx context.Context, params *rdsdata.BeginTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error) {
+			return &rdsdata.BeginTransactionOutput{TransactionId: aws.String("tx")}, nil
+		},
+		executeStatementFn: func(ctx context.Context, params *rdsdata.ExecuteStatementInput, optFns ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+			if aws.ToString(params.TransactionId) != "tx" {
+				t.Errorf("expected transaction tx, got %q", aws.ToString(params.TransactionId))
+			}
+			if sql := aws.ToString(params.Sql); sql == "fail" {
+				return nil, errors.New("syntax error")
+			}
+			executed = append(executed, aws.ToString(params.Sql))
+			return &rdsdata.ExecuteStatementOutput{}, nil
+		},
+		commitTransactionFn: func(ctx context.Context, params *rdsdata.CommitTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error) {
+			committed = true
+			return &rdsdata.CommitTransactionOutput{}, nil
+		},
+		rollbackTransactionFn: func(ctx context.Context, params *rdsdata.RollbackTransactionInput, optFns ...func(*rdsdata.Options)) (*rdsdata.RollbackTransactionOutput, error) {
+			rolledBack = true
+			return &rdsdata.RollbackTransactionOutput{}, nil
+		},
+	}
+
+	aurora := &Aurora{rdsDataClient: mockRDSDataClient}
+
+	if err := aurora.ExecuteTransaction(context.Background(), "databaseName", "clusterArn", "secretArn", []string{"a", "b"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(executed) != 2 || !committed || rolledBack {
+		t.Errorf("expected both statements to be committed, got %v (committed %v, rolled back %v)", executed, committed, rolledBack)
+	}
+
+	executed, committed = nil, false
+	if err := aurora.ExecuteTransaction(context.Background(), "databaseName", "clusterArn", "secretArn", []string{"a", "fail", "b"}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(executed) != 1 || committed || !rolledBack {
+		t.Errorf("expected the transaction to be rolled back after the first statement, got %v (committed %v, rolled back %v)", executed, committed, rolledBack)
+	}
+}
+
 func TestAurora_GetDBCluster(t *testing.T) {
 	mockClient := &mockAurora{
 		createDBClusterFn: func(ctx context.Context, params *rds.CreateDBClusterInput, optFns ...func(*rds.Options)) (*rds.CreateDBClusterOutput, error) {
@@ -133,13 +218,193 @@ func TestAurora_GetDBCluster(t *testing.T) {
 		secretsManagerClient: mockSecretsManager,
 	}
 
-	_, _, err := aurora.CreateDBCluster("identifier", "databaseName", "username", "password")
+	_, _, err := aurora.CreateDBCluster(context.Background(), "identifier", "databaseName", "username", "password")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
 
-	_, err = aurora.GetDBCluster("identifier")
+	_, err = aurora.GetDBCluster(context.Background(), "identifier")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing cluster, got %v", err)
+	}
+}
+
+func TestAurora_EnsureDBCluster(t *testing.T) {
+	mockClient := &mockAurora{
+		describeDBClustersFn: func(ctx context.Context, params *rds.DescribeDBClustersInput, optFns ...func(*rds.Options)) (*rds.DescribeDBClustersOutput, error) {
+			return &rds.DescribeDBClustersOutput{
+				DBClusters: []types.DBCluster{
+					{
+						DBClusterIdentifier: params.DBClusterIdentifier,
+						Engine:              aws.String(auroraEngine),
+					},
+				},
+			}, nil
+		},
+	}
+
+	secretCreated := false
+	mockSecretsManager := &mockSecretsManager{
+		getSecretValueFn: func(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+			return nil, Classify(&smithy.GenericAPIError{Code: "ResourceNotFoundException"})
+		},
+		createSecretFn: func(ctx context.Context, params *secretsmanager.CreateSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.CreateSecretOutput, error) {
+			secretCreated = true
+			return &secretsmanager.CreateSecretOutput{
+				ARN: aws.String("secret-arn"),
+			}, nil
+		},
+	}
+
+	aurora := &Aurora{
+		rdsClient:            mockClient,
+		secretsManagerClient: mockSecretsManager,
+	}
+
+	cluster, secretArn, err := aurora.EnsureDBCluster(context.Background(), "identifier", "databaseName", "username", "password")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
+
+	if aws.ToString(cluster.DBClusterIdentifier) != "identifier" {
+		t.Errorf("unexpected cluster: %v", aws.ToString(cluster.DBClusterIdentifier))
+	}
+	if !secretCreated || secretArn != "secret-arn" {
+		t.Errorf("expected secret to be created, got arn: %s", secretArn)
+	}
+}
+
+func TestAurora_DeleteDBCluster(t *testing.T) {
+	mockClient := &mockAurora{
+		deleteDBClusterFn: func(ctx context.Context, params *rds.DeleteDBClusterInput, optFns ...func(*rds.Options)) (*rds.DeleteDBClusterOutput, error) {
+			if !params.SkipFinalSnapshot {
+				t.Errorf("expected final snapshot to be skipped")
+			}
+			return &rds.DeleteDBClusterOutput{}, nil
+		},
+	}
+
+	mockSecretsManager := &mockSecretsManager{
+		deleteSecretFn: func(ctx context.Context, params *secretsmanager.DeleteSecretInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.DeleteSecretOutput, error) {
+			if aws.ToString(params.SecretId) != "username" {
+				t.Errorf("unexpected secret id: %s", aws.ToString(params.SecretId))
+			}
+			return &secretsmanager.DeleteSecretOutput{}, nil
+		},
+	}
+
+	aurora := &Aurora{
+		rdsClient:            mockClient,
+		secretsManagerClient: mockSecretsManager,
+	}
+
+	if err := aurora.DeleteDBCluster(context.Background(), "identifier"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := aurora.DeleteSecret(context.Background(), "username"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestAurora_PlanDBCluster(t *testing.T) {
+	mockClient := &mockAurora{
+		describeDBClustersFn: func(ctx context.Context, params *rds.DescribeDBClustersInput, optFns ...func(*rds.Options)) (*rds.DescribeDBClustersOutput, error) {
+			if aws.ToString(params.DBClusterIdentifier) != "identifier" {
+				return nil, Classify(&smithy.GenericAPIError{Code: "DBClusterNotFoundFault"})
+			}
+			return &rds.DescribeDBClustersOutput{
+				DBClusters: []types.DBCluster{
+					{Engine: aws.String(auroraEngine)},
+				},
+			}, nil
+		},
+	}
+
+	mockSecretsManager := &mockSecretsManager{
+		getSecretValueFn: func(ctx context.Context, params *secretsmanager.GetSecretValueInput, optFns ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+			return &secretsmanager.GetSecretValueOutput{
+				SecretString: aws.String("password"),
+			}, nil
+		},
+	}
+
+	aurora := &Aurora{
+		rdsClient:            mockClient,
+		secretsManagerClient: mockSecretsManager,
+	}
+
+	action, err := aurora.PlanDBCluster(context.Background(), "identifier", "username", "password")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if action != ActionNone {
+		t.Errorf("unexpected action for unchanged cluster: %s", action)
+	}
+
+	action, err = aurora.PlanDBCluster(context.Background(), "identifier", "username", "new-password")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if action != ActionUpdate {
+		t.Errorf("unexpected action for changed password: %s", action)
+	}
+
+	action, err = aurora.PlanDBCluster(context.Background(), "missing", "username", "password")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if action != ActionCreate {
+		t.Errorf("unexpected action for missing cluster: %s", action)
+	}
+}
+
+func TestAurora_WaitUntilAvailable(t *testing.T) {
+	setWaitDelay(t)
+
+	calls := 0
+	mockClient := &mockAurora{
+		describeDBClustersFn: func(ctx context.Context, params *rds.DescribeDBClustersInput, optFns ...func(*rds.Options)) (*rds.DescribeDBClustersOutput, error) {
+			calls++
+			status := "creating"
+			if calls == 2 {
+				status = "available"
+			}
+			return &rds.DescribeDBClustersOutput{
+				DBClusters: []types.DBCluster{{Status: aws.String(status)}},
+			}, nil
+		},
+	}
+
+	aurora := &Aurora{
+		rdsClient: mockClient,
+	}
+
+	err := aurora.WaitUntilAvailable(context.Background(), "identifier", time.Second)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestAurora_WaitUntilAvailable_Failed(t *testing.T) {
+	setWaitDelay(t)
+
+	mockClient := &mockAurora{
+		describeDBClustersFn: func(ctx context.Context, params *rds.DescribeDBClustersInput, optFns ...func(*rds.Options)) (*rds.DescribeDBClustersOutput, error) {
+			return &rds.DescribeDBClustersOutput{
+				DBClusters: []types.DBCluster{{Status: aws.String("failed")}},
+			}, nil
+		},
+	}
+
+	aurora := &Aurora{
+		rdsClient: mockClient,
+	}
+
+	err := aurora.WaitUntilAvailable(context.Background(), "identifier", time.Second)
+	if err == nil || errors.Is(err, ErrWaitTimeout) {
+		t.Errorf("expected failed cluster error, got %v", err)
+	}
 }
@@ -21,15 +21,21 @@ type CloudWatch struct {
 // NewCloudWatch creates a new CloudWatch client with the given configuration.
 func NewCloudWatch(config aws.Config) *CloudWatch {
 	return &CloudWatch{
-		client: cloudwatch.NewFromConfig(config),
+		client: cloudwatch.NewFromConfig(clientConfig(config)),
 	}
 }
 
+// NewCloudWatchFromClient creates a new CloudWatch wrapper around the given client, e.g. a
+// fake of the `awsfake` package.
+func NewCloudWatchFromClient(client cloudWatchAPI) *CloudWatch {
+	return &CloudWatch{client: client}
+}
+
 // PutMetricAlarm creates a CloudWatch alarm with the given name, metric name and namespace.
 // The alarm is set to evaluate the metric every `30` seconds and the alarm will be
 // triggered if the metric value is less than `1.0` for `1` evaluation period.
-func (c *CloudWatch) PutMetricAlarm(alarmName, metricName, namespace string) error {
-	_, err := c.client.PutMetricAlarm(context.TODO(), &cloudwatch.PutMetricAlarmInput{
+func (c *CloudWatch) PutMetricAlarm(ctx context.Context, alarmName, metricName, namespace string) error {
+	_, err := c.client.PutMetricAlarm(ctx, &cloudwatch.PutMetricAlarmInput{
 		AlarmName:          aws.String(alarmName),
 		MetricName:         aws.String(metricName),
 		Namespace:          aws.String(namespace),
@@ -48,8 +54,8 @@ func (c *CloudWatch) PutMetricAlarm(alarmName, metricName, namespace string) err
 }
 
 // PutMetricData puts a metric into CloudWatch with the given name and namespace.
-func (c *CloudWatch) PutMetricData(metricName, namespace string, value float64) error {
-	_, err := c.client.PutMetricData(context.TODO(), &cloudwatch.PutMetricDataInput{
+func (c *CloudWatch) PutMetricData(ctx context.Context, metricName, namespace string, value float64) error {
+	_, err := c.client.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
 		Namespace: aws.String(namespace),
 		MetricData: []types.MetricDatum{
 			{
@@ -65,3 +71,27 @@ func (c *CloudWatch) PutMetricData(metricName, namespace string, value float64)
 
 	return nil
 }
+
+// maxMetricData is the maximum number of metric data of a single PutMetricData call.
+const maxMetricData = 1000
+
+// PutMetrics puts the given metric data into the given namespace of CloudWatch. The data
+// is split into as many calls as needed.
+func (c *CloudWatch) PutMetrics(ctx context.Context, namespace string, data []types.MetricDatum) error {
+	for start := 0; start < len(data); start += maxMetricData {
+		end := start + maxMetricData
+		if end > len(data) {
+			end = len(data)
+		}
+
+		_, err := c.client.PutMetricData(ctx, &cloudwatch.PutMetricDataInput{
+			Namespace:  aws.String(namespace),
+			MetricData: data[start:end],
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -31,7 +31,7 @@ func TestCloudWatch_PutMetricAlarm(t *testing.T) {
 		client: mockClient,
 	}
 
-	err := cw.PutMetricAlarm("test", "test", "test")
+	err := cw.PutMetricAlarm(context.Background(), "test", "test", "test")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -48,7 +48,7 @@ func TestCloudWatch_PutMetricData(t *testing.T) {
 		client: mockClient,
 	}
 
-	err := cw.PutMetricData("test", "test", 1.0)
+	err := cw.PutMetricData(context.Background(), "test", "test", 1.0)
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
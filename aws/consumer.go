@@ -0,0 +1,534 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+)
+
+// shardEnd is the checkpoint of a shard whose records were all processed. The children of
+// a shard are only processed once their parents reached the end.
+const shardEnd = "SHARD_END"
+
+// leaseTableTimeout is the maximum time to wait for a created lease table to be active.
+const leaseTableTimeout = 5 * time.Minute
+
+// errLeaseLost means that another worker took the lease of a shard.
+var errLeaseLost = errors.New("lease lost")
+
+// ConsumerConfig describes how a Consumer reads the shards of a stream and shares them with
+// the other workers of the same lease table.
+type ConsumerConfig struct {
+	// LeaseTable is the name of the DynamoDB table with the leases and the checkpoints of
+	// the shards. It is created if it does not exist. Empty means `<stream>-leases`.
+	LeaseTable string
+	// WorkerID identifies the worker among the workers that share the lease table. Empty
+	// means the host name and the process ID with a random suffix.
+	WorkerID string
+	// LeaseDuration is the time after which the lease of a worker that did not renew it
+	// is taken over by another worker. Leases are renewed every third of it.
+	LeaseDuration time.Duration
+	// PollInterval is the time to wait before reading a shard again that had no new
+	// records.
+	PollInterval time.Duration
+	// MaxRecords is the maximum number of records of a batch.
+	MaxRecords int32
+	// StartPosition is where shards without a checkpoint are read from, either
+	// TRIM_HORIZON or LATEST.
+	StartPosition types.ShardIteratorType
+	// ConsumerARN is the ARN of a registered enhanced fan-out consumer of the stream, see
+	// Kinesis.RegisterConsumer. Shards are read through its subscriptions, which push the
+	// records with a throughput of their own, instead of being polled with GetRecords.
+	ConsumerARN string
+}
+
+// DefaultConsumerConfig is the configuration of a consumer whose configuration leaves
+// fields empty.
+var DefaultConsumerConfig = ConsumerConfig{
+	LeaseDuration: 30 * time.Second,
+	PollInterval:  time.Second,
+	MaxRecords:    1000,
+	StartPosition: types.ShardIteratorTypeTrimHorizon,
+}
+
+// withDefaults returns the configuration of a consumer of the given stream with the
+// defaults of its empty fields.
+func (c ConsumerConfig) withDefaults(stream string) ConsumerConfig {
+	if c.LeaseTable == "" {
+		c.LeaseTable = stream + "-leases"
+	}
+	if c.WorkerID == "" {
+		hostname, _ := os.Hostname()
+		c.WorkerID = fmt.Sprintf("%s-%d-%08x", hostname, os.Getpid(), rand.Uint32())
+	}
+	if c.LeaseDuration <= 0 {
+		c.LeaseDuration = DefaultConsumerConfig.LeaseDuration
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = DefaultConsumerConfig.PollInterval
+	}
+	if c.MaxRecords <= 0 {
+		c.MaxRecords = DefaultConsumerConfig.MaxRecords
+	}
+	if c.StartPosition == "" {
+		c.StartPosition = DefaultConsumerConfig.StartPosition
+	}
+	return c
+}
+
+// Batch is a batch of records of a shard.
+type Batch struct {
+	// ShardID is the ID of the shard of the records.
+	ShardID string
+	// Records are the records in the order of their sequence numbers.
+	Records []types.Record
+	// MillisBehindLatest is how far the records are behind the latest record of the shard.
+	MillisBehindLatest int64
+}
+
+// lease is an item of the lease table, which records the worker that reads a shard and
+// how far it got. Every change increments the counter, so changes are only written if no
+// other worker changed the lease in between.
+type lease struct {
+	ShardID string `dynamodbav:"id"`
+	Owner   string `dynamodbav:"owner"`
+	Counter int64  `dynamodbav:"counter"`
+	// Expires is the time in Unix milliseconds until which the owner holds the lease.
+	Expires    int64    `dynamodbav:"expires"`
+	Checkpoint string   `dynamodbav:"checkpoint"`
+	Parents    []string `dynamodbav:"parents,omitempty"`
+}
+
+// expired reports whether the lease is not held by any worker at the given time.
+func (l lease) expired(now time.Time) bool {
+	return l.Owner == "" || l.Expires < now.UnixMilli()
+}
+
+// ownedLease is a lease that is held by the worker and the reader of its shard.
+type ownedLease struct {
+	mu     sync.Mutex
+	lease  lease
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Consumer reads the records of every shard of a Kinesis stream and checkpoints their
+// sequence numbers in a DynamoDB lease table. Workers that share the lease table share
+// the shards: every worker takes leases until it holds its share of the shards and takes
+// over the leases of workers that stopped renewing them. The records of a shard are
+// processed at least once, in order, and only after the records of its parents, so
+// splitting and merging shards keeps the order of a partition key.
+type Consumer struct {
+	kinesis  kinesisAPI
+	dynamoDB *DynamoDB
+	stream   string
+	config   ConsumerConfig
+}
+
+// NewConsumer returns a consumer of the Kinesis stream with the given name that keeps its
+// leases in a table of the given DynamoDB wrapper. Empty fields of the configuration are
+// taken from DefaultConsumerConfig.
+func (k *Kinesis) NewConsumer(stream string, dynamoDB *DynamoDB, config ConsumerConfig) *Consumer {
+	return &Consumer{
+		kinesis:  k.client,
+		dynamoDB: dynamoDB,
+		stream:   stream,
+		config:   config.withDefaults(stream),
+	}
+}
+
+// WorkerID returns the ID of the worker in the lease table.
+func (c *Consumer) WorkerID() string {
+	return c.config.WorkerID
+}
+
+// Run reads the shards the worker holds leases for until the given context is done or the
+// handler fails. The handler is called with the batches of several shards at the same
+// time, and the records of a batch are checkpointed once it returns. Run releases the
+// leases before it returns, so other workers take them over at once.
+func (c *Consumer) Run(ctx context.Context, handler func(context.Context, Batch) error) error {
+	if err := c.dynamoDB.EnsureTable(ctx, c.config.LeaseTable); err != nil {
+		return fmt.Errorf("creating lease table %s: %w", c.config.LeaseTable, err)
+	}
+	if err := c.dynamoDB.WaitUntilActive(ctx, c.config.LeaseTable, leaseTableTimeout); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	errs := make(chan error, 1)
+	owned := map[string]*ownedLease{}
+	defer func() {
+		cancel()
+		c.release(owned)
+	}()
+
+	ticker := time.NewTicker(c.config.LeaseDuration / 3)
+	defer ticker.Stop()
+	for {
+		if err := c.balance(ctx, owned, func(ctx context.Context, o *ownedLease) { go c.read(ctx, o, handler, errs) }); err != nil && ctx.Err() == nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errs:
+			return err
+		case <-ticker.C:
+		}
+	}
+}
+
+// balance creates the leases of new shards, renews the leases of the worker and takes the
+// leases the worker needs for its share of the shards. The given function starts reading
+// a taken shard until the given context is done.
+func (c *Consumer) balance(ctx context.Context, owned map[string]*ownedLease, start func(context.Context, *ownedLease)) error {
+	for id, o := range owned {
+		select {
+		case <-o.done:
+			delete(owned, id)
+		default:
+		}
+	}
+
+	shards, err := listShards(ctx, c.kinesis, c.stream)
+	if err != nil {
+		return err
+	}
+	leases, err := c.listLeases(ctx)
+	if err != nil {
+		return err
+	}
+	for _, shard := range shards {
+		id := aws.ToString(shard.ShardId)
+		if _, ok := leases[id]; ok {
+			continue
+		}
+		l := lease{ShardID: id}
+		for _, parent := range []*string{shard.ParentShardId, shard.AdjacentParentShardId} {
+			if parent != nil {
+				l.Parents = append(l.Parents, *parent)
+			}
+		}
+		if err := c.writeLease(ctx, l, nil); err != nil && !errors.Is(err, errLeaseLost) {
+			return err
+		}
+		leases[id] = l
+	}
+
+	now := time.Now()
+	for id, o := range owned {
+		if err := c.renew(ctx, o, now); err != nil {
+			if !errors.Is(err, errLeaseLost) {
+				return err
+			}
+			o.cancel()
+			delete(owned, id)
+			continue
+		}
+		o.mu.Lock()
+		leases[id] = o.lease
+		o.mu.Unlock()
+	}
+
+	// The share of the worker are the readable shards divided by the workers that hold
+	// leases, rounded up.
+	var readable []lease
+	workers := map[string]int{c.config.WorkerID: len(owned)}
+	for _, l := range leases {
+		if !readableLease(l, leases) {
+			continue
+		}
+		readable = append(readable, l)
+		if !l.expired(now) && l.Owner != c.config.WorkerID {
+			workers[l.Owner]++
+		}
+	}
+	sort.Slice(readable, func(i, j int) bool { return readable[i].ShardID < readable[j].ShardID })
+	share := (len(readable) + len(workers) - 1) / len(workers)
+
+	for _, l := range readable {
+		if len(owned) >= share {
+			return nil
+		}
+		if _, ok := owned[l.ShardID]; ok || !l.expired(now) {
+			continue
+		}
+		if err := c.take(ctx, l, owned, start, now); err != nil {
+			return err
+		}
+	}
+
+	// Without free leases, a lease is taken over from the worker with the most leases if
+	// it holds more than its share.
+	busiest := ""
+	for worker, count := range workers {
+		if worker != c.config.WorkerID && count > share && (busiest == "" || count > workers[busiest]) {
+			busiest = worker
+		}
+	}
+	if len(owned) >= share || busiest == "" {
+		return nil
+	}
+	for _, l := range readable {
+		if l.Owner == busiest && !l.expired(now) {
+			return c.take(ctx, l, owned, start, now)
+		}
+	}
+	return nil
+}
+
+// readableLease reports whether the shard of the given lease can be read, which is the
+// case if it did not reach its end and its parents did or are not in the lease table.
+func readableLease(l lease, leases map[string]lease) bool {
+	if l.Checkpoint == shardEnd {
+		return false
+	}
+	for _, parent := range l.Parents {
+		if p, ok := leases[parent]; ok && p.Checkpoint != shardEnd {
+			return false
+		}
+	}
+	return true
+}
+
+// take takes the given lease for the worker and starts reading its shard. A lease that
+// another worker changed in the meantime is left to it.
+func (c *Consumer) take(ctx context.Context, l lease, owned map[string]*ownedLease, start func(context.Context, *ownedLease), now time.Time) error {
+	taken := l
+	taken.Owner = c.config.WorkerID
+	taken.Counter++
+	taken.Expires = now.Add(c.config.LeaseDuration).UnixMilli()
+	if err := c.writeLease(ctx, taken, &l.Counter); err != nil {
+		if errors.Is(err, errLeaseLost) {
+			return nil
+		}
+		return err
+	}
+
+	readCtx, cancel := context.WithCancel(ctx)
+	o := &ownedLease{lease: taken, cancel: cancel, done: make(chan struct{})}
+	owned[l.ShardID] = o
+	start(readCtx, o)
+	return nil
+}
+
+// renew extends the lease of the worker.
+func (c *Consumer) renew(ctx context.Context, o *ownedLease, now time.Time) error {
+	return c.updateLease(ctx, o, func(l *lease) {
+		l.Expires = now.Add(c.config.LeaseDuration).UnixMilli()
+	})
+}
+
+// checkpoint records the given sequence number as the checkpoint of the lease.
+func (c *Consumer) checkpoint(ctx context.Context, o *ownedLease, sequenceNumber string) error {
+	return c.updateLease(ctx, o, func(l *lease) {
+		l.Checkpoint = sequenceNumber
+	})
+}
+
+// updateLease writes the given change of the lease of the worker.
+func (c *Consumer) updateLease(ctx context.Context, o *ownedLease, change func(*lease)) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	updated := o.lease
+	change(&updated)
+	updated.Counter++
+	if err := c.writeLease(ctx, updated, &o.lease.Counter); err != nil {
+		return err
+	}
+	o.lease = updated
+	return nil
+}
+
+// release gives up the leases of the worker once their readers stopped, so other workers
+// do not wait for them to expire.
+func (c *Consumer) release(owned map[string]*ownedLease) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.LeaseDuration)
+	defer cancel()
+
+	for _, o := range owned {
+		o.cancel()
+		<-o.done
+		_ = c.updateLease(ctx, o, func(l *lease) {
+			l.Owner = ""
+			l.Expires = 0
+		})
+	}
+}
+
+// read reads the shard of the lease from its checkpoint and hands the batches to the
+// handler until the shard ends, the lease is lost or the context is done. Errors are sent
+// to the given channel.
+func (c *Consumer) read(ctx context.Context, o *ownedLease, handler func(context.Context, Batch) error, errs chan<- error) {
+	defer close(o.done)
+
+	read := c.poll
+	if c.config.ConsumerARN != "" {
+		read = c.subscribe
+	}
+	err := read(ctx, o, handler)
+	if err == nil || ctx.Err() != nil || errors.Is(err, errLeaseLost) {
+		return
+	}
+	select {
+	case errs <- err:
+	default:
+	}
+}
+
+// poll reads the shard of the lease from its checkpoint with GetRecords until the shard
+// ends, the lease is lost or the context is done.
+func (c *Consumer) poll(ctx context.Context, o *ownedLease, handler func(context.Context, Batch) error) error {
+	o.mu.Lock()
+	shardID, checkpoint := o.lease.ShardID, o.lease.Checkpoint
+	o.mu.Unlock()
+
+	iterator, err := c.shardIterator(ctx, shardID, checkpoint)
+	for err == nil {
+		var output *kinesis.GetRecordsOutput
+		output, err = c.kinesis.GetRecords(ctx, &kinesis.GetRecordsInput{
+			ShardIterator: iterator,
+			Limit:         aws.Int32(c.config.MaxRecords),
+		})
+		if hasErrorCode(err, "ExpiredIteratorException") {
+			iterator, err = c.shardIterator(ctx, shardID, checkpoint)
+			continue
+		}
+		if err != nil {
+			break
+		}
+
+		if records := output.Records; len(records) > 0 {
+			batch := Batch{ShardID: shardID, Records: records, MillisBehindLatest: aws.ToInt64(output.MillisBehindLatest)}
+			if err = c.handle(ctx, o, batch, handler); err != nil {
+				break
+			}
+			checkpoint = aws.ToString(records[len(records)-1].SequenceNumber)
+		}
+
+		// A closed shard ends once its records are read, and its children are read next.
+		if output.NextShardIterator == nil {
+			return c.end(ctx, o)
+		}
+		iterator = output.NextShardIterator
+
+		if len(output.Records) == 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(c.config.PollInterval):
+			}
+		}
+	}
+	return err
+}
+
+// handle hands the batch to the handler and checkpoints its last record once the handler
+// returns.
+func (c *Consumer) handle(ctx context.Context, o *ownedLease, batch Batch, handler func(context.Context, Batch) error) error {
+	if err := handler(ctx, batch); err != nil {
+		return fmt.Errorf("handling records of shard %s: %w", batch.ShardID, err)
+	}
+
+	return c.checkpoint(ctx, o, aws.ToString(batch.Records[len(batch.Records)-1].SequenceNumber))
+}
+
+// end checkpoints the end of the shard of the lease and releases the lease, so the
+// children of the shard are read next.
+func (c *Consumer) end(ctx context.Context, o *ownedLease) error {
+	return c.updateLease(ctx, o, func(l *lease) {
+		l.Checkpoint = shardEnd
+		l.Owner = ""
+		l.Expires = 0
+	})
+}
+
+// shardIterator returns an iterator of the shard after the given checkpoint or from the
+// start position of the consumer if there is no checkpoint.
+func (c *Consumer) shardIterator(ctx context.Context, shardID, checkpoint string) (*string, error) {
+	input := &kinesis.GetShardIteratorInput{
+		StreamName:        aws.String(c.stream),
+		ShardId:           aws.String(shardID),
+		ShardIteratorType: c.config.StartPosition,
+	}
+	if checkpoint != "" {
+		input.ShardIteratorType = types.ShardIteratorTypeAfterSequenceNumber
+		input.StartingSequenceNumber = aws.String(checkpoint)
+	}
+
+	output, err := c.kinesis.GetShardIterator(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return output.ShardIterator, nil
+}
+
+// listLeases returns the leases of the lease table by the IDs of their shards.
+func (c *Consumer) listLeases(ctx context.Context) (map[string]lease, error) {
+	leases := map[string]lease{}
+	input := &dynamodb.ExecuteStatementInput{
+		Statement: aws.String(fmt.Sprintf(`SELECT * FROM "%s"`, c.config.LeaseTable)),
+	}
+	for {
+		output, err := c.dynamoDB.client.ExecuteStatement(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range output.Items {
+			var l lease
+			if err := attributevalue.UnmarshalMap(item, &l); err != nil {
+				return nil, fmt.Errorf("reading lease: %w", err)
+			}
+			leases[l.ShardID] = l
+		}
+		if output.NextToken == nil {
+			return leases, nil
+		}
+		input.NextToken = output.NextToken
+	}
+}
+
+// writeLease writes the given lease if the lease in the table has the given counter or if
+// there is no lease of its shard yet if the counter is nil. It returns errLeaseLost if
+// the lease in the table is different.
+func (c *Consumer) writeLease(ctx context.Context, l lease, counter *int64) error {
+	item, err := attributevalue.MarshalMap(l)
+	if err != nil {
+		return err
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName:           aws.String(c.config.LeaseTable),
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(id)"),
+	}
+	if counter != nil {
+		input.ConditionExpression = aws.String("#counter = :counter")
+		input.ExpressionAttributeNames = map[string]string{"#counter": "counter"}
+		input.ExpressionAttributeValues = map[string]dynamodbtypes.AttributeValue{
+			":counter": &dynamodbtypes.AttributeValueMemberN{Value: fmt.Sprint(*counter)},
+		}
+	}
+
+	if _, err := c.dynamoDB.client.PutItem(ctx, input); err != nil {
+		if hasErrorCode(err, "ConditionalCheckFailedException") {
+			return fmt.Errorf("lease of shard %s: %w", l.ShardID, errLeaseLost)
+		}
+		return err
+	}
+	return nil
+}
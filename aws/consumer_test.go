@@ -0,0 +1,224 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodbtypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"github.com/aws/smithy-go"
+)
+
+// newTestConsumer returns a consumer of worker `a` of a stream with the given shards whose
+// lease table is the given map. Writes of the leases check their counters like DynamoDB.
+func newTestConsumer(t *testing.T, shards []types.Shard, leases map[string]lease) *Consumer {
+	kinesisClient := &mockKinesisClient{
+		listShardsFunc: func(ctx context.Context, params *kinesis.ListShardsInput, optFns ...func(*kinesis.Options)) (*kinesis.ListShardsOutput, error) {
+			return &kinesis.ListShardsOutput{Shards: shards}, nil
+		},
+	}
+	dynamoDBClient := &mockDynamoDBClient{
+		executeStatementFunc: func(ctx context.Context, params *dynamodb.ExecuteStatementInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ExecuteStatementOutput, error) {
+			output := &dynamodb.ExecuteStatementOutput{}
+			for _, l := range leases {
+				item, err := attributevalue.MarshalMap(l)
+				if err != nil {
+					t.Fatal(err)
+				}
+				output.Items = append(output.Items, item)
+			}
+			return output, nil
+		},
+		putItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+			var l lease
+			if err := attributevalue.UnmarshalMap(params.Item, &l); err != nil {
+				t.Fatal(err)
+			}
+
+			existing, ok := leases[l.ShardID]
+			conflict := ok
+			if counter, isCounter := params.ExpressionAttributeValues[":counter"].(*dynamodbtypes.AttributeValueMemberN); isCounter {
+				conflict = !ok || fmt.Sprint(existing.Counter) != counter.Value
+			}
+			if conflict {
+				return nil, &smithy.GenericAPIError{Code: "ConditionalCheckFailedException"}
+			}
+
+			leases[l.ShardID] = l
+			return &dynamodb.PutItemOutput{}, nil
+		},
+	}
+
+	return &Consumer{
+		kinesis:  kinesisClient,
+		dynamoDB: &DynamoDB{client: dynamoDBClient},
+		stream:   "test-stream",
+		config:   ConsumerConfig{WorkerID: "a", LeaseDuration: time.Minute}.withDefaults("test-stream"),
+	}
+}
+
+// shard returns a shard with the given ID and parents.
+func shard(id string, parents ...string) types.Shard {
+	s := types.Shard{ShardId: aws.String(id)}
+	if len(parents) > 0 {
+		s.ParentShardId = aws.String(parents[0])
+	}
+	if len(parents) > 1 {
+		s.AdjacentParentShardId = aws.String(parents[1])
+	}
+	return s
+}
+
+// balance balances the leases of the consumer and returns the IDs of the shards that it
+// started reading.
+func balance(t *testing.T, c *Consumer, owned map[string]*ownedLease) []string {
+	t.Helper()
+
+	var started []string
+	err := c.balance(context.Background(), owned, func(ctx context.Context, o *ownedLease) {
+		started = append(started, o.lease.ShardID)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sort.Strings(started)
+	return started
+}
+
+func TestConsumer_Balance_TakesFreeLeases(t *testing.T) {
+	leases := map[string]lease{}
+	c := newTestConsumer(t, []types.Shard{shard("shard-0"), shard("shard-1")}, leases)
+
+	owned := map[string]*ownedLease{}
+	if started := balance(t, c, owned); !reflect.DeepEqual(started, []string{"shard-0", "shard-1"}) {
+		t.Fatalf("expected both shards to be read, got %v", started)
+	}
+	for _, id := range []string{"shard-0", "shard-1"} {
+		if l := leases[id]; l.Owner != "a" || l.Counter != 1 {
+			t.Errorf("expected lease %s to be taken by a, got %+v", id, l)
+		}
+	}
+}
+
+func TestConsumer_Balance_StealsFromBusiestWorker(t *testing.T) {
+	expires := time.Now().Add(time.Minute).UnixMilli()
+	leases := map[string]lease{}
+	var shards []types.Shard
+	for i := 0; i < 4; i++ {
+		id := fmt.Sprintf("shard-%d", i)
+		shards = append(shards, shard(id))
+		leases[id] = lease{ShardID: id, Owner: "b", Counter: 3, Expires: expires}
+	}
+	c := newTestConsumer(t, shards, leases)
+
+	// The share of each of the two workers is two shards, and a lease is taken over per
+	// balance.
+	owned := map[string]*ownedLease{}
+	if started := balance(t, c, owned); len(started) != 1 {
+		t.Fatalf("expected one lease to be taken over, got %v", started)
+	}
+	if started := balance(t, c, owned); len(started) != 1 {
+		t.Fatalf("expected another lease to be taken over, got %v", started)
+	}
+	if started := balance(t, c, owned); len(started) != 0 {
+		t.Fatalf("expected no lease beyond the share to be taken over, got %v", started)
+	}
+
+	owners := map[string]int{}
+	for _, l := range leases {
+		owners[l.Owner]++
+	}
+	if !reflect.DeepEqual(owners, map[string]int{"a": 2, "b": 2}) {
+		t.Errorf("expected the leases to be shared equally, got %v", owners)
+	}
+}
+
+func TestConsumer_Balance_ReadsChildrenAfterParents(t *testing.T) {
+	leases := map[string]lease{}
+	c := newTestConsumer(t, []types.Shard{
+		shard("shard-0"),
+		shard("shard-1"),
+		shard("shard-2", "shard-0", "shard-1"),
+	}, leases)
+
+	owned := map[string]*ownedLease{}
+	if started := balance(t, c, owned); !reflect.DeepEqual(started, []string{"shard-0", "shard-1"}) {
+		t.Fatalf("expected only the parents to be read, got %v", started)
+	}
+	if got := leases["shard-2"].Parents; !reflect.DeepEqual(got, []string{"shard-0", "shard-1"}) {
+		t.Errorf("expected the lease of the child to record its parents, got %v", got)
+	}
+
+	// The child is not read until both parents reached their end.
+	if err := c.end(context.Background(), owned["shard-0"]); err != nil {
+		t.Fatal(err)
+	}
+	close(owned["shard-0"].done)
+	if started := balance(t, c, owned); len(started) != 0 {
+		t.Fatalf("expected the child to wait for its other parent, got %v", started)
+	}
+
+	if err := c.end(context.Background(), owned["shard-1"]); err != nil {
+		t.Fatal(err)
+	}
+	close(owned["shard-1"].done)
+	if started := balance(t, c, owned); !reflect.DeepEqual(started, []string{"shard-2"}) {
+		t.Fatalf("expected the child to be read, got %v", started)
+	}
+}
+
+func TestConsumer_Balance_LeaseConflicts(t *testing.T) {
+	leases := map[string]lease{}
+	c := newTestConsumer(t, []types.Shard{shard("shard-0")}, leases)
+
+	owned := map[string]*ownedLease{}
+	if started := balance(t, c, owned); len(started) != 1 {
+		t.Fatalf("expected the shard to be read, got %v", started)
+	}
+	cancelled := false
+	owned["shard-0"].cancel = func() { cancelled = true }
+
+	// Another worker took the lease in the meantime, so renewing it fails and the worker
+	// stops reading the shard without taking the lease back.
+	taken := leases["shard-0"]
+	taken.Owner = "b"
+	taken.Counter++
+	taken.Expires = time.Now().Add(time.Minute).UnixMilli()
+	leases["shard-0"] = taken
+
+	if started := balance(t, c, owned); len(started) != 0 {
+		t.Fatalf("expected no shard to be read, got %v", started)
+	}
+	if !cancelled || len(owned) != 0 {
+		t.Errorf("expected the lost lease to stop being read, got %v", owned)
+	}
+	if l := leases["shard-0"]; !reflect.DeepEqual(l, taken) {
+		t.Errorf("expected the lease of the other worker to be kept, got %+v", l)
+	}
+}
+
+func TestConsumer_Take_Conflict(t *testing.T) {
+	leases := map[string]lease{"shard-0": {ShardID: "shard-0", Counter: 2}}
+	c := newTestConsumer(t, nil, leases)
+
+	// The lease was read with an older counter, e.g. before another worker took it.
+	stale := lease{ShardID: "shard-0", Counter: 1}
+	owned := map[string]*ownedLease{}
+	err := c.take(context.Background(), stale, owned, func(context.Context, *ownedLease) {
+		t.Error("expected the shard not to be read")
+	}, time.Now())
+	if err != nil {
+		t.Fatalf("expected a conflict to leave the lease to the other worker, got %v", err)
+	}
+	if len(owned) != 0 || leases["shard-0"].Owner != "" {
+		t.Errorf("expected the lease not to be taken, got %v, %+v", owned, leases["shard-0"])
+	}
+}
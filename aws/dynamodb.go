@@ -2,7 +2,9 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
@@ -27,28 +29,141 @@ type DynamoDB struct {
 // NewDynamoDB creates a new DynamoDB client with the given configuration.
 func NewDynamoDB(config aws.Config) *DynamoDB {
 	return &DynamoDB{
-		client: dynamodb.NewFromConfig(config.Copy()),
+		client: dynamodb.NewFromConfig(clientConfig(config)),
 	}
 }
 
-// CreateTable creates a DynamoDB table with the given name. This function assumes that
-// the table has a primary key called `id` of type `string`.
-func (d *DynamoDB) CreateTable(name string) error {
-	_, err := d.client.CreateTable(context.TODO(), &dynamodb.CreateTableInput{
-		TableName: aws.String(name),
-		AttributeDefinitions: []types.AttributeDefinition{
-			{
-				AttributeName: aws.String("id"),
-				AttributeType: types.ScalarAttributeTypeS,
-			},
-		},
+// NewDynamoDBFromClient creates a new DynamoDB wrapper around the given client, e.g. a
+// fake of the `awsfake` package.
+func NewDynamoDBFromClient(client dynamoDBAPI) *DynamoDB {
+	return &DynamoDB{client: client}
+}
+
+// tableSettings are the settings of a DynamoDB table.
+type tableSettings struct {
+	billingMode   types.BillingMode
+	readCapacity  int64
+	writeCapacity int64
+	hashKey       types.AttributeDefinition
+	rangeKey      *types.AttributeDefinition
+	tags          map[string]string
+}
+
+// newTableSettings returns the settings of a table with the given options. A table has a
+// hash key called `id` of type string and is billed per request by default.
+func newTableSettings(opts []TableOption) tableSettings {
+	settings := tableSettings{
+		billingMode: types.BillingModePayPerRequest,
+		hashKey:     types.AttributeDefinition{AttributeName: aws.String("id"), AttributeType: types.ScalarAttributeTypeS},
+	}
+	for _, opt := range opts {
+		opt.applyTable(&settings)
+	}
+	return settings
+}
+
+// WithBillingMode bills the table with the given mode instead of per request. Provisioned
+// tables need WithProvisionedThroughput as well.
+func WithBillingMode(mode types.BillingMode) TableOption {
+	return tableOptionFunc(func(s *tableSettings) {
+		s.billingMode = mode
+	})
+}
+
+// WithProvisionedThroughput provisions the given read and write capacity units for the
+// table and bills it in the provisioned mode.
+func WithProvisionedThroughput(readCapacity, writeCapacity int64) TableOption {
+	return tableOptionFunc(func(s *tableSettings) {
+		s.billingMode = types.BillingModeProvisioned
+		s.readCapacity = readCapacity
+		s.writeCapacity = writeCapacity
+	})
+}
+
+// WithHashKey uses the attribute with the given name and type as the hash key of the table
+// instead of a string called `id`. The key of an existing table cannot be changed.
+func WithHashKey(name string, attributeType types.ScalarAttributeType) TableOption {
+	return tableOptionFunc(func(s *tableSettings) {
+		s.hashKey = types.AttributeDefinition{AttributeName: aws.String(name), AttributeType: attributeType}
+	})
+}
+
+// WithRangeKey adds the attribute with the given name and type as the range key to the
+// primary key of the table. The key of an existing table cannot be changed.
+func WithRangeKey(name string, attributeType types.ScalarAttributeType) TableOption {
+	return tableOptionFunc(func(s *tableSettings) {
+		s.rangeKey = &types.AttributeDefinition{AttributeName: aws.String(name), AttributeType: attributeType}
+	})
+}
+
+// provisionedThroughput returns the throughput of a provisioned table or nil if the table
+// is billed per request.
+func (s tableSettings) provisionedThroughput() *types.ProvisionedThroughput {
+	if s.billingMode != types.BillingModeProvisioned {
+		return nil
+	}
+	return &types.ProvisionedThroughput{
+		ReadCapacityUnits:  aws.Int64(s.readCapacity),
+		WriteCapacityUnits: aws.Int64(s.writeCapacity),
+	}
+}
+
+// CreateTable creates a DynamoDB table with the given name and options. Without options,
+// the table has a primary key called `id` of type `string` and is billed per request.
+func (d *DynamoDB) CreateTable(ctx context.Context, name string, opts ...TableOption) error {
+	settings := newTableSettings(opts)
+	input := &dynamodb.CreateTableInput{
+		TableName:            aws.String(name),
+		AttributeDefinitions: []types.AttributeDefinition{settings.hashKey},
 		KeySchema: []types.KeySchemaElement{
 			{
-				AttributeName: aws.String("id"),
+				AttributeName: settings.hashKey.AttributeName,
 				KeyType:       types.KeyTypeHash,
 			},
 		},
-		BillingMode: types.BillingModePayPerRequest,
+		BillingMode:           settings.billingMode,
+		ProvisionedThroughput: settings.provisionedThroughput(),
+	}
+	if settings.rangeKey != nil {
+		input.AttributeDefinitions = append(input.AttributeDefinitions, *settings.rangeKey)
+		input.KeySchema = append(input.KeySchema, types.KeySchemaElement{
+			AttributeName: settings.rangeKey.AttributeName,
+			KeyType:       types.KeyTypeRange,
+		})
+	}
+	for _, key := range sortedKeys(settings.tags) {
+		input.Tags = append(input.Tags, types.Tag{Key: aws.String(key), Value: aws.String(settings.tags[key])})
+	}
+
+	_, err := d.client.CreateTable(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// EnsureTable creates a DynamoDB table with the given name and options if it does not
+// exist yet. The billing mode and the provisioned throughput of an existing table are
+// updated if they differ from the options.
+func (d *DynamoDB) EnsureTable(ctx context.Context, name string, opts ...TableOption) error {
+	table, err := d.DescribeTable(ctx, name)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		return d.CreateTable(ctx, name, opts...)
+	}
+
+	settings := newTableSettings(opts)
+	if len(tableDifferences(table.Table, settings)) == 0 {
+		return nil
+	}
+
+	_, err = d.client.UpdateTable(ctx, &dynamodb.UpdateTableInput{
+		TableName:             aws.String(name),
+		BillingMode:           settings.billingMode,
+		ProvisionedThroughput: settings.provisionedThroughput(),
 	})
 	if err != nil {
 		return err
@@ -57,10 +172,72 @@ func (d *DynamoDB) CreateTable(name string) error {
 	return nil
 }
 
+// WaitUntilActive waits until the DynamoDB table with the given name is active.
+func (d *DynamoDB) WaitUntilActive(ctx context.Context, name string, timeout time.Duration) error {
+	return wait(ctx, fmt.Sprintf("dynamodb table %s", name), timeout, func(ctx context.Context) (bool, string, error) {
+		table, err := d.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
+			TableName: aws.String(name),
+		})
+		if err != nil {
+			return false, "", err
+		}
+
+		status := table.Table.TableStatus
+		return status == types.TableStatusActive, string(status), nil
+	})
+}
+
+// PlanTable returns the action EnsureTable would take for the DynamoDB table with the
+// given name and options without changing it.
+func (d *DynamoDB) PlanTable(ctx context.Context, name string, opts ...TableOption) (Action, error) {
+	drift, err := d.DriftTable(ctx, name, opts...)
+	return drift.Action, err
+}
+
+// DriftTable compares the DynamoDB table with the given name with the table EnsureTable
+// creates with the given options without changing it.
+func (d *DynamoDB) DriftTable(ctx context.Context, name string, opts ...TableOption) (Drift, error) {
+	table, err := d.DescribeTable(ctx, name)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return Drift{}, err
+		}
+		return Drift{Action: ActionCreate}, nil
+	}
+
+	return drift(tableDifferences(table.Table, newTableSettings(opts))), nil
+}
+
+// tableDifferences returns the billing settings of the given table that differ from the
+// given settings. The throughput is only compared for provisioned tables.
+func tableDifferences(table *types.TableDescription, settings tableSettings) differences {
+	var diffs differences
+	if table == nil {
+		table = &types.TableDescription{}
+	}
+
+	billingMode := types.BillingModeProvisioned
+	if summary := table.BillingModeSummary; summary != nil {
+		billingMode = summary.BillingMode
+	}
+	diffs.compare("billingMode", settings.billingMode, billingMode)
+
+	if settings.billingMode == types.BillingModeProvisioned {
+		throughput := table.ProvisionedThroughput
+		if throughput == nil {
+			throughput = &types.ProvisionedThroughputDescription{}
+		}
+		diffs.compare("readCapacityUnits", settings.readCapacity, aws.ToInt64(throughput.ReadCapacityUnits))
+		diffs.compare("writeCapacityUnits", settings.writeCapacity, aws.ToInt64(throughput.WriteCapacityUnits))
+	}
+
+	return diffs
+}
+
 // UpdateReplicas updates the DynamoDB table with the given name to have replicas in
 // `eu-central-1` and `us-west-1`.
-func (d *DynamoDB) UpdateReplicas(name string) error {
-	_, err := d.client.UpdateTable(context.TODO(), &dynamodb.UpdateTableInput{
+func (d *DynamoDB) UpdateReplicas(ctx context.Context, name string) error {
+	_, err := d.client.UpdateTable(ctx, &dynamodb.UpdateTableInput{
 		TableName: aws.String(name),
 		ReplicaUpdates: []types.ReplicationGroupUpdate{
 			{
@@ -83,8 +260,8 @@ func (d *DynamoDB) UpdateReplicas(name string) error {
 }
 
 // DeleteTable deletes the DynamoDB table with the given name.
-func (d *DynamoDB) DeleteTable(name string) error {
-	_, err := d.client.DeleteTable(context.TODO(), &dynamodb.DeleteTableInput{
+func (d *DynamoDB) DeleteTable(ctx context.Context, name string) error {
+	_, err := d.client.DeleteTable(ctx, &dynamodb.DeleteTableInput{
 		TableName: aws.String(name),
 	})
 	if err != nil {
@@ -95,15 +272,15 @@ func (d *DynamoDB) DeleteTable(name string) error {
 }
 
 // DescribeTable describes the DynamoDB table with the given name.
-func (d *DynamoDB) DescribeTable(name string) (*dynamodb.DescribeTableOutput, error) {
-	return d.client.DescribeTable(context.TODO(), &dynamodb.DescribeTableInput{
+func (d *DynamoDB) DescribeTable(ctx context.Context, name string) (*dynamodb.DescribeTableOutput, error) {
+	return d.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
 		TableName: aws.String(name),
 	})
 }
 
 // PutItem puts an item into the DynamoDB table with the given name and attributes.
-func (d *DynamoDB) PutItem(tableName string, item map[string]types.AttributeValue) error {
-	_, err := d.client.PutItem(context.TODO(), &dynamodb.PutItemInput{
+func (d *DynamoDB) PutItem(ctx context.Context, tableName string, item map[string]types.AttributeValue) error {
+	_, err := d.client.PutItem(ctx, &dynamodb.PutItemInput{
 		TableName: aws.String(tableName),
 		Item:      item,
 	})
@@ -115,8 +292,8 @@ func (d *DynamoDB) PutItem(tableName string, item map[string]types.AttributeValu
 }
 
 // DeleteItem deletes an item from the DynamoDB table with the given name and key.
-func (d *DynamoDB) DeleteItem(name string, key map[string]types.AttributeValue) error {
-	_, err := d.client.DeleteItem(context.TODO(), &dynamodb.DeleteItemInput{
+func (d *DynamoDB) DeleteItem(ctx context.Context, name string, key map[string]types.AttributeValue) error {
+	_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
 		TableName: aws.String(name),
 		Key:       key,
 	})
@@ -127,9 +304,14 @@ func (d *DynamoDB) DeleteItem(name string, key map[string]types.AttributeValue)
 	return nil
 }
 
-// GetItemById gets an item from the DynamoDB table with the id in the data.
-func (d *DynamoDB) GetItemById(tableName, id string) (map[string]types.AttributeValue, error) {
-	result, err := d.client.ExecuteStatement(context.TODO(), &dynamodb.ExecuteStatementInput{
+// ErrTableNotFound means that the DynamoDB table of a requested item does not exist. Unlike
+// a missing item, it does not match ErrNotFound.
+var ErrTableNotFound = errors.New("table not found")
+
+// GetItemById gets an item from the DynamoDB table with the id in the data. It returns
+// ErrNotFound if there is no item with the id and ErrTableNotFound if there is no table.
+func (d *DynamoDB) GetItemById(ctx context.Context, tableName, id string) (map[string]types.AttributeValue, error) {
+	result, err := d.client.ExecuteStatement(ctx, &dynamodb.ExecuteStatementInput{
 		Statement: aws.String(fmt.Sprintf("SELECT * FROM %s WHERE id=?", tableName)),
 		Parameters: []types.AttributeValue{
 			&types.AttributeValueMemberS{
@@ -138,11 +320,16 @@ func (d *DynamoDB) GetItemById(tableName, id string) (map[string]types.Attribute
 		},
 	})
 	if err != nil {
+		// The error of the SDK stays available, but its kind is replaced.
+		var classified *Error
+		if errors.As(err, &classified) && classified.Kind == ErrNotFound {
+			return nil, fmt.Errorf("table %s: %w: %w", tableName, ErrTableNotFound, classified.Err)
+		}
 		return nil, err
 	}
 
 	if len(result.Items) == 0 {
-		return nil, nil
+		return nil, fmt.Errorf("item %s of table %s: %w", id, tableName, ErrNotFound)
 	}
 
 	return result.Items[0], nil
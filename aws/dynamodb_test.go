@@ -2,11 +2,15 @@ package aws
 
 import (
 	"context"
+	"errors"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
 )
 
 type mockDynamoDBClient struct {
@@ -58,12 +62,115 @@ func TestDynamoDB_CreateTable(t *testing.T) {
 		client: mockClient,
 	}
 
-	err := dynamoDB.CreateTable("test")
+	err := dynamoDB.CreateTable(context.Background(), "test")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
 }
 
+func TestDynamoDB_EnsureTable(t *testing.T) {
+	mockClient := &mockDynamoDBClient{
+		describeTableFunc: func(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+			return &dynamodb.DescribeTableOutput{
+				Table: &types.TableDescription{
+					TableName: aws.String("test"),
+					BillingModeSummary: &types.BillingModeSummary{
+						BillingMode: types.BillingModeProvisioned,
+					},
+				},
+			}, nil
+		},
+		updateTableFunc: func(ctx context.Context, params *dynamodb.UpdateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error) {
+			if params.BillingMode != types.BillingModePayPerRequest {
+				t.Errorf("unexpected billing mode: %v", params.BillingMode)
+			}
+			return &dynamodb.UpdateTableOutput{}, nil
+		},
+	}
+
+	dynamoDB := &DynamoDB{
+		client: mockClient,
+	}
+
+	err := dynamoDB.EnsureTable(context.Background(), "test")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDynamoDB_CreateTableWithOptions(t *testing.T) {
+	var input *dynamodb.CreateTableInput
+	mockClient := &mockDynamoDBClient{
+		createTableFunc: func(ctx context.Context, params *dynamodb.CreateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+			input = params
+			return &dynamodb.CreateTableOutput{}, nil
+		},
+	}
+
+	dynamoDB := &DynamoDB{
+		client: mockClient,
+	}
+
+	err := dynamoDB.CreateTable(context.Background(), "test",
+		WithProvisionedThroughput(5, 10),
+		WithHashKey("segment", types.ScalarAttributeTypeS),
+		WithRangeKey("hour", types.ScalarAttributeTypeN),
+		WithTags(map[string]string{"team": "data", "env": "dev"}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if input.BillingMode != types.BillingModeProvisioned {
+		t.Errorf("unexpected billing mode: %v", input.BillingMode)
+	}
+	if throughput := input.ProvisionedThroughput; aws.ToInt64(throughput.ReadCapacityUnits) != 5 || aws.ToInt64(throughput.WriteCapacityUnits) != 10 {
+		t.Errorf("unexpected throughput: %+v", throughput)
+	}
+	if len(input.KeySchema) != 2 || aws.ToString(input.KeySchema[0].AttributeName) != "segment" || input.KeySchema[1].KeyType != types.KeyTypeRange {
+		t.Errorf("unexpected key schema: %+v", input.KeySchema)
+	}
+	if len(input.Tags) != 2 || aws.ToString(input.Tags[0].Key) != "env" || aws.ToString(input.Tags[1].Key) != "team" {
+		t.Errorf("expected the tags sorted by key, got %+v", input.Tags)
+	}
+}
+
+func TestDynamoDB_EnsureTable_Throughput(t *testing.T) {
+	updated := false
+	mockClient := &mockDynamoDBClient{
+		describeTableFunc: func(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+			return &dynamodb.DescribeTableOutput{
+				Table: &types.TableDescription{
+					TableName:          aws.String("test"),
+					BillingModeSummary: &types.BillingModeSummary{BillingMode: types.BillingModeProvisioned},
+					ProvisionedThroughput: &types.ProvisionedThroughputDescription{
+						ReadCapacityUnits:  aws.Int64(5),
+						WriteCapacityUnits: aws.Int64(5),
+					},
+				},
+			}, nil
+		},
+		updateTableFunc: func(ctx context.Context, params *dynamodb.UpdateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error) {
+			updated = true
+			if aws.ToInt64(params.ProvisionedThroughput.WriteCapacityUnits) != 10 {
+				t.Errorf("unexpected throughput: %+v", params.ProvisionedThroughput)
+			}
+			return &dynamodb.UpdateTableOutput{}, nil
+		},
+	}
+
+	dynamoDB := &DynamoDB{
+		client: mockClient,
+	}
+
+	if err := dynamoDB.EnsureTable(context.Background(), "test", WithProvisionedThroughput(5, 5)); err != nil || updated {
+		t.Errorf("expected the matching table to be left as is, got updated %v and error %v", updated, err)
+	}
+	if err := dynamoDB.EnsureTable(context.Background(), "test", WithProvisionedThroughput(5, 10)); err != nil || !updated {
+		t.Errorf("expected the throughput to be updated, got updated %v and error %v", updated, err)
+	}
+}
+
 func TestDynamoDB_UpdateReplicas(t *testing.T) {
 	mockClient := &mockDynamoDBClient{
 		updateTableFunc: func(ctx context.Context, params *dynamodb.UpdateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error) {
@@ -75,7 +182,7 @@ func TestDynamoDB_UpdateReplicas(t *testing.T) {
 		client: mockClient,
 	}
 
-	err := dynamoDB.UpdateReplicas("test")
+	err := dynamoDB.UpdateReplicas(context.Background(), "test")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -92,7 +199,7 @@ func TestDynamoDB_DeleteTable(t *testing.T) {
 		client: mockClient,
 	}
 
-	err := dynamoDB.DeleteTable("test")
+	err := dynamoDB.DeleteTable(context.Background(), "test")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -113,7 +220,7 @@ func TestDynamoDB_DescribeTable(t *testing.T) {
 		client: mockClient,
 	}
 
-	table, err := dynamoDB.DescribeTable("test")
+	table, err := dynamoDB.DescribeTable(context.Background(), "test")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -134,7 +241,7 @@ func TestDynamoDB_PutItem(t *testing.T) {
 		client: mockClient,
 	}
 
-	err := dynamoDB.PutItem("test", map[string]types.AttributeValue{"test": &types.AttributeValueMemberS{Value: "test"}})
+	err := dynamoDB.PutItem(context.Background(), "test", map[string]types.AttributeValue{"test": &types.AttributeValueMemberS{Value: "test"}})
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -151,7 +258,7 @@ func TestDynamoDB_DeleteItem(t *testing.T) {
 		client: mockClient,
 	}
 
-	err := dynamoDB.DeleteItem("test", map[string]types.AttributeValue{"test": &types.AttributeValueMemberS{Value: "test"}})
+	err := dynamoDB.DeleteItem(context.Background(), "test", map[string]types.AttributeValue{"test": &types.AttributeValueMemberS{Value: "test"}})
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -174,7 +281,7 @@ func TestDynamoDB_GetItemById(t *testing.T) {
 		client: mockClient,
 	}
 
-	item, err := dynamoDB.GetItemById("test", "test")
+	item, err := dynamoDB.GetItemById(context.Background(), "test", "test")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -183,3 +290,120 @@ func TestDynamoDB_GetItemById(t *testing.T) {
 		t.Errorf("unexpected item: %v", item)
 	}
 }
+
+func TestDynamoDB_GetItemById_Missing(t *testing.T) {
+	var executeErr error
+	dynamoDB := &DynamoDB{
+		client: &mockDynamoDBClient{
+			executeStatementFunc: func(ctx context.Context, params *dynamodb.ExecuteStatementInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ExecuteStatementOutput, error) {
+				if executeErr != nil {
+					return nil, executeErr
+				}
+				return &dynamodb.ExecuteStatementOutput{}, nil
+			},
+		},
+	}
+
+	if item, err := dynamoDB.GetItemById(context.Background(), "test", "test"); item != nil || !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a missing item, got %v, %v", item, err)
+	}
+
+	executeErr = Classify(&smithy.GenericAPIError{Code: "ResourceNotFoundException"})
+	_, err := dynamoDB.GetItemById(context.Background(), "test", "test")
+	var apiErr smithy.APIError
+	if !errors.Is(err, ErrTableNotFound) || errors.Is(err, ErrNotFound) || !errors.As(err, &apiErr) {
+		t.Errorf("expected a missing table, got %v", err)
+	}
+}
+
+func TestDynamoDB_PlanTable(t *testing.T) {
+	billingMode := types.BillingModeProvisioned
+	mockClient := &mockDynamoDBClient{
+		describeTableFunc: func(ctx context.Context, input *dynamodb.DescribeTableInput, opts ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+			return &dynamodb.DescribeTableOutput{
+				Table: &types.TableDescription{
+					TableName:          input.TableName,
+					BillingModeSummary: &types.BillingModeSummary{BillingMode: billingMode},
+				},
+			}, nil
+		},
+	}
+
+	dynamoDBClient := &DynamoDB{
+		client: mockClient,
+	}
+
+	action, err := dynamoDBClient.PlanTable(context.Background(), "test-table")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if action != ActionUpdate {
+		t.Errorf("unexpected action for provisioned table: %s", action)
+	}
+
+	billingMode = types.BillingModePayPerRequest
+	action, err = dynamoDBClient.PlanTable(context.Background(), "test-table")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if action != ActionNone {
+		t.Errorf("unexpected action for unchanged table: %s", action)
+	}
+}
+
+func TestDynamoDB_DriftTable(t *testing.T) {
+	mockClient := &mockDynamoDBClient{
+		describeTableFunc: func(ctx context.Context, input *dynamodb.DescribeTableInput, opts ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+			return &dynamodb.DescribeTableOutput{
+				Table: &types.TableDescription{TableName: input.TableName},
+			}, nil
+		},
+	}
+
+	dynamoDBClient := &DynamoDB{
+		client: mockClient,
+	}
+
+	drift, err := dynamoDBClient.DriftTable(context.Background(), "test-table")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if drift.Action != ActionUpdate {
+		t.Errorf("unexpected action for provisioned table: %s", drift.Action)
+	}
+
+	expected := []Difference{{Field: "billingMode", Expected: "PAY_PER_REQUEST", Actual: "PROVISIONED"}}
+	if !reflect.DeepEqual(drift.Differences, expected) {
+		t.Errorf("unexpected differences: %v", drift.Differences)
+	}
+}
+
+func TestDynamoDB_WaitUntilActive(t *testing.T) {
+	setWaitDelay(t)
+
+	calls := 0
+	mockClient := &mockDynamoDBClient{
+		describeTableFunc: func(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+			calls++
+			status := types.TableStatusCreating
+			if calls == 2 {
+				status = types.TableStatusActive
+			}
+			return &dynamodb.DescribeTableOutput{
+				Table: &types.TableDescription{TableStatus: status},
+			}, nil
+		},
+	}
+
+	dynamoDB := &DynamoDB{
+		client: mockClient,
+	}
+
+	err := dynamoDB.WaitUntilActive(context.Background(), "test", time.Second)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
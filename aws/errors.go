@@ -0,0 +1,187 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// Kinds of errors of the wrapped AWS APIs. Every error of a wrapper whose kind is known
+// matches its kind with `errors.Is`, while the error of the SDK stays available with
+// `errors.As`.
+var (
+	// ErrNotFound means that the requested resource does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrAlreadyExists means that the resource to be created exists already.
+	ErrAlreadyExists = errors.New("already exists")
+	// ErrConflict means that the resource is busy with another change, e.g. a stream that
+	// is not active yet or a function whose update is in progress. The request can succeed
+	// once the change finished.
+	ErrConflict = errors.New("conflict")
+	// ErrThrottled means that the request was throttled, even after retrying it.
+	ErrThrottled = errors.New("throttled")
+	// ErrValidation means that the request was invalid and retrying it does not help.
+	ErrValidation = errors.New("validation failed")
+)
+
+// notFoundErrorCodes are the error codes the wrapped AWS APIs use for missing resources.
+var notFoundErrorCodes = []string{
+	"ResourceNotFoundException",
+	"NotFoundException",
+	"NotFound",
+	"NoSuchBucket",
+	"NoSuchKey",
+	"NoSuchEntity",
+	"EntityNotFoundException",
+	"DBClusterNotFoundFault",
+}
+
+// alreadyExistsErrorCodes are the error codes the wrapped AWS APIs use for resources that
+// exist already.
+var alreadyExistsErrorCodes = []string{
+	"ResourceExistsException",
+	"AlreadyExistsException",
+	"EntityAlreadyExists",
+	"BucketAlreadyExists",
+	"BucketAlreadyOwnedByYou",
+	"DBClusterAlreadyExistsFault",
+}
+
+// conflictErrorCodes are the error codes the wrapped AWS APIs use both for resources that
+// exist already and for resources that are busy with another change. Kinesis, for
+// example, rejects creating an existing stream and updating a stream that is not active
+// with `ResourceInUseException`, so their kind depends on the operation.
+var conflictErrorCodes = []string{
+	"ResourceInUseException",
+	"ResourceConflictException",
+	"ConflictException",
+}
+
+// validationErrorCodes are the error codes the wrapped AWS APIs use for invalid requests.
+var validationErrorCodes = []string{
+	"ValidationException",
+	"ValidationError",
+	"InvalidParameterException",
+	"InvalidParameterValueException",
+	"InvalidParameterValue",
+	"InvalidParameterCombination",
+	"InvalidRequestException",
+	"InvalidInputException",
+	"BadRequestException",
+	"MalformedPolicyDocument",
+	"InvalidBucketName",
+	"InvalidArgumentException",
+}
+
+// Error is an error of an AWS API call together with its kind.
+type Error struct {
+	// Kind is one of ErrNotFound, ErrAlreadyExists, ErrConflict, ErrThrottled and
+	// ErrValidation.
+	Kind error
+	// Err is the error of the SDK.
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the kind and the error of the SDK, so both match with `errors.Is` and
+// `errors.As`.
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// Classify returns the given error as an Error of its kind. Errors of an unknown kind are
+// returned as is. Conflicts are ErrConflict, see ClassifyOperation.
+func Classify(err error) error {
+	return ClassifyOperation("", err)
+}
+
+// ClassifyOperation returns the given error of the operation with the given name, e.g.
+// `CreateStream`, as an Error of its kind. A conflict means that the resource exists
+// already if the operation creates it and that the resource is busy otherwise.
+func ClassifyOperation(operation string, err error) error {
+	var classified *Error
+	if err == nil || errors.As(err, &classified) {
+		return err
+	}
+
+	if kind := errorKind(operation, err); kind != nil {
+		return &Error{Kind: kind, Err: err}
+	}
+	return err
+}
+
+// errorKind returns the kind of the given error of the operation with the given name or
+// `nil` if its kind is unknown.
+func errorKind(operation string, err error) error {
+	switch {
+	case hasErrorCode(err, notFoundErrorCodes...):
+		return ErrNotFound
+	case hasErrorCode(err, alreadyExistsErrorCodes...):
+		return ErrAlreadyExists
+	case hasErrorCode(err, conflictErrorCodes...) && createsResource(operation):
+		return ErrAlreadyExists
+	case hasErrorCode(err, conflictErrorCodes...):
+		return ErrConflict
+	case hasErrorCode(err, validationErrorCodes...):
+		return ErrValidation
+	case isThrottle(err):
+		return ErrThrottled
+	}
+	return nil
+}
+
+// createsResource reports whether the operation with the given name creates a resource.
+func createsResource(operation string) bool {
+	return strings.HasPrefix(operation, "Create") || strings.HasPrefix(operation, "Register")
+}
+
+// isThrottle reports whether the given error was returned because the request was
+// throttled, in the same way as the retryer.
+func isThrottle(err error) bool {
+	return retry.IsErrorThrottles(retry.DefaultThrottles).IsErrorThrottle(err) == aws.TrueTernary
+}
+
+// classifyErrors is a middleware that classifies the error of every call after the
+// retries.
+var classifyErrors = middleware.InitializeMiddlewareFunc("ClassifyErrors", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+	out, metadata, err := next.HandleInitialize(ctx, in)
+	return out, metadata, ClassifyOperation(awsmiddleware.GetOperationName(ctx), err)
+})
+
+// addClassifyErrors adds the classifyErrors middleware to the given stack. It runs after
+// the middleware that registers the operation of the call.
+func addClassifyErrors(stack *middleware.Stack) error {
+	return stack.Initialize.Add(classifyErrors, middleware.After)
+}
+
+// IsNotFound reports whether the given error was returned because the requested resource
+// does not exist.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound) || hasErrorCode(err, notFoundErrorCodes...)
+}
+
+// hasErrorCode reports whether the given error is an AWS API error with one of the given
+// error codes.
+func hasErrorCode(err error, codes ...string) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	for _, code := range codes {
+		if apiErr.ErrorCode() == code {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,188 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/smithy-go"
+)
+
+func TestIsNotFound(t *testing.T) {
+	err := fmt.Errorf("wrapped: %w", &smithy.GenericAPIError{Code: "NoSuchBucket"})
+	if !IsNotFound(err) {
+		t.Errorf("expected wrapped not found error to be detected")
+	}
+
+	if IsNotFound(&smithy.GenericAPIError{Code: "ThrottlingException"}) {
+		t.Errorf("unexpected not found error for throttling")
+	}
+
+	if IsNotFound(errors.New("test")) || IsNotFound(nil) {
+		t.Errorf("unexpected not found error for non api error")
+	}
+
+	if !IsNotFound(fmt.Errorf("item: %w", ErrNotFound)) {
+		t.Errorf("expected ErrNotFound to be detected")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := map[string]error{
+		"ResourceNotFoundException": ErrNotFound,
+		"EntityAlreadyExists":       ErrAlreadyExists,
+		"ResourceInUseException":    ErrConflict,
+		"ThrottlingException":       ErrThrottled,
+		"TooManyRequestsException":  ErrThrottled,
+		"ValidationException":       ErrValidation,
+	}
+
+	kinds := []error{ErrNotFound, ErrAlreadyExists, ErrConflict, ErrThrottled, ErrValidation}
+	for code, expected := range tests {
+		apiErr := &smithy.GenericAPIError{Code: code}
+		err := Classify(apiErr)
+		for _, kind := range kinds {
+			if errors.Is(err, kind) != (kind == expected) {
+				t.Errorf("%s: unexpected errors.Is(err, %v) = %v", code, kind, !(kind == expected))
+			}
+		}
+
+		var unwrapped smithy.APIError
+		if !errors.As(err, &unwrapped) || unwrapped.ErrorCode() != code {
+			t.Errorf("%s: expected the API error to stay available", code)
+		}
+	}
+
+	plain := errors.New("test")
+	if err := Classify(plain); err != plain {
+		t.Errorf("expected an unknown error to be returned as is, got %v", err)
+	}
+	if Classify(nil) != nil {
+		t.Error("expected nil to stay nil")
+	}
+}
+
+func TestClassifyOperation(t *testing.T) {
+	tests := []struct {
+		operation, code string
+		expected        error
+	}{
+		{"CreateStream", "ResourceInUseException", ErrAlreadyExists},
+		{"UpdateShardCount", "ResourceInUseException", ErrConflict},
+		{"CreateFunction", "ResourceConflictException", ErrAlreadyExists},
+		{"UpdateFunctionCode", "ResourceConflictException", ErrConflict},
+		{"RegisterStreamConsumer", "ResourceInUseException", ErrAlreadyExists},
+		{"CreateRoute", "ConflictException", ErrAlreadyExists},
+		{"UpdateFunctionCode", "ResourceNotFoundException", ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		err := ClassifyOperation(tt.operation, &smithy.GenericAPIError{Code: tt.code})
+		if !errors.Is(err, tt.expected) {
+			t.Errorf("%s %s: expected %v, got %v", tt.operation, tt.code, tt.expected, err)
+		}
+	}
+}
+
+func TestClassifyErrors_Operation(t *testing.T) {
+	client := roundTripFunc(func(req *http.Request) *http.Response {
+		return dynamoDBError("ResourceInUseException")
+	})
+	dynamoDB := NewDynamoDB(testConfig(client))
+
+	if err := dynamoDB.CreateTable(context.Background(), "test"); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("expected an existing table, got %v", err)
+	}
+	if err := dynamoDB.DeleteTable(context.Background(), "test"); !errors.Is(err, ErrConflict) || errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("expected a table that is busy, got %v", err)
+	}
+}
+
+// roundTripFunc is an HTTP client that answers every request with the given function.
+type roundTripFunc func(*http.Request) *http.Response
+
+func (f roundTripFunc) Do(req *http.Request) (*http.Response, error) {
+	return f(req), nil
+}
+
+// testConfig returns a configuration whose clients send their requests to the given
+// HTTP client.
+func testConfig(client aws.HTTPClient) aws.Config {
+	return aws.Config{
+		Region:      "us-east-1",
+		Credentials: credentials.NewStaticCredentialsProvider("test", "test", ""),
+		HTTPClient:  client,
+		EndpointResolverWithOptions: aws.EndpointResolverWithOptionsFunc(func(service, region string, _ ...interface{}) (aws.Endpoint, error) {
+			return aws.Endpoint{URL: "http://localhost"}, nil
+		}),
+	}
+}
+
+// dynamoDBError returns a response of the DynamoDB API with the given error code.
+func dynamoDBError(code string) *http.Response {
+	return &http.Response{
+		StatusCode: http.StatusBadRequest,
+		Header:     http.Header{"Content-Type": []string{"application/x-amz-json-1.0"}},
+		Body:       io.NopCloser(strings.NewReader(fmt.Sprintf(`{"__type":"com.amazonaws.dynamodb.v20120810#%s","message":"test"}`, code))),
+	}
+}
+
+func TestRetryPolicy_Throttled(t *testing.T) {
+	attempts := 0
+	client := roundTripFunc(func(req *http.Request) *http.Response {
+		attempts++
+		return dynamoDBError("ThrottlingException")
+	})
+
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	dynamoDB := NewDynamoDB(WithRetryPolicy(testConfig(client), policy))
+
+	_, err := dynamoDB.DescribeTable(context.Background(), "test")
+	if !errors.Is(err, ErrThrottled) {
+		t.Errorf("expected ErrThrottled, got %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestRetryPolicy_NotRetried(t *testing.T) {
+	attempts := 0
+	client := roundTripFunc(func(req *http.Request) *http.Response {
+		attempts++
+		return dynamoDBError("ResourceNotFoundException")
+	})
+
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	dynamoDB := NewDynamoDB(WithRetryPolicy(testConfig(client), policy))
+
+	_, err := dynamoDB.DescribeTable(context.Background(), "test")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("expected a single attempt, got %d", attempts)
+	}
+}
+
+func TestRetryPolicy_BackoffDelay(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, limit := range map[int]time.Duration{1: 100 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second, 100: time.Second} {
+		for i := 0; i < 20; i++ {
+			delay, err := policy.BackoffDelay(attempt, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if delay <= 0 || delay > limit {
+				t.Errorf("attempt %d: expected a delay up to %s, got %s", attempt, limit, delay)
+			}
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+)
+
+// kinesisClient is the Kinesis client of the SDK. SubscribeToShardEvents returns the event
+// stream of SubscribeToShard, whose output cannot be created outside of the SDK, so fakes
+// implement SubscribeToShardEvents instead.
+type kinesisClient struct {
+	*kinesis.Client
+}
+
+// SubscribeToShardEvents subscribes to the shard and returns the event stream of the
+// subscription.
+func (c kinesisClient) SubscribeToShardEvents(ctx context.Context, params *kinesis.SubscribeToShardInput, optFns ...func(*kinesis.Options)) (*kinesis.SubscribeToShardEventStream, error) {
+	output, err := c.SubscribeToShard(ctx, params, optFns...)
+	if err != nil {
+		return nil, err
+	}
+
+	return output.GetStream(), nil
+}
+
+// RegisterConsumer registers an enhanced fan-out consumer with the given name for the
+// Kinesis stream with the given name and returns its ARN. Every registered consumer reads
+// each shard with its own throughput of 2 MB per second, so consumers do not compete with
+// each other for reads.
+func (k *Kinesis) RegisterConsumer(ctx context.Context, stream, name string) (string, error) {
+	streamARN, err := k.GetARN(ctx, stream)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := k.client.RegisterStreamConsumer(ctx, &kinesis.RegisterStreamConsumerInput{
+		StreamARN:    aws.String(streamARN),
+		ConsumerName: aws.String(name),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.ToString(output.Consumer.ConsumerARN), nil
+}
+
+// EnsureConsumer registers an enhanced fan-out consumer with the given name for the
+// Kinesis stream with the given name if it is not registered yet and returns its ARN.
+func (k *Kinesis) EnsureConsumer(ctx context.Context, stream, name string) (string, error) {
+	arn, err := k.GetConsumerARN(ctx, stream, name)
+	if err == nil {
+		return arn, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return "", err
+	}
+
+	return k.RegisterConsumer(ctx, stream, name)
+}
+
+// PlanConsumer returns the action EnsureConsumer would take for the consumer with the
+// given name of the Kinesis stream with the given name without changing it.
+func (k *Kinesis) PlanConsumer(ctx context.Context, stream, name string) (Action, error) {
+	_, err := k.GetConsumerARN(ctx, stream, name)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return "", err
+		}
+		return ActionCreate, nil
+	}
+
+	return ActionNone, nil
+}
+
+// GetConsumerARN returns the ARN of the consumer with the given name of the Kinesis stream
+// with the given name.
+func (k *Kinesis) GetConsumerARN(ctx context.Context, stream, name string) (string, error) {
+	consumer, err := k.describeConsumer(ctx, stream, name)
+	if err != nil {
+		return "", err
+	}
+
+	return aws.ToString(consumer.ConsumerARN), nil
+}
+
+// WaitUntilConsumerActive waits until the consumer with the given name of the Kinesis
+// stream with the given name is active. Consumers can only subscribe to shards once they
+// are active.
+func (k *Kinesis) WaitUntilConsumerActive(ctx context.Context, stream, name string, timeout time.Duration) error {
+	return wait(ctx, fmt.Sprintf("kinesis consumer %s of stream %s", name, stream), timeout, func(ctx context.Context) (bool, string, error) {
+		consumer, err := k.describeConsumer(ctx, stream, name)
+		if err != nil {
+			return false, "", err
+		}
+
+		status := consumer.ConsumerStatus
+		return status == types.ConsumerStatusActive, string(status), nil
+	})
+}
+
+// DeregisterConsumer deregisters the consumer with the given name of the Kinesis stream
+// with the given name. Its subscriptions end and the Lambda functions bound to it stop
+// receiving records.
+func (k *Kinesis) DeregisterConsumer(ctx context.Context, stream, name string) error {
+	streamARN, err := k.GetARN(ctx, stream)
+	if err != nil {
+		return err
+	}
+
+	_, err = k.client.DeregisterStreamConsumer(ctx, &kinesis.DeregisterStreamConsumerInput{
+		StreamARN:    aws.String(streamARN),
+		ConsumerName: aws.String(name),
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// describeConsumer returns the description of the consumer with the given name of the
+// Kinesis stream with the given name.
+func (k *Kinesis) describeConsumer(ctx context.Context, stream, name string) (*types.ConsumerDescription, error) {
+	streamARN, err := k.GetARN(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := k.client.DescribeStreamConsumer(ctx, &kinesis.DescribeStreamConsumerInput{
+		StreamARN:    aws.String(streamARN),
+		ConsumerName: aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if output.ConsumerDescription == nil {
+		return &types.ConsumerDescription{}, nil
+	}
+
+	return output.ConsumerDescription, nil
+}
+
+// subscribe reads the shard of the lease through the registered consumer of the
+// configuration from its checkpoint and hands the events with records to the handler
+// until the shard ends, the lease is lost or the context is done. Subscriptions end after
+// five minutes and are renewed from the last event.
+func (c *Consumer) subscribe(ctx context.Context, o *ownedLease, handler func(context.Context, Batch) error) error {
+	o.mu.Lock()
+	shardID, checkpoint := o.lease.ShardID, o.lease.Checkpoint
+	o.mu.Unlock()
+
+	position := checkpoint
+	for {
+		input := &kinesis.SubscribeToShardInput{
+			ConsumerARN:      aws.String(c.config.ConsumerARN),
+			ShardId:          aws.String(shardID),
+			StartingPosition: &types.StartingPosition{Type: c.config.StartPosition},
+		}
+		if position != "" {
+			input.StartingPosition = &types.StartingPosition{
+				Type:           types.ShardIteratorTypeAfterSequenceNumber,
+				SequenceNumber: aws.String(position),
+			}
+		}
+
+		stream, err := c.kinesis.SubscribeToShardEvents(ctx, input)
+		// The subscription of the previous owner of the lease may still be active.
+		if errors.Is(err, ErrConflict) {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(c.config.PollInterval):
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		ended, err := c.readEvents(ctx, o, shardID, stream, &position, handler)
+		stream.Close()
+		if err != nil || ended || ctx.Err() != nil {
+			return err
+		}
+	}
+}
+
+// readEvents hands the records of the events of the subscription to the handler and
+// records the position to continue from. It reports whether the shard ended.
+func (c *Consumer) readEvents(ctx context.Context, o *ownedLease, shardID string, stream *kinesis.SubscribeToShardEventStream, position *string, handler func(context.Context, Batch) error) (bool, error) {
+	for {
+		var event types.SubscribeToShardEventStream
+		select {
+		case <-ctx.Done():
+			return false, nil
+		case e, ok := <-stream.Events():
+			if !ok {
+				return false, stream.Err()
+			}
+			event = e
+		}
+
+		shardEvent, ok := event.(*types.SubscribeToShardEventStreamMemberSubscribeToShardEvent)
+		if !ok {
+			continue
+		}
+
+		records := shardEvent.Value.Records
+		if len(records) > 0 {
+			batch := Batch{ShardID: shardID, Records: records, MillisBehindLatest: aws.ToInt64(shardEvent.Value.MillisBehindLatest)}
+			if err := c.handle(ctx, o, batch, handler); err != nil {
+				return false, err
+			}
+			*position = aws.ToString(records[len(records)-1].SequenceNumber)
+		}
+
+		// The last event of a closed shard has no continuation sequence number.
+		if shardEvent.Value.ContinuationSequenceNumber == nil {
+			return true, c.end(ctx, o)
+		}
+		*position = aws.ToString(shardEvent.Value.ContinuationSequenceNumber)
+	}
+}
@@ -2,6 +2,7 @@ package aws
 
 import (
 	"context"
+	"errors"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/glue"
@@ -10,8 +11,14 @@ import (
 
 type glueAPI interface {
 	CreateJob(ctx context.Context, params *glue.CreateJobInput, optFns ...func(*glue.Options)) (*glue.CreateJobOutput, error)
+	GetJob(ctx context.Context, params *glue.GetJobInput, optFns ...func(*glue.Options)) (*glue.GetJobOutput, error)
+	UpdateJob(ctx context.Context, params *glue.UpdateJobInput, optFns ...func(*glue.Options)) (*glue.UpdateJobOutput, error)
+	DeleteJob(ctx context.Context, params *glue.DeleteJobInput, optFns ...func(*glue.Options)) (*glue.DeleteJobOutput, error)
 }
 
+// glueJobRole is the ARN of the role that is assumed by the Glue jobs.
+const glueJobRole = "arn:aws:iam::000000000000:role/glue-role"
+
 // Glue is a wrapper around the AWS Glue API.
 type Glue struct {
 	client glueAPI
@@ -20,18 +27,136 @@ type Glue struct {
 // NewGlue creates a new Glue client.
 func NewGlue(config aws.Config) *Glue {
 	return &Glue{
-		client: glue.NewFromConfig(config.Copy()),
+		client: glue.NewFromConfig(clientConfig(config)),
+	}
+}
+
+// NewGlueFromClient creates a new Glue wrapper around the given client, e.g. a fake of the
+// `awsfake` package.
+func NewGlueFromClient(client glueAPI) *Glue {
+	return &Glue{client: client}
+}
+
+// jobSettings are the settings of a Glue job.
+type jobSettings struct {
+	command          string
+	glueVersion      string
+	maxCapacity      float64
+	defaultArguments map[string]string
+	tags             map[string]string
+}
+
+// newJobSettings returns the settings of a job with the given options. A job is a Python
+// shell job by default.
+func newJobSettings(opts []JobOption) jobSettings {
+	settings := jobSettings{command: "pythonshell"}
+	for _, opt := range opts {
+		opt.applyJob(&settings)
+	}
+	return settings
+}
+
+// WithJobCommand runs the script of the job with the given command, e.g. `glueetl` for an
+// Apache Spark job, instead of `pythonshell`.
+func WithJobCommand(command string) JobOption {
+	return jobOptionFunc(func(s *jobSettings) {
+		s.command = command
+	})
+}
+
+// WithGlueVersion runs the job with the given Glue version, e.g. `4.0`, instead of the
+// default version of Glue.
+func WithGlueVersion(version string) JobOption {
+	return jobOptionFunc(func(s *jobSettings) {
+		s.glueVersion = version
+	})
+}
+
+// WithMaxCapacity lets the job use the given number of data processing units instead of
+// the default capacity of its command.
+func WithMaxCapacity(units float64) JobOption {
+	return jobOptionFunc(func(s *jobSettings) {
+		s.maxCapacity = units
+	})
+}
+
+// WithDefaultArguments passes the given arguments to every run of the job.
+func WithDefaultArguments(arguments map[string]string) JobOption {
+	return jobOptionFunc(func(s *jobSettings) {
+		s.defaultArguments = arguments
+	})
+}
+
+// jobCommand returns the command that runs the script at the given location.
+func (s jobSettings) jobCommand(scriptLocation string) *types.JobCommand {
+	return &types.JobCommand{
+		Name:           aws.String(s.command),
+		ScriptLocation: aws.String(scriptLocation),
+	}
+}
+
+// optionalString returns nil for an empty string, so the default of the API applies.
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return aws.String(value)
+}
+
+// optionalFloat64 returns nil for zero, so the default of the API applies.
+func optionalFloat64(value float64) *float64 {
+	if value == 0 {
+		return nil
 	}
+	return aws.Float64(value)
 }
 
-// CreateJob creates a new Glue job with the given name and script location.
-func (g *Glue) CreateJob(jobName, scriptLocation string) error {
-	_, err := g.client.CreateJob(context.TODO(), &glue.CreateJobInput{
-		Name: aws.String(jobName),
-		Role: aws.String("arn:aws:iam::000000000000:role/glue-role"),
-		Command: &types.JobCommand{
-			Name:           aws.String("pythonshell"),
-			ScriptLocation: aws.String(scriptLocation),
+// CreateJob creates a new Glue job with the given name, script location and options.
+func (g *Glue) CreateJob(ctx context.Context, jobName, scriptLocation string, opts ...JobOption) error {
+	settings := newJobSettings(opts)
+	_, err := g.client.CreateJob(ctx, &glue.CreateJobInput{
+		Name:             aws.String(jobName),
+		Role:             aws.String(glueJobRole),
+		Command:          settings.jobCommand(scriptLocation),
+		GlueVersion:      optionalString(settings.glueVersion),
+		MaxCapacity:      optionalFloat64(settings.maxCapacity),
+		DefaultArguments: settings.defaultArguments,
+		Tags:             settings.tags,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// EnsureJob creates a new Glue job with the given name, script location and options if it
+// does not exist yet. An existing job is updated if its role, command or the settings of
+// the options differ.
+func (g *Glue) EnsureJob(ctx context.Context, jobName, scriptLocation string, opts ...JobOption) error {
+	output, err := g.client.GetJob(ctx, &glue.GetJobInput{
+		JobName: aws.String(jobName),
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		return g.CreateJob(ctx, jobName, scriptLocation, opts...)
+	}
+
+	settings := newJobSettings(opts)
+	if jobMatches(output.Job, scriptLocation, settings) {
+		return nil
+	}
+
+	_, err = g.client.UpdateJob(ctx, &glue.UpdateJobInput{
+		JobName: aws.String(jobName),
+		JobUpdate: &types.JobUpdate{
+			Role:             aws.String(glueJobRole),
+			Command:          settings.jobCommand(scriptLocation),
+			GlueVersion:      optionalString(settings.glueVersion),
+			MaxCapacity:      optionalFloat64(settings.maxCapacity),
+			DefaultArguments: settings.defaultArguments,
 		},
 	})
 	if err != nil {
@@ -40,3 +165,90 @@ func (g *Glue) CreateJob(jobName, scriptLocation string) error {
 
 	return nil
 }
+
+// PlanJob returns the action EnsureJob would take for the Glue job with the given name,
+// script location and options without changing it.
+func (g *Glue) PlanJob(ctx context.Context, jobName, scriptLocation string, opts ...JobOption) (Action, error) {
+	drift, err := g.DriftJob(ctx, jobName, scriptLocation, opts...)
+	return drift.Action, err
+}
+
+// DriftJob compares the Glue job with the given name with the job EnsureJob creates for
+// the given script location and options without changing it.
+func (g *Glue) DriftJob(ctx context.Context, jobName, scriptLocation string, opts ...JobOption) (Drift, error) {
+	output, err := g.client.GetJob(ctx, &glue.GetJobInput{
+		JobName: aws.String(jobName),
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return Drift{}, err
+		}
+		return Drift{Action: ActionCreate}, nil
+	}
+
+	return drift(jobDifferences(output.Job, scriptLocation, newJobSettings(opts))), nil
+}
+
+// jobMatches reports whether the given job uses the Glue job role and runs the script
+// at the given location with the given settings.
+func jobMatches(job *types.Job, scriptLocation string, settings jobSettings) bool {
+	return len(jobDifferences(job, scriptLocation, settings)) == 0
+}
+
+// jobDifferences returns the fields of the given job that differ from a job that uses
+// the Glue job role and runs the script at the given location with the given settings.
+// The Glue version, the capacity and the default arguments are only compared if they are
+// set, tags are not compared.
+func jobDifferences(job *types.Job, scriptLocation string, settings jobSettings) differences {
+	var diffs differences
+	if job == nil {
+		job = &types.Job{}
+	}
+	command := job.Command
+	if command == nil {
+		command = &types.JobCommand{}
+	}
+
+	diffs.compare("role", glueJobRole, aws.ToString(job.Role))
+	diffs.compare("command.name", settings.command, aws.ToString(command.Name))
+	diffs.compare("command.scriptLocation", scriptLocation, aws.ToString(command.ScriptLocation))
+	if settings.glueVersion != "" {
+		diffs.compare("glueVersion", settings.glueVersion, aws.ToString(job.GlueVersion))
+	}
+	if settings.maxCapacity != 0 {
+		diffs.compare("maxCapacity", settings.maxCapacity, aws.ToFloat64(job.MaxCapacity))
+	}
+	if settings.defaultArguments != nil {
+		diffs.compareMaps("defaultArguments", settings.defaultArguments, job.DefaultArguments)
+	}
+
+	return diffs
+}
+
+// JobExists reports whether a Glue job with the given name exists.
+func (g *Glue) JobExists(ctx context.Context, jobName string) (bool, error) {
+	_, err := g.client.GetJob(ctx, &glue.GetJobInput{
+		JobName: aws.String(jobName),
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return false, err
+		}
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// DeleteJob deletes the Glue job with the given name. Like the Glue API, it succeeds if
+// the job does not exist.
+func (g *Glue) DeleteJob(ctx context.Context, jobName string) error {
+	_, err := g.client.DeleteJob(ctx, &glue.DeleteJobInput{
+		JobName: aws.String(jobName),
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -4,17 +4,35 @@ import (
 	"context"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/glue"
+	"github.com/aws/aws-sdk-go-v2/service/glue/types"
+	"github.com/aws/smithy-go"
 )
 
 type mockGlueAPI struct {
 	createJobFn func(ctx context.Context, params *glue.CreateJobInput, optFns ...func(*glue.Options)) (*glue.CreateJobOutput, error)
+	getJobFn    func(ctx context.Context, params *glue.GetJobInput, optFns ...func(*glue.Options)) (*glue.GetJobOutput, error)
+	updateJobFn func(ctx context.Context, params *glue.UpdateJobInput, optFns ...func(*glue.Options)) (*glue.UpdateJobOutput, error)
+	deleteJobFn func(ctx context.Context, params *glue.DeleteJobInput, optFns ...func(*glue.Options)) (*glue.DeleteJobOutput, error)
 }
 
 func (m *mockGlueAPI) CreateJob(ctx context.Context, params *glue.CreateJobInput, optFns ...func(*glue.Options)) (*glue.CreateJobOutput, error) {
 	return m.createJobFn(ctx, params, optFns...)
 }
 
+func (m *mockGlueAPI) GetJob(ctx context.Context, params *glue.GetJobInput, optFns ...func(*glue.Options)) (*glue.GetJobOutput, error) {
+	return m.getJobFn(ctx, params, optFns...)
+}
+
+func (m *mockGlueAPI) UpdateJob(ctx context.Context, params *glue.UpdateJobInput, optFns ...func(*glue.Options)) (*glue.UpdateJobOutput, error) {
+	return m.updateJobFn(ctx, params, optFns...)
+}
+
+func (m *mockGlueAPI) DeleteJob(ctx context.Context, params *glue.DeleteJobInput, optFns ...func(*glue.Options)) (*glue.DeleteJobOutput, error) {
+	return m.deleteJobFn(ctx, params, optFns...)
+}
+
 func TestGlue_CreateJob(t *testing.T) {
 	mockClient := &mockGlueAPI{
 		createJobFn: func(ctx context.Context, params *glue.CreateJobInput, optFns ...func(*glue.Options)) (*glue.CreateJobOutput, error) {
@@ -24,8 +42,110 @@ func TestGlue_CreateJob(t *testing.T) {
 
 	g := &Glue{client: mockClient}
 
-	err := g.CreateJob("test-job", "s3://test-bucket/test-job.py")
+	err := g.CreateJob(context.Background(), "test-job", "s3://test-bucket/test-job.py")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func TestGlue_CreateJobWithOptions(t *testing.T) {
+	var input *glue.CreateJobInput
+	mockClient := &mockGlueAPI{
+		createJobFn: func(ctx context.Context, params *glue.CreateJobInput, optFns ...func(*glue.Options)) (*glue.CreateJobOutput, error) {
+			input = params
+			return &glue.CreateJobOutput{}, nil
+		},
+	}
+
+	g := &Glue{client: mockClient}
+
+	err := g.CreateJob(context.Background(), "test-job", "s3://test-bucket/test-job.py",
+		WithJobCommand("glueetl"),
+		WithGlueVersion("4.0"),
+		WithMaxCapacity(2),
+		WithTags(map[string]string{"team": "data"}),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if aws.ToString(input.Command.Name) != "glueetl" || aws.ToString(input.GlueVersion) != "4.0" || aws.ToFloat64(input.MaxCapacity) != 2 {
+		t.Errorf("unexpected job: command %s, version %s, capacity %v", aws.ToString(input.Command.Name), aws.ToString(input.GlueVersion), aws.ToFloat64(input.MaxCapacity))
+	}
+	if input.Tags["team"] != "data" {
+		t.Errorf("unexpected tags: %v", input.Tags)
+	}
+}
+
+func TestGlue_EnsureJob(t *testing.T) {
+	updated := false
+	mockClient := &mockGlueAPI{
+		getJobFn: func(ctx context.Context, params *glue.GetJobInput, optFns ...func(*glue.Options)) (*glue.GetJobOutput, error) {
+			return &glue.GetJobOutput{
+				Job: &types.Job{
+					Name: params.JobName,
+					Role: aws.String(glueJobRole),
+					Command: &types.JobCommand{
+						Name:           aws.String("pythonshell"),
+						ScriptLocation: aws.String("s3://test-bucket/old-job.py"),
+					},
+				},
+			}, nil
+		},
+		updateJobFn: func(ctx context.Context, params *glue.UpdateJobInput, optFns ...func(*glue.Options)) (*glue.UpdateJobOutput, error) {
+			updated = true
+			return &glue.UpdateJobOutput{}, nil
+		},
+	}
+
+	g := &Glue{client: mockClient}
+
+	err := g.EnsureJob(context.Background(), "test-job", "s3://test-bucket/test-job.py")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !updated {
+		t.Errorf("expected job to be updated")
+	}
+}
+
+func TestGlue_DeleteJob(t *testing.T) {
+	mockClient := &mockGlueAPI{
+		deleteJobFn: func(ctx context.Context, params *glue.DeleteJobInput, optFns ...func(*glue.Options)) (*glue.DeleteJobOutput, error) {
+			if aws.ToString(params.JobName) != "test-job" {
+				t.Errorf("unexpected job name: %s", aws.ToString(params.JobName))
+			}
+			return &glue.DeleteJobOutput{}, nil
+		},
+	}
+
+	g := &Glue{client: mockClient}
+
+	err := g.DeleteJob(context.Background(), "test-job")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestGlue_JobExists(t *testing.T) {
+	mockClient := &mockGlueAPI{
+		getJobFn: func(ctx context.Context, params *glue.GetJobInput, optFns ...func(*glue.Options)) (*glue.GetJobOutput, error) {
+			if aws.ToString(params.JobName) != "test-job" {
+				return nil, Classify(&smithy.GenericAPIError{Code: "EntityNotFoundException"})
+			}
+			return &glue.GetJobOutput{Job: &types.Job{Name: params.JobName}}, nil
+		},
+	}
+
+	g := &Glue{client: mockClient}
+
+	exists, err := g.JobExists(context.Background(), "test-job")
+	if err != nil || !exists {
+		t.Errorf("expected job to exist, got %v, %v", exists, err)
+	}
+
+	exists, err = g.JobExists(context.Background(), "missing-job")
+	if err != nil || exists {
+		t.Errorf("expected job to be missing, got %v, %v", exists, err)
+	}
+}
@@ -1,12 +1,19 @@
 package aws
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
@@ -19,7 +26,10 @@ var policies = map[string]string{
 				"Action": [
 					"s3:PutObject",
 					"s3:GetObject",
-					"s3:CreateBucket"
+					"s3:CreateBucket",
+					"s3:DeleteBucket",
+					"s3:ListBucket",
+					"s3:DeleteObject"
 				],
 				"Resource": [
 					"arn:aws:s3:::*/*",
@@ -37,7 +47,26 @@ var policies = map[string]string{
 					"kinesis:CreateStream",
 					"kinesis:DeleteStream",
 					"kinesis:PutRecord",
-					"kinesis:DescribeStream"
+					"kinesis:PutRecords",
+					"kinesis:DescribeStream",
+					"kinesis:DescribeStreamSummary",
+					"kinesis:ListShards",
+					"kinesis:GetShardIterator",
+					"kinesis:GetRecords",
+					"kinesis:UpdateShardCount",
+					"kinesis:SplitShard",
+					"kinesis:MergeShards",
+					"kinesis:RegisterStreamConsumer",
+					"kinesis:DeregisterStreamConsumer",
+					"kinesis:DescribeStreamConsumer",
+					"kinesis:SubscribeToShard",
+					"kinesis:UpdateStreamMode",
+					"kinesis:IncreaseStreamRetentionPeriod",
+					"kinesis:DecreaseStreamRetentionPeriod",
+					"kinesis:StartStreamEncryption",
+					"kinesis:StopStreamEncryption",
+					"kms:GenerateDataKey",
+					"kms:Decrypt"
 				],
 				"Resource": "*"	
 			}
@@ -51,12 +80,20 @@ var policies = map[string]string{
 				"Effect": "Allow",
 				"Action": [
 					"lambda:CreateFunction",
+					"lambda:DeleteFunction",
 					"iam:PassRole",
 					"logs:CreateLogGroup",
 					"logs:CreateLogStream",
 					"logs:PutLogEvents",
 					"s3:PutObject",
 					"lambda:CreateEventSourceMapping",
+					"lambda:GetFunction",
+					"lambda:UpdateFunctionCode",
+					"lambda:UpdateFunctionConfiguration",
+					"lambda:ListEventSourceMappings",
+					"lambda:UpdateEventSourceMapping",
+					"lambda:DeleteEventSourceMapping",
+					"kms:Decrypt",
 					"dynamodb:PutItem"
 				],
 				"Resource": "*"
@@ -121,8 +158,12 @@ var policies = map[string]string{
 					"rds:AddSourceIdentifierToSubscription",
 					"rds:AddTagsToResource",
 					"rds:DescribeDBClusters",
+					"rds:DeleteDBCluster",
 					"rds-data:ExecuteStatement",
-					"secretsmanager:CreateSecret"
+					"secretsmanager:CreateSecret",
+					"secretsmanager:DeleteSecret",
+					"secretsmanager:GetSecretValue",
+					"secretsmanager:PutSecretValue"
 				],
 				"Resource": "*"	
 			}
@@ -146,47 +187,68 @@ type iamAPI interface {
 	CreateRole(context.Context, *iam.CreateRoleInput, ...func(*iam.Options)) (*iam.CreateRoleOutput, error)
 	CreatePolicy(context.Context, *iam.CreatePolicyInput, ...func(*iam.Options)) (*iam.CreatePolicyOutput, error)
 	AttachRolePolicy(context.Context, *iam.AttachRolePolicyInput, ...func(*iam.Options)) (*iam.AttachRolePolicyOutput, error)
+	GetRole(context.Context, *iam.GetRoleInput, ...func(*iam.Options)) (*iam.GetRoleOutput, error)
+	GetPolicy(context.Context, *iam.GetPolicyInput, ...func(*iam.Options)) (*iam.GetPolicyOutput, error)
+	GetPolicyVersion(context.Context, *iam.GetPolicyVersionInput, ...func(*iam.Options)) (*iam.GetPolicyVersionOutput, error)
+	ListPolicyVersions(context.Context, *iam.ListPolicyVersionsInput, ...func(*iam.Options)) (*iam.ListPolicyVersionsOutput, error)
+	CreatePolicyVersion(context.Context, *iam.CreatePolicyVersionInput, ...func(*iam.Options)) (*iam.CreatePolicyVersionOutput, error)
+	DeletePolicyVersion(context.Context, *iam.DeletePolicyVersionInput, ...func(*iam.Options)) (*iam.DeletePolicyVersionOutput, error)
+	DetachRolePolicy(context.Context, *iam.DetachRolePolicyInput, ...func(*iam.Options)) (*iam.DetachRolePolicyOutput, error)
+	DeletePolicy(context.Context, *iam.DeletePolicyInput, ...func(*iam.Options)) (*iam.DeletePolicyOutput, error)
+	DeleteRole(context.Context, *iam.DeleteRoleInput, ...func(*iam.Options)) (*iam.DeleteRoleOutput, error)
 }
 
+// assumeRolePolicyDocument allows every principal to assume the created roles.
+const assumeRolePolicyDocument = `{
+	"Version": "2012-10-17",
+	"Statement": [
+		{
+			"Action": "sts:AssumeRole",
+			"Principal": "*",
+			"Effect": "Allow"
+		}
+	]
+}`
+
+// maxPolicyVersions is the maximum number of versions a managed policy can have.
+const maxPolicyVersions = 5
+
 // IAM is a wrapper around the AWS IAM client.
 type IAM struct {
 	iamClient iamAPI
-	stsClient *sts.Client
+	stsClient stscreds.AssumeRoleAPIClient
 }
 
 // NewIAM creates a new IAM client with the given configuration.
 func NewIAM(config aws.Config) *IAM {
 	return &IAM{
-		iamClient: iam.NewFromConfig(config),
-		stsClient: sts.NewFromConfig(config),
+		iamClient: iam.NewFromConfig(clientConfig(config)),
+		stsClient: sts.NewFromConfig(clientConfig(config)),
 	}
 }
 
+// NewIAMFromClients creates a new IAM wrapper around the given IAM and STS clients, e.g.
+// fakes of the `awsfake` package.
+func NewIAMFromClients(iamClient iamAPI, stsClient stscreds.AssumeRoleAPIClient) *IAM {
+	return &IAM{iamClient: iamClient, stsClient: stsClient}
+}
+
 // CreateRoleWithPolicy creates a role with the given name and service. It first creates
 // a role with the given name and then attaches a policy to it. The policy is defined
 // in the policies map. The service is the AWS service that will assume the role.
 // It returns a credentials cache that can be used to assume the role.
-func (i *IAM) CreateRoleWithPolicy(name, service string) (*aws.CredentialsCache, error) {
+func (i *IAM) CreateRoleWithPolicy(ctx context.Context, name, service string) (*aws.CredentialsCache, error) {
 	// Creates a role for the given service.
-	role, err := i.iamClient.CreateRole(context.TODO(), &iam.CreateRoleInput{
-		RoleName: aws.String(name),
-		AssumeRolePolicyDocument: aws.String(`{
-			"Version": "2012-10-17",
-			"Statement": [
-				{
-					"Action": "sts:AssumeRole",
-					"Principal": "*",
-					"Effect": "Allow"
-				}
-			]
-		}`),
+	role, err := i.iamClient.CreateRole(ctx, &iam.CreateRoleInput{
+		RoleName:                 aws.String(name),
+		AssumeRolePolicyDocument: aws.String(assumeRolePolicyDocument),
 	})
 	if err != nil {
 		return nil, err
 	}
 
 	// Creates a policy for the given service.
-	policyOutput, err := i.iamClient.CreatePolicy(context.TODO(), &iam.CreatePolicyInput{
+	policyOutput, err := i.iamClient.CreatePolicy(ctx, &iam.CreatePolicyInput{
 		PolicyDocument: aws.String(policies[service]),
 		PolicyName:     aws.String(fmt.Sprintf("%s-policy", name)),
 	})
@@ -195,7 +257,7 @@ func (i *IAM) CreateRoleWithPolicy(name, service string) (*aws.CredentialsCache,
 	}
 
 	// Attaches the policy to the role.
-	_, err = i.iamClient.AttachRolePolicy(context.TODO(), &iam.AttachRolePolicyInput{
+	_, err = i.iamClient.AttachRolePolicy(ctx, &iam.AttachRolePolicyInput{
 		PolicyArn: policyOutput.Policy.Arn,
 		RoleName:  role.Role.RoleName,
 	})
@@ -206,3 +268,332 @@ func (i *IAM) CreateRoleWithPolicy(name, service string) (*aws.CredentialsCache,
 	provider := stscreds.NewAssumeRoleProvider(i.stsClient, aws.ToString(role.Role.Arn))
 	return aws.NewCredentialsCache(provider), nil
 }
+
+// EnsureRoleWithPolicy works like CreateRoleWithPolicy but reuses the role and the policy
+// if they already exist. The document of an existing policy is replaced by a new default
+// policy version if it differs from the one in the policies map.
+func (i *IAM) EnsureRoleWithPolicy(ctx context.Context, name, service string) (*aws.CredentialsCache, error) {
+	role, err := i.ensureRole(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	policyArn, err := i.ensurePolicy(ctx, aws.ToString(role.Arn), fmt.Sprintf("%s-policy", name), policies[service])
+	if err != nil {
+		return nil, err
+	}
+
+	// Attaching an already attached policy is a no-op.
+	_, err = i.iamClient.AttachRolePolicy(ctx, &iam.AttachRolePolicyInput{
+		PolicyArn: aws.String(policyArn),
+		RoleName:  role.RoleName,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	provider := stscreds.NewAssumeRoleProvider(i.stsClient, aws.ToString(role.Arn))
+	return aws.NewCredentialsCache(provider), nil
+}
+
+// PlanRoleWithPolicy returns the action EnsureRoleWithPolicy would take for the role with
+// the given name and its policy without changing them.
+func (i *IAM) PlanRoleWithPolicy(ctx context.Context, name, service string) (Action, error) {
+	drift, err := i.DriftRoleWithPolicy(ctx, name, service)
+	return drift.Action, err
+}
+
+// DriftRoleWithPolicy compares the role with the given name and its policy with the role
+// EnsureRoleWithPolicy creates for the given service without changing them.
+func (i *IAM) DriftRoleWithPolicy(ctx context.Context, name, service string) (Drift, error) {
+	role, err := i.iamClient.GetRole(ctx, &iam.GetRoleInput{
+		RoleName: aws.String(name),
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return Drift{}, err
+		}
+		return Drift{Action: ActionCreate}, nil
+	}
+
+	policyName := fmt.Sprintf("%s-policy", name)
+	policyArn, err := policyArnForRole(aws.ToString(role.Role.Arn), policyName)
+	if err != nil {
+		return Drift{}, err
+	}
+
+	var diffs differences
+	policy, err := i.iamClient.GetPolicy(ctx, &iam.GetPolicyInput{
+		PolicyArn: aws.String(policyArn),
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return Drift{}, err
+		}
+		diffs.compare("policy", policyName, missingValue)
+		return drift(diffs), nil
+	}
+
+	version, err := i.iamClient.GetPolicyVersion(ctx, &iam.GetPolicyVersionInput{
+		PolicyArn: aws.String(policyArn),
+		VersionId: policy.Policy.DefaultVersionId,
+	})
+	if err != nil {
+		return Drift{}, err
+	}
+
+	document := aws.ToString(version.PolicyVersion.Document)
+	if !equalPolicyDocuments(document, policies[service]) {
+		diffs.compare("policy.document", compactPolicyDocument(policies[service]), compactPolicyDocument(document))
+	}
+
+	return drift(diffs), nil
+}
+
+// RoleCredentials returns a credentials cache that can be used to assume the existing
+// role with the given name.
+func (i *IAM) RoleCredentials(ctx context.Context, name string) (*aws.CredentialsCache, error) {
+	output, err := i.iamClient.GetRole(ctx, &iam.GetRoleInput{
+		RoleName: aws.String(name),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	provider := stscreds.NewAssumeRoleProvider(i.stsClient, aws.ToString(output.Role.Arn))
+	return aws.NewCredentialsCache(provider), nil
+}
+
+// RoleARN returns the ARN of the existing role with the given name.
+func (i *IAM) RoleARN(ctx context.Context, name string) (string, error) {
+	output, err := i.iamClient.GetRole(ctx, &iam.GetRoleInput{
+		RoleName: aws.String(name),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return aws.ToString(output.Role.Arn), nil
+}
+
+// DeleteRoleWithPolicy deletes the role with the given name together with the policy that
+// was created for it by CreateRoleWithPolicy. A policy that does not exist anymore is
+// skipped.
+func (i *IAM) DeleteRoleWithPolicy(ctx context.Context, name string) error {
+	role, err := i.iamClient.GetRole(ctx, &iam.GetRoleInput{
+		RoleName: aws.String(name),
+	})
+	if err != nil {
+		return err
+	}
+
+	policyArn, err := policyArnForRole(aws.ToString(role.Role.Arn), fmt.Sprintf("%s-policy", name))
+	if err != nil {
+		return err
+	}
+
+	_, err = i.iamClient.DetachRolePolicy(ctx, &iam.DetachRolePolicyInput{
+		PolicyArn: aws.String(policyArn),
+		RoleName:  aws.String(name),
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	if err := i.deletePolicy(ctx, policyArn); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	_, err = i.iamClient.DeleteRole(ctx, &iam.DeleteRoleInput{
+		RoleName: aws.String(name),
+	})
+	return err
+}
+
+// deletePolicy deletes the non-default versions of the policy with the given ARN and the
+// policy itself.
+func (i *IAM) deletePolicy(ctx context.Context, policyArn string) error {
+	versions, err := i.iamClient.ListPolicyVersions(ctx, &iam.ListPolicyVersionsInput{
+		PolicyArn: aws.String(policyArn),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, version := range versions.Versions {
+		if version.IsDefaultVersion {
+			continue
+		}
+
+		_, err := i.iamClient.DeletePolicyVersion(ctx, &iam.DeletePolicyVersionInput{
+			PolicyArn: aws.String(policyArn),
+			VersionId: version.VersionId,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = i.iamClient.DeletePolicy(ctx, &iam.DeletePolicyInput{
+		PolicyArn: aws.String(policyArn),
+	})
+	return err
+}
+
+// ensureRole returns the role with the given name and creates it if it does not exist.
+func (i *IAM) ensureRole(ctx context.Context, name string) (*types.Role, error) {
+	output, err := i.iamClient.GetRole(ctx, &iam.GetRoleInput{
+		RoleName: aws.String(name),
+	})
+	if err == nil {
+		return output.Role, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+
+	created, err := i.iamClient.CreateRole(ctx, &iam.CreateRoleInput{
+		RoleName:                 aws.String(name),
+		AssumeRolePolicyDocument: aws.String(assumeRolePolicyDocument),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return created.Role, nil
+}
+
+// ensurePolicy creates the policy with the given name and document in the account of the
+// given role if it does not exist yet and returns its ARN.
+func (i *IAM) ensurePolicy(ctx context.Context, roleArn, name, document string) (string, error) {
+	policyArn, err := policyArnForRole(roleArn, name)
+	if err != nil {
+		return "", err
+	}
+
+	policy, err := i.iamClient.GetPolicy(ctx, &iam.GetPolicyInput{
+		PolicyArn: aws.String(policyArn),
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return "", err
+		}
+
+		created, err := i.iamClient.CreatePolicy(ctx, &iam.CreatePolicyInput{
+			PolicyDocument: aws.String(document),
+			PolicyName:     aws.String(name),
+		})
+		if err != nil {
+			return "", err
+		}
+		return aws.ToString(created.Policy.Arn), nil
+	}
+
+	version, err := i.iamClient.GetPolicyVersion(ctx, &iam.GetPolicyVersionInput{
+		PolicyArn: aws.String(policyArn),
+		VersionId: policy.Policy.DefaultVersionId,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if equalPolicyDocuments(aws.ToString(version.PolicyVersion.Document), document) {
+		return policyArn, nil
+	}
+
+	if err := i.pruneOldestPolicyVersion(ctx, policyArn); err != nil {
+		return "", err
+	}
+
+	_, err = i.iamClient.CreatePolicyVersion(ctx, &iam.CreatePolicyVersionInput{
+		PolicyArn:      aws.String(policyArn),
+		PolicyDocument: aws.String(document),
+		SetAsDefault:   true,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return policyArn, nil
+}
+
+// pruneOldestPolicyVersion deletes the oldest non-default version of the policy with
+// the given ARN if the policy reached the maximum number of versions.
+func (i *IAM) pruneOldestPolicyVersion(ctx context.Context, policyArn string) error {
+	versions, err := i.iamClient.ListPolicyVersions(ctx, &iam.ListPolicyVersionsInput{
+		PolicyArn: aws.String(policyArn),
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(versions.Versions) < maxPolicyVersions {
+		return nil
+	}
+
+	var oldest *types.PolicyVersion
+	for j := range versions.Versions {
+		version := &versions.Versions[j]
+		if version.IsDefaultVersion {
+			continue
+		}
+		if oldest == nil || aws.ToTime(version.CreateDate).Before(aws.ToTime(oldest.CreateDate)) {
+			oldest = version
+		}
+	}
+
+	if oldest == nil {
+		return nil
+	}
+
+	_, err = i.iamClient.DeletePolicyVersion(ctx, &iam.DeletePolicyVersionInput{
+		PolicyArn: aws.String(policyArn),
+		VersionId: oldest.VersionId,
+	})
+	return err
+}
+
+// policyArnForRole returns the ARN of the policy with the given name in the same
+// partition and account as the role with the given ARN.
+func policyArnForRole(roleArn, name string) (string, error) {
+	parts := strings.Split(roleArn, ":")
+	if len(parts) < 6 {
+		return "", fmt.Errorf("invalid role arn %q: %w", roleArn, ErrValidation)
+	}
+
+	return fmt.Sprintf("arn:%s:iam::%s:policy/%s", parts[1], parts[4], name), nil
+}
+
+// equalPolicyDocuments reports whether the given policy documents are semantically equal.
+// IAM returns URL-encoded documents, so both documents are decoded before comparing them.
+func equalPolicyDocuments(a, b string) bool {
+	decode := func(document string) (any, bool) {
+		if unescaped, err := url.PathUnescape(document); err == nil {
+			document = unescaped
+		}
+
+		var value any
+		if err := json.Unmarshal([]byte(document), &value); err != nil {
+			return nil, false
+		}
+		return value, true
+	}
+
+	valueA, okA := decode(a)
+	valueB, okB := decode(b)
+	return okA && okB && reflect.DeepEqual(valueA, valueB)
+}
+
+// compactPolicyDocument returns the given, possibly URL-encoded, policy document without
+// insignificant whitespace. Invalid documents are returned unchanged.
+func compactPolicyDocument(document string) string {
+	if unescaped, err := url.PathUnescape(document); err == nil {
+		document = unescaped
+	}
+
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, []byte(document)); err != nil {
+		return document
+	}
+	return compacted.String()
+}
@@ -13,6 +13,15 @@ type mockIAMAPI struct {
 	createRoleFn       func(ctx context.Context, params *iam.CreateRoleInput, opts ...func(*iam.Options)) (*iam.CreateRoleOutput, error)
 	createPolicyFn     func(ctx context.Context, params *iam.CreatePolicyInput, opts ...func(*iam.Options)) (*iam.CreatePolicyOutput, error)
 	attachRolePolicyFn func(ctx context.Context, params *iam.AttachRolePolicyInput, opts ...func(*iam.Options)) (*iam.AttachRolePolicyOutput, error)
+	getRoleFn          func(ctx context.Context, params *iam.GetRoleInput, opts ...func(*iam.Options)) (*iam.GetRoleOutput, error)
+	getPolicyFn        func(ctx context.Context, params *iam.GetPolicyInput, opts ...func(*iam.Options)) (*iam.GetPolicyOutput, error)
+	getPolicyVersionFn func(ctx context.Context, params *iam.GetPolicyVersionInput, opts ...func(*iam.Options)) (*iam.GetPolicyVersionOutput, error)
+	listVersionsFn     func(ctx context.Context, params *iam.ListPolicyVersionsInput, opts ...func(*iam.Options)) (*iam.ListPolicyVersionsOutput, error)
+	createVersionFn    func(ctx context.Context, params *iam.CreatePolicyVersionInput, opts ...func(*iam.Options)) (*iam.CreatePolicyVersionOutput, error)
+	deleteVersionFn    func(ctx context.Context, params *iam.DeletePolicyVersionInput, opts ...func(*iam.Options)) (*iam.DeletePolicyVersionOutput, error)
+	detachRolePolicyFn func(ctx context.Context, params *iam.DetachRolePolicyInput, opts ...func(*iam.Options)) (*iam.DetachRolePolicyOutput, error)
+	deletePolicyFn     func(ctx context.Context, params *iam.DeletePolicyInput, opts ...func(*iam.Options)) (*iam.DeletePolicyOutput, error)
+	deleteRoleFn       func(ctx context.Context, params *iam.DeleteRoleInput, opts ...func(*iam.Options)) (*iam.DeleteRoleOutput, error)
 }
 
 func (m *mockIAMAPI) CreateRole(ctx context.Context, params *iam.CreateRoleInput, opts ...func(*iam.Options)) (*iam.CreateRoleOutput, error) {
@@ -27,6 +36,42 @@ func (m *mockIAMAPI) AttachRolePolicy(ctx context.Context, params *iam.AttachRol
 	return m.attachRolePolicyFn(ctx, params, opts...)
 }
 
+func (m *mockIAMAPI) GetRole(ctx context.Context, params *iam.GetRoleInput, opts ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+	return m.getRoleFn(ctx, params, opts...)
+}
+
+func (m *mockIAMAPI) GetPolicy(ctx context.Context, params *iam.GetPolicyInput, opts ...func(*iam.Options)) (*iam.GetPolicyOutput, error) {
+	return m.getPolicyFn(ctx, params, opts...)
+}
+
+func (m *mockIAMAPI) GetPolicyVersion(ctx context.Context, params *iam.GetPolicyVersionInput, opts ...func(*iam.Options)) (*iam.GetPolicyVersionOutput, error) {
+	return m.getPolicyVersionFn(ctx, params, opts...)
+}
+
+func (m *mockIAMAPI) ListPolicyVersions(ctx context.Context, params *iam.ListPolicyVersionsInput, opts ...func(*iam.Options)) (*iam.ListPolicyVersionsOutput, error) {
+	return m.listVersionsFn(ctx, params, opts...)
+}
+
+func (m *mockIAMAPI) CreatePolicyVersion(ctx context.Context, params *iam.CreatePolicyVersionInput, opts ...func(*iam.Options)) (*iam.CreatePolicyVersionOutput, error) {
+	return m.createVersionFn(ctx, params, opts...)
+}
+
+func (m *mockIAMAPI) DeletePolicyVersion(ctx context.Context, params *iam.DeletePolicyVersionInput, opts ...func(*iam.Options)) (*iam.DeletePolicyVersionOutput, error) {
+	return m.deleteVersionFn(ctx, params, opts...)
+}
+
+func (m *mockIAMAPI) DetachRolePolicy(ctx context.Context, params *iam.DetachRolePolicyInput, opts ...func(*iam.Options)) (*iam.DetachRolePolicyOutput, error) {
+	return m.detachRolePolicyFn(ctx, params, opts...)
+}
+
+func (m *mockIAMAPI) DeletePolicy(ctx context.Context, params *iam.DeletePolicyInput, opts ...func(*iam.Options)) (*iam.DeletePolicyOutput, error) {
+	return m.deletePolicyFn(ctx, params, opts...)
+}
+
+func (m *mockIAMAPI) DeleteRole(ctx context.Context, params *iam.DeleteRoleInput, opts ...func(*iam.Options)) (*iam.DeleteRoleOutput, error) {
+	return m.deleteRoleFn(ctx, params, opts...)
+}
+
 func TestIAM_CreateRoleWithPolicy(t *testing.T) {
 	mockClient := &mockIAMAPI{
 		createRoleFn: func(ctx context.Context, params *iam.CreateRoleInput, opts ...func(*iam.Options)) (*iam.CreateRoleOutput, error) {
@@ -50,8 +95,120 @@ func TestIAM_CreateRoleWithPolicy(t *testing.T) {
 
 	iam := &IAM{iamClient: mockClient}
 
-	_, err := iam.CreateRoleWithPolicy("test-role", "test-service")
+	_, err := iam.CreateRoleWithPolicy(context.Background(), "test-role", "test-service")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func TestIAM_EnsureRoleWithPolicy(t *testing.T) {
+	versionCreated := false
+	mockClient := &mockIAMAPI{
+		getRoleFn: func(ctx context.Context, params *iam.GetRoleInput, opts ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+			return &iam.GetRoleOutput{
+				Role: &types.Role{
+					RoleName: params.RoleName,
+					Arn:      aws.String("arn:aws:iam::000000000000:role/test-role"),
+				},
+			}, nil
+		},
+		getPolicyFn: func(ctx context.Context, params *iam.GetPolicyInput, opts ...func(*iam.Options)) (*iam.GetPolicyOutput, error) {
+			if aws.ToString(params.PolicyArn) != "arn:aws:iam::000000000000:policy/test-role-policy" {
+				t.Errorf("unexpected policy arn: %s", aws.ToString(params.PolicyArn))
+			}
+			return &iam.GetPolicyOutput{
+				Policy: &types.Policy{
+					Arn:              params.PolicyArn,
+					DefaultVersionId: aws.String("v1"),
+				},
+			}, nil
+		},
+		getPolicyVersionFn: func(ctx context.Context, params *iam.GetPolicyVersionInput, opts ...func(*iam.Options)) (*iam.GetPolicyVersionOutput, error) {
+			return &iam.GetPolicyVersionOutput{
+				PolicyVersion: &types.PolicyVersion{
+					Document: aws.String("%7B%22Version%22%3A%222012-10-17%22%7D"),
+				},
+			}, nil
+		},
+		listVersionsFn: func(ctx context.Context, params *iam.ListPolicyVersionsInput, opts ...func(*iam.Options)) (*iam.ListPolicyVersionsOutput, error) {
+			return &iam.ListPolicyVersionsOutput{}, nil
+		},
+		createVersionFn: func(ctx context.Context, params *iam.CreatePolicyVersionInput, opts ...func(*iam.Options)) (*iam.CreatePolicyVersionOutput, error) {
+			if !params.SetAsDefault {
+				t.Errorf("expected new policy version to be the default")
+			}
+			versionCreated = true
+			return &iam.CreatePolicyVersionOutput{}, nil
+		},
+		attachRolePolicyFn: func(ctx context.Context, params *iam.AttachRolePolicyInput, opts ...func(*iam.Options)) (*iam.AttachRolePolicyOutput, error) {
+			return &iam.AttachRolePolicyOutput{}, nil
+		},
+	}
+
+	iam := &IAM{iamClient: mockClient}
+
+	_, err := iam.EnsureRoleWithPolicy(context.Background(), "test-role", "s3")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !versionCreated {
+		t.Errorf("expected a new policy version for a changed document")
+	}
+}
+
+func TestEqualPolicyDocuments(t *testing.T) {
+	if !equalPolicyDocuments("%7B%22Version%22%3A%20%222012-10-17%22%7D", `{"Version":"2012-10-17"}`) {
+		t.Errorf("expected encoded and plain documents to be equal")
+	}
+	if equalPolicyDocuments(`{"Version":"2012-10-17"}`, `{"Version":"2008-10-17"}`) {
+		t.Errorf("expected different documents to differ")
+	}
+}
+
+func TestIAM_DeleteRoleWithPolicy(t *testing.T) {
+	roleDeleted := false
+	mockClient := &mockIAMAPI{
+		getRoleFn: func(ctx context.Context, params *iam.GetRoleInput, opts ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+			return &iam.GetRoleOutput{
+				Role: &types.Role{
+					RoleName: params.RoleName,
+					Arn:      aws.String("arn:aws:iam::000000000000:role/test-role"),
+				},
+			}, nil
+		},
+		detachRolePolicyFn: func(ctx context.Context, params *iam.DetachRolePolicyInput, opts ...func(*iam.Options)) (*iam.DetachRolePolicyOutput, error) {
+			return &iam.DetachRolePolicyOutput{}, nil
+		},
+		listVersionsFn: func(ctx context.Context, params *iam.ListPolicyVersionsInput, opts ...func(*iam.Options)) (*iam.ListPolicyVersionsOutput, error) {
+			return &iam.ListPolicyVersionsOutput{
+				Versions: []types.PolicyVersion{
+					{VersionId: aws.String("v1")},
+					{VersionId: aws.String("v2"), IsDefaultVersion: true},
+				},
+			}, nil
+		},
+		deleteVersionFn: func(ctx context.Context, params *iam.DeletePolicyVersionInput, opts ...func(*iam.Options)) (*iam.DeletePolicyVersionOutput, error) {
+			if aws.ToString(params.VersionId) != "v1" {
+				t.Errorf("unexpected version id: %s", aws.ToString(params.VersionId))
+			}
+			return &iam.DeletePolicyVersionOutput{}, nil
+		},
+		deletePolicyFn: func(ctx context.Context, params *iam.DeletePolicyInput, opts ...func(*iam.Options)) (*iam.DeletePolicyOutput, error) {
+			return &iam.DeletePolicyOutput{}, nil
+		},
+		deleteRoleFn: func(ctx context.Context, params *iam.DeleteRoleInput, opts ...func(*iam.Options)) (*iam.DeleteRoleOutput, error) {
+			roleDeleted = true
+			return &iam.DeleteRoleOutput{}, nil
+		},
+	}
+
+	iam := &IAM{iamClient: mockClient}
+
+	err := iam.DeleteRoleWithPolicy(context.Background(), "test-role")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !roleDeleted {
+		t.Errorf("expected role to be deleted")
+	}
+}
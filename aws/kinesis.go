@@ -2,16 +2,95 @@ package aws
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
 )
 
 type kinesisAPI interface {
 	CreateStream(ctx context.Context, params *kinesis.CreateStreamInput, optFns ...func(*kinesis.Options)) (*kinesis.CreateStreamOutput, error)
 	DeleteStream(ctx context.Context, params *kinesis.DeleteStreamInput, optFns ...func(*kinesis.Options)) (*kinesis.DeleteStreamOutput, error)
 	PutRecord(ctx context.Context, params *kinesis.PutRecordInput, optFns ...func(*kinesis.Options)) (*kinesis.PutRecordOutput, error)
+	PutRecords(ctx context.Context, params *kinesis.PutRecordsInput, optFns ...func(*kinesis.Options)) (*kinesis.PutRecordsOutput, error)
 	DescribeStream(ctx context.Context, params *kinesis.DescribeStreamInput, optFns ...func(*kinesis.Options)) (*kinesis.DescribeStreamOutput, error)
+	DescribeStreamSummary(ctx context.Context, params *kinesis.DescribeStreamSummaryInput, optFns ...func(*kinesis.Options)) (*kinesis.DescribeStreamSummaryOutput, error)
+	ListShards(ctx context.Context, params *kinesis.ListShardsInput, optFns ...func(*kinesis.Options)) (*kinesis.ListShardsOutput, error)
+	GetShardIterator(ctx context.Context, params *kinesis.GetShardIteratorInput, optFns ...func(*kinesis.Options)) (*kinesis.GetShardIteratorOutput, error)
+	GetRecords(ctx context.Context, params *kinesis.GetRecordsInput, optFns ...func(*kinesis.Options)) (*kinesis.GetRecordsOutput, error)
+	AddTagsToStream(ctx context.Context, params *kinesis.AddTagsToStreamInput, optFns ...func(*kinesis.Options)) (*kinesis.AddTagsToStreamOutput, error)
+	UpdateShardCount(ctx context.Context, params *kinesis.UpdateShardCountInput, optFns ...func(*kinesis.Options)) (*kinesis.UpdateShardCountOutput, error)
+	SplitShard(ctx context.Context, params *kinesis.SplitShardInput, optFns ...func(*kinesis.Options)) (*kinesis.SplitShardOutput, error)
+	MergeShards(ctx context.Context, params *kinesis.MergeShardsInput, optFns ...func(*kinesis.Options)) (*kinesis.MergeShardsOutput, error)
+	RegisterStreamConsumer(ctx context.Context, params *kinesis.RegisterStreamConsumerInput, optFns ...func(*kinesis.Options)) (*kinesis.RegisterStreamConsumerOutput, error)
+	DeregisterStreamConsumer(ctx context.Context, params *kinesis.DeregisterStreamConsumerInput, optFns ...func(*kinesis.Options)) (*kinesis.DeregisterStreamConsumerOutput, error)
+	DescribeStreamConsumer(ctx context.Context, params *kinesis.DescribeStreamConsumerInput, optFns ...func(*kinesis.Options)) (*kinesis.DescribeStreamConsumerOutput, error)
+	SubscribeToShardEvents(ctx context.Context, params *kinesis.SubscribeToShardInput, optFns ...func(*kinesis.Options)) (*kinesis.SubscribeToShardEventStream, error)
+	UpdateStreamMode(ctx context.Context, params *kinesis.UpdateStreamModeInput, optFns ...func(*kinesis.Options)) (*kinesis.UpdateStreamModeOutput, error)
+	IncreaseStreamRetentionPeriod(ctx context.Context, params *kinesis.IncreaseStreamRetentionPeriodInput, optFns ...func(*kinesis.Options)) (*kinesis.IncreaseStreamRetentionPeriodOutput, error)
+	DecreaseStreamRetentionPeriod(ctx context.Context, params *kinesis.DecreaseStreamRetentionPeriodInput, optFns ...func(*kinesis.Options)) (*kinesis.DecreaseStreamRetentionPeriodOutput, error)
+	StartStreamEncryption(ctx context.Context, params *kinesis.StartStreamEncryptionInput, optFns ...func(*kinesis.Options)) (*kinesis.StartStreamEncryptionOutput, error)
+	StopStreamEncryption(ctx context.Context, params *kinesis.StopStreamEncryptionInput, optFns ...func(*kinesis.Options)) (*kinesis.StopStreamEncryptionOutput, error)
+}
+
+// streamSettings are the settings of a created Kinesis stream. A zero retention period
+// and an empty KMS key keep the retention period and the encryption of the stream.
+type streamSettings struct {
+	mode           types.StreamMode
+	shardCount     int32
+	retentionHours int32
+	kmsKeyID       string
+	tags           map[string]string
+	waitTimeout    time.Duration
+}
+
+// newStreamSettings returns the settings of a stream with the given options. A stream is
+// provisioned with a single shard by default.
+func newStreamSettings(opts []StreamOption) streamSettings {
+	settings := streamSettings{mode: types.StreamModeProvisioned, shardCount: 1, waitTimeout: defaultWaitTimeout}
+	for _, opt := range opts {
+		opt.applyStream(&settings)
+	}
+	return settings
+}
+
+// WithShardCount creates the stream with the given number of shards instead of one.
+func WithShardCount(count int32) StreamOption {
+	return streamOptionFunc(func(s *streamSettings) {
+		s.shardCount = count
+	})
+}
+
+// WithOnDemand creates the stream in on-demand mode, in which Kinesis scales the shards of
+// the stream with its traffic. The shard count of an on-demand stream is ignored.
+func WithOnDemand() StreamOption {
+	return streamOptionFunc(func(s *streamSettings) {
+		s.mode = types.StreamModeOnDemand
+	})
+}
+
+// WithRetention keeps the records of the stream for the given period, rounded down to
+// hours. Kinesis keeps the records of a created stream for 24 hours and for at most 365
+// days. Without it, the retention period of an existing stream is kept.
+func WithRetention(period time.Duration) StreamOption {
+	return streamOptionFunc(func(s *streamSettings) {
+		s.retentionHours = int32(period / time.Hour)
+	})
+}
+
+// WithEncryption encrypts the records of the stream on the server side with the KMS key
+// with the given ID, ARN or alias. The alias `alias/aws/kinesis` is the key that Kinesis
+// manages. Without it, the encryption of an existing stream is kept, and StopEncryption
+// stops it.
+func WithEncryption(kmsKeyID string) StreamOption {
+	return streamOptionFunc(func(s *streamSettings) {
+		s.kmsKeyID = kmsKeyID
+	})
 }
 
 // Kinesis is a wrapper around the AWS Kinesis client.
@@ -22,16 +101,327 @@ type Kinesis struct {
 // NewKinesis creates a new Kinesis client with the given configuration.
 func NewKinesis(config aws.Config) *Kinesis {
 	return &Kinesis{
-		client: kinesis.NewFromConfig(config.Copy()),
+		client: kinesisClient{kinesis.NewFromConfig(clientConfig(config))},
+	}
+}
+
+// NewKinesisFromClient creates a new Kinesis wrapper around the given client, e.g. a fake
+// of the `awsfake` package.
+func NewKinesisFromClient(client kinesisAPI) *Kinesis {
+	return &Kinesis{client: client}
+}
+
+// Create creates a Kinesis stream with the given name and options. The stream has a single
+// shard unless WithShardCount or WithOnDemand is given. Kinesis creates streams with the
+// default retention period and without encryption, so Create waits for the stream to be
+// active before it applies WithRetention and WithEncryption.
+func (k *Kinesis) Create(ctx context.Context, name string, opts ...StreamOption) error {
+	settings := newStreamSettings(opts)
+	input := &kinesis.CreateStreamInput{
+		StreamName:        aws.String(name),
+		StreamModeDetails: &types.StreamModeDetails{StreamMode: settings.mode},
+	}
+	if settings.mode == types.StreamModeProvisioned {
+		input.ShardCount = aws.Int32(settings.shardCount)
+	}
+	_, err := k.client.CreateStream(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	// Kinesis tags streams in a separate call after their creation.
+	if len(settings.tags) > 0 {
+		_, err := k.client.AddTagsToStream(ctx, &kinesis.AddTagsToStreamInput{
+			StreamName: aws.String(name),
+			Tags:       settings.tags,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if settings.retentionHours == 0 && settings.kmsKeyID == "" {
+		return nil
+	}
+	if err := k.WaitUntilActive(ctx, name, settings.waitTimeout); err != nil {
+		return err
+	}
+	summary, err := k.describeSummary(ctx, name)
+	if err != nil {
+		return err
+	}
+	return k.update(ctx, name, summary, settings)
+}
+
+// Ensure creates a Kinesis stream with the given name and options if it does not exist
+// yet and updates the mode, the shard count, the retention period and the encryption of
+// the stream that differ from the options. Kinesis rejects updates of a stream that is
+// not active, so Ensure waits for the stream to be active before every update, at most
+// for the time of WithWaitTimeout. The shards of a provisioned stream are scaled uniformly
+// and at most doubled or halved per update. The retention period and the encryption
+// are kept unless WithRetention and WithEncryption are given.
+func (k *Kinesis) Ensure(ctx context.Context, name string, opts ...StreamOption) error {
+	summary, err := k.describeSummary(ctx, name)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		return k.Create(ctx, name, opts...)
+	}
+
+	return k.update(ctx, name, summary, newStreamSettings(opts))
+}
+
+// update changes the given stream to the given settings and waits for the stream to be
+// active before every update.
+func (k *Kinesis) update(ctx context.Context, name string, summary *types.StreamDescriptionSummary, settings streamSettings) error {
+	for _, update := range k.streamUpdates(name, summary, settings) {
+		if err := k.WaitUntilActive(ctx, name, settings.waitTimeout); err != nil {
+			return err
+		}
+		if err := update(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Plan returns the action Ensure would take for the Kinesis stream with the given name
+// and options without changing it.
+func (k *Kinesis) Plan(ctx context.Context, name string, opts ...StreamOption) (Action, error) {
+	drift, err := k.Drift(ctx, name, opts...)
+	return drift.Action, err
+}
+
+// Drift compares the Kinesis stream with the given name with the stream Ensure creates
+// with the given options without changing it.
+func (k *Kinesis) Drift(ctx context.Context, name string, opts ...StreamOption) (Drift, error) {
+	summary, err := k.describeSummary(ctx, name)
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return Drift{}, err
+		}
+		return Drift{Action: ActionCreate}, nil
+	}
+
+	return drift(streamDifferences(summary, newStreamSettings(opts))), nil
+}
+
+// StreamDescription is the current configuration of a Kinesis stream.
+type StreamDescription struct {
+	Name   string
+	ARN    string
+	Status types.StreamStatus
+	Mode   types.StreamMode
+	// OpenShardCount is the number of shards that take new records.
+	OpenShardCount int32
+	// Retention is the period for which the stream keeps its records.
+	Retention time.Duration
+	// KMSKeyID is the ID, ARN or alias of the KMS key that encrypts the records of the
+	// stream. It is empty if the stream is not encrypted.
+	KMSKeyID string
+	// ConsumerCount is the number of registered enhanced fan-out consumers of the stream.
+	ConsumerCount int32
+}
+
+// Describe returns the current configuration of the Kinesis stream with the given name.
+func (k *Kinesis) Describe(ctx context.Context, name string) (StreamDescription, error) {
+	summary, err := k.describeSummary(ctx, name)
+	if err != nil {
+		return StreamDescription{}, err
 	}
+
+	return StreamDescription{
+		Name:           aws.ToString(summary.StreamName),
+		ARN:            aws.ToString(summary.StreamARN),
+		Status:         summary.StreamStatus,
+		Mode:           streamMode(summary),
+		OpenShardCount: aws.ToInt32(summary.OpenShardCount),
+		Retention:      time.Duration(aws.ToInt32(summary.RetentionPeriodHours)) * time.Hour,
+		KMSKeyID:       kmsKeyID(summary),
+		ConsumerCount:  aws.ToInt32(summary.ConsumerCount),
+	}, nil
 }
 
-// Create creates a Kinesis stream with the given name and sets the shard count to `1`.
-func (k *Kinesis) Create(name string) error {
-	_, err := k.client.CreateStream(context.TODO(), &kinesis.CreateStreamInput{
-		ShardCount: aws.Int32(1),
+// describeSummary returns the summary of the Kinesis stream with the given name.
+func (k *Kinesis) describeSummary(ctx context.Context, name string) (*types.StreamDescriptionSummary, error) {
+	output, err := k.client.DescribeStreamSummary(ctx, &kinesis.DescribeStreamSummaryInput{
 		StreamName: aws.String(name),
 	})
+	if err != nil {
+		return nil, err
+	}
+	if output.StreamDescriptionSummary == nil {
+		return &types.StreamDescriptionSummary{}, nil
+	}
+
+	return output.StreamDescriptionSummary, nil
+}
+
+// streamDifferences returns the settings of the given stream that Ensure would update. The
+// shard count is only compared if the stream should be provisioned, and the retention
+// period and the KMS key only if they are set.
+func streamDifferences(summary *types.StreamDescriptionSummary, settings streamSettings) differences {
+	var diffs differences
+
+	diffs.compare("mode", settings.mode, streamMode(summary))
+	if settings.mode == types.StreamModeProvisioned {
+		diffs.compare("shardCount", settings.shardCount, aws.ToInt32(summary.OpenShardCount))
+	}
+	if settings.retentionHours != 0 {
+		diffs.compare("retentionHours", settings.retentionHours, aws.ToInt32(summary.RetentionPeriodHours))
+	}
+	if settings.kmsKeyID != "" {
+		diffs.compare("kmsKeyId", settings.kmsKeyID, kmsKeyID(summary))
+	}
+
+	return diffs
+}
+
+// streamUpdates returns the updates that change the given stream to the given settings in
+// the order Ensure makes them. A stream keeps its shards when its mode is switched, so the
+// shard count of a stream that becomes provisioned is scaled afterwards, in as many
+// updates as Kinesis needs to reach it.
+func (k *Kinesis) streamUpdates(name string, summary *types.StreamDescriptionSummary, settings streamSettings) []func(context.Context) error {
+	var updates []func(context.Context) error
+
+	if mode := streamMode(summary); mode != settings.mode {
+		updates = append(updates, func(ctx context.Context) error {
+			return k.UpdateMode(ctx, name, settings.mode)
+		})
+	}
+	if settings.mode == types.StreamModeProvisioned {
+		for _, count := range shardCountSteps(aws.ToInt32(summary.OpenShardCount), settings.shardCount) {
+			count := count
+			updates = append(updates, func(ctx context.Context) error {
+				return k.UpdateShardCount(ctx, name, count)
+			})
+		}
+	}
+
+	retention := time.Duration(settings.retentionHours) * time.Hour
+	switch hours := aws.ToInt32(summary.RetentionPeriodHours); {
+	case settings.retentionHours == 0:
+	case hours < settings.retentionHours:
+		updates = append(updates, func(ctx context.Context) error {
+			return k.IncreaseRetention(ctx, name, retention)
+		})
+	case hours > settings.retentionHours:
+		updates = append(updates, func(ctx context.Context) error {
+			return k.DecreaseRetention(ctx, name, retention)
+		})
+	}
+
+	if settings.kmsKeyID != "" && kmsKeyID(summary) != settings.kmsKeyID {
+		updates = append(updates, func(ctx context.Context) error {
+			return k.StartEncryption(ctx, name, settings.kmsKeyID)
+		})
+	}
+
+	return updates
+}
+
+// shardCountSteps returns the shard counts through which a stream with the given number of
+// open shards is scaled to the given number of shards. Kinesis at most doubles or halves
+// the open shards in a single update.
+func shardCountSteps(open, target int32) []int32 {
+	if open <= 0 {
+		return []int32{target}
+	}
+
+	var steps []int32
+	for open != target {
+		switch {
+		case target > open*2:
+			open *= 2
+		case target < (open+1)/2:
+			open = (open + 1) / 2
+		default:
+			open = target
+		}
+		steps = append(steps, open)
+	}
+	return steps
+}
+
+// streamMode returns the mode of the given stream. Streams without mode details are
+// provisioned.
+func streamMode(summary *types.StreamDescriptionSummary) types.StreamMode {
+	if details := summary.StreamModeDetails; details != nil {
+		return details.StreamMode
+	}
+	return types.StreamModeProvisioned
+}
+
+// kmsKeyID returns the ID of the KMS key that encrypts the given stream, or an empty
+// string if the stream is not encrypted.
+func kmsKeyID(summary *types.StreamDescriptionSummary) string {
+	if summary.EncryptionType != types.EncryptionTypeKms {
+		return ""
+	}
+	return aws.ToString(summary.KeyId)
+}
+
+// UpdateShardCount scales the provisioned Kinesis stream with the given name uniformly to
+// the given number of open shards. Kinesis splits and merges the shards while the stream
+// is updating, and a single update can at most double or halve the open shards.
+func (k *Kinesis) UpdateShardCount(ctx context.Context, name string, count int32) error {
+	_, err := k.client.UpdateShardCount(ctx, &kinesis.UpdateShardCountInput{
+		StreamName:       aws.String(name),
+		TargetShardCount: aws.Int32(count),
+		ScalingType:      types.ScalingTypeUniformScaling,
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateMode switches the Kinesis stream with the given name to the given mode. A stream
+// keeps its shards when it is switched, and Kinesis switches the mode of a stream at most
+// twice within 24 hours.
+func (k *Kinesis) UpdateMode(ctx context.Context, name string, mode types.StreamMode) error {
+	streamARN, err := k.GetARN(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = k.client.UpdateStreamMode(ctx, &kinesis.UpdateStreamModeInput{
+		StreamARN:         aws.String(streamARN),
+		StreamModeDetails: &types.StreamModeDetails{StreamMode: mode},
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// IncreaseRetention keeps the records of the Kinesis stream with the given name for the
+// given period, rounded down to hours, which must be longer than its current retention
+// period.
+func (k *Kinesis) IncreaseRetention(ctx context.Context, name string, period time.Duration) error {
+	_, err := k.client.IncreaseStreamRetentionPeriod(ctx, &kinesis.IncreaseStreamRetentionPeriodInput{
+		StreamName:           aws.String(name),
+		RetentionPeriodHours: aws.Int32(int32(period / time.Hour)),
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DecreaseRetention keeps the records of the Kinesis stream with the given name for the
+// given period, rounded down to hours, which must be shorter than its current retention
+// period. Records that are older than the period are no longer readable.
+func (k *Kinesis) DecreaseRetention(ctx context.Context, name string, period time.Duration) error {
+	_, err := k.client.DecreaseStreamRetentionPeriod(ctx, &kinesis.DecreaseStreamRetentionPeriodInput{
+		StreamName:           aws.String(name),
+		RetentionPeriodHours: aws.Int32(int32(period / time.Hour)),
+	})
 	if err != nil {
 		return err
 	}
@@ -39,9 +429,161 @@ func (k *Kinesis) Create(name string) error {
 	return nil
 }
 
+// StartEncryption encrypts the records that are put into the Kinesis stream with the given
+// name with the KMS key with the given ID, ARN or alias. It also replaces the key of an
+// encrypted stream. Records that were put before are not encrypted again.
+func (k *Kinesis) StartEncryption(ctx context.Context, name, kmsKeyID string) error {
+	_, err := k.client.StartStreamEncryption(ctx, &kinesis.StartStreamEncryptionInput{
+		StreamName:     aws.String(name),
+		EncryptionType: types.EncryptionTypeKms,
+		KeyId:          aws.String(kmsKeyID),
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// StopEncryption stops encrypting the records that are put into the Kinesis stream with
+// the given name with the KMS key with the given ID, ARN or alias, which must be the key
+// of the stream.
+func (k *Kinesis) StopEncryption(ctx context.Context, name, kmsKeyID string) error {
+	_, err := k.client.StopStreamEncryption(ctx, &kinesis.StopStreamEncryptionInput{
+		StreamName:     aws.String(name),
+		EncryptionType: types.EncryptionTypeKms,
+		KeyId:          aws.String(kmsKeyID),
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SplitShard splits the open shard with the given ID of the Kinesis stream with the given
+// name into two child shards, which own the lower and the upper half of its hash keys.
+func (k *Kinesis) SplitShard(ctx context.Context, name, shardID string) error {
+	shards, err := k.OpenShards(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	for _, shard := range shards {
+		if aws.ToString(shard.ShardId) != shardID {
+			continue
+		}
+
+		start, end, err := hashKeyRange(shard)
+		if err != nil {
+			return err
+		}
+		middle := new(big.Int).Add(start, end)
+		middle.Rsh(middle, 1).Add(middle, big.NewInt(1))
+
+		_, err = k.client.SplitShard(ctx, &kinesis.SplitShardInput{
+			StreamName:         aws.String(name),
+			ShardToSplit:       aws.String(shardID),
+			NewStartingHashKey: aws.String(middle.String()),
+		})
+		if err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("open shard %s of stream %s: %w", shardID, name, ErrNotFound)
+}
+
+// MergeShards merges the open shard with the given ID of the Kinesis stream with the given
+// name with the adjacent open shard, whose hash keys directly follow or precede its own.
+func (k *Kinesis) MergeShards(ctx context.Context, name, shardID, adjacentShardID string) error {
+	_, err := k.client.MergeShards(ctx, &kinesis.MergeShardsInput{
+		StreamName:           aws.String(name),
+		ShardToMerge:         aws.String(shardID),
+		AdjacentShardToMerge: aws.String(adjacentShardID),
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// OpenShards returns the shards of the Kinesis stream with the given name that take new
+// records, ordered by their hash keys.
+func (k *Kinesis) OpenShards(ctx context.Context, name string) ([]types.Shard, error) {
+	shards, err := listShards(ctx, k.client, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var open []types.Shard
+	for _, shard := range shards {
+		if shard.SequenceNumberRange == nil || shard.SequenceNumberRange.EndingSequenceNumber == nil {
+			open = append(open, shard)
+		}
+	}
+	sort.Slice(open, func(i, j int) bool {
+		a, _, _ := hashKeyRange(open[i])
+		b, _, _ := hashKeyRange(open[j])
+		return a.Cmp(b) < 0
+	})
+
+	return open, nil
+}
+
+// hashKeyRange returns the first and the last hash key of the given shard. The hash keys
+// of an invalid range are zero.
+func hashKeyRange(shard types.Shard) (*big.Int, *big.Int, error) {
+	start, end := new(big.Int), new(big.Int)
+	if r := shard.HashKeyRange; r != nil {
+		_, ok := start.SetString(aws.ToString(r.StartingHashKey), 10)
+		_, ok2 := end.SetString(aws.ToString(r.EndingHashKey), 10)
+		if ok && ok2 {
+			return start, end, nil
+		}
+	}
+	return new(big.Int), new(big.Int), fmt.Errorf("invalid hash key range of shard %s", aws.ToString(shard.ShardId))
+}
+
+// listShards returns every shard of the stream with the given name, including the closed
+// shards.
+func listShards(ctx context.Context, client kinesisAPI, name string) ([]types.Shard, error) {
+	var shards []types.Shard
+	input := &kinesis.ListShardsInput{StreamName: aws.String(name)}
+	for {
+		output, err := client.ListShards(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		shards = append(shards, output.Shards...)
+		if output.NextToken == nil {
+			return shards, nil
+		}
+		input = &kinesis.ListShardsInput{NextToken: output.NextToken}
+	}
+}
+
+// WaitUntilActive waits until the Kinesis stream with the given name is active.
+func (k *Kinesis) WaitUntilActive(ctx context.Context, name string, timeout time.Duration) error {
+	return wait(ctx, fmt.Sprintf("kinesis stream %s", name), timeout, func(ctx context.Context) (bool, string, error) {
+		stream, err := k.client.DescribeStream(ctx, &kinesis.DescribeStreamInput{
+			StreamName: aws.String(name),
+		})
+		if err != nil {
+			return false, "", err
+		}
+
+		status := stream.StreamDescription.StreamStatus
+		return status == types.StreamStatusActive, string(status), nil
+	})
+}
+
 // GetARN returns the ARN of a Kinesis stream with the given name.
-func (k *Kinesis) GetARN(name string) (string, error) {
-	stream, err := k.client.DescribeStream(context.TODO(), &kinesis.DescribeStreamInput{
+func (k *Kinesis) GetARN(ctx context.Context, name string) (string, error) {
+	stream, err := k.client.DescribeStream(ctx, &kinesis.DescribeStreamInput{
 		StreamName: aws.String(name),
 	})
 	if err != nil {
@@ -52,8 +594,8 @@ func (k *Kinesis) GetARN(name string) (string, error) {
 }
 
 // Delete deletes a Kinesis stream with the given name.
-func (k *Kinesis) Delete(name string) error {
-	_, err := k.client.DeleteStream(context.TODO(), &kinesis.DeleteStreamInput{
+func (k *Kinesis) Delete(ctx context.Context, name string) error {
+	_, err := k.client.DeleteStream(ctx, &kinesis.DeleteStreamInput{
 		StreamName: aws.String(name),
 	})
 	if err != nil {
@@ -64,8 +606,8 @@ func (k *Kinesis) Delete(name string) error {
 }
 
 // PutRecord puts a record into a Kinesis stream with the given name and partition key.
-func (k *Kinesis) PutRecord(name, partitionKey string, data []byte) error {
-	_, err := k.client.PutRecord(context.TODO(), &kinesis.PutRecordInput{
+func (k *Kinesis) PutRecord(ctx context.Context, name, partitionKey string, data []byte) error {
+	_, err := k.client.PutRecord(ctx, &kinesis.PutRecordInput{
 		Data:         data,
 		PartitionKey: aws.String(partitionKey),
 		StreamName:   aws.String(name),
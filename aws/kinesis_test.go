@@ -3,18 +3,39 @@ package aws
 import (
 	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/kinesis"
 	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"github.com/aws/smithy-go"
 )
 
 type mockKinesisClient struct {
-	createStreamFunc   func(context.Context, *kinesis.CreateStreamInput, ...func(*kinesis.Options)) (*kinesis.CreateStreamOutput, error)
-	deleteStreamFunc   func(context.Context, *kinesis.DeleteStreamInput, ...func(*kinesis.Options)) (*kinesis.DeleteStreamOutput, error)
-	putRecordFunc      func(context.Context, *kinesis.PutRecordInput, ...func(*kinesis.Options)) (*kinesis.PutRecordOutput, error)
-	describeStreamFunc func(context.Context, *kinesis.DescribeStreamInput, ...func(*kinesis.Options)) (*kinesis.DescribeStreamOutput, error)
+	createStreamFunc     func(context.Context, *kinesis.CreateStreamInput, ...func(*kinesis.Options)) (*kinesis.CreateStreamOutput, error)
+	deleteStreamFunc     func(context.Context, *kinesis.DeleteStreamInput, ...func(*kinesis.Options)) (*kinesis.DeleteStreamOutput, error)
+	putRecordFunc        func(context.Context, *kinesis.PutRecordInput, ...func(*kinesis.Options)) (*kinesis.PutRecordOutput, error)
+	putRecordsFunc       func(context.Context, *kinesis.PutRecordsInput, ...func(*kinesis.Options)) (*kinesis.PutRecordsOutput, error)
+	describeStreamFunc   func(context.Context, *kinesis.DescribeStreamInput, ...func(*kinesis.Options)) (*kinesis.DescribeStreamOutput, error)
+	describeSummaryFunc  func(context.Context, *kinesis.DescribeStreamSummaryInput, ...func(*kinesis.Options)) (*kinesis.DescribeStreamSummaryOutput, error)
+	addTagsFunc          func(context.Context, *kinesis.AddTagsToStreamInput, ...func(*kinesis.Options)) (*kinesis.AddTagsToStreamOutput, error)
+	listShardsFunc       func(context.Context, *kinesis.ListShardsInput, ...func(*kinesis.Options)) (*kinesis.ListShardsOutput, error)
+	getIteratorFunc      func(context.Context, *kinesis.GetShardIteratorInput, ...func(*kinesis.Options)) (*kinesis.GetShardIteratorOutput, error)
+	getRecordsFunc       func(context.Context, *kinesis.GetRecordsInput, ...func(*kinesis.Options)) (*kinesis.GetRecordsOutput, error)
+	updateShardsFunc     func(context.Context, *kinesis.UpdateShardCountInput, ...func(*kinesis.Options)) (*kinesis.UpdateShardCountOutput, error)
+	splitShardFunc       func(context.Context, *kinesis.SplitShardInput, ...func(*kinesis.Options)) (*kinesis.SplitShardOutput, error)
+	mergeShardsFunc      func(context.Context, *kinesis.MergeShardsInput, ...func(*kinesis.Options)) (*kinesis.MergeShardsOutput, error)
+	registerFunc         func(context.Context, *kinesis.RegisterStreamConsumerInput, ...func(*kinesis.Options)) (*kinesis.RegisterStreamConsumerOutput, error)
+	deregisterFunc       func(context.Context, *kinesis.DeregisterStreamConsumerInput, ...func(*kinesis.Options)) (*kinesis.DeregisterStreamConsumerOutput, error)
+	describeConsumerFunc func(context.Context, *kinesis.DescribeStreamConsumerInput, ...func(*kinesis.Options)) (*kinesis.DescribeStreamConsumerOutput, error)
+	subscribeFunc        func(context.Context, *kinesis.SubscribeToShardInput, ...func(*kinesis.Options)) (*kinesis.SubscribeToShardEventStream, error)
+	updateModeFunc       func(context.Context, *kinesis.UpdateStreamModeInput, ...func(*kinesis.Options)) (*kinesis.UpdateStreamModeOutput, error)
+	increaseFunc         func(context.Context, *kinesis.IncreaseStreamRetentionPeriodInput, ...func(*kinesis.Options)) (*kinesis.IncreaseStreamRetentionPeriodOutput, error)
+	decreaseFunc         func(context.Context, *kinesis.DecreaseStreamRetentionPeriodInput, ...func(*kinesis.Options)) (*kinesis.DecreaseStreamRetentionPeriodOutput, error)
+	startEncryptionFunc  func(context.Context, *kinesis.StartStreamEncryptionInput, ...func(*kinesis.Options)) (*kinesis.StartStreamEncryptionOutput, error)
+	stopEncryptionFunc   func(context.Context, *kinesis.StopStreamEncryptionInput, ...func(*kinesis.Options)) (*kinesis.StopStreamEncryptionOutput, error)
 }
 
 func (m *mockKinesisClient) CreateStream(ctx context.Context, input *kinesis.CreateStreamInput, opts ...func(*kinesis.Options)) (*kinesis.CreateStreamOutput, error) {
@@ -29,10 +50,82 @@ func (m *mockKinesisClient) PutRecord(ctx context.Context, input *kinesis.PutRec
 	return m.putRecordFunc(ctx, input, opts...)
 }
 
+func (m *mockKinesisClient) PutRecords(ctx context.Context, input *kinesis.PutRecordsInput, opts ...func(*kinesis.Options)) (*kinesis.PutRecordsOutput, error) {
+	return m.putRecordsFunc(ctx, input, opts...)
+}
+
 func (m *mockKinesisClient) DescribeStream(ctx context.Context, input *kinesis.DescribeStreamInput, opts ...func(*kinesis.Options)) (*kinesis.DescribeStreamOutput, error) {
 	return m.describeStreamFunc(ctx, input, opts...)
 }
 
+func (m *mockKinesisClient) DescribeStreamSummary(ctx context.Context, input *kinesis.DescribeStreamSummaryInput, opts ...func(*kinesis.Options)) (*kinesis.DescribeStreamSummaryOutput, error) {
+	return m.describeSummaryFunc(ctx, input, opts...)
+}
+
+func (m *mockKinesisClient) AddTagsToStream(ctx context.Context, input *kinesis.AddTagsToStreamInput, opts ...func(*kinesis.Options)) (*kinesis.AddTagsToStreamOutput, error) {
+	return m.addTagsFunc(ctx, input, opts...)
+}
+
+func (m *mockKinesisClient) ListShards(ctx context.Context, input *kinesis.ListShardsInput, opts ...func(*kinesis.Options)) (*kinesis.ListShardsOutput, error) {
+	return m.listShardsFunc(ctx, input, opts...)
+}
+
+func (m *mockKinesisClient) GetShardIterator(ctx context.Context, input *kinesis.GetShardIteratorInput, opts ...func(*kinesis.Options)) (*kinesis.GetShardIteratorOutput, error) {
+	return m.getIteratorFunc(ctx, input, opts...)
+}
+
+func (m *mockKinesisClient) GetRecords(ctx context.Context, input *kinesis.GetRecordsInput, opts ...func(*kinesis.Options)) (*kinesis.GetRecordsOutput, error) {
+	return m.getRecordsFunc(ctx, input, opts...)
+}
+
+func (m *mockKinesisClient) UpdateShardCount(ctx context.Context, input *kinesis.UpdateShardCountInput, opts ...func(*kinesis.Options)) (*kinesis.UpdateShardCountOutput, error) {
+	return m.updateShardsFunc(ctx, input, opts...)
+}
+
+func (m *mockKinesisClient) SplitShard(ctx context.Context, input *kinesis.SplitShardInput, opts ...func(*kinesis.Options)) (*kinesis.SplitShardOutput, error) {
+	return m.splitShardFunc(ctx, input, opts...)
+}
+
+func (m *mockKinesisClient) MergeShards(ctx context.Context, input *kinesis.MergeShardsInput, opts ...func(*kinesis.Options)) (*kinesis.MergeShardsOutput, error) {
+	return m.mergeShardsFunc(ctx, input, opts...)
+}
+
+func (m *mockKinesisClient) RegisterStreamConsumer(ctx context.Context, input *kinesis.RegisterStreamConsumerInput, opts ...func(*kinesis.Options)) (*kinesis.RegisterStreamConsumerOutput, error) {
+	return m.registerFunc(ctx, input, opts...)
+}
+
+func (m *mockKinesisClient) DeregisterStreamConsumer(ctx context.Context, input *kinesis.DeregisterStreamConsumerInput, opts ...func(*kinesis.Options)) (*kinesis.DeregisterStreamConsumerOutput, error) {
+	return m.deregisterFunc(ctx, input, opts...)
+}
+
+func (m *mockKinesisClient) DescribeStreamConsumer(ctx context.Context, input *kinesis.DescribeStreamConsumerInput, opts ...func(*kinesis.Options)) (*kinesis.DescribeStreamConsumerOutput, error) {
+	return m.describeConsumerFunc(ctx, input, opts...)
+}
+
+func (m *mockKinesisClient) SubscribeToShardEvents(ctx context.Context, input *kinesis.SubscribeToShardInput, opts ...func(*kinesis.Options)) (*kinesis.SubscribeToShardEventStream, error) {
+	return m.subscribeFunc(ctx, input, opts...)
+}
+
+func (m *mockKinesisClient) UpdateStreamMode(ctx context.Context, input *kinesis.UpdateStreamModeInput, opts ...func(*kinesis.Options)) (*kinesis.UpdateStreamModeOutput, error) {
+	return m.updateModeFunc(ctx, input, opts...)
+}
+
+func (m *mockKinesisClient) IncreaseStreamRetentionPeriod(ctx context.Context, input *kinesis.IncreaseStreamRetentionPeriodInput, opts ...func(*kinesis.Options)) (*kinesis.IncreaseStreamRetentionPeriodOutput, error) {
+	return m.increaseFunc(ctx, input, opts...)
+}
+
+func (m *mockKinesisClient) DecreaseStreamRetentionPeriod(ctx context.Context, input *kinesis.DecreaseStreamRetentionPeriodInput, opts ...func(*kinesis.Options)) (*kinesis.DecreaseStreamRetentionPeriodOutput, error) {
+	return m.decreaseFunc(ctx, input, opts...)
+}
+
+func (m *mockKinesisClient) StartStreamEncryption(ctx context.Context, input *kinesis.StartStreamEncryptionInput, opts ...func(*kinesis.Options)) (*kinesis.StartStreamEncryptionOutput, error) {
+	return m.startEncryptionFunc(ctx, input, opts...)
+}
+
+func (m *mockKinesisClient) StopStreamEncryption(ctx context.Context, input *kinesis.StopStreamEncryptionInput, opts ...func(*kinesis.Options)) (*kinesis.StopStreamEncryptionOutput, error) {
+	return m.stopEncryptionFunc(ctx, input, opts...)
+}
+
 func TestKinesis_Create(t *testing.T) {
 	mockClient := &mockKinesisClient{
 		createStreamFunc: func(ctx context.Context, input *kinesis.CreateStreamInput, opts ...func(*kinesis.Options)) (*kinesis.CreateStreamOutput, error) {
@@ -47,10 +140,399 @@ func TestKinesis_Create(t *testing.T) {
 		client: mockClient,
 	}
 
-	err := kinesisClient.Create("test-stream")
+	err := kinesisClient.Create(context.Background(), "test-stream")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestKinesis_CreateWithOptions(t *testing.T) {
+	var tags map[string]string
+	mockClient := &mockKinesisClient{
+		createStreamFunc: func(ctx context.Context, input *kinesis.CreateStreamInput, opts ...func(*kinesis.Options)) (*kinesis.CreateStreamOutput, error) {
+			if aws.ToInt32(input.ShardCount) != 4 {
+				return nil, fmt.Errorf("unexpected shard count %d", aws.ToInt32(input.ShardCount))
+			}
+			return &kinesis.CreateStreamOutput{}, nil
+		},
+		addTagsFunc: func(ctx context.Context, input *kinesis.AddTagsToStreamInput, opts ...func(*kinesis.Options)) (*kinesis.AddTagsToStreamOutput, error) {
+			tags = input.Tags
+			return &kinesis.AddTagsToStreamOutput{}, nil
+		},
+	}
+
+	kinesisClient := &Kinesis{
+		client: mockClient,
+	}
+
+	err := kinesisClient.Create(context.Background(), "test-stream", WithShardCount(4), WithTags(map[string]string{"team": "data"}), WithTags(map[string]string{"env": "dev"}))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(tags) != 2 || tags["team"] != "data" || tags["env"] != "dev" {
+		t.Errorf("expected the stream to be tagged, got %v", tags)
+	}
+}
+
+func TestKinesis_CreateWithRetentionAndEncryption(t *testing.T) {
+	var calls []string
+	mockClient := &mockKinesisClient{
+		createStreamFunc: func(ctx context.Context, input *kinesis.CreateStreamInput, opts ...func(*kinesis.Options)) (*kinesis.CreateStreamOutput, error) {
+			calls = append(calls, "create")
+			return &kinesis.CreateStreamOutput{}, nil
+		},
+		describeStreamFunc: func(ctx context.Context, input *kinesis.DescribeStreamInput, opts ...func(*kinesis.Options)) (*kinesis.DescribeStreamOutput, error) {
+			calls = append(calls, "wait")
+			return &kinesis.DescribeStreamOutput{
+				StreamDescription: &types.StreamDescription{StreamStatus: types.StreamStatusActive},
+			}, nil
+		},
+		describeSummaryFunc: func(ctx context.Context, input *kinesis.DescribeStreamSummaryInput, opts ...func(*kinesis.Options)) (*kinesis.DescribeStreamSummaryOutput, error) {
+			return &kinesis.DescribeStreamSummaryOutput{
+				StreamDescriptionSummary: &types.StreamDescriptionSummary{OpenShardCount: aws.Int32(1), RetentionPeriodHours: aws.Int32(24)},
+			}, nil
+		},
+		increaseFunc: func(ctx context.Context, input *kinesis.IncreaseStreamRetentionPeriodInput, opts ...func(*kinesis.Options)) (*kinesis.IncreaseStreamRetentionPeriodOutput, error) {
+			calls = append(calls, fmt.Sprintf("increase %d", aws.ToInt32(input.RetentionPeriodHours)))
+			return &kinesis.IncreaseStreamRetentionPeriodOutput{}, nil
+		},
+		startEncryptionFunc: func(ctx context.Context, input *kinesis.StartStreamEncryptionInput, opts ...func(*kinesis.Options)) (*kinesis.StartStreamEncryptionOutput, error) {
+			calls = append(calls, "start "+aws.ToString(input.KeyId))
+			return &kinesis.StartStreamEncryptionOutput{}, nil
+		},
+	}
+
+	kinesisClient := &Kinesis{
+		client: mockClient,
+	}
+
+	err := kinesisClient.Create(context.Background(), "test-stream", WithRetention(7*24*time.Hour), WithEncryption("alias/aws/kinesis"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The settings are only applied once the created stream is active.
+	expected := []string{"create", "wait", "wait", "increase 168", "wait", "start alias/aws/kinesis"}
+	if fmt.Sprint(calls) != fmt.Sprint(expected) {
+		t.Errorf("expected calls %v, got %v", expected, calls)
+	}
+}
+
+func TestKinesis_CreateOnDemand(t *testing.T) {
+	mockClient := &mockKinesisClient{
+		createStreamFunc: func(ctx context.Context, input *kinesis.CreateStreamInput, opts ...func(*kinesis.Options)) (*kinesis.CreateStreamOutput, error) {
+			if input.StreamModeDetails == nil || input.StreamModeDetails.StreamMode != types.StreamModeOnDemand {
+				return nil, errors.New("expected an on-demand stream")
+			}
+			if input.ShardCount != nil {
+				return nil, errors.New("unexpected shard count of an on-demand stream")
+			}
+			return &kinesis.CreateStreamOutput{}, nil
+		},
+	}
+
+	kinesisClient := &Kinesis{
+		client: mockClient,
+	}
+
+	err := kinesisClient.Create(context.Background(), "test-stream", WithShardCount(4), WithOnDemand())
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestKinesis_Ensure(t *testing.T) {
+	created := false
+	mockClient := &mockKinesisClient{
+		describeSummaryFunc: func(ctx context.Context, input *kinesis.DescribeStreamSummaryInput, opts ...func(*kinesis.Options)) (*kinesis.DescribeStreamSummaryOutput, error) {
+			return nil, Classify(&smithy.GenericAPIError{Code: "ResourceNotFoundException"})
+		},
+		createStreamFunc: func(ctx context.Context, input *kinesis.CreateStreamInput, opts ...func(*kinesis.Options)) (*kinesis.CreateStreamOutput, error) {
+			created = true
+			return &kinesis.CreateStreamOutput{}, nil
+		},
+	}
+
+	kinesisClient := &Kinesis{
+		client: mockClient,
+	}
+
+	err := kinesisClient.Ensure(context.Background(), "test-stream")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
+	if !created {
+		t.Errorf("expected stream to be created")
+	}
+}
+
+func TestKinesis_Ensure_Updates(t *testing.T) {
+	provisioned := &types.StreamModeDetails{StreamMode: types.StreamModeProvisioned}
+	onDemand := &types.StreamModeDetails{StreamMode: types.StreamModeOnDemand}
+
+	tests := []struct {
+		name    string
+		summary types.StreamDescriptionSummary
+		opts    []StreamOption
+		updates []string
+	}{
+		{
+			name:    "keeps a matching stream",
+			summary: types.StreamDescriptionSummary{StreamModeDetails: provisioned, OpenShardCount: aws.Int32(2), RetentionPeriodHours: aws.Int32(24)},
+			opts:    []StreamOption{WithShardCount(2)},
+		},
+		{
+			name:    "scales a provisioned stream",
+			summary: types.StreamDescriptionSummary{StreamModeDetails: provisioned, OpenShardCount: aws.Int32(2), RetentionPeriodHours: aws.Int32(24)},
+			opts:    []StreamOption{WithShardCount(4)},
+			updates: []string{"shardCount 4"},
+		},
+		{
+			name:    "scales a provisioned stream in steps up",
+			summary: types.StreamDescriptionSummary{StreamModeDetails: provisioned, OpenShardCount: aws.Int32(1), RetentionPeriodHours: aws.Int32(24)},
+			opts:    []StreamOption{WithShardCount(5)},
+			updates: []string{"shardCount 2", "shardCount 4", "shardCount 5"},
+		},
+		{
+			name:    "scales a provisioned stream in steps down",
+			summary: types.StreamDescriptionSummary{StreamModeDetails: provisioned, OpenShardCount: aws.Int32(9), RetentionPeriodHours: aws.Int32(24)},
+			opts:    []StreamOption{WithShardCount(2)},
+			updates: []string{"shardCount 5", "shardCount 3", "shardCount 2"},
+		},
+		{
+			name:    "switches a stream to on-demand without scaling it",
+			summary: types.StreamDescriptionSummary{StreamModeDetails: provisioned, OpenShardCount: aws.Int32(2), RetentionPeriodHours: aws.Int32(24)},
+			opts:    []StreamOption{WithOnDemand(), WithShardCount(8)},
+			updates: []string{"mode ON_DEMAND"},
+		},
+		{
+			name:    "switches a stream to provisioned before scaling it",
+			summary: types.StreamDescriptionSummary{StreamModeDetails: onDemand, OpenShardCount: aws.Int32(4), RetentionPeriodHours: aws.Int32(24)},
+			opts:    []StreamOption{WithShardCount(2)},
+			updates: []string{"mode PROVISIONED", "shardCount 2"},
+		},
+		{
+			name:    "increases the retention period",
+			summary: types.StreamDescriptionSummary{StreamModeDetails: provisioned, OpenShardCount: aws.Int32(1), RetentionPeriodHours: aws.Int32(24)},
+			opts:    []StreamOption{WithRetention(7 * 24 * time.Hour)},
+			updates: []string{"increase 168"},
+		},
+		{
+			name:    "decreases the retention period",
+			summary: types.StreamDescriptionSummary{StreamModeDetails: provisioned, OpenShardCount: aws.Int32(1), RetentionPeriodHours: aws.Int32(168)},
+			opts:    []StreamOption{WithRetention(24 * time.Hour)},
+			updates: []string{"decrease 24"},
+		},
+		{
+			name:    "starts the encryption",
+			summary: types.StreamDescriptionSummary{StreamModeDetails: provisioned, OpenShardCount: aws.Int32(1), RetentionPeriodHours: aws.Int32(24), EncryptionType: types.EncryptionTypeNone},
+			opts:    []StreamOption{WithEncryption("alias/aws/kinesis")},
+			updates: []string{"start alias/aws/kinesis"},
+		},
+		{
+			name:    "replaces the key",
+			summary: types.StreamDescriptionSummary{StreamModeDetails: provisioned, OpenShardCount: aws.Int32(1), RetentionPeriodHours: aws.Int32(24), EncryptionType: types.EncryptionTypeKms, KeyId: aws.String("alias/aws/kinesis")},
+			opts:    []StreamOption{WithEncryption("alias/speeds")},
+			updates: []string{"start alias/speeds"},
+		},
+		{
+			name:    "keeps the retention period and the encryption without options",
+			summary: types.StreamDescriptionSummary{StreamModeDetails: provisioned, OpenShardCount: aws.Int32(1), RetentionPeriodHours: aws.Int32(168), EncryptionType: types.EncryptionTypeKms, KeyId: aws.String("alias/aws/kinesis")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updates []string
+			waits := 0
+			mockClient := &mockKinesisClient{
+				describeSummaryFunc: func(ctx context.Context, input *kinesis.DescribeStreamSummaryInput, opts ...func(*kinesis.Options)) (*kinesis.DescribeStreamSummaryOutput, error) {
+					summary := tt.summary
+					return &kinesis.DescribeStreamSummaryOutput{StreamDescriptionSummary: &summary}, nil
+				},
+				describeStreamFunc: func(ctx context.Context, input *kinesis.DescribeStreamInput, opts ...func(*kinesis.Options)) (*kinesis.DescribeStreamOutput, error) {
+					waits++
+					return &kinesis.DescribeStreamOutput{
+						StreamDescription: &types.StreamDescription{
+							StreamARN:    aws.String("arn:aws:kinesis:us-east-1:000000000000:stream/test-stream"),
+							StreamStatus: types.StreamStatusActive,
+						},
+					}, nil
+				},
+				updateModeFunc: func(ctx context.Context, input *kinesis.UpdateStreamModeInput, opts ...func(*kinesis.Options)) (*kinesis.UpdateStreamModeOutput, error) {
+					updates = append(updates, fmt.Sprintf("mode %s", input.StreamModeDetails.StreamMode))
+					return &kinesis.UpdateStreamModeOutput{}, nil
+				},
+				updateShardsFunc: func(ctx context.Context, input *kinesis.UpdateShardCountInput, opts ...func(*kinesis.Options)) (*kinesis.UpdateShardCountOutput, error) {
+					if input.ScalingType != types.ScalingTypeUniformScaling {
+						return nil, errors.New("unexpected scaling type")
+					}
+					updates = append(updates, fmt.Sprintf("shardCount %d", aws.ToInt32(input.TargetShardCount)))
+					return &kinesis.UpdateShardCountOutput{}, nil
+				},
+				increaseFunc: func(ctx context.Context, input *kinesis.IncreaseStreamRetentionPeriodInput, opts ...func(*kinesis.Options)) (*kinesis.IncreaseStreamRetentionPeriodOutput, error) {
+					updates = append(updates, fmt.Sprintf("increase %d", aws.ToInt32(input.RetentionPeriodHours)))
+					return &kinesis.IncreaseStreamRetentionPeriodOutput{}, nil
+				},
+				decreaseFunc: func(ctx context.Context, input *kinesis.DecreaseStreamRetentionPeriodInput, opts ...func(*kinesis.Options)) (*kinesis.DecreaseStreamRetentionPeriodOutput, error) {
+					updates = append(updates, fmt.Sprintf("decrease %d", aws.ToInt32(input.RetentionPeriodHours)))
+					return &kinesis.DecreaseStreamRetentionPeriodOutput{}, nil
+				},
+				startEncryptionFunc: func(ctx context.Context, input *kinesis.StartStreamEncryptionInput, opts ...func(*kinesis.Options)) (*kinesis.StartStreamEncryptionOutput, error) {
+					updates = append(updates, fmt.Sprintf("start %s", aws.ToString(input.KeyId)))
+					return &kinesis.StartStreamEncryptionOutput{}, nil
+				},
+				stopEncryptionFunc: func(ctx context.Context, input *kinesis.StopStreamEncryptionInput, opts ...func(*kinesis.Options)) (*kinesis.StopStreamEncryptionOutput, error) {
+					updates = append(updates, fmt.Sprintf("stop %s", aws.ToString(input.KeyId)))
+					return &kinesis.StopStreamEncryptionOutput{}, nil
+				},
+			}
+
+			kinesisClient := &Kinesis{
+				client: mockClient,
+			}
+
+			drift, err := kinesisClient.Drift(context.Background(), "test-stream", tt.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (drift.Action == ActionUpdate) != (len(tt.updates) > 0) {
+				t.Errorf("unexpected drift %+v", drift)
+			}
+
+			if err := kinesisClient.Ensure(context.Background(), "test-stream", tt.opts...); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if fmt.Sprint(updates) != fmt.Sprint(tt.updates) {
+				t.Errorf("expected updates %v, got %v", tt.updates, updates)
+			}
+			if waits < len(tt.updates) {
+				t.Errorf("expected a wait before each of the %d updates, got %d", len(tt.updates), waits)
+			}
+		})
+	}
+}
+
+func TestKinesis_Describe(t *testing.T) {
+	mockClient := &mockKinesisClient{
+		describeSummaryFunc: func(ctx context.Context, input *kinesis.DescribeStreamSummaryInput, opts ...func(*kinesis.Options)) (*kinesis.DescribeStreamSummaryOutput, error) {
+			return &kinesis.DescribeStreamSummaryOutput{
+				StreamDescriptionSummary: &types.StreamDescriptionSummary{
+					StreamName:           input.StreamName,
+					StreamStatus:         types.StreamStatusActive,
+					StreamModeDetails:    &types.StreamModeDetails{StreamMode: types.StreamModeOnDemand},
+					OpenShardCount:       aws.Int32(4),
+					RetentionPeriodHours: aws.Int32(168),
+					EncryptionType:       types.EncryptionTypeKms,
+					KeyId:                aws.String("alias/aws/kinesis"),
+				},
+			}, nil
+		},
+	}
+
+	kinesisClient := &Kinesis{
+		client: mockClient,
+	}
+
+	stream, err := kinesisClient.Describe(context.Background(), "test-stream")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stream.Name != "test-stream" || stream.Mode != types.StreamModeOnDemand || stream.OpenShardCount != 4 {
+		t.Errorf("unexpected stream: %+v", stream)
+	}
+	if stream.Retention != 7*24*time.Hour || stream.KMSKeyID != "alias/aws/kinesis" {
+		t.Errorf("unexpected retention or key: %+v", stream)
+	}
+}
+
+func TestKinesis_SplitShard(t *testing.T) {
+	var newStart string
+	mockClient := &mockKinesisClient{
+		listShardsFunc: func(ctx context.Context, input *kinesis.ListShardsInput, opts ...func(*kinesis.Options)) (*kinesis.ListShardsOutput, error) {
+			return &kinesis.ListShardsOutput{
+				Shards: []types.Shard{
+					{
+						ShardId:             aws.String("shardId-000000000000"),
+						HashKeyRange:        &types.HashKeyRange{StartingHashKey: aws.String("0"), EndingHashKey: aws.String("99")},
+						SequenceNumberRange: &types.SequenceNumberRange{StartingSequenceNumber: aws.String("1"), EndingSequenceNumber: aws.String("2")},
+					},
+					{
+						ShardId:             aws.String("shardId-000000000001"),
+						HashKeyRange:        &types.HashKeyRange{StartingHashKey: aws.String("0"), EndingHashKey: aws.String("99")},
+						SequenceNumberRange: &types.SequenceNumberRange{StartingSequenceNumber: aws.String("3")},
+					},
+				},
+			}, nil
+		},
+		splitShardFunc: func(ctx context.Context, input *kinesis.SplitShardInput, opts ...func(*kinesis.Options)) (*kinesis.SplitShardOutput, error) {
+			if aws.ToString(input.ShardToSplit) != "shardId-000000000001" {
+				return nil, errors.New("unexpected shard")
+			}
+			newStart = aws.ToString(input.NewStartingHashKey)
+			return &kinesis.SplitShardOutput{}, nil
+		},
+	}
+
+	kinesisClient := &Kinesis{
+		client: mockClient,
+	}
+
+	if err := kinesisClient.SplitShard(context.Background(), "test-stream", "shardId-000000000001"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if newStart != "50" {
+		t.Errorf("expected the shard to be split in the middle, got %s", newStart)
+	}
+
+	err := kinesisClient.SplitShard(context.Background(), "test-stream", "shardId-000000000000")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a closed shard not to be found, got %v", err)
+	}
+}
+
+func TestKinesis_EnsureConsumer(t *testing.T) {
+	registered := ""
+	mockClient := &mockKinesisClient{
+		describeStreamFunc: func(ctx context.Context, input *kinesis.DescribeStreamInput, opts ...func(*kinesis.Options)) (*kinesis.DescribeStreamOutput, error) {
+			return &kinesis.DescribeStreamOutput{
+				StreamDescription: &types.StreamDescription{StreamARN: aws.String("test-arn")},
+			}, nil
+		},
+		describeConsumerFunc: func(ctx context.Context, input *kinesis.DescribeStreamConsumerInput, opts ...func(*kinesis.Options)) (*kinesis.DescribeStreamConsumerOutput, error) {
+			if registered == "" {
+				return nil, Classify(&smithy.GenericAPIError{Code: "ResourceNotFoundException"})
+			}
+			return &kinesis.DescribeStreamConsumerOutput{
+				ConsumerDescription: &types.ConsumerDescription{ConsumerARN: aws.String(registered)},
+			}, nil
+		},
+		registerFunc: func(ctx context.Context, input *kinesis.RegisterStreamConsumerInput, opts ...func(*kinesis.Options)) (*kinesis.RegisterStreamConsumerOutput, error) {
+			if aws.ToString(input.StreamARN) != "test-arn" || aws.ToString(input.ConsumerName) != "test-consumer" {
+				return nil, errors.New("unexpected consumer")
+			}
+			if registered != "" {
+				return nil, ClassifyOperation("RegisterStreamConsumer", &smithy.GenericAPIError{Code: "ResourceInUseException"})
+			}
+			registered = "test-arn/consumer/test-consumer:1"
+			return &kinesis.RegisterStreamConsumerOutput{
+				Consumer: &types.Consumer{ConsumerARN: aws.String(registered)},
+			}, nil
+		},
+	}
+
+	kinesisClient := &Kinesis{
+		client: mockClient,
+	}
+
+	for i := 0; i < 2; i++ {
+		arn, err := kinesisClient.EnsureConsumer(context.Background(), "test-stream", "test-consumer")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if arn != "test-arn/consumer/test-consumer:1" {
+			t.Errorf("unexpected consumer arn: %s", arn)
+		}
+	}
 }
 
 func TestKinesis_Delete(t *testing.T) {
@@ -67,7 +549,7 @@ func TestKinesis_Delete(t *testing.T) {
 		client: mockClient,
 	}
 
-	err := kinesisClient.Delete("test-stream")
+	err := kinesisClient.Delete(context.Background(), "test-stream")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -93,7 +575,7 @@ func TestKinesis_PutRecord(t *testing.T) {
 		client: mockClient,
 	}
 
-	err := kinesisClient.PutRecord("test-stream", "test-key", []byte("test-data"))
+	err := kinesisClient.PutRecord(context.Background(), "test-stream", "test-key", []byte("test-data"))
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -117,7 +599,7 @@ func TestKinesis_GetARN(t *testing.T) {
 		client: mockClient,
 	}
 
-	arn, err := kinesisClient.GetARN("test-stream")
+	arn, err := kinesisClient.GetARN(context.Background(), "test-stream")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -125,3 +607,26 @@ func TestKinesis_GetARN(t *testing.T) {
 		t.Errorf("unexpected arn: %s", arn)
 	}
 }
+
+func TestKinesis_WaitUntilActive_Timeout(t *testing.T) {
+	setWaitDelay(t)
+
+	mockClient := &mockKinesisClient{
+		describeStreamFunc: func(ctx context.Context, input *kinesis.DescribeStreamInput, opts ...func(*kinesis.Options)) (*kinesis.DescribeStreamOutput, error) {
+			return &kinesis.DescribeStreamOutput{
+				StreamDescription: &types.StreamDescription{
+					StreamStatus: types.StreamStatusCreating,
+				},
+			}, nil
+		},
+	}
+
+	kinesisClient := &Kinesis{
+		client: mockClient,
+	}
+
+	err := kinesisClient.WaitUntilActive(context.Background(), "test-stream", 10*time.Millisecond)
+	if !errors.Is(err, ErrWaitTimeout) {
+		t.Errorf("expected timeout error, got %v", err)
+	}
+}
@@ -2,6 +2,11 @@ package aws
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
@@ -12,37 +17,115 @@ type lambdaAPI interface {
 	CreateFunction(ctx context.Context, params *lambda.CreateFunctionInput, optFns ...func(*lambda.Options)) (*lambda.CreateFunctionOutput, error)
 	DeleteFunction(ctx context.Context, params *lambda.DeleteFunctionInput, optFns ...func(*lambda.Options)) (*lambda.DeleteFunctionOutput, error)
 	CreateEventSourceMapping(ctx context.Context, params *lambda.CreateEventSourceMappingInput, optFns ...func(*lambda.Options)) (*lambda.CreateEventSourceMappingOutput, error)
+	GetFunction(ctx context.Context, params *lambda.GetFunctionInput, optFns ...func(*lambda.Options)) (*lambda.GetFunctionOutput, error)
+	UpdateFunctionCode(ctx context.Context, params *lambda.UpdateFunctionCodeInput, optFns ...func(*lambda.Options)) (*lambda.UpdateFunctionCodeOutput, error)
+	UpdateFunctionConfiguration(ctx context.Context, params *lambda.UpdateFunctionConfigurationInput, optFns ...func(*lambda.Options)) (*lambda.UpdateFunctionConfigurationOutput, error)
+	ListEventSourceMappings(ctx context.Context, params *lambda.ListEventSourceMappingsInput, optFns ...func(*lambda.Options)) (*lambda.ListEventSourceMappingsOutput, error)
+	UpdateEventSourceMapping(ctx context.Context, params *lambda.UpdateEventSourceMappingInput, optFns ...func(*lambda.Options)) (*lambda.UpdateEventSourceMappingOutput, error)
+	DeleteEventSourceMapping(ctx context.Context, params *lambda.DeleteEventSourceMappingInput, optFns ...func(*lambda.Options)) (*lambda.DeleteEventSourceMappingOutput, error)
 }
 
+// Default settings of the Lambda functions and event source mappings.
+const (
+	lambdaTimeout    = 60
+	lambdaMemorySize = 128
+	lambdaBatchSize  = 100
+)
+
 // Lambda is a wrapper around the AWS Lambda client.
 type Lambda struct {
 	client lambdaAPI
 }
 
+// FunctionConfig is the configuration of a Lambda function besides its code, handler and
+// runtime.
+type FunctionConfig struct {
+	// RoleARN is the ARN of the IAM role the function assumes, e.g. of IAM.RoleARN.
+	RoleARN string
+	// Environment are the environment variables of the function.
+	Environment map[string]string
+}
+
+// functionSettings are the settings of a Lambda function besides its FunctionConfig.
+type functionSettings struct {
+	memorySize  int32
+	timeout     int32
+	tags        map[string]string
+	waitTimeout time.Duration
+}
+
+// newFunctionSettings returns the settings of a function with the given options. A
+// function has 128 MB of memory and times out after 60 seconds by default.
+func newFunctionSettings(opts []FunctionOption) functionSettings {
+	settings := functionSettings{memorySize: lambdaMemorySize, timeout: lambdaTimeout, waitTimeout: defaultWaitTimeout}
+	for _, opt := range opts {
+		opt.applyFunction(&settings)
+	}
+	return settings
+}
+
+// WithMemory gives the function the given amount of memory in MB instead of 128 MB.
+func WithMemory(megabytes int32) FunctionOption {
+	return functionOptionFunc(func(s *functionSettings) {
+		s.memorySize = megabytes
+	})
+}
+
+// WithTimeout lets the function run for the given duration, rounded down to seconds,
+// instead of 60 seconds.
+func WithTimeout(timeout time.Duration) FunctionOption {
+	return functionOptionFunc(func(s *functionSettings) {
+		s.timeout = int32(timeout / time.Second)
+	})
+}
+
 // NewLambda creates a new Lambda client with the given configuration.
 func NewLambda(config aws.Config) *Lambda {
 	return &Lambda{
-		client: lambda.NewFromConfig(config.Copy()),
+		client: lambda.NewFromConfig(clientConfig(config)),
 	}
 }
 
-// CreateGo creates a Lambda function from a Go binary. The binary must be zipped and
-// uploaded to S3. The bucketName and bucketKey parameters are the name of the bucket.
-// It will return the ARN of the Lambda function and an error if there is one.
-func (l *Lambda) CreateGo(name, bucketName, bucketObjectKey string) (string, error) {
-	createOutput, err := l.client.CreateFunction(context.TODO(), &lambda.CreateFunctionInput{
+// NewLambdaFromClient creates a new Lambda wrapper around the given client, e.g. a fake of
+// the `awsfake` package.
+func NewLambdaFromClient(client lambdaAPI) *Lambda {
+	return &Lambda{client: client}
+}
+
+// CreateGo creates a Lambda function from a Go binary with the given configuration and
+// options. The binary must be zipped and uploaded to S3. The bucketName and bucketKey
+// parameters are the name of the bucket. It will return the ARN of the Lambda function
+// and an error if there is one.
+func (l *Lambda) CreateGo(ctx context.Context, name, bucketName, bucketObjectKey string, functionConfig FunctionConfig, opts ...FunctionOption) (string, error) {
+	return l.create(ctx, name, bucketName, bucketObjectKey, "main", types.RuntimeGo1x, functionConfig, opts)
+}
+
+// CreateNode creates a Lambda function from a Node.js binary with the given configuration
+// and options. The binary must be zipped and uploaded to S3. The bucketName and bucketKey
+// parameters are the name of the bucket. It will return the ARN of the Lambda function
+// and an error if there is one.
+func (l *Lambda) CreateNode(ctx context.Context, name, bucketName, bucketObjectKey string, functionConfig FunctionConfig, opts ...FunctionOption) (string, error) {
+	return l.create(ctx, name, bucketName, bucketObjectKey, "index.handler", types.RuntimeNodejs16x, functionConfig, opts)
+}
+
+// create creates a Lambda function with the given handler and runtime from the code in the
+// given bucket object.
+func (l *Lambda) create(ctx context.Context, name, bucketName, bucketObjectKey, handler string, runtime types.Runtime, functionConfig FunctionConfig, opts []FunctionOption) (string, error) {
+	settings := newFunctionSettings(opts)
+	createOutput, err := l.client.CreateFunction(ctx, &lambda.CreateFunctionInput{
 		Code: &types.FunctionCode{
 			S3Bucket: aws.String(bucketName),
 			S3Key:    aws.String(bucketObjectKey),
 		},
 		FunctionName: aws.String(name),
-		Handler:      aws.String("main"),
-		Runtime:      types.RuntimeGo1x,
-		Role:         aws.String("arn:aws:iam::000000000000:role/lambda-role"),
-		Timeout:      aws.Int32(60),
-		MemorySize:   aws.Int32(128),
+		Handler:      aws.String(handler),
+		Runtime:      runtime,
+		Role:         aws.String(functionConfig.RoleARN),
+		Timeout:      aws.Int32(settings.timeout),
+		MemorySize:   aws.Int32(settings.memorySize),
 		Publish:      true,
-		Environment:  &types.Environment{},
+		Environment:  &types.Environment{Variables: functionConfig.Environment},
+		Tags:         settings.tags,
 	})
 	if err != nil {
 		return "", err
@@ -51,34 +134,219 @@ func (l *Lambda) CreateGo(name, bucketName, bucketObjectKey string) (string, err
 	return aws.ToString(createOutput.FunctionArn), nil
 }
 
-// CreateNode creates a Lambda function from a Node.js binary. The binary must be zipped
-// and uploaded to S3. The bucketName and bucketKey parameters are the name of the bucket.
-// It will return the ARN of the Lambda function and an error if there is one.
-func (l *Lambda) CreateNode(name, bucketName, bucketObjecyKey string) (string, error) {
-	createOutput, err := l.client.CreateFunction(context.TODO(), &lambda.CreateFunctionInput{
-		Code: &types.FunctionCode{
-			S3Bucket: aws.String(bucketName),
-			S3Key:    aws.String(bucketObjecyKey),
-		},
+// EnsureGo creates a Lambda function from a Go binary if it does not exist yet. The code
+// of an existing function is updated from the given bucket object unless it equals the
+// given zipped binary, which is the content of the bucket object. Without a zipped binary
+// the code is always updated. The configuration is updated if it differs from the given
+// one and options. It will return the ARN of the Lambda function and an error if there is
+// one.
+func (l *Lambda) EnsureGo(ctx context.Context, name, bucketName, bucketObjectKey string, code []byte, functionConfig FunctionConfig, opts ...FunctionOption) (string, error) {
+	return l.ensure(ctx, name, bucketName, bucketObjectKey, code, "main", types.RuntimeGo1x, functionConfig, opts)
+}
+
+// EnsureNode creates a Lambda function from a Node.js binary if it does not exist yet.
+// The code of an existing function is updated from the given bucket object unless it
+// equals the given zipped binary, which is the content of the bucket object. Without a
+// zipped binary the code is always updated. The configuration is updated if it differs
+// from the given one and options. It will return the ARN of the Lambda function and an
+// error if there is one.
+func (l *Lambda) EnsureNode(ctx context.Context, name, bucketName, bucketObjectKey string, code []byte, functionConfig FunctionConfig, opts ...FunctionOption) (string, error) {
+	return l.ensure(ctx, name, bucketName, bucketObjectKey, code, "index.handler", types.RuntimeNodejs16x, functionConfig, opts)
+}
+
+// ensure creates the Lambda function with the given handler and runtime if it does not
+// exist yet. Otherwise, the configuration and the code of the existing function are
+// updated if they differ. Lambda rejects an update while another one is in progress, so
+// ensure waits for the function to finish its update before and after every update.
+func (l *Lambda) ensure(ctx context.Context, name, bucketName, bucketObjectKey string, code []byte, handler string, runtime types.Runtime, functionConfig FunctionConfig, opts []FunctionOption) (string, error) {
+	function, err := l.client.GetFunction(ctx, &lambda.GetFunctionInput{
 		FunctionName: aws.String(name),
-		Handler:      aws.String("index.handler"),
-		Runtime:      types.RuntimeNodejs16x,
-		Role:         aws.String("arn:aws:iam::000000000000:role/lambda-role"),
-		Timeout:      aws.Int32(60),
-		MemorySize:   aws.Int32(128),
-		Publish:      true,
-		Environment:  &types.Environment{},
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return "", err
+		}
+		return l.create(ctx, name, bucketName, bucketObjectKey, handler, runtime, functionConfig, opts)
+	}
+
+	settings := newFunctionSettings(opts)
+	update := func(call func() error) error {
+		if err := l.WaitUntilActive(ctx, name, settings.waitTimeout); err != nil {
+			return err
+		}
+		if err := call(); err != nil {
+			return err
+		}
+		return l.WaitUntilActive(ctx, name, settings.waitTimeout)
+	}
+
+	config := function.Configuration
+	if !functionMatches(config, handler, runtime, functionConfig, settings) {
+		err := update(func() error {
+			_, err := l.client.UpdateFunctionConfiguration(ctx, &lambda.UpdateFunctionConfigurationInput{
+				FunctionName: aws.String(name),
+				Handler:      aws.String(handler),
+				Runtime:      runtime,
+				Role:         aws.String(functionConfig.RoleARN),
+				Timeout:      aws.Int32(settings.timeout),
+				MemorySize:   aws.Int32(settings.memorySize),
+				Environment:  &types.Environment{Variables: functionConfig.Environment},
+			})
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if code != nil && aws.ToString(config.CodeSha256) == codeSha256(code) {
+		return aws.ToString(config.FunctionArn), nil
+	}
+
+	err = update(func() error {
+		_, err := l.client.UpdateFunctionCode(ctx, &lambda.UpdateFunctionCodeInput{
+			FunctionName: aws.String(name),
+			S3Bucket:     aws.String(bucketName),
+			S3Key:        aws.String(bucketObjectKey),
+			Publish:      true,
+		})
+		return err
 	})
 	if err != nil {
 		return "", err
 	}
 
-	return aws.ToString(createOutput.FunctionArn), nil
+	return aws.ToString(config.FunctionArn), nil
+}
+
+// WaitUntilActive waits until the Lambda function with the given name is active and its
+// last update finished. A function or an update that failed is an error.
+func (l *Lambda) WaitUntilActive(ctx context.Context, name string, timeout time.Duration) error {
+	return wait(ctx, fmt.Sprintf("lambda function %s", name), timeout, func(ctx context.Context) (bool, string, error) {
+		function, err := l.client.GetFunction(ctx, &lambda.GetFunctionInput{
+			FunctionName: aws.String(name),
+		})
+		if err != nil {
+			return false, "", err
+		}
+
+		config := function.Configuration
+		if config == nil {
+			return false, "unknown", nil
+		}
+
+		switch {
+		case config.State == types.StateFailed:
+			return false, "", fmt.Errorf("function failed: %s", aws.ToString(config.StateReason))
+		case config.LastUpdateStatus == types.LastUpdateStatusFailed:
+			return false, "", fmt.Errorf("update failed: %s", aws.ToString(config.LastUpdateStatusReason))
+		case config.LastUpdateStatus == types.LastUpdateStatusInProgress:
+			return false, "updating", nil
+		}
+
+		return config.State == types.StateActive, string(config.State), nil
+	})
+}
+
+// PlanGo returns the ARN of the Lambda function with the given name and the action
+// EnsureGo would take for it with the given configuration and options without changing
+// it. The given code is the zipped binary that would be uploaded. The ARN is empty if the
+// function does not exist yet.
+func (l *Lambda) PlanGo(ctx context.Context, name string, code []byte, functionConfig FunctionConfig, opts ...FunctionOption) (string, Action, error) {
+	return l.plan(ctx, name, code, "main", types.RuntimeGo1x, functionConfig, opts)
+}
+
+// PlanNode returns the ARN of the Lambda function with the given name and the action
+// EnsureNode would take for it with the given configuration and options without changing
+// it. The given code is the zipped binary that would be uploaded. The ARN is empty if the
+// function does not exist yet.
+func (l *Lambda) PlanNode(ctx context.Context, name string, code []byte, functionConfig FunctionConfig, opts ...FunctionOption) (string, Action, error) {
+	return l.plan(ctx, name, code, "index.handler", types.RuntimeNodejs16x, functionConfig, opts)
+}
+
+// plan compares the configuration and the code of the existing Lambda function with the
+// given handler, runtime, configuration, options and code.
+func (l *Lambda) plan(ctx context.Context, name string, code []byte, handler string, runtime types.Runtime, functionConfig FunctionConfig, opts []FunctionOption) (string, Action, error) {
+	arn, drift, err := l.drift(ctx, name, code, handler, runtime, functionConfig, opts)
+	return arn, drift.Action, err
+}
+
+// DriftGo returns the ARN of the Lambda function with the given name and compares it
+// with the function EnsureGo creates with the given configuration and options without
+// changing it. The code is only compared if the zipped binary is given. The ARN is empty
+// if the function does not exist yet.
+func (l *Lambda) DriftGo(ctx context.Context, name string, code []byte, functionConfig FunctionConfig, opts ...FunctionOption) (string, Drift, error) {
+	return l.drift(ctx, name, code, "main", types.RuntimeGo1x, functionConfig, opts)
+}
+
+// DriftNode returns the ARN of the Lambda function with the given name and compares it
+// with the function EnsureNode creates with the given configuration and options without
+// changing it. The code is only compared if the zipped binary is given. The ARN is empty
+// if the function does not exist yet.
+func (l *Lambda) DriftNode(ctx context.Context, name string, code []byte, functionConfig FunctionConfig, opts ...FunctionOption) (string, Drift, error) {
+	return l.drift(ctx, name, code, "index.handler", types.RuntimeNodejs16x, functionConfig, opts)
+}
+
+// drift compares the configuration and the code of the existing Lambda function with the
+// given handler, runtime, configuration, options and code.
+func (l *Lambda) drift(ctx context.Context, name string, code []byte, handler string, runtime types.Runtime, functionConfig FunctionConfig, opts []FunctionOption) (string, Drift, error) {
+	function, err := l.client.GetFunction(ctx, &lambda.GetFunctionInput{
+		FunctionName: aws.String(name),
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return "", Drift{}, err
+		}
+		return "", Drift{Action: ActionCreate}, nil
+	}
+
+	config := function.Configuration
+	if config == nil {
+		return "", Drift{}, fmt.Errorf("function %s has no configuration", name)
+	}
+
+	diffs := functionDifferences(config, handler, runtime, functionConfig, newFunctionSettings(opts))
+	if code != nil {
+		diffs.compare("codeSha256", codeSha256(code), aws.ToString(config.CodeSha256))
+	}
+
+	return aws.ToString(config.FunctionArn), drift(diffs), nil
+}
+
+// codeSha256 returns the hash of the given zipped code in the format Lambda reports it.
+func codeSha256(code []byte) string {
+	hash := sha256.Sum256(code)
+	return base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// functionMatches reports whether the given function configuration uses the given
+// handler, runtime, configuration and settings.
+func functionMatches(config *types.FunctionConfiguration, handler string, runtime types.Runtime, functionConfig FunctionConfig, settings functionSettings) bool {
+	return config != nil && len(functionDifferences(config, handler, runtime, functionConfig, settings)) == 0
+}
+
+// functionDifferences returns the fields of the given function configuration that differ
+// from the given handler, runtime, configuration and settings. Tags are not compared.
+func functionDifferences(config *types.FunctionConfiguration, handler string, runtime types.Runtime, functionConfig FunctionConfig, settings functionSettings) differences {
+	var diffs differences
+	diffs.compare("handler", handler, aws.ToString(config.Handler))
+	diffs.compare("runtime", runtime, config.Runtime)
+	diffs.compare("role", functionConfig.RoleARN, aws.ToString(config.Role))
+	diffs.compare("timeout", settings.timeout, aws.ToInt32(config.Timeout))
+	diffs.compare("memorySize", settings.memorySize, aws.ToInt32(config.MemorySize))
+
+	var variables map[string]string
+	if config.Environment != nil {
+		variables = config.Environment.Variables
+	}
+	diffs.compareMaps("environment", functionConfig.Environment, variables)
+
+	return diffs
 }
 
 // Delete deletes a Lambda function with the given name.
-func (l *Lambda) Delete(name string) error {
-	_, err := l.client.DeleteFunction(context.TODO(), &lambda.DeleteFunctionInput{
+func (l *Lambda) Delete(ctx context.Context, name string) error {
+	_, err := l.client.DeleteFunction(ctx, &lambda.DeleteFunctionInput{
 		FunctionName: aws.String(name),
 	})
 	if err != nil {
@@ -91,12 +359,14 @@ func (l *Lambda) Delete(name string) error {
 // BindToService binds a Lambda function to an event source. This can be used to bind a
 // Lambda function to an SQS queue or an SNS topic. For instance, if you want to bind a
 // Lambda function to Kinesis, you would pass in the ARN of the Kinesis stream as the
-// eventSourceArn parameter.
-func (l *Lambda) BindToService(name, eventSourceArn string) error {
-	_, err := l.client.CreateEventSourceMapping(context.TODO(), &lambda.CreateEventSourceMappingInput{
+// eventSourceArn parameter. The ARN of a registered stream consumer, see
+// Kinesis.RegisterConsumer, binds the function through enhanced fan-out instead, so it
+// reads the shards with a throughput of its own.
+func (l *Lambda) BindToService(ctx context.Context, name, eventSourceArn string) error {
+	_, err := l.client.CreateEventSourceMapping(ctx, &lambda.CreateEventSourceMappingInput{
 		FunctionName:     aws.String(name),
 		EventSourceArn:   aws.String(eventSourceArn),
-		BatchSize:        aws.Int32(100),
+		BatchSize:        aws.Int32(lambdaBatchSize),
 		StartingPosition: types.EventSourcePositionLatest,
 	})
 	if err != nil {
@@ -105,3 +375,86 @@ func (l *Lambda) BindToService(name, eventSourceArn string) error {
 
 	return nil
 }
+
+// EnsureBoundToService binds a Lambda function to an event source if there is no event
+// source mapping between them yet. The batch size of an existing mapping is updated if
+// it differs.
+func (l *Lambda) EnsureBoundToService(ctx context.Context, name, eventSourceArn string) error {
+	mappings, err := l.client.ListEventSourceMappings(ctx, &lambda.ListEventSourceMappingsInput{
+		FunctionName:   aws.String(name),
+		EventSourceArn: aws.String(eventSourceArn),
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(mappings.EventSourceMappings) == 0 {
+		return l.BindToService(ctx, name, eventSourceArn)
+	}
+
+	mapping := mappings.EventSourceMappings[0]
+	if aws.ToInt32(mapping.BatchSize) == lambdaBatchSize {
+		return nil
+	}
+
+	_, err = l.client.UpdateEventSourceMapping(ctx, &lambda.UpdateEventSourceMappingInput{
+		UUID:      mapping.UUID,
+		BatchSize: aws.Int32(lambdaBatchSize),
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// PlanBoundToService returns the action EnsureBoundToService would take for the event
+// source mapping between the Lambda function and the event source without changing it.
+func (l *Lambda) PlanBoundToService(ctx context.Context, name, eventSourceArn string) (Action, error) {
+	drift, err := l.DriftBoundToService(ctx, name, eventSourceArn)
+	return drift.Action, err
+}
+
+// DriftBoundToService compares the event source mapping between the Lambda function and
+// the event source with the mapping EnsureBoundToService creates without changing it.
+func (l *Lambda) DriftBoundToService(ctx context.Context, name, eventSourceArn string) (Drift, error) {
+	mappings, err := l.client.ListEventSourceMappings(ctx, &lambda.ListEventSourceMappingsInput{
+		FunctionName:   aws.String(name),
+		EventSourceArn: aws.String(eventSourceArn),
+	})
+	if err != nil {
+		return Drift{}, err
+	}
+
+	if len(mappings.EventSourceMappings) == 0 {
+		return Drift{Action: ActionCreate}, nil
+	}
+
+	var diffs differences
+	diffs.compare("batchSize", lambdaBatchSize, aws.ToInt32(mappings.EventSourceMappings[0].BatchSize))
+
+	return drift(diffs), nil
+}
+
+// UnbindFromService deletes every event source mapping between the Lambda function with
+// the given name and the given event source. It returns the number of deleted mappings.
+func (l *Lambda) UnbindFromService(ctx context.Context, name, eventSourceArn string) (int, error) {
+	mappings, err := l.client.ListEventSourceMappings(ctx, &lambda.ListEventSourceMappingsInput{
+		FunctionName:   aws.String(name),
+		EventSourceArn: aws.String(eventSourceArn),
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for i, mapping := range mappings.EventSourceMappings {
+		_, err := l.client.DeleteEventSourceMapping(ctx, &lambda.DeleteEventSourceMappingInput{
+			UUID: mapping.UUID,
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+
+	return len(mappings.EventSourceMappings), nil
+}
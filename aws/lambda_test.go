@@ -2,15 +2,28 @@ package aws
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+	"github.com/aws/smithy-go"
 )
 
 type mockLambdaClient struct {
 	createFunctionFunc       func(context.Context, *lambda.CreateFunctionInput, ...func(*lambda.Options)) (*lambda.CreateFunctionOutput, error)
 	deleteFunctionFunc       func(context.Context, *lambda.DeleteFunctionInput, ...func(*lambda.Options)) (*lambda.DeleteFunctionOutput, error)
 	createEventSourceMapping func(context.Context, *lambda.CreateEventSourceMappingInput, ...func(*lambda.Options)) (*lambda.CreateEventSourceMappingOutput, error)
+	getFunctionFunc          func(context.Context, *lambda.GetFunctionInput, ...func(*lambda.Options)) (*lambda.GetFunctionOutput, error)
+	updateFunctionCodeFunc   func(context.Context, *lambda.UpdateFunctionCodeInput, ...func(*lambda.Options)) (*lambda.UpdateFunctionCodeOutput, error)
+	updateFunctionConfigFunc func(context.Context, *lambda.UpdateFunctionConfigurationInput, ...func(*lambda.Options)) (*lambda.UpdateFunctionConfigurationOutput, error)
+	listEventSourceMappings  func(context.Context, *lambda.ListEventSourceMappingsInput, ...func(*lambda.Options)) (*lambda.ListEventSourceMappingsOutput, error)
+	updateEventSourceMapping func(context.Context, *lambda.UpdateEventSourceMappingInput, ...func(*lambda.Options)) (*lambda.UpdateEventSourceMappingOutput, error)
+	deleteEventSourceMapping func(context.Context, *lambda.DeleteEventSourceMappingInput, ...func(*lambda.Options)) (*lambda.DeleteEventSourceMappingOutput, error)
 }
 
 func (m *mockLambdaClient) CreateFunction(ctx context.Context, input *lambda.CreateFunctionInput, opts ...func(*lambda.Options)) (*lambda.CreateFunctionOutput, error) {
@@ -25,12 +38,45 @@ func (m *mockLambdaClient) CreateEventSourceMapping(ctx context.Context, input *
 	return m.createEventSourceMapping(ctx, input, opts...)
 }
 
+func (m *mockLambdaClient) GetFunction(ctx context.Context, input *lambda.GetFunctionInput, opts ...func(*lambda.Options)) (*lambda.GetFunctionOutput, error) {
+	return m.getFunctionFunc(ctx, input, opts...)
+}
+
+func (m *mockLambdaClient) UpdateFunctionCode(ctx context.Context, input *lambda.UpdateFunctionCodeInput, opts ...func(*lambda.Options)) (*lambda.UpdateFunctionCodeOutput, error) {
+	return m.updateFunctionCodeFunc(ctx, input, opts...)
+}
+
+func (m *mockLambdaClient) UpdateFunctionConfiguration(ctx context.Context, input *lambda.UpdateFunctionConfigurationInput, opts ...func(*lambda.Options)) (*lambda.UpdateFunctionConfigurationOutput, error) {
+	return m.updateFunctionConfigFunc(ctx, input, opts...)
+}
+
+func (m *mockLambdaClient) ListEventSourceMappings(ctx context.Context, input *lambda.ListEventSourceMappingsInput, opts ...func(*lambda.Options)) (*lambda.ListEventSourceMappingsOutput, error) {
+	return m.listEventSourceMappings(ctx, input, opts...)
+}
+
+func (m *mockLambdaClient) UpdateEventSourceMapping(ctx context.Context, input *lambda.UpdateEventSourceMappingInput, opts ...func(*lambda.Options)) (*lambda.UpdateEventSourceMappingOutput, error) {
+	return m.updateEventSourceMapping(ctx, input, opts...)
+}
+
+func (m *mockLambdaClient) DeleteEventSourceMapping(ctx context.Context, input *lambda.DeleteEventSourceMappingInput, opts ...func(*lambda.Options)) (*lambda.DeleteEventSourceMappingOutput, error) {
+	return m.deleteEventSourceMapping(ctx, input, opts...)
+}
+
+// testRoleARN is the ARN of the role of the functions in the tests.
+const testRoleARN = "arn:aws:iam::000000000000:role/test-role"
+
 func TestLambda_CreateGo(t *testing.T) {
 	mockClient := &mockLambdaClient{
 		createFunctionFunc: func(ctx context.Context, input *lambda.CreateFunctionInput, opts ...func(*lambda.Options)) (*lambda.CreateFunctionOutput, error) {
 			if *input.FunctionName != "test-function" {
 				t.Errorf("unexpected function name: %s", *input.FunctionName)
 			}
+			if aws.ToString(input.Role) != testRoleARN {
+				t.Errorf("unexpected role: %s", aws.ToString(input.Role))
+			}
+			if input.Environment.Variables["ENVIRONMENT"] != "test" {
+				t.Errorf("unexpected environment variables: %v", input.Environment.Variables)
+			}
 			return &lambda.CreateFunctionOutput{
 				FunctionArn: input.FunctionName,
 			}, nil
@@ -41,7 +87,10 @@ func TestLambda_CreateGo(t *testing.T) {
 		client: mockClient,
 	}
 
-	_, err := lambdaClient.CreateGo("test-function", "test-bucket", "test-key")
+	_, err := lambdaClient.CreateGo(context.Background(), "test-function", "test-bucket", "test-key", FunctionConfig{
+		RoleARN:     testRoleARN,
+		Environment: map[string]string{"ENVIRONMENT": "test"},
+	})
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -63,7 +112,31 @@ func TestLambda_CreateNode(t *testing.T) {
 		client: mockClient,
 	}
 
-	_, err := lambdaClient.CreateNode("test-function", "test-bucket", "test-key")
+	_, err := lambdaClient.CreateNode(context.Background(), "test-function", "test-bucket", "test-key", FunctionConfig{RoleARN: testRoleARN})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLambda_CreateGoWithOptions(t *testing.T) {
+	mockClient := &mockLambdaClient{
+		createFunctionFunc: func(ctx context.Context, input *lambda.CreateFunctionInput, opts ...func(*lambda.Options)) (*lambda.CreateFunctionOutput, error) {
+			if aws.ToInt32(input.MemorySize) != 512 || aws.ToInt32(input.Timeout) != 300 {
+				return nil, fmt.Errorf("unexpected memory %d and timeout %d", aws.ToInt32(input.MemorySize), aws.ToInt32(input.Timeout))
+			}
+			if input.Tags["team"] != "data" {
+				return nil, fmt.Errorf("unexpected tags %v", input.Tags)
+			}
+			return &lambda.CreateFunctionOutput{FunctionArn: aws.String("test-arn")}, nil
+		},
+	}
+
+	lambdaClient := &Lambda{
+		client: mockClient,
+	}
+
+	_, err := lambdaClient.CreateGo(context.Background(), "test-function", "test-bucket", "test-key", FunctionConfig{RoleARN: testRoleARN},
+		WithMemory(512), WithTimeout(5*time.Minute), WithTags(map[string]string{"team": "data"}))
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -83,7 +156,7 @@ func TestLambda_Delete(t *testing.T) {
 		client: mockClient,
 	}
 
-	err := lambdaClient.Delete("test-function")
+	err := lambdaClient.Delete(context.Background(), "test-function")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -106,8 +179,397 @@ func TestLambda_BindToService(t *testing.T) {
 		client: mockClient,
 	}
 
-	err := lambdaClient.BindToService("test-function", "test-arn")
+	err := lambdaClient.BindToService(context.Background(), "test-function", "test-arn")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLambda_EnsureGo_Missing(t *testing.T) {
+	created := false
+	mockClient := &mockLambdaClient{
+		getFunctionFunc: func(ctx context.Context, input *lambda.GetFunctionInput, opts ...func(*lambda.Options)) (*lambda.GetFunctionOutput, error) {
+			return nil, Classify(&smithy.GenericAPIError{Code: "ResourceNotFoundException"})
+		},
+		createFunctionFunc: func(ctx context.Context, input *lambda.CreateFunctionInput, opts ...func(*lambda.Options)) (*lambda.CreateFunctionOutput, error) {
+			created = true
+			return &lambda.CreateFunctionOutput{
+				FunctionArn: aws.String("test-arn"),
+			}, nil
+		},
+	}
+
+	lambdaClient := &Lambda{
+		client: mockClient,
+	}
+
+	arn, err := lambdaClient.EnsureGo(context.Background(), "test-function", "test-bucket", "test-key", nil, FunctionConfig{RoleARN: testRoleARN})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !created || arn != "test-arn" {
+		t.Errorf("expected function to be created, got arn: %s", arn)
+	}
+}
+
+func TestLambda_EnsureGo_Existing(t *testing.T) {
+	codeUpdated := false
+	mockClient := &mockLambdaClient{
+		getFunctionFunc: func(ctx context.Context, input *lambda.GetFunctionInput, opts ...func(*lambda.Options)) (*lambda.GetFunctionOutput, error) {
+			return &lambda.GetFunctionOutput{
+				Configuration: &types.FunctionConfiguration{
+					FunctionArn: aws.String("test-arn"),
+					Handler:     aws.String("main"),
+					Runtime:     types.RuntimeGo1x,
+					Role:        aws.String(testRoleARN),
+					Timeout:     aws.Int32(lambdaTimeout),
+					MemorySize:  aws.Int32(lambdaMemorySize),
+					State:       types.StateActive,
+				},
+			}, nil
+		},
+		updateFunctionCodeFunc: func(ctx context.Context, input *lambda.UpdateFunctionCodeInput, opts ...func(*lambda.Options)) (*lambda.UpdateFunctionCodeOutput, error) {
+			if aws.ToString(input.S3Key) != "test-key" {
+				t.Errorf("unexpected s3 key: %s", aws.ToString(input.S3Key))
+			}
+			codeUpdated = true
+			return &lambda.UpdateFunctionCodeOutput{}, nil
+		},
+	}
+
+	lambdaClient := &Lambda{
+		client: mockClient,
+	}
+
+	arn, err := lambdaClient.EnsureGo(context.Background(), "test-function", "test-bucket", "test-key", nil, FunctionConfig{RoleARN: testRoleARN})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !codeUpdated || arn != "test-arn" {
+		t.Errorf("expected code to be updated, got arn: %s", arn)
+	}
+}
+
+func TestLambda_EnsureGo_UnchangedCode(t *testing.T) {
+	code := []byte("test-code")
+	mockClient := &mockLambdaClient{
+		getFunctionFunc: func(ctx context.Context, input *lambda.GetFunctionInput, opts ...func(*lambda.Options)) (*lambda.GetFunctionOutput, error) {
+			return &lambda.GetFunctionOutput{
+				Configuration: &types.FunctionConfiguration{
+					FunctionArn: aws.String("test-arn"),
+					Handler:     aws.String("main"),
+					Runtime:     types.RuntimeGo1x,
+					Role:        aws.String(testRoleARN),
+					Timeout:     aws.Int32(lambdaTimeout),
+					MemorySize:  aws.Int32(lambdaMemorySize),
+					CodeSha256:  aws.String(codeSha256(code)),
+					State:       types.StateActive,
+				},
+			}, nil
+		},
+		updateFunctionCodeFunc: func(ctx context.Context, input *lambda.UpdateFunctionCodeInput, opts ...func(*lambda.Options)) (*lambda.UpdateFunctionCodeOutput, error) {
+			t.Errorf("unexpected code update of unchanged function")
+			return &lambda.UpdateFunctionCodeOutput{}, nil
+		},
+	}
+
+	lambdaClient := &Lambda{
+		client: mockClient,
+	}
+
+	arn, err := lambdaClient.EnsureGo(context.Background(), "test-function", "test-bucket", "test-key", code, FunctionConfig{RoleARN: testRoleARN})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if arn != "test-arn" {
+		t.Errorf("unexpected arn: %s", arn)
+	}
+}
+
+func TestLambda_EnsureGo_WaitsForUpdates(t *testing.T) {
+	setWaitDelay(t)
+
+	// An update is in progress until the function was read twice after it.
+	pendingReads := 0
+	var calls []string
+	update := func(call string) error {
+		if pendingReads > 0 {
+			return ClassifyOperation(call, &smithy.GenericAPIError{Code: "ResourceConflictException"})
+		}
+		calls = append(calls, call)
+		pendingReads = 2
+		return nil
+	}
+
+	for _, code := range [][]byte{nil, []byte("test-code")} {
+		calls, pendingReads = nil, 0
+		mockClient := &mockLambdaClient{
+			getFunctionFunc: func(ctx context.Context, input *lambda.GetFunctionInput, opts ...func(*lambda.Options)) (*lambda.GetFunctionOutput, error) {
+				status := types.LastUpdateStatusSuccessful
+				if pendingReads > 0 {
+					pendingReads--
+					status = types.LastUpdateStatusInProgress
+				}
+				return &lambda.GetFunctionOutput{
+					Configuration: &types.FunctionConfiguration{
+						FunctionArn:      aws.String("test-arn"),
+						Handler:          aws.String("main"),
+						Runtime:          types.RuntimeGo1x,
+						Role:             aws.String(testRoleARN),
+						Timeout:          aws.Int32(lambdaTimeout),
+						MemorySize:       aws.Int32(256),
+						CodeSha256:       aws.String(codeSha256([]byte("test-code"))),
+						State:            types.StateActive,
+						LastUpdateStatus: status,
+					},
+				}, nil
+			},
+			updateFunctionConfigFunc: func(ctx context.Context, input *lambda.UpdateFunctionConfigurationInput, opts ...func(*lambda.Options)) (*lambda.UpdateFunctionConfigurationOutput, error) {
+				return &lambda.UpdateFunctionConfigurationOutput{}, update("UpdateFunctionConfiguration")
+			},
+			updateFunctionCodeFunc: func(ctx context.Context, input *lambda.UpdateFunctionCodeInput, opts ...func(*lambda.Options)) (*lambda.UpdateFunctionCodeOutput, error) {
+				return &lambda.UpdateFunctionCodeOutput{}, update("UpdateFunctionCode")
+			},
+		}
+
+		lambdaClient := &Lambda{
+			client: mockClient,
+		}
+
+		// Without the code, the code is updated after the configuration. With unchanged
+		// code, only the configuration is updated.
+		if _, err := lambdaClient.EnsureGo(context.Background(), "test-function", "test-bucket", "test-key", code, FunctionConfig{RoleARN: testRoleARN}, WithWaitTimeout(time.Second)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if pendingReads > 0 {
+			t.Errorf("expected the last update to be finished, got %v", calls)
+		}
+		if code == nil && len(calls) != 2 || code != nil && len(calls) != 1 {
+			t.Errorf("unexpected updates %v", calls)
+		}
+	}
+}
+
+func TestLambda_EnsureBoundToService(t *testing.T) {
+	mockClient := &mockLambdaClient{
+		listEventSourceMappings: func(ctx context.Context, input *lambda.ListEventSourceMappingsInput, opts ...func(*lambda.Options)) (*lambda.ListEventSourceMappingsOutput, error) {
+			return &lambda.ListEventSourceMappingsOutput{
+				EventSourceMappings: []types.EventSourceMappingConfiguration{
+					{
+						UUID:      aws.String("test-uuid"),
+						BatchSize: aws.Int32(10),
+					},
+				},
+			}, nil
+		},
+		updateEventSourceMapping: func(ctx context.Context, input *lambda.UpdateEventSourceMappingInput, opts ...func(*lambda.Options)) (*lambda.UpdateEventSourceMappingOutput, error) {
+			if aws.ToInt32(input.BatchSize) != lambdaBatchSize {
+				t.Errorf("unexpected batch size: %d", aws.ToInt32(input.BatchSize))
+			}
+			return &lambda.UpdateEventSourceMappingOutput{}, nil
+		},
+	}
+
+	lambdaClient := &Lambda{
+		client: mockClient,
+	}
+
+	err := lambdaClient.EnsureBoundToService(context.Background(), "test-function", "test-arn")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLambda_UnbindFromService(t *testing.T) {
+	mockClient := &mockLambdaClient{
+		listEventSourceMappings: func(ctx context.Context, input *lambda.ListEventSourceMappingsInput, opts ...func(*lambda.Options)) (*lambda.ListEventSourceMappingsOutput, error) {
+			return &lambda.ListEventSourceMappingsOutput{
+				EventSourceMappings: []types.EventSourceMappingConfiguration{
+					{UUID: aws.String("test-uuid")},
+				},
+			}, nil
+		},
+		deleteEventSourceMapping: func(ctx context.Context, input *lambda.DeleteEventSourceMappingInput, opts ...func(*lambda.Options)) (*lambda.DeleteEventSourceMappingOutput, error) {
+			if aws.ToString(input.UUID) != "test-uuid" {
+				t.Errorf("unexpected uuid: %s", aws.ToString(input.UUID))
+			}
+			return &lambda.DeleteEventSourceMappingOutput{}, nil
+		},
+	}
+
+	lambdaClient := &Lambda{
+		client: mockClient,
+	}
+
+	deleted, err := lambdaClient.UnbindFromService(context.Background(), "test-function", "test-arn")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if deleted != 1 {
+		t.Errorf("unexpected number of deleted mappings: %d", deleted)
+	}
+}
+
+func TestLambda_PlanGo(t *testing.T) {
+	code := []byte("test-code")
+	codeSha256 := "Ol9LCJz9lYigDU2nREk5eZk82H5TYdJyCsw2qD64wE0="
+
+	mockClient := &mockLambdaClient{
+		getFunctionFunc: func(ctx context.Context, input *lambda.GetFunctionInput, opts ...func(*lambda.Options)) (*lambda.GetFunctionOutput, error) {
+			return &lambda.GetFunctionOutput{
+				Configuration: &types.FunctionConfiguration{
+					FunctionArn: aws.String("test-arn"),
+					Handler:     aws.String("main"),
+					Runtime:     types.RuntimeGo1x,
+					Role:        aws.String(testRoleARN),
+					Timeout:     aws.Int32(lambdaTimeout),
+					MemorySize:  aws.Int32(lambdaMemorySize),
+					CodeSha256:  aws.String(codeSha256),
+				},
+			}, nil
+		},
+	}
+
+	lambdaClient := &Lambda{
+		client: mockClient,
+	}
+
+	arn, action, err := lambdaClient.PlanGo(context.Background(), "test-function", code, FunctionConfig{RoleARN: testRoleARN})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if arn != "test-arn" {
+		t.Errorf("unexpected arn: %s", arn)
+	}
+	if action != ActionNone {
+		t.Errorf("unexpected action for unchanged function: %s", action)
+	}
+
+	_, action, err = lambdaClient.PlanGo(context.Background(), "test-function", []byte("changed-code"), FunctionConfig{RoleARN: testRoleARN})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if action != ActionUpdate {
+		t.Errorf("unexpected action for changed code: %s", action)
+	}
+
+	_, action, err = lambdaClient.PlanNode(context.Background(), "test-function", code, FunctionConfig{RoleARN: testRoleARN})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if action != ActionUpdate {
+		t.Errorf("unexpected action for changed runtime: %s", action)
+	}
+
+	_, action, err = lambdaClient.PlanGo(context.Background(), "test-function", code, FunctionConfig{
+		RoleARN:     "arn:aws:iam::000000000000:role/staging-lambda-role",
+		Environment: map[string]string{"ENVIRONMENT": "staging"},
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if action != ActionUpdate {
+		t.Errorf("unexpected action for changed configuration: %s", action)
+	}
+}
+
+func TestLambda_DriftGo(t *testing.T) {
+	mockClient := &mockLambdaClient{
+		getFunctionFunc: func(ctx context.Context, input *lambda.GetFunctionInput, opts ...func(*lambda.Options)) (*lambda.GetFunctionOutput, error) {
+			return &lambda.GetFunctionOutput{
+				Configuration: &types.FunctionConfiguration{
+					FunctionArn: aws.String("test-arn"),
+					Handler:     aws.String("main"),
+					Runtime:     types.RuntimeGo1x,
+					Role:        aws.String(testRoleARN),
+					Timeout:     aws.Int32(lambdaTimeout),
+					MemorySize:  aws.Int32(256),
+					Environment: &types.EnvironmentResponse{
+						Variables: map[string]string{"DEBUG": "true"},
+					},
+				},
+			}, nil
+		},
+	}
+
+	lambdaClient := &Lambda{
+		client: mockClient,
+	}
+
+	arn, drift, err := lambdaClient.DriftGo(context.Background(), "test-function", nil, FunctionConfig{RoleARN: testRoleARN})
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
+	if arn != "test-arn" {
+		t.Errorf("unexpected arn: %s", arn)
+	}
+	if drift.Action != ActionUpdate {
+		t.Errorf("unexpected action for changed function: %s", drift.Action)
+	}
+
+	expected := []Difference{
+		{Field: "memorySize", Expected: fmt.Sprint(lambdaMemorySize), Actual: "256"},
+		{Field: "environment.DEBUG", Expected: "<missing>", Actual: "true"},
+	}
+	if !reflect.DeepEqual(drift.Differences, expected) {
+		t.Errorf("unexpected differences: %v", drift.Differences)
+	}
+}
+
+func TestLambda_WaitUntilActive(t *testing.T) {
+	setWaitDelay(t)
+
+	calls := 0
+	mockClient := &mockLambdaClient{
+		getFunctionFunc: func(ctx context.Context, input *lambda.GetFunctionInput, opts ...func(*lambda.Options)) (*lambda.GetFunctionOutput, error) {
+			calls++
+			if calls < 2 {
+				return &lambda.GetFunctionOutput{
+					Configuration: &types.FunctionConfiguration{State: types.StatePending},
+				}, nil
+			}
+			return &lambda.GetFunctionOutput{
+				Configuration: &types.FunctionConfiguration{
+					State:            types.StateActive,
+					LastUpdateStatus: types.LastUpdateStatusSuccessful,
+				},
+			}, nil
+		},
+	}
+
+	lambdaClient := &Lambda{
+		client: mockClient,
+	}
+
+	err := lambdaClient.WaitUntilActive(context.Background(), "test-function", time.Second)
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 calls, got %d", calls)
+	}
+}
+
+func TestLambda_WaitUntilActive_Failed(t *testing.T) {
+	setWaitDelay(t)
+
+	mockClient := &mockLambdaClient{
+		getFunctionFunc: func(ctx context.Context, input *lambda.GetFunctionInput, opts ...func(*lambda.Options)) (*lambda.GetFunctionOutput, error) {
+			return &lambda.GetFunctionOutput{
+				Configuration: &types.FunctionConfiguration{
+					State:       types.StateFailed,
+					StateReason: aws.String("invalid code"),
+				},
+			}, nil
+		},
+	}
+
+	lambdaClient := &Lambda{
+		client: mockClient,
+	}
+
+	err := lambdaClient.WaitUntilActive(context.Background(), "test-function", time.Second)
+	if err == nil || !strings.Contains(err.Error(), "invalid code") {
+		t.Errorf("expected failed function error, got %v", err)
+	}
 }
@@ -0,0 +1,287 @@
+package aws
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// latencyBuckets are the upper bounds of the buckets of the latency histogram that is
+// exposed to Prometheus.
+var latencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Metrics records the calls of the wrapped clients whose configuration has the metrics,
+// see WithMetrics. For every operation of a service, it records the number of calls, the
+// calls that failed, the retries and the latency of the calls including their retries.
+// The metrics can be published to CloudWatch and exposed to Prometheus, since Metrics is
+// an HTTP handler that serves them in the text format of Prometheus.
+type Metrics struct {
+	mu         sync.Mutex
+	operations map[operation]*operationMetrics
+	// published are the metrics at the time they were last published to CloudWatch.
+	published map[operation]OperationMetrics
+}
+
+// operation is an operation of a service, e.g. `PutItem` of `DynamoDB`.
+type operation struct {
+	service, name string
+}
+
+// operationMetrics are the metrics of an operation and its latency histogram.
+type operationMetrics struct {
+	OperationMetrics
+	buckets []int64
+}
+
+// OperationMetrics are the metrics of an operation of a service.
+type OperationMetrics struct {
+	// Service is the ID of the service, e.g. `DynamoDB`.
+	Service string
+	// Operation is the name of the operation, e.g. `PutItem`.
+	Operation string
+	// Calls is the number of calls of the operation.
+	Calls int64
+	// Errors is the number of calls that failed, even after retrying them.
+	Errors int64
+	// Retries is the number of retried attempts of the calls.
+	Retries int64
+	// Latency is the total latency of the calls.
+	Latency time.Duration
+}
+
+// AverageLatency returns the average latency of the calls or zero without calls.
+func (m OperationMetrics) AverageLatency() time.Duration {
+	if m.Calls == 0 {
+		return 0
+	}
+	return m.Latency / time.Duration(m.Calls)
+}
+
+// NewMetrics returns metrics without calls.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		operations: map[operation]*operationMetrics{},
+		published:  map[operation]OperationMetrics{},
+	}
+}
+
+// WithMetrics returns a copy of the given configuration whose clients record their calls
+// in the given metrics.
+func WithMetrics(config aws.Config, metrics *Metrics) aws.Config {
+	return withAPIOption(config, metrics.addMiddleware)
+}
+
+// addMiddleware adds the middleware that records the calls to the given stack. It runs
+// after the middleware that registers the service and the operation of the call.
+func (m *Metrics) addMiddleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("RecordMetrics", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+		start := time.Now()
+		out, metadata, err := next.HandleInitialize(ctx, in)
+
+		retries := 0
+		if results, ok := retry.GetAttemptResults(metadata); ok && len(results.Results) > 1 {
+			retries = len(results.Results) - 1
+		}
+		m.record(awsmiddleware.GetServiceID(ctx), awsmiddleware.GetOperationName(ctx), time.Since(start), retries, err)
+
+		return out, metadata, err
+	}), middleware.After)
+}
+
+// record records a call of the given operation of the given service.
+func (m *Metrics) record(service, name string, latency time.Duration, retries int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := operation{service: service, name: name}
+	metrics, ok := m.operations[key]
+	if !ok {
+		metrics = &operationMetrics{
+			OperationMetrics: OperationMetrics{Service: service, Operation: name},
+			buckets:          make([]int64, len(latencyBuckets)),
+		}
+		m.operations[key] = metrics
+	}
+
+	metrics.Calls++
+	metrics.Retries += int64(retries)
+	metrics.Latency += latency
+	if err != nil {
+		metrics.Errors++
+	}
+	for i, bound := range latencyBuckets {
+		if latency <= bound {
+			metrics.buckets[i]++
+		}
+	}
+}
+
+// Snapshot returns the metrics of every called operation, ordered by their service and
+// operation.
+func (m *Metrics) Snapshot() []OperationMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make([]OperationMetrics, 0, len(m.operations))
+	for _, metrics := range m.operations {
+		snapshot = append(snapshot, metrics.OperationMetrics)
+	}
+	sort.Slice(snapshot, func(i, j int) bool {
+		if snapshot[i].Service != snapshot[j].Service {
+			return snapshot[i].Service < snapshot[j].Service
+		}
+		return snapshot[i].Operation < snapshot[j].Operation
+	})
+	return snapshot
+}
+
+// metricsPerOperation is the number of metric data that Publish puts for an operation.
+const metricsPerOperation = 4
+
+// Publish puts the calls since they were last published into the given namespace of
+// CloudWatch. Every operation has the metrics `Calls`, `Errors`, `Retries` and `Latency`,
+// which is the average latency in milliseconds, with the dimensions `Service` and
+// `Operation`. Operations without calls since they were last published are skipped. The
+// operations are put in chunks, and the operations of a chunk count as published once
+// the chunk is put, so a failed Publish does not put earlier chunks again.
+func (m *Metrics) Publish(ctx context.Context, cloudWatch *CloudWatch, namespace string) error {
+	snapshot := m.Snapshot()
+
+	m.mu.Lock()
+	var data []types.MetricDatum
+	var published []OperationMetrics
+	for _, current := range snapshot {
+		previous := m.published[operation{service: current.Service, name: current.Operation}]
+		delta := OperationMetrics{
+			Calls:   current.Calls - previous.Calls,
+			Errors:  current.Errors - previous.Errors,
+			Retries: current.Retries - previous.Retries,
+			Latency: current.Latency - previous.Latency,
+		}
+		if delta.Calls == 0 {
+			continue
+		}
+
+		dimensions := []types.Dimension{
+			{Name: aws.String("Service"), Value: aws.String(current.Service)},
+			{Name: aws.String("Operation"), Value: aws.String(current.Operation)},
+		}
+		datum := func(name string, unit types.StandardUnit, value float64) types.MetricDatum {
+			return types.MetricDatum{MetricName: aws.String(name), Dimensions: dimensions, Unit: unit, Value: aws.Float64(value)}
+		}
+		data = append(data,
+			datum("Calls", types.StandardUnitCount, float64(delta.Calls)),
+			datum("Errors", types.StandardUnitCount, float64(delta.Errors)),
+			datum("Retries", types.StandardUnitCount, float64(delta.Retries)),
+			datum("Latency", types.StandardUnitMilliseconds, float64(delta.AverageLatency())/float64(time.Millisecond)),
+		)
+		published = append(published, current)
+	}
+	m.mu.Unlock()
+
+	chunk := maxMetricData / metricsPerOperation
+	for start := 0; start < len(published); start += chunk {
+		end := start + chunk
+		if end > len(published) {
+			end = len(published)
+		}
+
+		if err := cloudWatch.PutMetrics(ctx, namespace, data[start*metricsPerOperation:end*metricsPerOperation]); err != nil {
+			return err
+		}
+
+		m.mu.Lock()
+		for _, current := range published[start:end] {
+			m.published[operation{service: current.Service, name: current.Operation}] = current
+		}
+		m.mu.Unlock()
+	}
+	return nil
+}
+
+// WritePrometheus writes the metrics in the text format of Prometheus to the given writer.
+// The counters `aws_calls_total`, `aws_call_errors_total` and `aws_call_retries_total`
+// and the histogram `aws_call_duration_seconds` have the labels `service` and `operation`.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	operations := make([]operationMetrics, 0, len(m.operations))
+	for _, metrics := range m.operations {
+		operations = append(operations, operationMetrics{
+			OperationMetrics: metrics.OperationMetrics,
+			buckets:          append([]int64(nil), metrics.buckets...),
+		})
+	}
+	m.mu.Unlock()
+	sort.Slice(operations, func(i, j int) bool {
+		if operations[i].Service != operations[j].Service {
+			return operations[i].Service < operations[j].Service
+		}
+		return operations[i].Operation < operations[j].Operation
+	})
+
+	buffered := bufio.NewWriter(w)
+	counters := []struct {
+		name, help string
+		value      func(OperationMetrics) int64
+	}{
+		{"aws_calls_total", "Number of calls of an AWS operation.", func(m OperationMetrics) int64 { return m.Calls }},
+		{"aws_call_errors_total", "Number of calls of an AWS operation that failed after retrying them.", func(m OperationMetrics) int64 { return m.Errors }},
+		{"aws_call_retries_total", "Number of retried attempts of calls of an AWS operation.", func(m OperationMetrics) int64 { return m.Retries }},
+	}
+	for _, counter := range counters {
+		fmt.Fprintf(buffered, "# HELP %s %s\n# TYPE %s counter\n", counter.name, counter.help, counter.name)
+		for _, metrics := range operations {
+			fmt.Fprintf(buffered, "%s{%s} %d\n", counter.name, labels(metrics.OperationMetrics), counter.value(metrics.OperationMetrics))
+		}
+	}
+
+	fmt.Fprint(buffered, "# HELP aws_call_duration_seconds Latency of the calls of an AWS operation including their retries.\n# TYPE aws_call_duration_seconds histogram\n")
+	for _, metrics := range operations {
+		l := labels(metrics.OperationMetrics)
+		for i, bound := range latencyBuckets {
+			fmt.Fprintf(buffered, "aws_call_duration_seconds_bucket{%s,le=\"%g\"} %d\n", l, bound.Seconds(), metrics.buckets[i])
+		}
+		fmt.Fprintf(buffered, "aws_call_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", l, metrics.Calls)
+		fmt.Fprintf(buffered, "aws_call_duration_seconds_sum{%s} %g\n", l, metrics.Latency.Seconds())
+		fmt.Fprintf(buffered, "aws_call_duration_seconds_count{%s} %d\n", l, metrics.Calls)
+	}
+
+	return buffered.Flush()
+}
+
+// ServeHTTP serves the metrics in the text format of Prometheus.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := m.WritePrometheus(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// labels returns the Prometheus labels of the given operation. Service IDs and operation
+// names consist of letters, digits and spaces, so they need no escaping.
+func labels(m OperationMetrics) string {
+	return fmt.Sprintf("service=%q,operation=%q", m.Service, m.Operation)
+}
@@ -0,0 +1,161 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+)
+
+func TestMetrics_Recorded(t *testing.T) {
+	attempts := 0
+	client := roundTripFunc(func(req *http.Request) *http.Response {
+		attempts++
+		if attempts < 3 {
+			return dynamoDBError("ThrottlingException")
+		}
+		return dynamoDBError("ResourceNotFoundException")
+	})
+
+	metrics := NewMetrics()
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	dynamoDB := NewDynamoDB(WithMetrics(WithRetryPolicy(testConfig(client), policy), metrics))
+
+	if _, err := dynamoDB.DescribeTable(context.Background(), "test"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	snapshot := metrics.Snapshot()
+	if len(snapshot) != 1 {
+		t.Fatalf("expected the metrics of a single operation, got %+v", snapshot)
+	}
+	m := snapshot[0]
+	if m.Service != "DynamoDB" || m.Operation != "DescribeTable" {
+		t.Errorf("unexpected operation %s %s", m.Service, m.Operation)
+	}
+	if m.Calls != 1 || m.Errors != 1 || m.Retries != 2 {
+		t.Errorf("expected 1 call, 1 error and 2 retries, got %+v", m)
+	}
+	if m.Latency <= 0 {
+		t.Errorf("expected a latency, got %s", m.Latency)
+	}
+}
+
+func TestMetrics_Publish(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.record("Kinesis", "PutRecord", 10*time.Millisecond, 0, nil)
+	metrics.record("Kinesis", "PutRecord", 30*time.Millisecond, 1, errors.New("test"))
+
+	var inputs []*cloudwatch.PutMetricDataInput
+	cw := &CloudWatch{
+		client: &mockCloudWatchClient{
+			putMetricDataFunc: func(ctx context.Context, params *cloudwatch.PutMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.PutMetricDataOutput, error) {
+				inputs = append(inputs, params)
+				return &cloudwatch.PutMetricDataOutput{}, nil
+			},
+		},
+	}
+
+	if err := metrics.Publish(context.Background(), cw, "test"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(inputs) != 1 || aws.ToString(inputs[0].Namespace) != "test" {
+		t.Fatalf("expected a single call in namespace test, got %d", len(inputs))
+	}
+
+	values := map[string]float64{}
+	for _, datum := range inputs[0].MetricData {
+		values[aws.ToString(datum.MetricName)] = aws.ToFloat64(datum.Value)
+		if len(datum.Dimensions) != 2 || aws.ToString(datum.Dimensions[0].Value) != "Kinesis" || aws.ToString(datum.Dimensions[1].Value) != "PutRecord" {
+			t.Errorf("unexpected dimensions of %s", aws.ToString(datum.MetricName))
+		}
+	}
+	expected := map[string]float64{"Calls": 2, "Errors": 1, "Retries": 1, "Latency": 20}
+	for name, value := range expected {
+		if values[name] != value {
+			t.Errorf("expected %s to be %v, got %v", name, value, values[name])
+		}
+	}
+
+	// Only the calls since the last publish are published.
+	if err := metrics.Publish(context.Background(), cw, "test"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(inputs) != 1 {
+		t.Errorf("expected no call without new calls, got %d", len(inputs))
+	}
+
+	metrics.record("Kinesis", "PutRecord", 5*time.Millisecond, 0, nil)
+	if err := metrics.Publish(context.Background(), cw, "test"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(inputs) != 2 || aws.ToFloat64(inputs[1].MetricData[0].Value) != 1 {
+		t.Errorf("expected a single new call to be published")
+	}
+}
+
+func TestMetrics_Publish_PartialFailure(t *testing.T) {
+	metrics := NewMetrics()
+	operations := maxMetricData/metricsPerOperation + 1
+	for i := 0; i < operations; i++ {
+		metrics.record("DynamoDB", fmt.Sprintf("Operation%03d", i), time.Millisecond, 0, nil)
+	}
+
+	var calls []int
+	fail := true
+	cw := &CloudWatch{
+		client: &mockCloudWatchClient{
+			putMetricDataFunc: func(ctx context.Context, params *cloudwatch.PutMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.PutMetricDataOutput, error) {
+				if len(calls) == 1 && fail {
+					return nil, errors.New("throttled")
+				}
+				calls = append(calls, len(params.MetricData))
+				return &cloudwatch.PutMetricDataOutput{}, nil
+			},
+		},
+	}
+
+	if err := metrics.Publish(context.Background(), cw, "test"); err == nil {
+		t.Fatal("expected the second chunk to fail")
+	}
+	fail = false
+	if err := metrics.Publish(context.Background(), cw, "test"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The first chunk is not put again by the second Publish.
+	if fmt.Sprint(calls) != fmt.Sprint([]int{maxMetricData, metricsPerOperation}) {
+		t.Errorf("expected a full chunk and then the remaining operation, got %v", calls)
+	}
+}
+
+func TestMetrics_ServeHTTP(t *testing.T) {
+	metrics := NewMetrics()
+	metrics.record("S3", "PutObject", 20*time.Millisecond, 2, nil)
+
+	recorder := httptest.NewRecorder()
+	metrics.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body, _ := io.ReadAll(recorder.Body)
+	for _, line := range []string{
+		`aws_calls_total{service="S3",operation="PutObject"} 1`,
+		`aws_call_errors_total{service="S3",operation="PutObject"} 0`,
+		`aws_call_retries_total{service="S3",operation="PutObject"} 2`,
+		`aws_call_duration_seconds_bucket{service="S3",operation="PutObject",le="0.01"} 0`,
+		`aws_call_duration_seconds_bucket{service="S3",operation="PutObject",le="0.025"} 1`,
+		`aws_call_duration_seconds_bucket{service="S3",operation="PutObject",le="+Inf"} 1`,
+		`aws_call_duration_seconds_count{service="S3",operation="PutObject"} 1`,
+	} {
+		if !strings.Contains(string(body), line+"\n") {
+			t.Errorf("expected %q in\n%s", line, body)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package aws
+
+import (
+	"sort"
+	"time"
+)
+
+// The creation calls of the wrappers take options of their resource, e.g.
+// `kinesis.Create(ctx, name, WithShardCount(4))`, and every setting without an option
+// keeps its default. WithTags is an option of every resource.
+
+// StreamOption configures a Kinesis stream that is created or updated by the Kinesis
+// wrapper.
+type StreamOption interface {
+	applyStream(*streamSettings)
+}
+
+// FunctionOption configures a Lambda function that is created or updated by the Lambda
+// wrapper.
+type FunctionOption interface {
+	applyFunction(*functionSettings)
+}
+
+// TableOption configures a DynamoDB table that is created or updated by the DynamoDB
+// wrapper.
+type TableOption interface {
+	applyTable(*tableSettings)
+}
+
+// JobOption configures a Glue job that is created or updated by the Glue wrapper.
+type JobOption interface {
+	applyJob(*jobSettings)
+}
+
+// streamOptionFunc, functionOptionFunc, tableOptionFunc and jobOptionFunc are the options
+// of a single resource.
+type (
+	streamOptionFunc   func(*streamSettings)
+	functionOptionFunc func(*functionSettings)
+	tableOptionFunc    func(*tableSettings)
+	jobOptionFunc      func(*jobSettings)
+)
+
+func (f streamOptionFunc) applyStream(s *streamSettings)       { f(s) }
+func (f functionOptionFunc) applyFunction(s *functionSettings) { f(s) }
+func (f tableOptionFunc) applyTable(s *tableSettings)          { f(s) }
+func (f jobOptionFunc) applyJob(s *jobSettings)                { f(s) }
+
+// TagsOption tags the resource it configures. Tags are only set when a resource is
+// created, the tags of existing resources are left as they are.
+type TagsOption map[string]string
+
+// WithTags tags the created resource with the given tags. Several WithTags options add up,
+// later tags replace earlier ones with the same key.
+func WithTags(tags map[string]string) TagsOption {
+	return TagsOption(tags)
+}
+
+func (t TagsOption) applyStream(s *streamSettings)     { s.tags = mergeTags(s.tags, t) }
+func (t TagsOption) applyFunction(s *functionSettings) { s.tags = mergeTags(s.tags, t) }
+func (t TagsOption) applyTable(s *tableSettings)       { s.tags = mergeTags(s.tags, t) }
+func (t TagsOption) applyJob(s *jobSettings)           { s.tags = mergeTags(s.tags, t) }
+
+// WaitTimeoutOption limits the time the wrapper waits for the resource it configures to
+// finish an update before it updates it again, e.g. a function whose code is updated after
+// its configuration or a stream that is scaled in several steps. It defaults to 10
+// minutes.
+type WaitTimeoutOption time.Duration
+
+// WithWaitTimeout waits at most the given time for the resource to finish an update.
+func WithWaitTimeout(timeout time.Duration) WaitTimeoutOption {
+	return WaitTimeoutOption(timeout)
+}
+
+func (t WaitTimeoutOption) applyStream(s *streamSettings)     { s.waitTimeout = time.Duration(t) }
+func (t WaitTimeoutOption) applyFunction(s *functionSettings) { s.waitTimeout = time.Duration(t) }
+
+// mergeTags returns the given tags with the added tags. The given tags are not modified.
+func mergeTags(tags, added map[string]string) map[string]string {
+	if len(added) == 0 {
+		return tags
+	}
+
+	merged := make(map[string]string, len(tags)+len(added))
+	for key, value := range tags {
+		merged[key] = value
+	}
+	for key, value := range added {
+		merged[key] = value
+	}
+	return merged
+}
+
+// sortedKeys returns the keys of the given tags in ascending order.
+func sortedKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,90 @@
+package aws
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Action is the change that setup would make to a resource.
+type Action string
+
+const (
+	// ActionNone means that the resource already matches the desired state.
+	ActionNone Action = "no-op"
+	// ActionCreate means that the resource does not exist yet.
+	ActionCreate Action = "create"
+	// ActionUpdate means that the resource exists but differs from the desired state.
+	ActionUpdate Action = "update"
+	// ActionDelete means that the resource exists and would be deleted.
+	ActionDelete Action = "delete"
+)
+
+// Difference is a field of a live resource whose value differs from the value setup
+// would produce.
+type Difference struct {
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// Drift is the result of comparing a live resource with the resource setup would
+// produce.
+type Drift struct {
+	// Action is the change that setup would make to the resource.
+	Action Action
+	// Differences are the fields that setup would update. It is empty for missing
+	// resources.
+	Differences []Difference
+}
+
+// differences collects the fields of a live resource that differ from the desired state.
+type differences []Difference
+
+// compare records the given field if its actual value differs from the expected value.
+func (d *differences) compare(field string, expected, actual any) {
+	e, a := fmt.Sprint(expected), fmt.Sprint(actual)
+	if e != a {
+		*d = append(*d, Difference{Field: field, Expected: e, Actual: a})
+	}
+}
+
+// compareMaps records every key of the given maps whose value differs as a field with the
+// given prefix. Missing keys are reported as `<missing>`.
+func (d *differences) compareMaps(prefix string, expected, actual map[string]string) {
+	keys := map[string]bool{}
+	for key := range expected {
+		keys[key] = true
+	}
+	for key := range actual {
+		keys[key] = true
+	}
+
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	for _, key := range sorted {
+		e, ok := expected[key]
+		if !ok {
+			e = missingValue
+		}
+		a, ok := actual[key]
+		if !ok {
+			a = missingValue
+		}
+		d.compare(fmt.Sprintf("%s.%s", prefix, key), e, a)
+	}
+}
+
+// missingValue is the value of a field that does not exist.
+const missingValue = "<missing>"
+
+// drift returns the drift of an existing resource with the given differences.
+func drift(d differences) Drift {
+	if len(d) == 0 {
+		return Drift{Action: ActionNone}
+	}
+	return Drift{Action: ActionUpdate, Differences: d}
+}
@@ -0,0 +1,37 @@
+package aws
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDifferences_CompareMaps(t *testing.T) {
+	var diffs differences
+	diffs.compareMaps("environment", map[string]string{
+		"ENVIRONMENT": "staging",
+		"LOG_LEVEL":   "info",
+	}, map[string]string{
+		"DEBUG":     "true",
+		"LOG_LEVEL": "info",
+	})
+
+	expected := differences{
+		{Field: "environment.DEBUG", Expected: "<missing>", Actual: "true"},
+		{Field: "environment.ENVIRONMENT", Expected: "staging", Actual: "<missing>"},
+	}
+	if !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("unexpected differences: %v", diffs)
+	}
+}
+
+func TestDrift(t *testing.T) {
+	if d := drift(nil); d.Action != ActionNone || d.Differences != nil {
+		t.Errorf("unexpected drift without differences: %v", d)
+	}
+
+	var diffs differences
+	diffs.compare("batchSize", 100, 10)
+	if d := drift(diffs); d.Action != ActionUpdate || len(d.Differences) != 1 {
+		t.Errorf("unexpected drift with differences: %v", d)
+	}
+}
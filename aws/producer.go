@@ -0,0 +1,314 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	"github.com/aws/smithy-go"
+)
+
+// Limits of Kinesis for a single PutRecords call and a single record. The size of a record
+// is the size of its data and its partition key.
+const (
+	maxBatchRecords = 500
+	maxBatchBytes   = 5 << 20
+	maxRecordBytes  = 1 << 20
+)
+
+// ErrProducerClosed is returned when records are put into a closed producer.
+var ErrProducerClosed = errors.New("producer is closed")
+
+// ProducerConfig describes when a Producer sends its buffered records and how it retries
+// the records that Kinesis rejected.
+type ProducerConfig struct {
+	// MaxRecords is the number of buffered records that are sent at once. It is at most
+	// 500, the limit of Kinesis.
+	MaxRecords int
+	// MaxBytes is the size of the buffered records that are sent at once. It is at most
+	// 5 MB, the limit of Kinesis.
+	MaxBytes int
+	// Linger is the maximum time a record is buffered before it is sent.
+	Linger time.Duration
+	// Retry is the retry policy of the records that Kinesis rejected in a response, e.g.
+	// because their shard was throttled. Failed PutRecords calls are retried by the
+	// client with the retry policy of its configuration instead.
+	Retry RetryPolicy
+}
+
+// DefaultProducerConfig is the configuration of a producer whose configuration leaves
+// fields empty.
+var DefaultProducerConfig = ProducerConfig{
+	MaxRecords: maxBatchRecords,
+	MaxBytes:   maxBatchBytes,
+	Linger:     100 * time.Millisecond,
+	Retry:      DefaultRetryPolicy,
+}
+
+// withDefaults returns the configuration with the defaults of its empty fields and the
+// limits of Kinesis applied.
+func (c ProducerConfig) withDefaults() ProducerConfig {
+	if c.MaxRecords <= 0 || c.MaxRecords > maxBatchRecords {
+		c.MaxRecords = DefaultProducerConfig.MaxRecords
+	}
+	if c.MaxBytes <= 0 || c.MaxBytes > maxBatchBytes {
+		c.MaxBytes = DefaultProducerConfig.MaxBytes
+	}
+	if c.Linger <= 0 {
+		c.Linger = DefaultProducerConfig.Linger
+	}
+	if c.Retry.MaxAttempts <= 0 {
+		c.Retry = DefaultProducerConfig.Retry
+	}
+	return c
+}
+
+// Delivery is the result of sending a record to Kinesis.
+type Delivery struct {
+	// PartitionKey and Data are the partition key and the data of the record.
+	PartitionKey string
+	Data         []byte
+	// ShardID and SequenceNumber identify the record in the stream once it is delivered.
+	ShardID        string
+	SequenceNumber string
+	// Attempts is the number of PutRecords calls that contained the record.
+	Attempts int
+	// Err is the reason why the record was not delivered. Records that Kinesis throttled
+	// until the retries were exhausted match ErrThrottled.
+	Err error
+}
+
+// producerRecord is a record that is buffered by a producer.
+type producerRecord struct {
+	entry    types.PutRecordsRequestEntry
+	size     int
+	callback func(Delivery)
+	attempts int
+	err      error
+}
+
+// deliver reports the result of sending the record to its callback.
+func (r *producerRecord) deliver(delivery Delivery) {
+	if r.callback == nil {
+		return
+	}
+	delivery.PartitionKey = aws.ToString(r.entry.PartitionKey)
+	delivery.Data = r.entry.Data
+	delivery.Attempts = r.attempts
+	r.callback(delivery)
+}
+
+// Producer buffers records of a Kinesis stream and sends them with PutRecords once enough
+// records are buffered or the oldest record lingered long enough. Records of a partition
+// key are sent in the order they were put, but a record that is retried arrives after
+// the records that were delivered with it.
+type Producer struct {
+	client kinesisAPI
+	stream string
+	config ProducerConfig
+
+	// sending is held while records are taken from the buffer and sent, so batches are
+	// sent one at a time in the order they were buffered.
+	sending sync.Mutex
+
+	mu      sync.Mutex
+	buffer  []*producerRecord
+	size    int
+	timer   *time.Timer
+	closed  bool
+	failed  int
+	failure error
+}
+
+// NewProducer returns a producer of the Kinesis stream with the given name. Empty fields
+// of the configuration are taken from DefaultProducerConfig.
+func (k *Kinesis) NewProducer(stream string, config ProducerConfig) *Producer {
+	return &Producer{
+		client: k.client,
+		stream: stream,
+		config: config.withDefaults(),
+	}
+}
+
+// Put buffers a record with the given partition key and data. The given callback, which
+// may be nil, is called with the delivery of the record once it was sent. Put sends the
+// buffered records before it returns if the buffer is full, so it blocks while Kinesis
+// is slower than the caller.
+func (p *Producer) Put(ctx context.Context, partitionKey string, data []byte, callback func(Delivery)) error {
+	if partitionKey == "" {
+		return fmt.Errorf("record without a partition key: %w", ErrValidation)
+	}
+	size := len(partitionKey) + len(data)
+	if size > maxRecordBytes {
+		return fmt.Errorf("record of %d bytes exceeds the limit of %d bytes: %w", size, maxRecordBytes, ErrValidation)
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return ErrProducerClosed
+	}
+	p.buffer = append(p.buffer, &producerRecord{
+		entry: types.PutRecordsRequestEntry{
+			PartitionKey: aws.String(partitionKey),
+			Data:         data,
+		},
+		size:     size,
+		callback: callback,
+	})
+	p.size += size
+	full := len(p.buffer) >= p.config.MaxRecords || p.size >= p.config.MaxBytes
+	if !full && p.timer == nil {
+		p.timer = time.AfterFunc(p.config.Linger, func() { p.flush(context.Background()) })
+	}
+	p.mu.Unlock()
+
+	if full {
+		p.flush(ctx)
+	}
+	return nil
+}
+
+// Flush sends the buffered records and waits until every record that was put before is
+// delivered or failed. It returns an error if records failed since the last flush.
+func (p *Producer) Flush(ctx context.Context) error {
+	p.flush(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	failed, failure := p.failed, p.failure
+	p.failed, p.failure = 0, nil
+	if failed > 0 {
+		return fmt.Errorf("%d records were not delivered to stream %s: %w", failed, p.stream, failure)
+	}
+	return nil
+}
+
+// Close flushes the producer. Records cannot be put into a closed producer.
+func (p *Producer) Close(ctx context.Context) error {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+
+	return p.Flush(ctx)
+}
+
+// flush takes the buffered records and sends them.
+func (p *Producer) flush(ctx context.Context) {
+	p.sending.Lock()
+	defer p.sending.Unlock()
+
+	p.mu.Lock()
+	records := p.buffer
+	p.buffer, p.size = nil, 0
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	p.mu.Unlock()
+
+	for _, batch := range p.batches(records) {
+		p.send(ctx, batch)
+	}
+}
+
+// batches splits the given records into batches within the limits of the configuration.
+func (p *Producer) batches(records []*producerRecord) [][]*producerRecord {
+	var result [][]*producerRecord
+	var batch []*producerRecord
+	size := 0
+	for _, record := range records {
+		if len(batch) == p.config.MaxRecords || (len(batch) > 0 && size+record.size > p.config.MaxBytes) {
+			result = append(result, batch)
+			batch, size = nil, 0
+		}
+		batch = append(batch, record)
+		size += record.size
+	}
+	if len(batch) > 0 {
+		result = append(result, batch)
+	}
+	return result
+}
+
+// send sends the batch with PutRecords and retries the records that Kinesis rejected
+// until they are delivered or the retries are exhausted.
+func (p *Producer) send(ctx context.Context, batch []*producerRecord) {
+	for attempt := 1; ; attempt++ {
+		entries := make([]types.PutRecordsRequestEntry, len(batch))
+		for i, record := range batch {
+			record.attempts++
+			entries[i] = record.entry
+		}
+
+		output, err := p.client.PutRecords(ctx, &kinesis.PutRecordsInput{
+			StreamName: aws.String(p.stream),
+			Records:    entries,
+		})
+		if err == nil && len(output.Records) != len(batch) {
+			err = fmt.Errorf("expected %d results of PutRecords, got %d", len(batch), len(output.Records))
+		}
+		if err != nil {
+			for _, record := range batch {
+				record.err = err
+			}
+			p.fail(batch)
+			return
+		}
+
+		var failed []*producerRecord
+		for i, result := range output.Records {
+			if result.ErrorCode == nil {
+				batch[i].deliver(Delivery{
+					ShardID:        aws.ToString(result.ShardId),
+					SequenceNumber: aws.ToString(result.SequenceNumber),
+				})
+				continue
+			}
+			batch[i].err = Classify(&smithy.GenericAPIError{
+				Code:    aws.ToString(result.ErrorCode),
+				Message: aws.ToString(result.ErrorMessage),
+			})
+			failed = append(failed, batch[i])
+		}
+		if len(failed) == 0 {
+			return
+		}
+		if attempt >= p.config.Retry.MaxAttempts {
+			p.fail(failed)
+			return
+		}
+
+		delay, _ := p.config.Retry.BackoffDelay(attempt, nil)
+		select {
+		case <-ctx.Done():
+			for _, record := range failed {
+				record.err = ctx.Err()
+			}
+			p.fail(failed)
+			return
+		case <-time.After(delay):
+		}
+		batch = failed
+	}
+}
+
+// fail reports the given records as failed with their errors.
+func (p *Producer) fail(records []*producerRecord) {
+	p.mu.Lock()
+	p.failed += len(records)
+	if p.failure == nil {
+		p.failure = records[0].err
+	}
+	p.mu.Unlock()
+
+	for _, record := range records {
+		record.deliver(Delivery{Err: record.err})
+	}
+}
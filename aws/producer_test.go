@@ -0,0 +1,224 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+)
+
+// testRetry retries rejected records without waiting long.
+var testRetry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+// putRecordsClient returns a client whose PutRecords calls are recorded and answered by
+// the given function, which rejects a record by returning its error code.
+func putRecordsClient(reject func(call int, entry types.PutRecordsRequestEntry) string) (*mockKinesisClient, func() [][]string) {
+	var mu sync.Mutex
+	var calls [][]string
+	client := &mockKinesisClient{
+		putRecordsFunc: func(ctx context.Context, input *kinesis.PutRecordsInput, opts ...func(*kinesis.Options)) (*kinesis.PutRecordsOutput, error) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			var keys []string
+			output := &kinesis.PutRecordsOutput{}
+			for i, entry := range input.Records {
+				keys = append(keys, aws.ToString(entry.PartitionKey))
+				if code := reject(len(calls), entry); code != "" {
+					output.Records = append(output.Records, types.PutRecordsResultEntry{ErrorCode: aws.String(code), ErrorMessage: aws.String("rejected")})
+					continue
+				}
+				output.Records = append(output.Records, types.PutRecordsResultEntry{
+					ShardId:        aws.String("shardId-000000000000"),
+					SequenceNumber: aws.String(fmt.Sprintf("%d-%d", len(calls), i)),
+				})
+			}
+			calls = append(calls, keys)
+			return output, nil
+		},
+	}
+	return client, func() [][]string {
+		mu.Lock()
+		defer mu.Unlock()
+		return calls
+	}
+}
+
+func TestProducer_Batches(t *testing.T) {
+	client, calls := putRecordsClient(func(int, types.PutRecordsRequestEntry) string { return "" })
+	producer := (&Kinesis{client: client}).NewProducer("test-stream", ProducerConfig{MaxRecords: 2, Linger: time.Hour})
+
+	var deliveries []Delivery
+	for i := 0; i < 5; i++ {
+		err := producer.Put(context.Background(), fmt.Sprint(i), []byte("data"), func(d Delivery) {
+			deliveries = append(deliveries, d)
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if len(calls()) != 2 {
+		t.Errorf("expected the full batches to be sent by Put, got %v", calls())
+	}
+	if err := producer.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fmt.Sprint(calls()) != "[[0 1] [2 3] [4]]" {
+		t.Errorf("unexpected batches %v", calls())
+	}
+	if len(deliveries) != 5 {
+		t.Fatalf("expected 5 deliveries, got %d", len(deliveries))
+	}
+	for i, d := range deliveries {
+		if d.Err != nil || d.PartitionKey != fmt.Sprint(i) || d.SequenceNumber == "" || d.Attempts != 1 {
+			t.Errorf("unexpected delivery %+v", d)
+		}
+	}
+}
+
+func TestProducer_MaxBytes(t *testing.T) {
+	client, calls := putRecordsClient(func(int, types.PutRecordsRequestEntry) string { return "" })
+	producer := (&Kinesis{client: client}).NewProducer("test-stream", ProducerConfig{MaxBytes: 10, Linger: time.Hour})
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := producer.Put(context.Background(), key, []byte("1234"), nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := producer.Flush(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fmt.Sprint(calls()) != "[[a b] [c]]" {
+		t.Errorf("expected batches of at most 10 bytes, got %v", calls())
+	}
+}
+
+func TestProducer_RetriesFailedRecords(t *testing.T) {
+	// The first call rejects the second record, which is sent again on its own.
+	client, calls := putRecordsClient(func(call int, entry types.PutRecordsRequestEntry) string {
+		if call == 0 && aws.ToString(entry.PartitionKey) == "b" {
+			return "ProvisionedThroughputExceededException"
+		}
+		return ""
+	})
+	producer := (&Kinesis{client: client}).NewProducer("test-stream", ProducerConfig{Linger: time.Hour, Retry: testRetry})
+
+	deliveries := map[string]Delivery{}
+	for _, key := range []string{"a", "b", "c"} {
+		err := producer.Put(context.Background(), key, []byte(key), func(d Delivery) {
+			deliveries[d.PartitionKey] = d
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := producer.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fmt.Sprint(calls()) != "[[a b c] [b]]" {
+		t.Errorf("expected only the rejected record to be retried, got %v", calls())
+	}
+	if d := deliveries["b"]; d.Err != nil || d.Attempts != 2 || d.SequenceNumber != "1-0" || !bytes.Equal(d.Data, []byte("b")) {
+		t.Errorf("unexpected delivery of the retried record %+v", d)
+	}
+	if d := deliveries["a"]; d.Err != nil || d.Attempts != 1 {
+		t.Errorf("unexpected delivery %+v", d)
+	}
+}
+
+func TestProducer_RetriesExhausted(t *testing.T) {
+	client, calls := putRecordsClient(func(call int, entry types.PutRecordsRequestEntry) string {
+		return "ProvisionedThroughputExceededException"
+	})
+	producer := (&Kinesis{client: client}).NewProducer("test-stream", ProducerConfig{Linger: time.Hour, Retry: testRetry})
+
+	var delivery Delivery
+	if err := producer.Put(context.Background(), "a", []byte("a"), func(d Delivery) { delivery = d }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := producer.Flush(context.Background())
+	if !errors.Is(err, ErrThrottled) {
+		t.Errorf("expected Flush to report the throttled record, got %v", err)
+	}
+
+	if len(calls()) != testRetry.MaxAttempts {
+		t.Errorf("expected %d attempts, got %v", testRetry.MaxAttempts, calls())
+	}
+	if !errors.Is(delivery.Err, ErrThrottled) || delivery.Attempts != testRetry.MaxAttempts {
+		t.Errorf("unexpected delivery %+v", delivery)
+	}
+	if err := producer.Flush(context.Background()); err != nil {
+		t.Errorf("expected the failure to be reported once, got %v", err)
+	}
+}
+
+func TestProducer_CallFails(t *testing.T) {
+	client := &mockKinesisClient{
+		putRecordsFunc: func(ctx context.Context, input *kinesis.PutRecordsInput, opts ...func(*kinesis.Options)) (*kinesis.PutRecordsOutput, error) {
+			return nil, &Error{Kind: ErrNotFound, Err: errors.New("stream not found")}
+		},
+	}
+	producer := (&Kinesis{client: client}).NewProducer("test-stream", ProducerConfig{Linger: time.Hour})
+
+	var delivery Delivery
+	if err := producer.Put(context.Background(), "a", []byte("a"), func(d Delivery) { delivery = d }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := producer.Flush(context.Background()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if !errors.Is(delivery.Err, ErrNotFound) || delivery.Attempts != 1 {
+		t.Errorf("expected the failed call not to be retried by the producer, got %+v", delivery)
+	}
+}
+
+func TestProducer_Linger(t *testing.T) {
+	client, _ := putRecordsClient(func(int, types.PutRecordsRequestEntry) string { return "" })
+	producer := (&Kinesis{client: client}).NewProducer("test-stream", ProducerConfig{Linger: 10 * time.Millisecond})
+
+	delivered := make(chan Delivery, 1)
+	if err := producer.Put(context.Background(), "a", []byte("a"), func(d Delivery) { delivered <- d }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	select {
+	case d := <-delivered:
+		if d.Err != nil {
+			t.Errorf("unexpected error: %v", d.Err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the record to be sent after lingering")
+	}
+}
+
+func TestProducer_Put_Invalid(t *testing.T) {
+	client, calls := putRecordsClient(func(int, types.PutRecordsRequestEntry) string { return "" })
+	producer := (&Kinesis{client: client}).NewProducer("test-stream", ProducerConfig{})
+
+	if err := producer.Put(context.Background(), "a", make([]byte, maxRecordBytes), nil); !errors.Is(err, ErrValidation) {
+		t.Errorf("expected a record above 1 MB to be invalid, got %v", err)
+	}
+	if err := producer.Put(context.Background(), "", []byte("a"), nil); !errors.Is(err, ErrValidation) {
+		t.Errorf("expected a record without a partition key to be invalid, got %v", err)
+	}
+
+	if err := producer.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := producer.Put(context.Background(), "a", []byte("a"), nil); !errors.Is(err, ErrProducerClosed) {
+		t.Errorf("expected ErrProducerClosed, got %v", err)
+	}
+	if len(calls()) != 0 {
+		t.Errorf("expected no calls, got %v", calls())
+	}
+}
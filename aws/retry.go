@@ -0,0 +1,80 @@
+package aws
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// RetryPolicy describes how the wrapped clients retry calls that were throttled or failed
+// transiently, e.g. with a server error or a broken connection. Invalid requests and
+// missing resources are never retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a call, including the first one.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. The delay doubles after every retry.
+	BaseDelay time.Duration
+	// MaxDelay is the maximum delay between two attempts.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy of the wrapped clients if the configuration has
+// no retryer.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    20 * time.Second,
+}
+
+// Retryer returns a retryer with the policy, which retries the errors that the standard
+// retryer of the SDK retries.
+func (p RetryPolicy) Retryer() aws.Retryer {
+	return retry.NewStandard(func(o *retry.StandardOptions) {
+		o.MaxAttempts = p.MaxAttempts
+		o.MaxBackoff = p.MaxDelay
+		o.Backoff = p
+	})
+}
+
+// BackoffDelay returns the delay before the given attempt, which is a random duration up
+// to BaseDelay doubled for every previous retry and at most MaxDelay.
+func (p RetryPolicy) BackoffDelay(attempt int, _ error) (time.Duration, error) {
+	delay := p.MaxDelay
+	if shift := attempt - 1; shift < 32 && p.BaseDelay<<shift < p.MaxDelay {
+		delay = p.BaseDelay << shift
+	}
+	if delay <= 0 {
+		return 0, nil
+	}
+	return time.Duration(rand.Int63n(int64(delay)) + 1), nil
+}
+
+// WithRetryPolicy returns a copy of the given configuration whose clients retry with the
+// given policy.
+func WithRetryPolicy(config aws.Config, policy RetryPolicy) aws.Config {
+	cfg := config.Copy()
+	cfg.Retryer = policy.Retryer
+	return cfg
+}
+
+// clientConfig returns the configuration of the clients of a wrapper. The clients retry
+// with DefaultRetryPolicy unless the given configuration has a retryer and their errors
+// are classified by their kind.
+func clientConfig(config aws.Config) aws.Config {
+	cfg := withAPIOption(config, addClassifyErrors)
+	if cfg.Retryer == nil {
+		cfg.Retryer = DefaultRetryPolicy.Retryer
+	}
+	return cfg
+}
+
+// withAPIOption returns a copy of the given configuration whose clients add the given
+// middleware. The given configuration is left unchanged.
+func withAPIOption(config aws.Config, option func(*middleware.Stack) error) aws.Config {
+	cfg := config.Copy()
+	cfg.APIOptions = append(append([]func(*middleware.Stack) error(nil), cfg.APIOptions...), option)
+	return cfg
+}
@@ -3,15 +3,22 @@ package aws
 import (
 	"bytes"
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 )
 
 type s3API interface {
 	CreateBucket(ctx context.Context, params *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error)
 	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
 	DeleteBucket(ctx context.Context, params *s3.DeleteBucketInput, optFns ...func(*s3.Options)) (*s3.DeleteBucketOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+	HeadBucket(ctx context.Context, params *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
 }
 
 // S3 is a wrapper around the AWS S3 client.
@@ -22,13 +29,19 @@ type S3 struct {
 // NewS3 creates a new S3 client with the given configuration.
 func NewS3(config aws.Config) *S3 {
 	return &S3{
-		client: s3.NewFromConfig(config.Copy()),
+		client: s3.NewFromConfig(clientConfig(config)),
 	}
 }
 
+// NewS3FromClient creates a new S3 wrapper around the given client, e.g. a fake of the
+// `awsfake` package.
+func NewS3FromClient(client s3API) *S3 {
+	return &S3{client: client}
+}
+
 // CreateBucket creates a S3 bucket with the given name.
-func (s *S3) CreateBucket(name string) error {
-	_, err := s.client.CreateBucket(context.TODO(), &s3.CreateBucketInput{
+func (s *S3) CreateBucket(ctx context.Context, name string) error {
+	_, err := s.client.CreateBucket(ctx, &s3.CreateBucketInput{
 		Bucket: aws.String(name),
 	})
 	if err != nil {
@@ -38,9 +51,36 @@ func (s *S3) CreateBucket(name string) error {
 	return nil
 }
 
+// EnsureBucket creates a S3 bucket with the given name if it does not exist yet. A
+// bucket that is already owned by the caller is not treated as an error.
+func (s *S3) EnsureBucket(ctx context.Context, name string) error {
+	err := s.CreateBucket(ctx, name)
+	if err != nil && !hasErrorCode(err, "BucketAlreadyOwnedByYou") {
+		return err
+	}
+
+	return nil
+}
+
+// PlanBucket returns the action EnsureBucket would take for the S3 bucket with the
+// given name without changing it.
+func (s *S3) PlanBucket(ctx context.Context, name string) (Action, error) {
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(name),
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return "", err
+		}
+		return ActionCreate, nil
+	}
+
+	return ActionNone, nil
+}
+
 // DeleteBucket deletes a S3 bucket with the given name.
-func (s *S3) DeleteBucket(name string) error {
-	_, err := s.client.DeleteBucket(context.TODO(), &s3.DeleteBucketInput{
+func (s *S3) DeleteBucket(ctx context.Context, name string) error {
+	_, err := s.client.DeleteBucket(ctx, &s3.DeleteBucketInput{
 		Bucket: aws.String(name),
 	})
 	if err != nil {
@@ -50,9 +90,52 @@ func (s *S3) DeleteBucket(name string) error {
 	return nil
 }
 
+// EmptyBucket deletes every object of the S3 bucket with the given name, so that the
+// bucket itself can be deleted. It returns the number of deleted objects.
+func (s *S3) EmptyBucket(ctx context.Context, name string) (int, error) {
+	deleted := 0
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(name),
+	}
+	for {
+		objects, err := s.client.ListObjectsV2(ctx, input)
+		if err != nil {
+			return deleted, err
+		}
+
+		if len(objects.Contents) > 0 {
+			identifiers := make([]types.ObjectIdentifier, 0, len(objects.Contents))
+			for _, object := range objects.Contents {
+				identifiers = append(identifiers, types.ObjectIdentifier{Key: object.Key})
+			}
+
+			output, err := s.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+				Bucket: aws.String(name),
+				Delete: &types.Delete{
+					Objects: identifiers,
+					Quiet:   true,
+				},
+			})
+			if err != nil {
+				return deleted, err
+			}
+			if len(output.Errors) > 0 {
+				failed := output.Errors[0]
+				return deleted, fmt.Errorf("deleting object %s: %s", aws.ToString(failed.Key), aws.ToString(failed.Message))
+			}
+			deleted += len(identifiers)
+		}
+
+		if !objects.IsTruncated {
+			return deleted, nil
+		}
+		input.ContinuationToken = objects.NextContinuationToken
+	}
+}
+
 // PutObject puts an object into a S3 bucket with the given name and key.
-func (s *S3) PutObject(bucket, key string, data []byte) error {
-	_, err := s.client.PutObject(context.TODO(), &s3.PutObjectInput{
+func (s *S3) PutObject(ctx context.Context, bucket, key string, data []byte) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
 		Bucket: aws.String(bucket),
 		Key:    aws.String(key),
 		Body:   bytes.NewReader(data),
@@ -63,3 +146,20 @@ func (s *S3) PutObject(bucket, key string, data []byte) error {
 
 	return nil
 }
+
+// ObjectExists reports whether the S3 bucket with the given name contains an object with
+// the given key.
+func (s *S3) ObjectExists(ctx context.Context, bucket, key string) (bool, error) {
+	_, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if !errors.Is(err, ErrNotFound) {
+			return false, err
+		}
+		return false, nil
+	}
+
+	return true, nil
+}
@@ -6,12 +6,18 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 )
 
 type mockS3Client struct {
 	createBucketFunc func(context.Context, *s3.CreateBucketInput, ...func(*s3.Options)) (*s3.CreateBucketOutput, error)
 	putObjectFunc    func(context.Context, *s3.PutObjectInput, ...func(*s3.Options)) (*s3.PutObjectOutput, error)
 	deleteBucketFunc func(context.Context, *s3.DeleteBucketInput, ...func(*s3.Options)) (*s3.DeleteBucketOutput, error)
+	listObjectsFunc  func(context.Context, *s3.ListObjectsV2Input, ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	deleteObjsFunc   func(context.Context, *s3.DeleteObjectsInput, ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+	headBucketFunc   func(context.Context, *s3.HeadBucketInput, ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+	headObjectFunc   func(context.Context, *s3.HeadObjectInput, ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
 }
 
 func (m *mockS3Client) CreateBucket(ctx context.Context, input *s3.CreateBucketInput, opts ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
@@ -26,6 +32,22 @@ func (m *mockS3Client) DeleteBucket(ctx context.Context, input *s3.DeleteBucketI
 	return m.deleteBucketFunc(ctx, input, opts...)
 }
 
+func (m *mockS3Client) ListObjectsV2(ctx context.Context, input *s3.ListObjectsV2Input, opts ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return m.listObjectsFunc(ctx, input, opts...)
+}
+
+func (m *mockS3Client) DeleteObjects(ctx context.Context, input *s3.DeleteObjectsInput, opts ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	return m.deleteObjsFunc(ctx, input, opts...)
+}
+
+func (m *mockS3Client) HeadBucket(ctx context.Context, input *s3.HeadBucketInput, opts ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	return m.headBucketFunc(ctx, input, opts...)
+}
+
+func (m *mockS3Client) HeadObject(ctx context.Context, input *s3.HeadObjectInput, opts ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	return m.headObjectFunc(ctx, input, opts...)
+}
+
 func TestS3_CreateBucket(t *testing.T) {
 	mockClient := &mockS3Client{
 		createBucketFunc: func(ctx context.Context, input *s3.CreateBucketInput, opts ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
@@ -40,7 +62,24 @@ func TestS3_CreateBucket(t *testing.T) {
 		client: mockClient,
 	}
 
-	err := s3Client.CreateBucket("test-bucket")
+	err := s3Client.CreateBucket(context.Background(), "test-bucket")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestS3_EnsureBucket(t *testing.T) {
+	mockClient := &mockS3Client{
+		createBucketFunc: func(ctx context.Context, input *s3.CreateBucketInput, opts ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
+			return nil, &smithy.GenericAPIError{Code: "BucketAlreadyOwnedByYou"}
+		},
+	}
+
+	s3Client := &S3{
+		client: mockClient,
+	}
+
+	err := s3Client.EnsureBucket(context.Background(), "test-bucket")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -60,7 +99,7 @@ func TestS3_DeleteBucket(t *testing.T) {
 		client: mockClient,
 	}
 
-	err := s3Client.DeleteBucket("test-bucket")
+	err := s3Client.DeleteBucket(context.Background(), "test-bucket")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
@@ -83,8 +122,105 @@ func TestS3_PutObject(t *testing.T) {
 		client: mockClient,
 	}
 
-	err := s3Client.PutObject("test-bucket", "test-key", []byte("test-data"))
+	err := s3Client.PutObject(context.Background(), "test-bucket", "test-key", []byte("test-data"))
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestS3_EmptyBucket(t *testing.T) {
+	mockClient := &mockS3Client{
+		listObjectsFunc: func(ctx context.Context, input *s3.ListObjectsV2Input, opts ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			if input.ContinuationToken == nil {
+				return &s3.ListObjectsV2Output{
+					Contents:              []types.Object{{Key: aws.String("a")}, {Key: aws.String("b")}},
+					IsTruncated:           true,
+					NextContinuationToken: aws.String("next"),
+				}, nil
+			}
+			return &s3.ListObjectsV2Output{
+				Contents: []types.Object{{Key: aws.String("c")}},
+			}, nil
+		},
+		deleteObjsFunc: func(ctx context.Context, input *s3.DeleteObjectsInput, opts ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+			if aws.ToString(input.Bucket) != "test-bucket" {
+				t.Errorf("unexpected bucket name: %s", aws.ToString(input.Bucket))
+			}
+			return &s3.DeleteObjectsOutput{}, nil
+		},
+	}
+
+	s3Client := &S3{
+		client: mockClient,
+	}
+
+	deleted, err := s3Client.EmptyBucket(context.Background(), "test-bucket")
 	if err != nil {
 		t.Errorf("unexpected error: %v", err)
 	}
+	if deleted != 3 {
+		t.Errorf("unexpected number of deleted objects: %d", deleted)
+	}
+}
+
+func TestS3_PlanBucket(t *testing.T) {
+	mockClient := &mockS3Client{
+		headBucketFunc: func(ctx context.Context, input *s3.HeadBucketInput, opts ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+			if aws.ToString(input.Bucket) == "existing-bucket" {
+				return &s3.HeadBucketOutput{}, nil
+			}
+			return nil, Classify(&smithy.GenericAPIError{Code: "NotFound"})
+		},
+	}
+
+	s3Client := &S3{
+		client: mockClient,
+	}
+
+	action, err := s3Client.PlanBucket(context.Background(), "existing-bucket")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if action != ActionNone {
+		t.Errorf("unexpected action for existing bucket: %s", action)
+	}
+
+	action, err = s3Client.PlanBucket(context.Background(), "missing-bucket")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if action != ActionCreate {
+		t.Errorf("unexpected action for missing bucket: %s", action)
+	}
+}
+
+func TestS3_ObjectExists(t *testing.T) {
+	mockClient := &mockS3Client{
+		headObjectFunc: func(ctx context.Context, input *s3.HeadObjectInput, opts ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			if aws.ToString(input.Key) == "existing-key" {
+				return &s3.HeadObjectOutput{}, nil
+			}
+			return nil, Classify(&smithy.GenericAPIError{Code: "NotFound"})
+		},
+	}
+
+	s3Client := &S3{
+		client: mockClient,
+	}
+
+	exists, err := s3Client.ObjectExists(context.Background(), "test-bucket", "existing-key")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !exists {
+		t.Errorf("expected existing object")
+	}
+
+	exists, err = s3Client.ObjectExists(context.Background(), "test-bucket", "missing-key")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if exists {
+		t.Errorf("unexpected missing object")
+	}
 }
@@ -0,0 +1,51 @@
+package aws
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the name of the tracer of the spans of the calls.
+const tracerName = "github.com/florianwoelki/uber-movement-speed/aws"
+
+// WithTracing returns a copy of the given configuration whose clients trace every call
+// with a client span of the given tracer provider. The span is named after the service
+// and the operation, e.g. `DynamoDB.PutItem`, covers the retries of the call and is a
+// child of the span of the context of the call.
+func WithTracing(config aws.Config, provider trace.TracerProvider) aws.Config {
+	tracer := provider.Tracer(tracerName)
+	addTracing := func(stack *middleware.Stack) error {
+		return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("TraceCalls", func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			service, operation := awsmiddleware.GetServiceID(ctx), awsmiddleware.GetOperationName(ctx)
+			ctx, span := tracer.Start(ctx, service+"."+operation,
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(
+					attribute.String("rpc.system", "aws-api"),
+					attribute.String("rpc.service", service),
+					attribute.String("rpc.method", operation),
+					attribute.String("cloud.region", awsmiddleware.GetRegion(ctx)),
+				),
+			)
+			defer span.End()
+
+			out, metadata, err := next.HandleInitialize(ctx, in)
+			if requestID, ok := awsmiddleware.GetRequestIDMetadata(metadata); ok {
+				span.SetAttributes(attribute.String("aws.request_id", requestID))
+			}
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+
+			return out, metadata, err
+		}), middleware.After)
+	}
+
+	return withAPIOption(config, addTracing)
+}
@@ -0,0 +1,59 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWithTracing(t *testing.T) {
+	client := roundTripFunc(func(req *http.Request) *http.Response {
+		return dynamoDBError("ResourceNotFoundException")
+	})
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	dynamoDB := NewDynamoDB(WithTracing(testConfig(client), provider))
+
+	ctx, parent := provider.Tracer("test").Start(context.Background(), "parent")
+	_, err := dynamoDB.DescribeTable(ctx, "test")
+	parent.End()
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected the span of the call and its parent, got %d spans", len(spans))
+	}
+	span := spans[0]
+	if span.Name() != "DynamoDB.DescribeTable" || span.SpanKind() != trace.SpanKindClient {
+		t.Errorf("unexpected span %s of kind %s", span.Name(), span.SpanKind())
+	}
+	if span.Parent().SpanID() != parent.SpanContext().SpanID() {
+		t.Errorf("expected the span to be a child of the span of the context")
+	}
+	if span.Status().Code != codes.Error {
+		t.Errorf("expected the failed call to set an error status, got %v", span.Status())
+	}
+
+	expected := map[attribute.Key]string{"rpc.system": "aws-api", "rpc.service": "DynamoDB", "rpc.method": "DescribeTable"}
+	for _, attr := range span.Attributes() {
+		if value, ok := expected[attr.Key]; ok {
+			if attr.Value.AsString() != value {
+				t.Errorf("expected %s to be %s, got %s", attr.Key, value, attr.Value.AsString())
+			}
+			delete(expected, attr.Key)
+		}
+	}
+	if len(expected) > 0 {
+		t.Errorf("missing attributes %v", expected)
+	}
+}
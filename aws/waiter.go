@@ -0,0 +1,68 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrWaitTimeout is returned by the waiters if a resource did not reach the desired state
+// before the timeout expired.
+var ErrWaitTimeout = errors.New("timed out waiting for resource")
+
+// defaultWaitTimeout is the maximum time the wrappers wait for a resource to finish an
+// update without WithWaitTimeout.
+const defaultWaitTimeout = 10 * time.Minute
+
+// Delays between two checks of a waiter. The delay starts at waitMinDelay and doubles
+// after every check up to waitMaxDelay.
+var (
+	waitMinDelay = time.Second
+	waitMaxDelay = 15 * time.Second
+)
+
+// waitCheck checks the state of a resource. It reports whether the resource reached the
+// desired state and returns the current state for the timeout error. An error stops the
+// waiter, e.g. if the resource reached a state it will never leave.
+type waitCheck func(ctx context.Context) (bool, string, error)
+
+// wait calls the given check with exponentially increasing delays until the resource
+// with the given description reached the desired state. It returns an error wrapping
+// ErrWaitTimeout if the resource did not reach the state within the given timeout or
+// before the deadline of the given context.
+func wait(ctx context.Context, resource string, timeout time.Duration, check waitCheck) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	state := "unknown"
+	delay := waitMinDelay
+	for {
+		done, current, err := check(ctx)
+		if err != nil && ctx.Err() == nil {
+			return fmt.Errorf("waiting for %s: %w", resource, err)
+		}
+		if err == nil && done {
+			return nil
+		}
+		if err == nil {
+			state = current
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("%w: %s is still %s after %s", ErrWaitTimeout, resource, state, timeout)
+			}
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		delay *= 2
+		if delay > waitMaxDelay {
+			delay = waitMaxDelay
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// setWaitDelay shortens the delays of the waiters for the duration of the test.
+func setWaitDelay(t *testing.T) {
+	minDelay, maxDelay := waitMinDelay, waitMaxDelay
+	waitMinDelay, waitMaxDelay = time.Millisecond, 2*time.Millisecond
+	t.Cleanup(func() {
+		waitMinDelay, waitMaxDelay = minDelay, maxDelay
+	})
+}
+
+func TestWait(t *testing.T) {
+	setWaitDelay(t)
+
+	calls := 0
+	err := wait(context.Background(), "test resource", time.Second, func(ctx context.Context) (bool, string, error) {
+		calls++
+		return calls == 3, "CREATING", nil
+	})
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 calls, got %d", calls)
+	}
+}
+
+func TestWait_Timeout(t *testing.T) {
+	setWaitDelay(t)
+
+	err := wait(context.Background(), "test resource", 10*time.Millisecond, func(ctx context.Context) (bool, string, error) {
+		return false, "CREATING", nil
+	})
+	if !errors.Is(err, ErrWaitTimeout) {
+		t.Fatalf("expected timeout error, got %v", err)
+	}
+	if want := "timed out waiting for resource: test resource is still CREATING after 10ms"; err.Error() != want {
+		t.Errorf("unexpected error message: %s", err)
+	}
+}
+
+func TestWait_Error(t *testing.T) {
+	setWaitDelay(t)
+
+	calls := 0
+	err := wait(context.Background(), "test resource", time.Second, func(ctx context.Context) (bool, string, error) {
+		calls++
+		return false, "", errors.New("failed")
+	})
+	if err == nil || errors.Is(err, ErrWaitTimeout) {
+		t.Errorf("expected check error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestWait_Cancelled(t *testing.T) {
+	setWaitDelay(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := wait(ctx, "test resource", time.Second, func(ctx context.Context) (bool, string, error) {
+		cancel()
+		return false, "CREATING", nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected cancellation, got %v", err)
+	}
+}
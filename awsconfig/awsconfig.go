@@ -0,0 +1,161 @@
+// Package awsconfig builds the AWS configuration that is shared by the setup program and
+// the Lambda functions. The configuration either targets real AWS, LocalStack or any other
+// local stand-in that serves the AWS APIs under a single endpoint.
+package awsconfig
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// Target is the backend the AWS clients talk to.
+type Target string
+
+const (
+	// TargetAWS uses the default endpoints of AWS.
+	TargetAWS Target = "aws"
+	// TargetLocalStack uses the endpoints of LocalStack.
+	TargetLocalStack Target = "localstack"
+	// TargetCustom uses a custom endpoint for every service.
+	TargetCustom Target = "custom"
+)
+
+// DefaultRegion is the region that is used if no region is configured.
+const DefaultRegion = "us-east-1"
+
+// Default LocalStack host and the endpoint of its S3 API, which uses virtual-hosted
+// buckets.
+const (
+	localStackHost       = "localhost.localstack.cloud"
+	localStackPort       = 4566
+	localStackS3Endpoint = "http://s3.localhost.localstack.cloud:4566"
+)
+
+// Settings describes how the AWS clients connect to their backend.
+type Settings struct {
+	// Target is the backend the clients talk to.
+	Target Target
+	// Region is the region of the clients.
+	Region string
+	// Profile is the shared configuration profile. Empty means the default profile.
+	Profile string
+	// Endpoint is the URL of every service except S3. It is ignored for TargetAWS.
+	Endpoint string
+	// S3Endpoint is the URL of S3. Empty means Endpoint. It is ignored for TargetAWS.
+	S3Endpoint string
+	// StaticCredentials uses fixed dummy credentials, which LocalStack and most local
+	// stand-ins accept, instead of the default credential chain.
+	StaticCredentials bool
+}
+
+// FromEnv returns the settings that are described by the environment:
+//
+//   - `AWS_ENDPOINT_URL` selects TargetCustom with the given endpoint for every service
+//     and `AWS_ENDPOINT_URL_S3` overrides the endpoint of S3.
+//   - `AWS_TARGET` selects either `aws` or `localstack`. Without it, LocalStack is used
+//     unless the program runs in a Lambda function outside of LocalStack.
+//   - `LOCALSTACK_HOSTNAME` is the host of LocalStack, which LocalStack sets for its
+//     Lambda functions.
+//   - `AWS_REGION` or `AWS_DEFAULT_REGION` is the region, DefaultRegion by default.
+//   - `AWS_PROFILE` is the shared configuration profile.
+//
+// Credentials are read by the default credential chain. If the target is not AWS and
+// neither `AWS_ACCESS_KEY_ID` nor a profile is set, dummy credentials are used.
+func FromEnv() (Settings, error) {
+	s := Settings{
+		Region:  firstNonEmpty(os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION"), DefaultRegion),
+		Profile: os.Getenv("AWS_PROFILE"),
+	}
+
+	localStackHostname := os.Getenv("LOCALSTACK_HOSTNAME")
+	switch target := Target(os.Getenv("AWS_TARGET")); {
+	case os.Getenv("AWS_ENDPOINT_URL") != "":
+		s.Target = TargetCustom
+		s.Endpoint = os.Getenv("AWS_ENDPOINT_URL")
+		s.S3Endpoint = os.Getenv("AWS_ENDPOINT_URL_S3")
+	case target == TargetAWS || target == TargetLocalStack:
+		s.Target = target
+	case target != "":
+		return Settings{}, fmt.Errorf("unsupported AWS_TARGET %q, expected %q or %q", target, TargetAWS, TargetLocalStack)
+	case localStackHostname == "" && os.Getenv("AWS_LAMBDA_FUNCTION_NAME") != "":
+		s.Target = TargetAWS
+	default:
+		s.Target = TargetLocalStack
+	}
+
+	if s.Target == TargetLocalStack {
+		host := firstNonEmpty(localStackHostname, localStackHost)
+		s.Endpoint = fmt.Sprintf("http://%s:%d", host, localStackPort)
+		s.S3Endpoint = firstNonEmpty(os.Getenv("AWS_ENDPOINT_URL_S3"), localStackS3Endpoint)
+	}
+
+	s.StaticCredentials = s.Target != TargetAWS && s.Profile == "" && os.Getenv("AWS_ACCESS_KEY_ID") == ""
+	return s, nil
+}
+
+// Load returns the AWS configuration for the given settings.
+func Load(ctx context.Context, s Settings) (aws.Config, error) {
+	region := firstNonEmpty(s.Region, DefaultRegion)
+	opts := []func(*config.LoadOptions) error{
+		config.WithRegion(region),
+	}
+	if s.Profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(s.Profile))
+	}
+	if s.StaticCredentials {
+		opts = append(opts, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("test", "test", "")))
+	}
+
+	if s.Target != TargetAWS {
+		if s.Endpoint == "" {
+			return aws.Config{}, fmt.Errorf("target %s requires an endpoint", s.Target)
+		}
+		opts = append(opts, config.WithEndpointResolverWithOptions(resolver(region, s.Endpoint, s.S3Endpoint)))
+	}
+
+	return config.LoadDefaultConfig(ctx, opts...)
+}
+
+// LoadFromEnv returns the AWS configuration for the settings that are described by the
+// environment.
+func LoadFromEnv(ctx context.Context) (aws.Config, error) {
+	s, err := FromEnv()
+	if err != nil {
+		return aws.Config{}, err
+	}
+
+	return Load(ctx, s)
+}
+
+// resolver returns an endpoint resolver that resolves S3 to the given S3 endpoint and
+// every other service to the given endpoint. An empty S3 endpoint means the endpoint.
+func resolver(region, endpoint, s3Endpoint string) aws.EndpointResolverWithOptions {
+	return aws.EndpointResolverWithOptionsFunc(func(service, _ string, _ ...interface{}) (aws.Endpoint, error) {
+		url := endpoint
+		if service == s3.ServiceID && s3Endpoint != "" {
+			url = s3Endpoint
+		}
+
+		return aws.Endpoint{
+			PartitionID:   "aws",
+			URL:           url,
+			SigningRegion: region,
+		}, nil
+	})
+}
+
+// firstNonEmpty returns the first of the given strings that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
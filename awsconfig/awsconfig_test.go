@@ -0,0 +1,167 @@
+package awsconfig
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// clearEnv unsets every environment variable that FromEnv reads for the duration of the
+// test.
+func clearEnv(t *testing.T) {
+	for _, key := range []string{
+		"AWS_ENDPOINT_URL",
+		"AWS_ENDPOINT_URL_S3",
+		"AWS_TARGET",
+		"LOCALSTACK_HOSTNAME",
+		"AWS_LAMBDA_FUNCTION_NAME",
+		"AWS_REGION",
+		"AWS_DEFAULT_REGION",
+		"AWS_PROFILE",
+		"AWS_ACCESS_KEY_ID",
+	} {
+		t.Setenv(key, "")
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want Settings
+	}{
+		{
+			name: "default",
+			want: Settings{
+				Target:            TargetLocalStack,
+				Region:            DefaultRegion,
+				Endpoint:          "http://localhost.localstack.cloud:4566",
+				S3Endpoint:        localStackS3Endpoint,
+				StaticCredentials: true,
+			},
+		},
+		{
+			name: "localstack lambda",
+			env: map[string]string{
+				"LOCALSTACK_HOSTNAME":      "172.17.0.2",
+				"AWS_LAMBDA_FUNCTION_NAME": "Preprocessing",
+				"AWS_ACCESS_KEY_ID":        "test",
+			},
+			want: Settings{
+				Target:     TargetLocalStack,
+				Region:     DefaultRegion,
+				Endpoint:   "http://172.17.0.2:4566",
+				S3Endpoint: localStackS3Endpoint,
+			},
+		},
+		{
+			name: "aws lambda",
+			env: map[string]string{
+				"AWS_LAMBDA_FUNCTION_NAME": "Preprocessing",
+				"AWS_REGION":               "eu-central-1",
+			},
+			want: Settings{
+				Target: TargetAWS,
+				Region: "eu-central-1",
+			},
+		},
+		{
+			name: "aws",
+			env: map[string]string{
+				"AWS_TARGET":         "aws",
+				"AWS_DEFAULT_REGION": "eu-west-1",
+				"AWS_PROFILE":        "dev",
+			},
+			want: Settings{
+				Target:  TargetAWS,
+				Region:  "eu-west-1",
+				Profile: "dev",
+			},
+		},
+		{
+			name: "custom",
+			env: map[string]string{
+				"AWS_ENDPOINT_URL":    "http://localhost:5000",
+				"AWS_ENDPOINT_URL_S3": "http://localhost:9000",
+			},
+			want: Settings{
+				Target:            TargetCustom,
+				Region:            DefaultRegion,
+				Endpoint:          "http://localhost:5000",
+				S3Endpoint:        "http://localhost:9000",
+				StaticCredentials: true,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clearEnv(t)
+			for key, value := range test.env {
+				t.Setenv(key, value)
+			}
+
+			s, err := FromEnv()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(s, test.want) {
+				t.Errorf("unexpected settings: %+v", s)
+			}
+		})
+	}
+}
+
+func TestFromEnv_UnsupportedTarget(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("AWS_TARGET", "azure")
+
+	if _, err := FromEnv(); err == nil {
+		t.Errorf("expected error for unsupported target")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	cfg, err := Load(context.Background(), Settings{
+		Target:            TargetCustom,
+		Endpoint:          "http://localhost:5000",
+		S3Endpoint:        "http://localhost:9000",
+		StaticCredentials: true,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Region != DefaultRegion {
+		t.Errorf("unexpected region: %s", cfg.Region)
+	}
+
+	for service, want := range map[string]string{
+		s3.ServiceID:       "http://localhost:9000",
+		dynamodb.ServiceID: "http://localhost:5000",
+	} {
+		endpoint, err := cfg.EndpointResolverWithOptions.ResolveEndpoint(service, cfg.Region)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if endpoint.URL != want {
+			t.Errorf("unexpected endpoint of %s: %s", service, endpoint.URL)
+		}
+	}
+
+	creds, err := cfg.Credentials.Retrieve(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds.AccessKeyID != "test" {
+		t.Errorf("unexpected access key: %s", creds.AccessKeyID)
+	}
+}
+
+func TestLoad_MissingEndpoint(t *testing.T) {
+	if _, err := Load(context.Background(), Settings{Target: TargetCustom}); err == nil {
+		t.Errorf("expected error for missing endpoint")
+	}
+}
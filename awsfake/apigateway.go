@@ -0,0 +1,331 @@
+package awsfake
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewayv2"
+	"github.com/aws/aws-sdk-go-v2/service/apigatewayv2/types"
+)
+
+// APIGateway is an in-memory fake of the API Gateway V2 API, which stores its APIs with
+// their integrations, routes and deployments. It does not serve the APIs. Every list is
+// returned in a single page.
+type APIGateway struct {
+	mu   sync.Mutex
+	apis map[string]*api
+	id   int
+}
+
+// api is an API Gateway API and its resources by their IDs.
+type api struct {
+	api          types.Api
+	integrations map[string]types.Integration
+	routes       map[string]types.Route
+	deployments  map[string]time.Time
+}
+
+// NewAPIGateway returns a fake of the API Gateway V2 API without APIs.
+func NewAPIGateway() *APIGateway {
+	return &APIGateway{apis: map[string]*api{}}
+}
+
+// Routes returns the targets of the routes of the API with the given ID by their route
+// key.
+func (f *APIGateway) Routes(id string) map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	a, ok := f.apis[id]
+	if !ok {
+		return nil
+	}
+
+	routes := map[string]string{}
+	for _, route := range a.routes {
+		routes[aws.ToString(route.RouteKey)] = aws.ToString(route.Target)
+	}
+	return routes
+}
+
+// nextID returns a new ID for a resource, which is unique among all resources of the fake.
+func (f *APIGateway) nextID() string {
+	f.id++
+	return fmt.Sprintf("%010x", f.id)
+}
+
+func (f *APIGateway) CreateApi(_ context.Context, params *apigatewayv2.CreateApiInput, _ ...func(*apigatewayv2.Options)) (*apigatewayv2.CreateApiOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if aws.ToString(params.Name) == "" || params.ProtocolType == "" {
+		return nil, apiError("BadRequestException", "an api needs a name and a protocol type")
+	}
+
+	id := f.nextID()
+	scheme := "https"
+	if params.ProtocolType == types.ProtocolTypeWebsocket {
+		scheme = "wss"
+	}
+	a := types.Api{
+		ApiId:                    aws.String(id),
+		Name:                     params.Name,
+		ProtocolType:             params.ProtocolType,
+		RouteSelectionExpression: params.RouteSelectionExpression,
+		ApiEndpoint:              aws.String(fmt.Sprintf("%s://%s.execute-api.%s.amazonaws.com", scheme, id, region)),
+		CreatedDate:              aws.Time(time.Now()),
+	}
+	f.apis[id] = &api{
+		api:          a,
+		integrations: map[string]types.Integration{},
+		routes:       map[string]types.Route{},
+		deployments:  map[string]time.Time{},
+	}
+
+	return &apigatewayv2.CreateApiOutput{
+		ApiId:                    a.ApiId,
+		Name:                     a.Name,
+		ProtocolType:             a.ProtocolType,
+		RouteSelectionExpression: a.RouteSelectionExpression,
+		ApiEndpoint:              a.ApiEndpoint,
+	}, nil
+}
+
+func (f *APIGateway) DeleteApi(_ context.Context, params *apigatewayv2.DeleteApiInput, _ ...func(*apigatewayv2.Options)) (*apigatewayv2.DeleteApiOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, err := f.api(aws.ToString(params.ApiId)); err != nil {
+		return nil, err
+	}
+
+	delete(f.apis, aws.ToString(params.ApiId))
+	return &apigatewayv2.DeleteApiOutput{}, nil
+}
+
+// GetApis returns the APIs in the order of their IDs.
+func (f *APIGateway) GetApis(_ context.Context, _ *apigatewayv2.GetApisInput, _ ...func(*apigatewayv2.Options)) (*apigatewayv2.GetApisOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	output := &apigatewayv2.GetApisOutput{}
+	for _, id := range sortedKeys(f.apis) {
+		output.Items = append(output.Items, f.apis[id].api)
+	}
+	return output, nil
+}
+
+// CreateDeployment deploys the API, which is deployed as soon as it is created.
+func (f *APIGateway) CreateDeployment(_ context.Context, params *apigatewayv2.CreateDeploymentInput, _ ...func(*apigatewayv2.Options)) (*apigatewayv2.CreateDeploymentOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	a, err := f.api(aws.ToString(params.ApiId))
+	if err != nil {
+		return nil, err
+	}
+
+	id := f.nextID()
+	a.deployments[id] = time.Now()
+	return &apigatewayv2.CreateDeploymentOutput{
+		DeploymentId:     aws.String(id),
+		DeploymentStatus: types.DeploymentStatusDeployed,
+		CreatedDate:      aws.Time(a.deployments[id]),
+	}, nil
+}
+
+func (f *APIGateway) GetDeployment(_ context.Context, params *apigatewayv2.GetDeploymentInput, _ ...func(*apigatewayv2.Options)) (*apigatewayv2.GetDeploymentOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	a, err := f.api(aws.ToString(params.ApiId))
+	if err != nil {
+		return nil, err
+	}
+	created, ok := a.deployments[aws.ToString(params.DeploymentId)]
+	if !ok {
+		return nil, apiError("NotFoundException", "deployment %s not found", aws.ToString(params.DeploymentId))
+	}
+
+	return &apigatewayv2.GetDeploymentOutput{
+		DeploymentId:     params.DeploymentId,
+		DeploymentStatus: types.DeploymentStatusDeployed,
+		CreatedDate:      aws.Time(created),
+	}, nil
+}
+
+func (f *APIGateway) CreateIntegration(_ context.Context, params *apigatewayv2.CreateIntegrationInput, _ ...func(*apigatewayv2.Options)) (*apigatewayv2.CreateIntegrationOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	a, err := f.api(aws.ToString(params.ApiId))
+	if err != nil {
+		return nil, err
+	}
+	if params.IntegrationType == "" {
+		return nil, apiError("BadRequestException", "an integration needs a type")
+	}
+
+	integration := types.Integration{
+		IntegrationId:     aws.String(f.nextID()),
+		IntegrationType:   params.IntegrationType,
+		IntegrationMethod: params.IntegrationMethod,
+		IntegrationUri:    params.IntegrationUri,
+		RequestParameters: params.RequestParameters,
+	}
+	a.integrations[*integration.IntegrationId] = integration
+
+	return &apigatewayv2.CreateIntegrationOutput{
+		IntegrationId:     integration.IntegrationId,
+		IntegrationType:   integration.IntegrationType,
+		IntegrationMethod: integration.IntegrationMethod,
+		IntegrationUri:    integration.IntegrationUri,
+		RequestParameters: integration.RequestParameters,
+	}, nil
+}
+
+// GetIntegrations returns the integrations of the API in the order of their IDs.
+func (f *APIGateway) GetIntegrations(_ context.Context, params *apigatewayv2.GetIntegrationsInput, _ ...func(*apigatewayv2.Options)) (*apigatewayv2.GetIntegrationsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	a, err := f.api(aws.ToString(params.ApiId))
+	if err != nil {
+		return nil, err
+	}
+
+	output := &apigatewayv2.GetIntegrationsOutput{}
+	for _, id := range sortedKeys(a.integrations) {
+		output.Items = append(output.Items, a.integrations[id])
+	}
+	return output, nil
+}
+
+// UpdateIntegration updates the given fields of the integration and keeps the others.
+func (f *APIGateway) UpdateIntegration(_ context.Context, params *apigatewayv2.UpdateIntegrationInput, _ ...func(*apigatewayv2.Options)) (*apigatewayv2.UpdateIntegrationOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	a, err := f.api(aws.ToString(params.ApiId))
+	if err != nil {
+		return nil, err
+	}
+	integration, ok := a.integrations[aws.ToString(params.IntegrationId)]
+	if !ok {
+		return nil, apiError("NotFoundException", "integration %s not found", aws.ToString(params.IntegrationId))
+	}
+
+	if params.IntegrationType != "" {
+		integration.IntegrationType = params.IntegrationType
+	}
+	if params.IntegrationMethod != nil {
+		integration.IntegrationMethod = params.IntegrationMethod
+	}
+	if params.IntegrationUri != nil {
+		integration.IntegrationUri = params.IntegrationUri
+	}
+	if params.RequestParameters != nil {
+		integration.RequestParameters = params.RequestParameters
+	}
+	a.integrations[aws.ToString(params.IntegrationId)] = integration
+
+	return &apigatewayv2.UpdateIntegrationOutput{
+		IntegrationId:     integration.IntegrationId,
+		IntegrationType:   integration.IntegrationType,
+		IntegrationMethod: integration.IntegrationMethod,
+		IntegrationUri:    integration.IntegrationUri,
+		RequestParameters: integration.RequestParameters,
+	}, nil
+}
+
+func (f *APIGateway) CreateRoute(_ context.Context, params *apigatewayv2.CreateRouteInput, _ ...func(*apigatewayv2.Options)) (*apigatewayv2.CreateRouteOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	a, err := f.api(aws.ToString(params.ApiId))
+	if err != nil {
+		return nil, err
+	}
+	for _, route := range a.routes {
+		if aws.ToString(route.RouteKey) == aws.ToString(params.RouteKey) {
+			return nil, operationError("CreateRoute", "ConflictException", "route %s already exists", aws.ToString(params.RouteKey))
+		}
+	}
+
+	route := types.Route{
+		RouteId:                          aws.String(f.nextID()),
+		RouteKey:                         params.RouteKey,
+		Target:                           params.Target,
+		RouteResponseSelectionExpression: params.RouteResponseSelectionExpression,
+	}
+	a.routes[*route.RouteId] = route
+
+	return &apigatewayv2.CreateRouteOutput{
+		RouteId:                          route.RouteId,
+		RouteKey:                         route.RouteKey,
+		Target:                           route.Target,
+		RouteResponseSelectionExpression: route.RouteResponseSelectionExpression,
+	}, nil
+}
+
+// GetRoutes returns the routes of the API in the order of their IDs.
+func (f *APIGateway) GetRoutes(_ context.Context, params *apigatewayv2.GetRoutesInput, _ ...func(*apigatewayv2.Options)) (*apigatewayv2.GetRoutesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	a, err := f.api(aws.ToString(params.ApiId))
+	if err != nil {
+		return nil, err
+	}
+
+	output := &apigatewayv2.GetRoutesOutput{}
+	for _, id := range sortedKeys(a.routes) {
+		output.Items = append(output.Items, a.routes[id])
+	}
+	return output, nil
+}
+
+// UpdateRoute updates the given fields of the route and keeps the others.
+func (f *APIGateway) UpdateRoute(_ context.Context, params *apigatewayv2.UpdateRouteInput, _ ...func(*apigatewayv2.Options)) (*apigatewayv2.UpdateRouteOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	a, err := f.api(aws.ToString(params.ApiId))
+	if err != nil {
+		return nil, err
+	}
+	route, ok := a.routes[aws.ToString(params.RouteId)]
+	if !ok {
+		return nil, apiError("NotFoundException", "route %s not found", aws.ToString(params.RouteId))
+	}
+
+	if params.RouteKey != nil {
+		route.RouteKey = params.RouteKey
+	}
+	if params.Target != nil {
+		route.Target = params.Target
+	}
+	if params.RouteResponseSelectionExpression != nil {
+		route.RouteResponseSelectionExpression = params.RouteResponseSelectionExpression
+	}
+	a.routes[aws.ToString(params.RouteId)] = route
+
+	return &apigatewayv2.UpdateRouteOutput{
+		RouteId:                          route.RouteId,
+		RouteKey:                         route.RouteKey,
+		Target:                           route.Target,
+		RouteResponseSelectionExpression: route.RouteResponseSelectionExpression,
+	}, nil
+}
+
+// api returns the API with the given ID.
+func (f *APIGateway) api(id string) (*api, error) {
+	a, ok := f.apis[id]
+	if !ok {
+		return nil, apiError("NotFoundException", "invalid api identifier specified %s", id)
+	}
+	return a, nil
+}
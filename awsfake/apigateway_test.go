@@ -0,0 +1,59 @@
+package awsfake
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+)
+
+func TestAPIGateway(t *testing.T) {
+	ctx := context.Background()
+	fake := NewAPIGateway()
+	client := awsService.NewAPIGatewayFromClient(fake)
+	options := awsService.EndpointOptions{Path: "/speed", Method: "GET", Uri: "arn:aws:lambda:us-east-1:000000000000:function:getter"}
+
+	if _, err := client.GetApiId(ctx, "test-api"); !errors.Is(err, awsService.ErrNotFound) {
+		t.Errorf("expected a missing api, got %v", err)
+	}
+	id, err := client.EnsureHTTPApi(ctx, "test-api")
+	if err != nil {
+		t.Fatalf("ensuring the api: %v", err)
+	}
+	if again, err := client.EnsureHTTPApi(ctx, "test-api"); err != nil || again != id {
+		t.Errorf("expected the existing api %s, got %s, %v", id, again, err)
+	}
+	if _, err := client.EnsureWebSocketApi(ctx, "test-api"); !errors.Is(err, awsService.ErrAlreadyExists) {
+		t.Errorf("expected a protocol mismatch, got %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := client.EnsureEndpoint(ctx, id, options); err != nil {
+			t.Fatalf("ensuring the endpoint: %v", err)
+		}
+	}
+	if action, err := client.PlanEndpoint(ctx, id, options); err != nil || action != awsService.ActionNone {
+		t.Errorf("expected no changes, got %q, %v", action, err)
+	}
+	routes := fake.Routes(id)
+	if len(routes) != 1 || routes["GET /speed"] == "" {
+		t.Errorf("unexpected routes %v", routes)
+	}
+
+	deployment, err := client.Deploy(ctx, id, "test")
+	if err != nil {
+		t.Fatalf("deploying the api: %v", err)
+	}
+	if err := client.WaitUntilDeployed(ctx, id, deployment, 0); err != nil {
+		t.Errorf("waiting for the deployment: %v", err)
+	}
+
+	if err := client.Delete(ctx, id); err != nil {
+		t.Fatalf("deleting the api: %v", err)
+	}
+	if routes := fake.Routes(id); !reflect.DeepEqual(routes, map[string]string(nil)) {
+		t.Errorf("expected no routes of the deleted api, got %v", routes)
+	}
+}
@@ -0,0 +1,48 @@
+// Package awsfake provides in-memory fakes of the AWS APIs that the wrappers of the `aws`
+// package use. Unlike the canned mocks of the wrapper tests, the fakes keep state: objects
+// put into a bucket can be listed, records put into a stream can be read, items put into a
+// table can be queried and the Data API executes SQL on an embedded SQLite database. The
+// fakes are safe for concurrent use and return the error codes of the real APIs, which
+// are classified like the errors of the real clients. The wrappers use the fakes through
+// their `FromClient` constructors:
+//
+//	s3 := awsService.NewS3FromClient(awsfake.NewS3())
+//
+// The fakes implement the calls the wrappers make and the calls tests need to inspect the
+// state, not the whole APIs.
+package awsfake
+
+import (
+	"fmt"
+
+	"github.com/aws/smithy-go"
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+)
+
+// Account and region of the ARNs of the fake resources.
+const (
+	account = "000000000000"
+	region  = "us-east-1"
+)
+
+// apiError returns an API error with the given code and message, which is classified by
+// its kind.
+func apiError(code, format string, args ...any) error {
+	return operationError("", code, format, args...)
+}
+
+// operationError returns an API error of the operation with the given name, which is
+// classified by its kind like the errors of the operation of a wrapped client, e.g. a
+// conflict of a created resource as an existing resource.
+func operationError(operation, code, format string, args ...any) error {
+	return awsService.ClassifyOperation(operation, &smithy.GenericAPIError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+		Fault:   smithy.FaultClient,
+	})
+}
+
+// arn returns the ARN of the resource of the given service.
+func arn(service, resource string) string {
+	return fmt.Sprintf("arn:aws:%s:%s:%s:%s", service, region, account, resource)
+}
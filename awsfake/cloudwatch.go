@@ -0,0 +1,77 @@
+package awsfake
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+// CloudWatch is an in-memory fake of the CloudWatch API, which stores its alarms and the
+// metric data that is put. It does not evaluate the alarms.
+type CloudWatch struct {
+	mu      sync.Mutex
+	alarms  map[string]*cloudwatch.PutMetricAlarmInput
+	metrics map[string][]types.MetricDatum
+}
+
+// NewCloudWatch returns a fake of the CloudWatch API without alarms and metric data.
+func NewCloudWatch() *CloudWatch {
+	return &CloudWatch{
+		alarms:  map[string]*cloudwatch.PutMetricAlarmInput{},
+		metrics: map[string][]types.MetricDatum{},
+	}
+}
+
+// Alarm returns the input of the alarm with the given name and whether it exists.
+func (f *CloudWatch) Alarm(name string) (*cloudwatch.PutMetricAlarmInput, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	alarm, ok := f.alarms[name]
+	return alarm, ok
+}
+
+// MetricData returns the metric data that was put into the given namespace in the order
+// it was put.
+func (f *CloudWatch) MetricData(namespace string) []types.MetricDatum {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]types.MetricDatum(nil), f.metrics[namespace]...)
+}
+
+// PutMetricAlarm creates the alarm or replaces the alarm with the same name.
+func (f *CloudWatch) PutMetricAlarm(_ context.Context, params *cloudwatch.PutMetricAlarmInput, _ ...func(*cloudwatch.Options)) (*cloudwatch.PutMetricAlarmOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if aws.ToString(params.AlarmName) == "" {
+		return nil, apiError("ValidationError", "the alarm name is empty")
+	}
+
+	copied := *params
+	f.alarms[aws.ToString(params.AlarmName)] = &copied
+	return &cloudwatch.PutMetricAlarmOutput{}, nil
+}
+
+func (f *CloudWatch) PutMetricData(_ context.Context, params *cloudwatch.PutMetricDataInput, _ ...func(*cloudwatch.Options)) (*cloudwatch.PutMetricDataOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	namespace := aws.ToString(params.Namespace)
+	if namespace == "" {
+		return nil, apiError("InvalidParameterValue", "the namespace is empty")
+	}
+
+	for _, datum := range params.MetricData {
+		if datum.Timestamp == nil {
+			datum.Timestamp = aws.Time(time.Now())
+		}
+		f.metrics[namespace] = append(f.metrics[namespace], datum)
+	}
+	return &cloudwatch.PutMetricDataOutput{}, nil
+}
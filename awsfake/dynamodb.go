@@ -0,0 +1,398 @@
+package awsfake
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// DynamoDB is an in-memory fake of the DynamoDB API, which stores the items of its tables
+// by their primary key. Its ExecuteStatement supports the PartiQL statements of the form
+// `SELECT * FROM <table>` with an optional `WHERE <attribute>=?`.
+type DynamoDB struct {
+	mu     sync.Mutex
+	tables map[string]*table
+}
+
+// table is a DynamoDB table and its items.
+type table struct {
+	description types.TableDescription
+	keys        []string
+	items       map[string]map[string]types.AttributeValue
+	tags        map[string]string
+}
+
+// NewDynamoDB returns a fake of the DynamoDB API without tables.
+func NewDynamoDB() *DynamoDB {
+	return &DynamoDB{tables: map[string]*table{}}
+}
+
+// Items returns the items of the table with the given name in the order of their keys.
+func (f *DynamoDB) Items(name string) []map[string]types.AttributeValue {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t, ok := f.tables[name]
+	if !ok {
+		return nil
+	}
+
+	var items []map[string]types.AttributeValue
+	for _, key := range sortedKeys(t.items) {
+		items = append(items, t.items[key])
+	}
+	return items
+}
+
+// Tags returns the tags of the table with the given name.
+func (f *DynamoDB) Tags(name string) map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if t, ok := f.tables[name]; ok {
+		return t.tags
+	}
+	return nil
+}
+
+func (f *DynamoDB) CreateTable(_ context.Context, params *dynamodb.CreateTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.TableName)
+	if _, ok := f.tables[name]; ok {
+		return nil, operationError("CreateTable", "ResourceInUseException", "table %s already exists", name)
+	}
+	if len(params.KeySchema) == 0 {
+		return nil, apiError("ValidationException", "table %s has no key schema", name)
+	}
+
+	billingMode := params.BillingMode
+	if billingMode == "" {
+		billingMode = types.BillingModeProvisioned
+	}
+	t := &table{
+		description: types.TableDescription{
+			TableName:            aws.String(name),
+			TableArn:             aws.String(arn("dynamodb", "table/"+name)),
+			TableStatus:          types.TableStatusActive,
+			CreationDateTime:     aws.Time(time.Now()),
+			KeySchema:            params.KeySchema,
+			AttributeDefinitions: params.AttributeDefinitions,
+			BillingModeSummary:   &types.BillingModeSummary{BillingMode: billingMode},
+		},
+		items: map[string]map[string]types.AttributeValue{},
+	}
+	if billingMode == types.BillingModeProvisioned {
+		if params.ProvisionedThroughput == nil {
+			return nil, apiError("ValidationException", "table %s needs a provisioned throughput", name)
+		}
+		t.description.ProvisionedThroughput = throughput(params.ProvisionedThroughput)
+	}
+	for _, tag := range params.Tags {
+		if t.tags == nil {
+			t.tags = map[string]string{}
+		}
+		t.tags[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	for _, key := range params.KeySchema {
+		t.keys = append(t.keys, aws.ToString(key.AttributeName))
+	}
+
+	f.tables[name] = t
+	return &dynamodb.CreateTableOutput{TableDescription: t.describe()}, nil
+}
+
+func (f *DynamoDB) DeleteTable(_ context.Context, params *dynamodb.DeleteTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteTableOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t, err := f.table(aws.ToString(params.TableName))
+	if err != nil {
+		return nil, err
+	}
+
+	delete(f.tables, aws.ToString(params.TableName))
+	description := t.describe()
+	description.TableStatus = types.TableStatusDeleting
+	return &dynamodb.DeleteTableOutput{TableDescription: description}, nil
+}
+
+// UpdateTable updates the billing mode, the provisioned throughput and the replicas of the
+// table.
+func (f *DynamoDB) UpdateTable(_ context.Context, params *dynamodb.UpdateTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.UpdateTableOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t, err := f.table(aws.ToString(params.TableName))
+	if err != nil {
+		return nil, err
+	}
+
+	if params.BillingMode != "" {
+		t.description.BillingModeSummary = &types.BillingModeSummary{BillingMode: params.BillingMode}
+	}
+	switch {
+	case params.ProvisionedThroughput != nil:
+		t.description.ProvisionedThroughput = throughput(params.ProvisionedThroughput)
+	case params.BillingMode == types.BillingModePayPerRequest:
+		t.description.ProvisionedThroughput = nil
+	case params.BillingMode == types.BillingModeProvisioned:
+		return nil, apiError("ValidationException", "table %s needs a provisioned throughput", aws.ToString(params.TableName))
+	}
+	for _, update := range params.ReplicaUpdates {
+		switch {
+		case update.Create != nil:
+			t.description.Replicas = append(t.description.Replicas, types.ReplicaDescription{
+				RegionName:    update.Create.RegionName,
+				ReplicaStatus: types.ReplicaStatusActive,
+			})
+		case update.Delete != nil:
+			replicas := t.description.Replicas[:0]
+			for _, replica := range t.description.Replicas {
+				if aws.ToString(replica.RegionName) != aws.ToString(update.Delete.RegionName) {
+					replicas = append(replicas, replica)
+				}
+			}
+			t.description.Replicas = replicas
+		}
+	}
+
+	return &dynamodb.UpdateTableOutput{TableDescription: t.describe()}, nil
+}
+
+// DescribeTable describes the table, which is active as soon as it is created.
+func (f *DynamoDB) DescribeTable(_ context.Context, params *dynamodb.DescribeTableInput, _ ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t, err := f.table(aws.ToString(params.TableName))
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.DescribeTableOutput{Table: t.describe()}, nil
+}
+
+// PutItem puts the item into the table and replaces the item with the same key. Condition
+// expressions are checked against the replaced item, see condition for the supported
+// expressions.
+func (f *DynamoDB) PutItem(_ context.Context, params *dynamodb.PutItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t, err := f.table(aws.ToString(params.TableName))
+	if err != nil {
+		return nil, err
+	}
+	key, err := t.key(params.Item)
+	if err != nil {
+		return nil, err
+	}
+	if expression := aws.ToString(params.ConditionExpression); expression != "" {
+		ok, err := condition(expression, params.ExpressionAttributeNames, params.ExpressionAttributeValues, t.items[key])
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, apiError("ConditionalCheckFailedException", "the conditional request failed")
+		}
+	}
+
+	item := make(map[string]types.AttributeValue, len(params.Item))
+	for name, value := range params.Item {
+		item[name] = value
+	}
+	t.items[key] = item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *DynamoDB) GetItem(_ context.Context, params *dynamodb.GetItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t, err := f.table(aws.ToString(params.TableName))
+	if err != nil {
+		return nil, err
+	}
+	key, err := t.key(params.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dynamodb.GetItemOutput{Item: t.items[key]}, nil
+}
+
+func (f *DynamoDB) DeleteItem(_ context.Context, params *dynamodb.DeleteItemInput, _ ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	t, err := f.table(aws.ToString(params.TableName))
+	if err != nil {
+		return nil, err
+	}
+	key, err := t.key(params.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(t.items, key)
+	return &dynamodb.DeleteItemOutput{}, nil
+}
+
+// selectStatement matches the supported PartiQL statements.
+var selectStatement = regexp.MustCompile(`(?i)^\s*SELECT\s+\*\s+FROM\s+"?([\w.-]+)"?(?:\s+WHERE\s+"?(\w+)"?\s*=\s*\?)?\s*$`)
+
+func (f *DynamoDB) ExecuteStatement(_ context.Context, params *dynamodb.ExecuteStatementInput, _ ...func(*dynamodb.Options)) (*dynamodb.ExecuteStatementOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	match := selectStatement.FindStringSubmatch(aws.ToString(params.Statement))
+	if match == nil {
+		return nil, apiError("ValidationException", "unsupported statement %s", aws.ToString(params.Statement))
+	}
+	want := 0
+	if match[2] != "" {
+		want = 1
+	}
+	if len(params.Parameters) != want {
+		return nil, apiError("ValidationException", "statement expects %d parameters, got %d", want, len(params.Parameters))
+	}
+	t, err := f.table(match[1])
+	if err != nil {
+		return nil, err
+	}
+
+	output := &dynamodb.ExecuteStatementOutput{}
+	for _, key := range sortedKeys(t.items) {
+		item := t.items[key]
+		if match[2] != "" {
+			value, ok := item[match[2]]
+			if !ok || !equal(value, params.Parameters[0]) {
+				continue
+			}
+		}
+		output.Items = append(output.Items, item)
+	}
+	return output, nil
+}
+
+// conditionOr, conditionAnd and conditionTerm split the supported condition expressions.
+var (
+	conditionOr   = regexp.MustCompile(`(?i)\s+OR\s+`)
+	conditionAnd  = regexp.MustCompile(`(?i)\s+AND\s+`)
+	conditionTerm = regexp.MustCompile(`^(?:(attribute_exists|attribute_not_exists)\(\s*([#\w]+)\s*\)|([#\w]+)\s*(=|<>)\s*(:\w+))$`)
+)
+
+// condition reports whether the given item, which is nil if it does not exist, meets the
+// condition expression. The expression joins `attribute_exists(a)`,
+// `attribute_not_exists(a)`, `a = :v` and `a <> :v` terms with AND and OR, without
+// parentheses.
+func condition(expression string, names map[string]string, values map[string]types.AttributeValue, item map[string]types.AttributeValue) (bool, error) {
+	name := func(path string) string {
+		if strings.HasPrefix(path, "#") {
+			return names[path]
+		}
+		return path
+	}
+
+	for _, disjunct := range conditionOr.Split(strings.TrimSpace(expression), -1) {
+		holds := true
+		for _, term := range conditionAnd.Split(disjunct, -1) {
+			match := conditionTerm.FindStringSubmatch(strings.TrimSpace(term))
+			if match == nil {
+				return false, apiError("ValidationException", "unsupported condition %s", term)
+			}
+
+			switch {
+			case match[1] != "":
+				_, exists := item[name(match[2])]
+				holds = holds && exists == (match[1] == "attribute_exists")
+			default:
+				value, ok := values[match[5]]
+				if !ok {
+					return false, apiError("ValidationException", "missing the value %s", match[5])
+				}
+				actual, exists := item[name(match[3])]
+				equals := exists && equal(actual, value)
+				holds = holds && equals == (match[4] == "=")
+			}
+		}
+		if holds {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// table returns the table with the given name.
+func (f *DynamoDB) table(name string) (*table, error) {
+	t, ok := f.tables[name]
+	if !ok {
+		return nil, apiError("ResourceNotFoundException", "requested resource not found: table %s", name)
+	}
+	return t, nil
+}
+
+// throughput returns the description of the given provisioned throughput.
+func throughput(p *types.ProvisionedThroughput) *types.ProvisionedThroughputDescription {
+	return &types.ProvisionedThroughputDescription{
+		ReadCapacityUnits:  p.ReadCapacityUnits,
+		WriteCapacityUnits: p.WriteCapacityUnits,
+	}
+}
+
+// describe returns the description of the table.
+func (t *table) describe() *types.TableDescription {
+	description := t.description
+	description.ItemCount = aws.Int64(int64(len(t.items)))
+	return &description
+}
+
+// key returns the primary key of the given item, which joins the values of its key
+// attributes.
+func (t *table) key(item map[string]types.AttributeValue) (string, error) {
+	var parts []string
+	for _, name := range t.keys {
+		value, ok := item[name]
+		if !ok {
+			return "", apiError("ValidationException", "missing the key %s in the item", name)
+		}
+		s, ok := scalar(value)
+		if !ok {
+			return "", apiError("ValidationException", "the key %s must be a string, number or binary", name)
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, "\x00"), nil
+}
+
+// scalar returns the given string, number or binary value as a string.
+func scalar(value types.AttributeValue) (string, bool) {
+	switch v := value.(type) {
+	case *types.AttributeValueMemberS:
+		return "S" + v.Value, true
+	case *types.AttributeValueMemberN:
+		return "N" + v.Value, true
+	case *types.AttributeValueMemberB:
+		return fmt.Sprintf("B%x", v.Value), true
+	}
+	return "", false
+}
+
+// equal reports whether the given scalar values are equal.
+func equal(a, b types.AttributeValue) bool {
+	sa, ok := scalar(a)
+	if !ok {
+		return false
+	}
+	sb, ok := scalar(b)
+	return ok && sa == sb
+}
@@ -0,0 +1,134 @@
+package awsfake
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+)
+
+func TestDynamoDB(t *testing.T) {
+	ctx := context.Background()
+	fake := NewDynamoDB()
+	client := awsService.NewDynamoDBFromClient(fake)
+
+	if _, err := client.GetItemById(ctx, "test-table", "1"); !errors.Is(err, awsService.ErrTableNotFound) || errors.Is(err, awsService.ErrNotFound) {
+		t.Errorf("expected a missing table, got %v", err)
+	}
+	if err := client.EnsureTable(ctx, "test-table"); err != nil {
+		t.Fatalf("ensuring the table: %v", err)
+	}
+	if drift, err := client.DriftTable(ctx, "test-table"); err != nil || drift.Action != awsService.ActionNone {
+		t.Errorf("expected no drift, got %+v, %v", drift, err)
+	}
+
+	for _, id := range []string{"1", "2"} {
+		item := map[string]types.AttributeValue{
+			"id":    &types.AttributeValueMemberS{Value: id},
+			"speed": &types.AttributeValueMemberN{Value: "12.5"},
+		}
+		if err := client.PutItem(ctx, "test-table", item); err != nil {
+			t.Fatalf("putting item %s: %v", id, err)
+		}
+	}
+
+	item, err := client.GetItemById(ctx, "test-table", "2")
+	if err != nil {
+		t.Fatalf("getting the item: %v", err)
+	}
+	if id, ok := item["id"].(*types.AttributeValueMemberS); !ok || id.Value != "2" {
+		t.Errorf("unexpected item %v", item)
+	}
+
+	if err := client.DeleteItem(ctx, "test-table", map[string]types.AttributeValue{"id": &types.AttributeValueMemberS{Value: "2"}}); err != nil {
+		t.Fatalf("deleting the item: %v", err)
+	}
+	if item, err := client.GetItemById(ctx, "test-table", "2"); item != nil || !errors.Is(err, awsService.ErrNotFound) {
+		t.Errorf("expected a deleted item, got %v, %v", item, err)
+	}
+	if items := fake.Items("test-table"); len(items) != 1 {
+		t.Errorf("expected 1 item, got %d", len(items))
+	}
+
+	if err := client.PutItem(ctx, "test-table", map[string]types.AttributeValue{"speed": &types.AttributeValueMemberN{Value: "1"}}); !errors.Is(err, awsService.ErrValidation) {
+		t.Errorf("expected an item without a key to be invalid, got %v", err)
+	}
+}
+
+func TestDynamoDB_ProvisionedThroughput(t *testing.T) {
+	ctx := context.Background()
+	fake := NewDynamoDB()
+	client := awsService.NewDynamoDBFromClient(fake)
+
+	tags := awsService.WithTags(map[string]string{"team": "data"})
+	if err := client.EnsureTable(ctx, "test-table", awsService.WithProvisionedThroughput(5, 5), tags); err != nil {
+		t.Fatalf("ensuring the table: %v", err)
+	}
+	if fake.Tags("test-table")["team"] != "data" {
+		t.Errorf("expected the table to be tagged, got %v", fake.Tags("test-table"))
+	}
+
+	scaled := awsService.WithProvisionedThroughput(5, 10)
+	if drift, err := client.DriftTable(ctx, "test-table", scaled); err != nil || drift.Action != awsService.ActionUpdate {
+		t.Errorf("expected the throughput to drift, got %+v, %v", drift, err)
+	}
+	if err := client.EnsureTable(ctx, "test-table", scaled); err != nil {
+		t.Fatalf("updating the table: %v", err)
+	}
+	if drift, err := client.DriftTable(ctx, "test-table", scaled); err != nil || drift.Action != awsService.ActionNone {
+		t.Errorf("expected no drift, got %+v, %v", drift, err)
+	}
+
+	if err := client.EnsureTable(ctx, "test-table"); err != nil {
+		t.Fatalf("switching to pay per request: %v", err)
+	}
+	if drift, err := client.DriftTable(ctx, "test-table"); err != nil || drift.Action != awsService.ActionNone {
+		t.Errorf("expected no drift, got %+v, %v", drift, err)
+	}
+}
+
+func TestDynamoDB_ConditionExpression(t *testing.T) {
+	ctx := context.Background()
+	fake := NewDynamoDB()
+	if err := awsService.NewDynamoDBFromClient(fake).CreateTable(ctx, "test-table"); err != nil {
+		t.Fatal(err)
+	}
+	put := func(version, condition string) error {
+		_, err := fake.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String("test-table"),
+			Item: map[string]types.AttributeValue{
+				"id":      &types.AttributeValueMemberS{Value: "1"},
+				"version": &types.AttributeValueMemberN{Value: version},
+			},
+			ConditionExpression:       aws.String(condition),
+			ExpressionAttributeNames:  map[string]string{"#version": "version"},
+			ExpressionAttributeValues: map[string]types.AttributeValue{":version": &types.AttributeValueMemberN{Value: "1"}},
+		})
+		return err
+	}
+	failed := func(err error) bool {
+		var apiErr smithy.APIError
+		return errors.As(err, &apiErr) && apiErr.ErrorCode() == "ConditionalCheckFailedException"
+	}
+
+	if err := put("1", "attribute_not_exists(id)"); err != nil {
+		t.Fatalf("creating the item: %v", err)
+	}
+	if err := put("1", "attribute_not_exists(id)"); !failed(err) {
+		t.Errorf("expected the existing item to fail the condition, got %v", err)
+	}
+	if err := put("2", "attribute_not_exists(id) OR #version = :version"); err != nil {
+		t.Errorf("expected the version to meet the condition, got %v", err)
+	}
+	if err := put("3", "attribute_exists(id) AND #version = :version"); !failed(err) {
+		t.Errorf("expected the changed version to fail the condition, got %v", err)
+	}
+	if err := put("3", "version (1)"); !errors.Is(err, awsService.ErrValidation) {
+		t.Errorf("expected an unsupported condition to be invalid, got %v", err)
+	}
+}
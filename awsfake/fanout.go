@@ -0,0 +1,235 @@
+package awsfake
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+)
+
+// RegisterStreamConsumer registers a consumer of the stream, which is active at once.
+func (f *Kinesis) RegisterStreamConsumer(_ context.Context, params *kinesis.RegisterStreamConsumerInput, _ ...func(*kinesis.Options)) (*kinesis.RegisterStreamConsumerOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, err := f.streamByARN(aws.ToString(params.StreamARN))
+	if err != nil {
+		return nil, err
+	}
+	name := aws.ToString(params.ConsumerName)
+	if name == "" {
+		return nil, apiError("ValidationException", "the consumer name is empty")
+	}
+	if _, ok := s.consumers[name]; ok {
+		return nil, operationError("RegisterStreamConsumer", "ResourceInUseException", "consumer %s of stream %s already exists", name, s.arn)
+	}
+
+	now := time.Now()
+	consumer := &types.ConsumerDescription{
+		ConsumerName:              aws.String(name),
+		ConsumerARN:               aws.String(s.arn + "/consumer/" + name + ":" + now.Format("20060102150405")),
+		ConsumerStatus:            types.ConsumerStatusActive,
+		ConsumerCreationTimestamp: aws.Time(now),
+		StreamARN:                 aws.String(s.arn),
+	}
+	s.consumers[name] = consumer
+	return &kinesis.RegisterStreamConsumerOutput{
+		Consumer: &types.Consumer{
+			ConsumerName:              consumer.ConsumerName,
+			ConsumerARN:               consumer.ConsumerARN,
+			ConsumerStatus:            consumer.ConsumerStatus,
+			ConsumerCreationTimestamp: consumer.ConsumerCreationTimestamp,
+		},
+	}, nil
+}
+
+// DeregisterStreamConsumer deregisters the consumer, which is given by its ARN or by the
+// ARN of its stream and its name. The subscriptions of the consumer end.
+func (f *Kinesis) DeregisterStreamConsumer(_ context.Context, params *kinesis.DeregisterStreamConsumerInput, _ ...func(*kinesis.Options)) (*kinesis.DeregisterStreamConsumerOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, consumer, err := f.consumer(aws.ToString(params.ConsumerARN), aws.ToString(params.StreamARN), aws.ToString(params.ConsumerName))
+	if err != nil {
+		return nil, err
+	}
+
+	delete(s.consumers, aws.ToString(consumer.ConsumerName))
+	return &kinesis.DeregisterStreamConsumerOutput{}, nil
+}
+
+// DescribeStreamConsumer describes the consumer, which is given by its ARN or by the ARN
+// of its stream and its name.
+func (f *Kinesis) DescribeStreamConsumer(_ context.Context, params *kinesis.DescribeStreamConsumerInput, _ ...func(*kinesis.Options)) (*kinesis.DescribeStreamConsumerOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	_, consumer, err := f.consumer(aws.ToString(params.ConsumerARN), aws.ToString(params.StreamARN), aws.ToString(params.ConsumerName))
+	if err != nil {
+		return nil, err
+	}
+
+	description := *consumer
+	return &kinesis.DescribeStreamConsumerOutput{ConsumerDescription: &description}, nil
+}
+
+// SubscribeToShardEvents subscribes the consumer to the shard. Like in Kinesis, a consumer
+// has at most one subscription to a shard at a time, which ends after five minutes. The
+// subscription sends an event as soon as the shard has new records, and its last event
+// of a closed shard has the child shards and no continuation sequence number.
+func (f *Kinesis) SubscribeToShardEvents(ctx context.Context, params *kinesis.SubscribeToShardInput, _ ...func(*kinesis.Options)) (*kinesis.SubscribeToShardEventStream, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	consumerARN := aws.ToString(params.ConsumerARN)
+	s, _, err := f.consumer(consumerARN, "", "")
+	if err != nil {
+		return nil, err
+	}
+	var sh *shard
+	for _, candidate := range s.shards {
+		if candidate.id == aws.ToString(params.ShardId) {
+			sh = candidate
+		}
+	}
+	if sh == nil {
+		return nil, apiError("ResourceNotFoundException", "shard %s of stream %s not found", aws.ToString(params.ShardId), s.arn)
+	}
+	if params.StartingPosition == nil {
+		return nil, apiError("ValidationException", "the starting position is missing")
+	}
+	position, err := sh.position(params.StartingPosition.Type, aws.ToString(params.StartingPosition.SequenceNumber))
+	if err != nil {
+		return nil, err
+	}
+	key := consumerARN + "/" + sh.id
+	if f.subscriptions[key] {
+		return nil, apiError("ResourceInUseException", "consumer %s is already subscribed to shard %s", consumerARN, sh.id)
+	}
+	f.subscriptions[key] = true
+
+	sub := &subscription{
+		events: make(chan types.SubscribeToShardEventStream),
+		done:   make(chan struct{}),
+	}
+	go func() {
+		defer func() {
+			f.mu.Lock()
+			delete(f.subscriptions, key)
+			f.mu.Unlock()
+			close(sub.events)
+		}()
+		f.push(ctx, sub, consumerARN, s, sh, position)
+	}()
+
+	return kinesis.NewSubscribeToShardEventStream(func(stream *kinesis.SubscribeToShardEventStream) {
+		stream.Reader = sub
+	}), nil
+}
+
+// push sends the records of the shard from the given position to the subscription until
+// the shard ends, the subscription ends or the consumer is deregistered.
+func (f *Kinesis) push(ctx context.Context, sub *subscription, consumerARN string, s *stream, sh *shard, position int) {
+	end := time.After(subscriptionDuration)
+	for {
+		f.mu.Lock()
+		if _, _, err := f.consumer(consumerARN, "", ""); err != nil {
+			f.mu.Unlock()
+			return
+		}
+		event := types.SubscribeToShardEvent{
+			Records:            append([]types.Record{}, sh.records[position:]...),
+			MillisBehindLatest: aws.Int64(0),
+		}
+		position = len(sh.records)
+		ended := sh.closed
+		if ended {
+			event.ChildShards = s.children(sh)
+		} else if len(event.Records) > 0 {
+			event.ContinuationSequenceNumber = event.Records[len(event.Records)-1].SequenceNumber
+		}
+		f.mu.Unlock()
+
+		if len(event.Records) > 0 || ended {
+			select {
+			case sub.events <- &types.SubscribeToShardEventStreamMemberSubscribeToShardEvent{Value: event}:
+			case <-sub.done:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+		if ended {
+			return
+		}
+
+		select {
+		case <-sub.done:
+			return
+		case <-ctx.Done():
+			return
+		case <-end:
+			return
+		case <-time.After(subscriptionInterval):
+		}
+	}
+}
+
+// subscription is the reader of the events of a subscription to a shard.
+type subscription struct {
+	events chan types.SubscribeToShardEventStream
+	done   chan struct{}
+	once   sync.Once
+}
+
+func (s *subscription) Events() <-chan types.SubscribeToShardEventStream {
+	return s.events
+}
+
+// Close ends the subscription.
+func (s *subscription) Close() error {
+	s.once.Do(func() { close(s.done) })
+	return nil
+}
+
+func (s *subscription) Err() error {
+	return nil
+}
+
+// consumer returns the consumer with the given ARN, or with the given name of the stream
+// with the given ARN, and its stream.
+func (f *Kinesis) consumer(consumerARN, streamARN, name string) (*stream, *types.ConsumerDescription, error) {
+	if consumerARN != "" {
+		for _, s := range f.streams {
+			for _, consumer := range s.consumers {
+				if aws.ToString(consumer.ConsumerARN) == consumerARN {
+					return s, consumer, nil
+				}
+			}
+		}
+		return nil, nil, apiError("ResourceNotFoundException", "consumer %s not found", consumerARN)
+	}
+
+	s, err := f.streamByARN(streamARN)
+	if err != nil {
+		return nil, nil, err
+	}
+	consumer, ok := s.consumers[name]
+	if !ok {
+		return nil, nil, apiError("ResourceNotFoundException", "consumer %s of stream %s not found", name, streamARN)
+	}
+	return s, consumer, nil
+}
+
+// streamByARN returns the stream with the given ARN.
+func (f *Kinesis) streamByARN(streamARN string) (*stream, error) {
+	for _, s := range f.streams {
+		if s.arn == streamARN {
+			return s, nil
+		}
+	}
+	return nil, apiError("ResourceNotFoundException", "stream %s not found", streamARN)
+}
@@ -0,0 +1,113 @@
+package awsfake
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/glue"
+	"github.com/aws/aws-sdk-go-v2/service/glue/types"
+)
+
+// Glue is an in-memory fake of the Glue API, which stores the definitions of its jobs. It
+// does not run the jobs.
+type Glue struct {
+	mu   sync.Mutex
+	jobs map[string]*types.Job
+	tags map[string]map[string]string
+}
+
+// NewGlue returns a fake of the Glue API without jobs.
+func NewGlue() *Glue {
+	return &Glue{jobs: map[string]*types.Job{}, tags: map[string]map[string]string{}}
+}
+
+// Tags returns the tags of the job with the given name.
+func (f *Glue) Tags(name string) map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.tags[name]
+}
+
+func (f *Glue) CreateJob(_ context.Context, params *glue.CreateJobInput, _ ...func(*glue.Options)) (*glue.CreateJobOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.Name)
+	if _, ok := f.jobs[name]; ok {
+		return nil, apiError("AlreadyExistsException", "job %s already exists", name)
+	}
+	if params.Role == nil || params.Command == nil {
+		return nil, apiError("InvalidInputException", "job %s needs a role and a command", name)
+	}
+
+	now := time.Now()
+	f.jobs[name] = &types.Job{
+		Name:             aws.String(name),
+		Role:             params.Role,
+		Command:          params.Command,
+		CreatedOn:        aws.Time(now),
+		LastModifiedOn:   aws.Time(now),
+		DefaultArguments: params.DefaultArguments,
+		GlueVersion:      params.GlueVersion,
+		MaxCapacity:      params.MaxCapacity,
+	}
+	f.tags[name] = params.Tags
+	return &glue.CreateJobOutput{Name: aws.String(name)}, nil
+}
+
+func (f *Glue) GetJob(_ context.Context, params *glue.GetJobInput, _ ...func(*glue.Options)) (*glue.GetJobOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	job, err := f.job(aws.ToString(params.JobName))
+	if err != nil {
+		return nil, err
+	}
+
+	copied := *job
+	return &glue.GetJobOutput{Job: &copied}, nil
+}
+
+// UpdateJob replaces the definition of the job, like Glue does.
+func (f *Glue) UpdateJob(_ context.Context, params *glue.UpdateJobInput, _ ...func(*glue.Options)) (*glue.UpdateJobOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	job, err := f.job(aws.ToString(params.JobName))
+	if err != nil {
+		return nil, err
+	}
+	if params.JobUpdate == nil || params.JobUpdate.Role == nil || params.JobUpdate.Command == nil {
+		return nil, apiError("InvalidInputException", "job %s needs a role and a command", aws.ToString(params.JobName))
+	}
+
+	job.Role = params.JobUpdate.Role
+	job.Command = params.JobUpdate.Command
+	job.DefaultArguments = params.JobUpdate.DefaultArguments
+	job.GlueVersion = params.JobUpdate.GlueVersion
+	job.MaxCapacity = params.JobUpdate.MaxCapacity
+	job.LastModifiedOn = aws.Time(time.Now())
+	return &glue.UpdateJobOutput{JobName: params.JobName}, nil
+}
+
+// DeleteJob deletes the job. Deleting a missing job succeeds, like in Glue.
+func (f *Glue) DeleteJob(_ context.Context, params *glue.DeleteJobInput, _ ...func(*glue.Options)) (*glue.DeleteJobOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.jobs, aws.ToString(params.JobName))
+	delete(f.tags, aws.ToString(params.JobName))
+	return &glue.DeleteJobOutput{JobName: params.JobName}, nil
+}
+
+// job returns the job with the given name.
+func (f *Glue) job(name string) (*types.Job, error) {
+	job, ok := f.jobs[name]
+	if !ok {
+		return nil, apiError("EntityNotFoundException", "job %s not found", name)
+	}
+	return job, nil
+}
@@ -0,0 +1,33 @@
+package awsfake
+
+import (
+	"context"
+	"testing"
+
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+)
+
+func TestGlue(t *testing.T) {
+	ctx := context.Background()
+	client := awsService.NewGlueFromClient(NewGlue())
+
+	if err := client.EnsureJob(ctx, "test-job", "s3://scripts/v1.py"); err != nil {
+		t.Fatalf("ensuring the job: %v", err)
+	}
+	if action, err := client.PlanJob(ctx, "test-job", "s3://scripts/v2.py"); err != nil || action != awsService.ActionUpdate {
+		t.Errorf("expected to update the script, got %q, %v", action, err)
+	}
+	if err := client.EnsureJob(ctx, "test-job", "s3://scripts/v2.py"); err != nil {
+		t.Fatalf("updating the job: %v", err)
+	}
+	if action, err := client.PlanJob(ctx, "test-job", "s3://scripts/v2.py"); err != nil || action != awsService.ActionNone {
+		t.Errorf("expected no changes, got %q, %v", action, err)
+	}
+
+	if err := client.DeleteJob(ctx, "test-job"); err != nil {
+		t.Fatalf("deleting the job: %v", err)
+	}
+	if action, err := client.PlanJob(ctx, "test-job", "s3://scripts/v2.py"); err != nil || action != awsService.ActionCreate {
+		t.Errorf("expected to create the deleted job, got %q, %v", action, err)
+	}
+}
@@ -0,0 +1,370 @@
+package awsfake
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/iam"
+	"github.com/aws/aws-sdk-go-v2/service/iam/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	ststypes "github.com/aws/aws-sdk-go-v2/service/sts/types"
+)
+
+// maxPolicyVersions is the maximum number of versions of a managed policy.
+const maxPolicyVersions = 5
+
+// IAM is an in-memory fake of the IAM API, which stores its roles and managed policies
+// together with their versions and attachments. It also implements the AssumeRole call of
+// STS, which returns dummy credentials for the existing roles, so it is both clients of
+// the IAM wrapper:
+//
+//	fake := awsfake.NewIAM()
+//	iam := awsService.NewIAMFromClients(fake, fake)
+//
+// Like IAM, the fake returns the policy documents URL-encoded.
+type IAM struct {
+	mu       sync.Mutex
+	roles    map[string]*types.Role
+	attached map[string]map[string]bool
+	policies map[string]*policy
+}
+
+// policy is a managed policy and its versions.
+type policy struct {
+	policy      types.Policy
+	versions    []types.PolicyVersion
+	nextVersion int
+}
+
+// NewIAM returns a fake of the IAM API without roles and policies.
+func NewIAM() *IAM {
+	return &IAM{
+		roles:    map[string]*types.Role{},
+		attached: map[string]map[string]bool{},
+		policies: map[string]*policy{},
+	}
+}
+
+// AttachedPolicies returns the sorted ARNs of the policies attached to the role with the
+// given name.
+func (f *IAM) AttachedPolicies(role string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return sortedKeys(f.attached[role])
+}
+
+func (f *IAM) CreateRole(_ context.Context, params *iam.CreateRoleInput, _ ...func(*iam.Options)) (*iam.CreateRoleOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.RoleName)
+	if _, ok := f.roles[name]; ok {
+		return nil, apiError("EntityAlreadyExists", "role with name %s already exists", name)
+	}
+	if params.AssumeRolePolicyDocument == nil {
+		return nil, apiError("ValidationError", "role %s has no assume role policy document", name)
+	}
+
+	role := &types.Role{
+		RoleName:                 aws.String(name),
+		RoleId:                   aws.String(fmt.Sprintf("AROA%016X", len(f.roles)+1)),
+		Arn:                      aws.String(iamARN("role", name)),
+		Path:                     aws.String("/"),
+		AssumeRolePolicyDocument: aws.String(url.PathEscape(aws.ToString(params.AssumeRolePolicyDocument))),
+		CreateDate:               aws.Time(time.Now()),
+	}
+	f.roles[name] = role
+	f.attached[name] = map[string]bool{}
+
+	copied := *role
+	return &iam.CreateRoleOutput{Role: &copied}, nil
+}
+
+func (f *IAM) GetRole(_ context.Context, params *iam.GetRoleInput, _ ...func(*iam.Options)) (*iam.GetRoleOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	role, err := f.role(aws.ToString(params.RoleName))
+	if err != nil {
+		return nil, err
+	}
+
+	copied := *role
+	return &iam.GetRoleOutput{Role: &copied}, nil
+}
+
+// DeleteRole deletes the role, which must not have attached policies.
+func (f *IAM) DeleteRole(_ context.Context, params *iam.DeleteRoleInput, _ ...func(*iam.Options)) (*iam.DeleteRoleOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.RoleName)
+	if _, err := f.role(name); err != nil {
+		return nil, err
+	}
+	if len(f.attached[name]) > 0 {
+		return nil, apiError("DeleteConflict", "cannot delete entity, must detach all policies first")
+	}
+
+	delete(f.roles, name)
+	delete(f.attached, name)
+	return &iam.DeleteRoleOutput{}, nil
+}
+
+func (f *IAM) AttachRolePolicy(_ context.Context, params *iam.AttachRolePolicyInput, _ ...func(*iam.Options)) (*iam.AttachRolePolicyOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.RoleName)
+	if _, err := f.role(name); err != nil {
+		return nil, err
+	}
+	p, err := f.policy(aws.ToString(params.PolicyArn))
+	if err != nil {
+		return nil, err
+	}
+
+	if !f.attached[name][aws.ToString(params.PolicyArn)] {
+		f.attached[name][aws.ToString(params.PolicyArn)] = true
+		p.policy.AttachmentCount = aws.Int32(aws.ToInt32(p.policy.AttachmentCount) + 1)
+	}
+	return &iam.AttachRolePolicyOutput{}, nil
+}
+
+func (f *IAM) DetachRolePolicy(_ context.Context, params *iam.DetachRolePolicyInput, _ ...func(*iam.Options)) (*iam.DetachRolePolicyOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.RoleName)
+	if _, err := f.role(name); err != nil {
+		return nil, err
+	}
+	if !f.attached[name][aws.ToString(params.PolicyArn)] {
+		return nil, apiError("NoSuchEntity", "policy %s is not attached to role %s", aws.ToString(params.PolicyArn), name)
+	}
+
+	delete(f.attached[name], aws.ToString(params.PolicyArn))
+	if p, ok := f.policies[aws.ToString(params.PolicyArn)]; ok {
+		p.policy.AttachmentCount = aws.Int32(aws.ToInt32(p.policy.AttachmentCount) - 1)
+	}
+	return &iam.DetachRolePolicyOutput{}, nil
+}
+
+func (f *IAM) CreatePolicy(_ context.Context, params *iam.CreatePolicyInput, _ ...func(*iam.Options)) (*iam.CreatePolicyOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.PolicyName)
+	policyARN := iamARN("policy", name)
+	if _, ok := f.policies[policyARN]; ok {
+		return nil, apiError("EntityAlreadyExists", "a policy called %s already exists", name)
+	}
+	if params.PolicyDocument == nil {
+		return nil, apiError("MalformedPolicyDocument", "policy %s has no document", name)
+	}
+
+	now := time.Now()
+	p := &policy{
+		policy: types.Policy{
+			PolicyName:       aws.String(name),
+			PolicyId:         aws.String(fmt.Sprintf("ANPA%016X", len(f.policies)+1)),
+			Arn:              aws.String(policyARN),
+			Path:             aws.String("/"),
+			DefaultVersionId: aws.String("v1"),
+			AttachmentCount:  aws.Int32(0),
+			IsAttachable:     true,
+			CreateDate:       aws.Time(now),
+			UpdateDate:       aws.Time(now),
+		},
+		versions: []types.PolicyVersion{{
+			VersionId:        aws.String("v1"),
+			Document:         aws.String(url.PathEscape(aws.ToString(params.PolicyDocument))),
+			IsDefaultVersion: true,
+			CreateDate:       aws.Time(now),
+		}},
+		nextVersion: 2,
+	}
+	f.policies[policyARN] = p
+
+	copied := p.policy
+	return &iam.CreatePolicyOutput{Policy: &copied}, nil
+}
+
+func (f *IAM) GetPolicy(_ context.Context, params *iam.GetPolicyInput, _ ...func(*iam.Options)) (*iam.GetPolicyOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, err := f.policy(aws.ToString(params.PolicyArn))
+	if err != nil {
+		return nil, err
+	}
+
+	copied := p.policy
+	return &iam.GetPolicyOutput{Policy: &copied}, nil
+}
+
+// DeletePolicy deletes the policy, which must neither be attached nor have other versions
+// than the default version.
+func (f *IAM) DeletePolicy(_ context.Context, params *iam.DeletePolicyInput, _ ...func(*iam.Options)) (*iam.DeletePolicyOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, err := f.policy(aws.ToString(params.PolicyArn))
+	if err != nil {
+		return nil, err
+	}
+	if aws.ToInt32(p.policy.AttachmentCount) > 0 {
+		return nil, apiError("DeleteConflict", "cannot delete a policy attached to entities")
+	}
+	if len(p.versions) > 1 {
+		return nil, apiError("DeleteConflict", "this policy has more than one version, delete all non-default versions first")
+	}
+
+	delete(f.policies, aws.ToString(params.PolicyArn))
+	return &iam.DeletePolicyOutput{}, nil
+}
+
+func (f *IAM) GetPolicyVersion(_ context.Context, params *iam.GetPolicyVersionInput, _ ...func(*iam.Options)) (*iam.GetPolicyVersionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, err := f.policy(aws.ToString(params.PolicyArn))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, version := range p.versions {
+		if aws.ToString(version.VersionId) == aws.ToString(params.VersionId) {
+			return &iam.GetPolicyVersionOutput{PolicyVersion: &version}, nil
+		}
+	}
+	return nil, apiError("NoSuchEntity", "policy version %s of policy %s does not exist", aws.ToString(params.VersionId), aws.ToString(params.PolicyArn))
+}
+
+// ListPolicyVersions lists the versions of the policy from the newest to the oldest, like
+// IAM.
+func (f *IAM) ListPolicyVersions(_ context.Context, params *iam.ListPolicyVersionsInput, _ ...func(*iam.Options)) (*iam.ListPolicyVersionsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, err := f.policy(aws.ToString(params.PolicyArn))
+	if err != nil {
+		return nil, err
+	}
+
+	output := &iam.ListPolicyVersionsOutput{}
+	for i := len(p.versions) - 1; i >= 0; i-- {
+		output.Versions = append(output.Versions, p.versions[i])
+	}
+	return output, nil
+}
+
+func (f *IAM) CreatePolicyVersion(_ context.Context, params *iam.CreatePolicyVersionInput, _ ...func(*iam.Options)) (*iam.CreatePolicyVersionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, err := f.policy(aws.ToString(params.PolicyArn))
+	if err != nil {
+		return nil, err
+	}
+	if len(p.versions) >= maxPolicyVersions {
+		return nil, apiError("LimitExceeded", "a managed policy can have up to %d versions", maxPolicyVersions)
+	}
+
+	version := types.PolicyVersion{
+		VersionId:  aws.String(fmt.Sprintf("v%d", p.nextVersion)),
+		Document:   aws.String(url.PathEscape(aws.ToString(params.PolicyDocument))),
+		CreateDate: aws.Time(time.Now()),
+	}
+	p.nextVersion++
+	if params.SetAsDefault {
+		for i := range p.versions {
+			p.versions[i].IsDefaultVersion = false
+		}
+		version.IsDefaultVersion = true
+		p.policy.DefaultVersionId = version.VersionId
+		p.policy.UpdateDate = version.CreateDate
+	}
+	p.versions = append(p.versions, version)
+
+	return &iam.CreatePolicyVersionOutput{PolicyVersion: &version}, nil
+}
+
+// DeletePolicyVersion deletes the version of the policy, which must not be the default
+// version.
+func (f *IAM) DeletePolicyVersion(_ context.Context, params *iam.DeletePolicyVersionInput, _ ...func(*iam.Options)) (*iam.DeletePolicyVersionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, err := f.policy(aws.ToString(params.PolicyArn))
+	if err != nil {
+		return nil, err
+	}
+
+	for i, version := range p.versions {
+		if aws.ToString(version.VersionId) != aws.ToString(params.VersionId) {
+			continue
+		}
+		if version.IsDefaultVersion {
+			return nil, apiError("DeleteConflict", "cannot delete the default version of a policy")
+		}
+		p.versions = append(p.versions[:i], p.versions[i+1:]...)
+		return &iam.DeletePolicyVersionOutput{}, nil
+	}
+	return nil, apiError("NoSuchEntity", "policy version %s of policy %s does not exist", aws.ToString(params.VersionId), aws.ToString(params.PolicyArn))
+}
+
+// AssumeRole returns dummy credentials of the role, which expire after an hour.
+func (f *IAM) AssumeRole(_ context.Context, params *sts.AssumeRoleInput, _ ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	roleARN := aws.ToString(params.RoleArn)
+	name := roleARN[strings.LastIndex(roleARN, "/")+1:]
+	role, ok := f.roles[name]
+	if !ok || aws.ToString(role.Arn) != roleARN {
+		return nil, apiError("AccessDenied", "not authorized to perform sts:AssumeRole on %s", roleARN)
+	}
+
+	return &sts.AssumeRoleOutput{
+		AssumedRoleUser: &ststypes.AssumedRoleUser{
+			Arn:           aws.String(fmt.Sprintf("arn:aws:sts::%s:assumed-role/%s/%s", account, name, aws.ToString(params.RoleSessionName))),
+			AssumedRoleId: aws.String(aws.ToString(role.RoleId) + ":" + aws.ToString(params.RoleSessionName)),
+		},
+		Credentials: &ststypes.Credentials{
+			AccessKeyId:     aws.String("ASIA" + aws.ToString(role.RoleId)[4:]),
+			SecretAccessKey: aws.String("fake"),
+			SessionToken:    aws.String("fake"),
+			Expiration:      aws.Time(time.Now().Add(time.Hour)),
+		},
+	}, nil
+}
+
+// role returns the role with the given name.
+func (f *IAM) role(name string) (*types.Role, error) {
+	role, ok := f.roles[name]
+	if !ok {
+		return nil, apiError("NoSuchEntity", "the role with name %s cannot be found", name)
+	}
+	return role, nil
+}
+
+// policy returns the policy with the given ARN.
+func (f *IAM) policy(policyARN string) (*policy, error) {
+	p, ok := f.policies[policyARN]
+	if !ok {
+		return nil, apiError("NoSuchEntity", "policy %s does not exist", policyARN)
+	}
+	return p, nil
+}
+
+// iamARN returns the ARN of the IAM resource of the given type, which has no region.
+func iamARN(resourceType, name string) string {
+	return fmt.Sprintf("arn:aws:iam::%s:%s/%s", account, resourceType, name)
+}
@@ -0,0 +1,49 @@
+package awsfake
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+)
+
+func TestIAM(t *testing.T) {
+	ctx := context.Background()
+	fake := NewIAM()
+	client := awsService.NewIAMFromClients(fake, fake)
+
+	if action, err := client.PlanRoleWithPolicy(ctx, "test-role", "s3"); err != nil || action != awsService.ActionCreate {
+		t.Fatalf("expected to create the role, got %q, %v", action, err)
+	}
+	credentials, err := client.EnsureRoleWithPolicy(ctx, "test-role", "s3")
+	if err != nil {
+		t.Fatalf("ensuring the role: %v", err)
+	}
+	if _, err := credentials.Retrieve(ctx); err != nil {
+		t.Errorf("assuming the role: %v", err)
+	}
+
+	expected := []string{"arn:aws:iam::000000000000:policy/test-role-policy"}
+	if policies := fake.AttachedPolicies("test-role"); !reflect.DeepEqual(policies, expected) {
+		t.Errorf("expected attached policies %v, got %v", expected, policies)
+	}
+	if drift, err := client.DriftRoleWithPolicy(ctx, "test-role", "s3"); err != nil || drift.Action != awsService.ActionNone {
+		t.Errorf("expected no drift, got %+v, %v", drift, err)
+	}
+
+	// A different service changes the document of the policy, which adds a policy version.
+	if _, err := client.EnsureRoleWithPolicy(ctx, "test-role", "kinesis"); err != nil {
+		t.Fatalf("updating the role: %v", err)
+	}
+	if drift, err := client.DriftRoleWithPolicy(ctx, "test-role", "kinesis"); err != nil || drift.Action != awsService.ActionNone {
+		t.Errorf("expected no drift after the update, got %+v, %v", drift, err)
+	}
+
+	if err := client.DeleteRoleWithPolicy(ctx, "test-role"); err != nil {
+		t.Fatalf("deleting the role: %v", err)
+	}
+	if _, err := client.RoleCredentials(ctx, "test-role"); !awsService.IsNotFound(err) {
+		t.Errorf("expected a missing role, got %v", err)
+	}
+}
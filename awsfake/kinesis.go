@@ -0,0 +1,754 @@
+package awsfake
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+)
+
+// Kinesis is an in-memory fake of the Kinesis API. The records of a stream are assigned to
+// its shards by the MD5 hash of their partition key, like in Kinesis, and stay readable
+// until the stream is deleted. Splitting, merging and scaling shards closes them and opens
+// child shards at once, without the stream being updated in between. An on-demand stream
+// has four shards, which are never scaled, and a stream keeps its shards when its mode is
+// switched. The retention period and the encryption of a stream are only reported, records
+// are neither expired nor encrypted. Registered consumers are active at once, and their
+// subscriptions push the records of a shard as soon as they are put.
+type Kinesis struct {
+	mu            sync.Mutex
+	streams       map[string]*stream
+	sequence      int64
+	subscriptions map[string]bool
+}
+
+// subscriptionDuration is the time after which a subscription to a shard ends, like in
+// Kinesis. Tests shorten it to renew subscriptions.
+var subscriptionDuration = 5 * time.Minute
+
+// subscriptionInterval is the time a subscription waits for new records of its shard.
+const subscriptionInterval = 10 * time.Millisecond
+
+// stream is a Kinesis stream and its records.
+type stream struct {
+	arn       string
+	mode      types.StreamMode
+	retention int32
+	keyID     string
+	shards    []*shard
+	tags      map[string]string
+	consumers map[string]*types.ConsumerDescription
+}
+
+// shard is a shard of a stream, which owns the hash keys from start to end. A closed shard
+// was split or merged and does not take new records.
+type shard struct {
+	id             string
+	start, end     *big.Int
+	records        []types.Record
+	parent         string
+	adjacentParent string
+	closed         bool
+}
+
+// NewKinesis returns a fake of the Kinesis API without streams.
+func NewKinesis() *Kinesis {
+	return &Kinesis{streams: map[string]*stream{}, subscriptions: map[string]bool{}}
+}
+
+// Records returns the records of the stream with the given name, ordered by their shard
+// and sequence number.
+func (f *Kinesis) Records(name string) []types.Record {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, ok := f.streams[name]
+	if !ok {
+		return nil
+	}
+
+	var records []types.Record
+	for _, sh := range s.shards {
+		records = append(records, sh.records...)
+	}
+	return records
+}
+
+// Tags returns the tags of the stream with the given name.
+func (f *Kinesis) Tags(name string) map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if s, ok := f.streams[name]; ok {
+		return s.tags
+	}
+	return nil
+}
+
+func (f *Kinesis) CreateStream(_ context.Context, params *kinesis.CreateStreamInput, _ ...func(*kinesis.Options)) (*kinesis.CreateStreamOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.StreamName)
+	if _, ok := f.streams[name]; ok {
+		return nil, operationError("CreateStream", "ResourceInUseException", "stream %s already exists", name)
+	}
+	mode := types.StreamModeProvisioned
+	if details := params.StreamModeDetails; details != nil {
+		mode = details.StreamMode
+	}
+	count := aws.ToInt32(params.ShardCount)
+	switch {
+	case mode == types.StreamModeOnDemand && params.ShardCount != nil:
+		return nil, apiError("ValidationException", "the shard count of on-demand stream %s is managed by Kinesis", name)
+	case mode == types.StreamModeOnDemand:
+		count = 4
+	case mode != types.StreamModeProvisioned:
+		return nil, apiError("ValidationException", "invalid stream mode %s", mode)
+	case count == 0:
+		count = 1
+	case count < 0:
+		return nil, apiError("InvalidArgumentException", "invalid shard count %d", count)
+	}
+
+	f.streams[name] = &stream{
+		arn:       arn("kinesis", "stream/"+name),
+		mode:      mode,
+		retention: 24,
+		shards:    splitHashKeys(int(count)),
+		consumers: map[string]*types.ConsumerDescription{},
+	}
+	return &kinesis.CreateStreamOutput{}, nil
+}
+
+// splitHashKeys returns the given number of shards, which split the 128-bit hash keys
+// evenly.
+func splitHashKeys(count int) []*shard {
+	size := new(big.Int).Lsh(big.NewInt(1), 128)
+	size.Div(size, big.NewInt(int64(count)))
+
+	shards := make([]*shard, count)
+	for i := range shards {
+		start := new(big.Int).Mul(size, big.NewInt(int64(i)))
+		end := new(big.Int).Add(start, size)
+		if i == count-1 {
+			end.Lsh(big.NewInt(1), 128)
+		}
+		shards[i] = &shard{
+			id:    shardID(i),
+			start: start,
+			end:   end.Sub(end, big.NewInt(1)),
+		}
+	}
+	return shards
+}
+
+// shardID returns the ID of the shard with the given index in its stream.
+func shardID(index int) string {
+	return fmt.Sprintf("shardId-%012d", index)
+}
+
+// AddTagsToStream adds the tags to the stream and replaces tags with the same key.
+func (f *Kinesis) AddTagsToStream(_ context.Context, params *kinesis.AddTagsToStreamInput, _ ...func(*kinesis.Options)) (*kinesis.AddTagsToStreamOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.StreamName)
+	s, ok := f.streams[name]
+	if !ok {
+		return nil, apiError("ResourceNotFoundException", "stream %s not found", name)
+	}
+
+	if s.tags == nil {
+		s.tags = map[string]string{}
+	}
+	for key, value := range params.Tags {
+		s.tags[key] = value
+	}
+	return &kinesis.AddTagsToStreamOutput{}, nil
+}
+
+func (f *Kinesis) DeleteStream(_ context.Context, params *kinesis.DeleteStreamInput, _ ...func(*kinesis.Options)) (*kinesis.DeleteStreamOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.StreamName)
+	if _, ok := f.streams[name]; !ok {
+		return nil, apiError("ResourceNotFoundException", "stream %s not found", name)
+	}
+
+	delete(f.streams, name)
+	return &kinesis.DeleteStreamOutput{}, nil
+}
+
+// DescribeStream describes the stream, which is active as soon as it is created.
+func (f *Kinesis) DescribeStream(_ context.Context, params *kinesis.DescribeStreamInput, _ ...func(*kinesis.Options)) (*kinesis.DescribeStreamOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.StreamName)
+	s, err := f.stream(name)
+	if err != nil {
+		return nil, err
+	}
+
+	description := &types.StreamDescription{
+		StreamName:           aws.String(name),
+		StreamARN:            aws.String(s.arn),
+		StreamStatus:         types.StreamStatusActive,
+		StreamModeDetails:    &types.StreamModeDetails{StreamMode: s.mode},
+		RetentionPeriodHours: aws.Int32(s.retention),
+		EncryptionType:       s.encryption(),
+		HasMoreShards:        aws.Bool(false),
+	}
+	if s.keyID != "" {
+		description.KeyId = aws.String(s.keyID)
+	}
+	for _, sh := range s.shards {
+		description.Shards = append(description.Shards, sh.describe())
+	}
+	return &kinesis.DescribeStreamOutput{StreamDescription: description}, nil
+}
+
+// DescribeStreamSummary describes the stream without its shards.
+func (f *Kinesis) DescribeStreamSummary(_ context.Context, params *kinesis.DescribeStreamSummaryInput, _ ...func(*kinesis.Options)) (*kinesis.DescribeStreamSummaryOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.StreamName)
+	s, err := f.stream(name)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &types.StreamDescriptionSummary{
+		StreamName:           aws.String(name),
+		StreamARN:            aws.String(s.arn),
+		StreamStatus:         types.StreamStatusActive,
+		StreamModeDetails:    &types.StreamModeDetails{StreamMode: s.mode},
+		RetentionPeriodHours: aws.Int32(s.retention),
+		EncryptionType:       s.encryption(),
+		OpenShardCount:       aws.Int32(int32(len(s.open()))),
+		ConsumerCount:        aws.Int32(int32(len(s.consumers))),
+	}
+	if s.keyID != "" {
+		summary.KeyId = aws.String(s.keyID)
+	}
+	return &kinesis.DescribeStreamSummaryOutput{StreamDescriptionSummary: summary}, nil
+}
+
+// encryption returns the encryption type of the stream.
+func (s *stream) encryption() types.EncryptionType {
+	if s.keyID == "" {
+		return types.EncryptionTypeNone
+	}
+	return types.EncryptionTypeKms
+}
+
+// UpdateStreamMode switches the stream to the mode, keeping its shards.
+func (f *Kinesis) UpdateStreamMode(_ context.Context, params *kinesis.UpdateStreamModeInput, _ ...func(*kinesis.Options)) (*kinesis.UpdateStreamModeOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, err := f.streamByARN(aws.ToString(params.StreamARN))
+	if err != nil {
+		return nil, err
+	}
+	if params.StreamModeDetails == nil {
+		return nil, apiError("ValidationException", "the stream mode is missing")
+	}
+	switch mode := params.StreamModeDetails.StreamMode; {
+	case mode != types.StreamModeProvisioned && mode != types.StreamModeOnDemand:
+		return nil, apiError("ValidationException", "invalid stream mode %s", mode)
+	case mode == s.mode:
+		return nil, apiError("ValidationException", "stream %s is already in mode %s", s.arn, mode)
+	}
+
+	s.mode = params.StreamModeDetails.StreamMode
+	return &kinesis.UpdateStreamModeOutput{}, nil
+}
+
+// IncreaseStreamRetentionPeriod extends the retention period of the stream. Like in
+// Kinesis, the period must be longer than the current one and at most a year.
+func (f *Kinesis) IncreaseStreamRetentionPeriod(_ context.Context, params *kinesis.IncreaseStreamRetentionPeriodInput, _ ...func(*kinesis.Options)) (*kinesis.IncreaseStreamRetentionPeriodOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.StreamName)
+	s, err := f.stream(name)
+	if err != nil {
+		return nil, err
+	}
+	hours := aws.ToInt32(params.RetentionPeriodHours)
+	if hours <= s.retention || hours > 8760 {
+		return nil, apiError("InvalidArgumentException", "retention period of %d hours is not longer than %d hours or longer than a year", hours, s.retention)
+	}
+
+	s.retention = hours
+	return &kinesis.IncreaseStreamRetentionPeriodOutput{}, nil
+}
+
+// DecreaseStreamRetentionPeriod shortens the retention period of the stream. Like in
+// Kinesis, the period must be shorter than the current one and at least 24 hours.
+func (f *Kinesis) DecreaseStreamRetentionPeriod(_ context.Context, params *kinesis.DecreaseStreamRetentionPeriodInput, _ ...func(*kinesis.Options)) (*kinesis.DecreaseStreamRetentionPeriodOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.StreamName)
+	s, err := f.stream(name)
+	if err != nil {
+		return nil, err
+	}
+	hours := aws.ToInt32(params.RetentionPeriodHours)
+	if hours >= s.retention || hours < 24 {
+		return nil, apiError("InvalidArgumentException", "retention period of %d hours is not shorter than %d hours or shorter than 24 hours", hours, s.retention)
+	}
+
+	s.retention = hours
+	return &kinesis.DecreaseStreamRetentionPeriodOutput{}, nil
+}
+
+// StartStreamEncryption encrypts the stream with the KMS key, which replaces the key of an
+// encrypted stream. Every key exists in the fake.
+func (f *Kinesis) StartStreamEncryption(_ context.Context, params *kinesis.StartStreamEncryptionInput, _ ...func(*kinesis.Options)) (*kinesis.StartStreamEncryptionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.StreamName)
+	s, err := f.stream(name)
+	if err != nil {
+		return nil, err
+	}
+	if params.EncryptionType != types.EncryptionTypeKms || aws.ToString(params.KeyId) == "" {
+		return nil, apiError("ValidationException", "streams are encrypted with a KMS key")
+	}
+
+	s.keyID = aws.ToString(params.KeyId)
+	return &kinesis.StartStreamEncryptionOutput{}, nil
+}
+
+// StopStreamEncryption stops encrypting the stream. Like in Kinesis, the KMS key must be
+// the key of the stream.
+func (f *Kinesis) StopStreamEncryption(_ context.Context, params *kinesis.StopStreamEncryptionInput, _ ...func(*kinesis.Options)) (*kinesis.StopStreamEncryptionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.StreamName)
+	s, err := f.stream(name)
+	if err != nil {
+		return nil, err
+	}
+	if params.EncryptionType != types.EncryptionTypeKms || aws.ToString(params.KeyId) != s.keyID || s.keyID == "" {
+		return nil, apiError("ValidationException", "stream %s is not encrypted with KMS key %s", name, aws.ToString(params.KeyId))
+	}
+
+	s.keyID = ""
+	return &kinesis.StopStreamEncryptionOutput{}, nil
+}
+
+// UpdateShardCount closes the open shards of a provisioned stream and opens the target
+// number of shards, which split the hash keys evenly. Like in Kinesis, the target must be
+// between half and double the open shards. The parents of a new shard are the closed
+// shards that own its first and its last hash key.
+func (f *Kinesis) UpdateShardCount(_ context.Context, params *kinesis.UpdateShardCountInput, _ ...func(*kinesis.Options)) (*kinesis.UpdateShardCountOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.StreamName)
+	s, err := f.stream(name)
+	if err != nil {
+		return nil, err
+	}
+	if s.mode == types.StreamModeOnDemand {
+		return nil, apiError("ValidationException", "the shard count of on-demand stream %s is managed by Kinesis", name)
+	}
+	if params.ScalingType != types.ScalingTypeUniformScaling {
+		return nil, apiError("ValidationException", "unsupported scaling type %s", params.ScalingType)
+	}
+	open := s.open()
+	target := int(aws.ToInt32(params.TargetShardCount))
+	if target < 1 || target*2 < len(open) || target > len(open)*2 {
+		return nil, apiError("ValidationException", "target shard count %d is not between half and double the %d open shards", target, len(open))
+	}
+
+	children := splitHashKeys(target)
+	for i, child := range children {
+		child.id = shardID(len(s.shards) + i)
+		for _, parent := range open {
+			if parent.start.Cmp(child.start) <= 0 && parent.end.Cmp(child.start) >= 0 {
+				child.parent = parent.id
+			}
+			if parent.start.Cmp(child.end) <= 0 && parent.end.Cmp(child.end) >= 0 && parent.id != child.parent {
+				child.adjacentParent = parent.id
+			}
+		}
+	}
+	for _, sh := range open {
+		sh.closed = true
+	}
+	s.shards = append(s.shards, children...)
+	return &kinesis.UpdateShardCountOutput{
+		StreamName:        aws.String(name),
+		CurrentShardCount: aws.Int32(int32(len(open))),
+		TargetShardCount:  aws.Int32(int32(target)),
+	}, nil
+}
+
+// open returns the open shards of the stream.
+func (s *stream) open() []*shard {
+	var open []*shard
+	for _, sh := range s.shards {
+		if !sh.closed {
+			open = append(open, sh)
+		}
+	}
+	return open
+}
+
+// ListShards lists every shard of the stream, including the closed shards, in one page.
+func (f *Kinesis) ListShards(_ context.Context, params *kinesis.ListShardsInput, _ ...func(*kinesis.Options)) (*kinesis.ListShardsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, err := f.stream(aws.ToString(params.StreamName))
+	if err != nil {
+		return nil, err
+	}
+
+	output := &kinesis.ListShardsOutput{}
+	for _, sh := range s.shards {
+		output.Shards = append(output.Shards, sh.describe())
+	}
+	return output, nil
+}
+
+// SplitShard closes the shard and opens two child shards, the second of which starts at
+// the new starting hash key.
+func (f *Kinesis) SplitShard(_ context.Context, params *kinesis.SplitShardInput, _ ...func(*kinesis.Options)) (*kinesis.SplitShardOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.StreamName)
+	parent, err := f.openShard(name, aws.ToString(params.ShardToSplit))
+	if err != nil {
+		return nil, err
+	}
+	start, ok := new(big.Int).SetString(aws.ToString(params.NewStartingHashKey), 10)
+	if !ok || start.Cmp(parent.start) <= 0 || start.Cmp(parent.end) > 0 {
+		return nil, apiError("InvalidArgumentException", "new starting hash key %s is not within shard %s", aws.ToString(params.NewStartingHashKey), parent.id)
+	}
+
+	s := f.streams[name]
+	parent.closed = true
+	s.shards = append(s.shards,
+		&shard{id: shardID(len(s.shards)), start: parent.start, end: new(big.Int).Sub(start, big.NewInt(1)), parent: parent.id},
+		&shard{id: shardID(len(s.shards) + 1), start: start, end: parent.end, parent: parent.id},
+	)
+	return &kinesis.SplitShardOutput{}, nil
+}
+
+// MergeShards closes two adjacent shards and opens a child shard with the hash keys of
+// both.
+func (f *Kinesis) MergeShards(_ context.Context, params *kinesis.MergeShardsInput, _ ...func(*kinesis.Options)) (*kinesis.MergeShardsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.StreamName)
+	parent, err := f.openShard(name, aws.ToString(params.ShardToMerge))
+	if err != nil {
+		return nil, err
+	}
+	adjacent, err := f.openShard(name, aws.ToString(params.AdjacentShardToMerge))
+	if err != nil {
+		return nil, err
+	}
+	low, high := parent, adjacent
+	if low.start.Cmp(high.start) > 0 {
+		low, high = high, low
+	}
+	if new(big.Int).Add(low.end, big.NewInt(1)).Cmp(high.start) != 0 {
+		return nil, apiError("InvalidArgumentException", "shards %s and %s are not adjacent", parent.id, adjacent.id)
+	}
+
+	s := f.streams[name]
+	parent.closed, adjacent.closed = true, true
+	s.shards = append(s.shards, &shard{
+		id:             shardID(len(s.shards)),
+		start:          low.start,
+		end:            high.end,
+		parent:         parent.id,
+		adjacentParent: adjacent.id,
+	})
+	return &kinesis.MergeShardsOutput{}, nil
+}
+
+// describe returns the description of the shard. A closed shard has an ending sequence
+// number.
+func (sh *shard) describe() types.Shard {
+	description := types.Shard{
+		ShardId: aws.String(sh.id),
+		HashKeyRange: &types.HashKeyRange{
+			StartingHashKey: aws.String(sh.start.String()),
+			EndingHashKey:   aws.String(sh.end.String()),
+		},
+		SequenceNumberRange: &types.SequenceNumberRange{StartingSequenceNumber: aws.String(fmt.Sprintf("%021d", 0))},
+	}
+	if sh.parent != "" {
+		description.ParentShardId = aws.String(sh.parent)
+	}
+	if sh.adjacentParent != "" {
+		description.AdjacentParentShardId = aws.String(sh.adjacentParent)
+	}
+	if sh.closed {
+		ending := fmt.Sprintf("%021d", 0)
+		if len(sh.records) > 0 {
+			ending = aws.ToString(sh.records[len(sh.records)-1].SequenceNumber)
+		}
+		description.SequenceNumberRange.EndingSequenceNumber = aws.String(ending)
+	}
+	return description
+}
+
+func (f *Kinesis) PutRecord(_ context.Context, params *kinesis.PutRecordInput, _ ...func(*kinesis.Options)) (*kinesis.PutRecordOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, err := f.stream(aws.ToString(params.StreamName))
+	if err != nil {
+		return nil, err
+	}
+
+	shardID, sequenceNumber, err := f.put(s, aws.ToString(params.PartitionKey), aws.ToString(params.ExplicitHashKey), params.Data)
+	if err != nil {
+		return nil, err
+	}
+	return &kinesis.PutRecordOutput{
+		ShardId:        aws.String(shardID),
+		SequenceNumber: aws.String(sequenceNumber),
+	}, nil
+}
+
+// PutRecords puts the records into the stream. Like in Kinesis, an invalid record fails
+// the whole call, while the limits of a call are checked before any record is put.
+func (f *Kinesis) PutRecords(_ context.Context, params *kinesis.PutRecordsInput, _ ...func(*kinesis.Options)) (*kinesis.PutRecordsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, err := f.stream(aws.ToString(params.StreamName))
+	if err != nil {
+		return nil, err
+	}
+	if len(params.Records) == 0 || len(params.Records) > 500 {
+		return nil, apiError("ValidationException", "%d records are not between 1 and 500", len(params.Records))
+	}
+	size := 0
+	for _, entry := range params.Records {
+		size += len(entry.Data) + len(aws.ToString(entry.PartitionKey))
+	}
+	if size > 5<<20 {
+		return nil, apiError("InvalidArgumentException", "%d bytes of records exceed 5 MB", size)
+	}
+
+	output := &kinesis.PutRecordsOutput{}
+	for _, entry := range params.Records {
+		shardID, sequenceNumber, err := f.put(s, aws.ToString(entry.PartitionKey), aws.ToString(entry.ExplicitHashKey), entry.Data)
+		if err != nil {
+			return nil, err
+		}
+		output.Records = append(output.Records, types.PutRecordsResultEntry{
+			ShardId:        aws.String(shardID),
+			SequenceNumber: aws.String(sequenceNumber),
+		})
+	}
+	return output, nil
+}
+
+// put appends a record to the shard of its hash key and returns the ID of the shard and
+// the sequence number of the record.
+func (f *Kinesis) put(s *stream, partitionKey, explicitHashKey string, data []byte) (string, string, error) {
+	if partitionKey == "" {
+		return "", "", apiError("ValidationException", "the partition key is empty")
+	}
+
+	hashKey := new(big.Int)
+	if explicitHashKey != "" {
+		if _, ok := hashKey.SetString(explicitHashKey, 10); !ok {
+			return "", "", apiError("InvalidArgumentException", "invalid explicit hash key %s", explicitHashKey)
+		}
+	} else {
+		sum := md5.Sum([]byte(partitionKey))
+		hashKey.SetBytes(sum[:])
+	}
+
+	for _, sh := range s.shards {
+		if sh.closed || hashKey.Cmp(sh.start) < 0 || hashKey.Cmp(sh.end) > 0 {
+			continue
+		}
+
+		f.sequence++
+		sequenceNumber := fmt.Sprintf("%021d", f.sequence)
+		sh.records = append(sh.records, types.Record{
+			Data:                        append([]byte(nil), data...),
+			PartitionKey:                aws.String(partitionKey),
+			SequenceNumber:              aws.String(sequenceNumber),
+			ApproximateArrivalTimestamp: aws.Time(time.Now()),
+		})
+		return sh.id, sequenceNumber, nil
+	}
+	return "", "", apiError("InvalidArgumentException", "hash key %s is out of range", hashKey)
+}
+
+// GetShardIterator returns an iterator of the shard. The iterator is the position of the
+// next record in the shard, so it never expires.
+func (f *Kinesis) GetShardIterator(_ context.Context, params *kinesis.GetShardIteratorInput, _ ...func(*kinesis.Options)) (*kinesis.GetShardIteratorOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.StreamName)
+	sh, err := f.shard(name, aws.ToString(params.ShardId))
+	if err != nil {
+		return nil, err
+	}
+
+	position, err := sh.position(params.ShardIteratorType, aws.ToString(params.StartingSequenceNumber))
+	if err != nil {
+		return nil, err
+	}
+
+	return &kinesis.GetShardIteratorOutput{ShardIterator: aws.String(iterator(name, sh.id, position))}, nil
+}
+
+// position returns the index of the first record of the shard that an iterator of the
+// given type and sequence number reads.
+func (sh *shard) position(iteratorType types.ShardIteratorType, sequenceNumber string) (int, error) {
+	switch iteratorType {
+	case types.ShardIteratorTypeTrimHorizon:
+		return 0, nil
+	case types.ShardIteratorTypeLatest:
+		return len(sh.records), nil
+	case types.ShardIteratorTypeAtSequenceNumber, types.ShardIteratorTypeAfterSequenceNumber:
+		for i, record := range sh.records {
+			if aws.ToString(record.SequenceNumber) >= sequenceNumber {
+				if iteratorType == types.ShardIteratorTypeAfterSequenceNumber && aws.ToString(record.SequenceNumber) == sequenceNumber {
+					return i + 1, nil
+				}
+				return i, nil
+			}
+		}
+		return len(sh.records), nil
+	default:
+		return 0, apiError("InvalidArgumentException", "unsupported shard iterator type %s", iteratorType)
+	}
+}
+
+// GetRecords returns the records of the shard from the position of the iterator.
+func (f *Kinesis) GetRecords(_ context.Context, params *kinesis.GetRecordsInput, _ ...func(*kinesis.Options)) (*kinesis.GetRecordsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	parts := strings.Split(aws.ToString(params.ShardIterator), "/")
+	if len(parts) != 3 {
+		return nil, apiError("InvalidArgumentException", "invalid shard iterator %s", aws.ToString(params.ShardIterator))
+	}
+	position, err := strconv.Atoi(parts[2])
+	if err != nil || position < 0 {
+		return nil, apiError("InvalidArgumentException", "invalid shard iterator %s", aws.ToString(params.ShardIterator))
+	}
+	sh, err := f.shard(parts[0], parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	if position > len(sh.records) {
+		position = len(sh.records)
+	}
+	limit := 10000
+	if l := aws.ToInt32(params.Limit); l > 0 && l < 10000 {
+		limit = int(l)
+	}
+	end := position + limit
+	if end > len(sh.records) {
+		end = len(sh.records)
+	}
+
+	output := &kinesis.GetRecordsOutput{
+		Records:            append([]types.Record(nil), sh.records[position:end]...),
+		MillisBehindLatest: aws.Int64(0),
+	}
+	// The iterator of a closed shard ends with its last record, like in Kinesis.
+	if sh.closed && end == len(sh.records) {
+		output.ChildShards = f.streams[parts[0]].children(sh)
+		return output, nil
+	}
+	output.NextShardIterator = aws.String(iterator(parts[0], sh.id, end))
+	return output, nil
+}
+
+// children returns the child shards of the given shard of the stream.
+func (s *stream) children(sh *shard) []types.ChildShard {
+	var children []types.ChildShard
+	for _, child := range s.shards {
+		if child.parent != sh.id && child.adjacentParent != sh.id {
+			continue
+		}
+		parents := []string{child.parent}
+		if child.adjacentParent != "" {
+			parents = append(parents, child.adjacentParent)
+		}
+		children = append(children, types.ChildShard{
+			ShardId:      aws.String(child.id),
+			ParentShards: parents,
+			HashKeyRange: child.describe().HashKeyRange,
+		})
+	}
+	return children
+}
+
+// iterator returns the shard iterator of the given position in the shard.
+func iterator(stream, shardID string, position int) string {
+	return fmt.Sprintf("%s/%s/%d", stream, shardID, position)
+}
+
+// stream returns the stream with the given name.
+func (f *Kinesis) stream(name string) (*stream, error) {
+	s, ok := f.streams[name]
+	if !ok {
+		return nil, apiError("ResourceNotFoundException", "stream %s not found", name)
+	}
+	return s, nil
+}
+
+// openShard returns the shard with the given ID of the stream with the given name, which
+// must not be closed.
+func (f *Kinesis) openShard(name, id string) (*shard, error) {
+	sh, err := f.shard(name, id)
+	if err != nil {
+		return nil, err
+	}
+	if sh.closed {
+		return nil, apiError("ResourceInUseException", "shard %s of stream %s is closed", id, name)
+	}
+	return sh, nil
+}
+
+// shard returns the shard with the given ID of the stream with the given name.
+func (f *Kinesis) shard(name, id string) (*shard, error) {
+	s, err := f.stream(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, sh := range s.shards {
+		if sh.id == id {
+			return sh, nil
+		}
+	}
+	return nil, apiError("ResourceNotFoundException", "shard %s of stream %s not found", id, name)
+}
@@ -0,0 +1,626 @@
+package awsfake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+)
+
+func TestKinesis(t *testing.T) {
+	ctx := context.Background()
+	fake := NewKinesis()
+	client := awsService.NewKinesisFromClient(fake)
+
+	if err := client.Ensure(ctx, "test-stream"); err != nil {
+		t.Fatalf("ensuring the stream: %v", err)
+	}
+	if err := client.WaitUntilActive(ctx, "test-stream", 0); err != nil {
+		t.Fatalf("waiting for the stream: %v", err)
+	}
+	if arn, err := client.GetARN(ctx, "test-stream"); err != nil || arn != "arn:aws:kinesis:us-east-1:000000000000:stream/test-stream" {
+		t.Errorf("unexpected arn %q, %v", arn, err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := client.PutRecord(ctx, "test-stream", fmt.Sprint(i), []byte(fmt.Sprint(i))); err != nil {
+			t.Fatalf("putting record %d: %v", i, err)
+		}
+	}
+
+	iterator, err := fake.GetShardIterator(ctx, &kinesis.GetShardIteratorInput{
+		StreamName:        aws.String("test-stream"),
+		ShardId:           aws.String("shardId-000000000000"),
+		ShardIteratorType: types.ShardIteratorTypeTrimHorizon,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := fake.GetRecords(ctx, &kinesis.GetRecordsInput{ShardIterator: iterator.ShardIterator, Limit: aws.Int32(2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records.Records) != 2 || string(records.Records[0].Data) != "0" || string(records.Records[1].Data) != "1" {
+		t.Fatalf("unexpected first records %v", records.Records)
+	}
+	records, err = fake.GetRecords(ctx, &kinesis.GetRecordsInput{ShardIterator: records.NextShardIterator})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records.Records) != 1 || string(records.Records[0].Data) != "2" {
+		t.Fatalf("unexpected next records %v", records.Records)
+	}
+
+	if err := client.Delete(ctx, "test-stream"); err != nil {
+		t.Fatalf("deleting the stream: %v", err)
+	}
+	if err := client.PutRecord(ctx, "test-stream", "0", nil); !awsService.IsNotFound(err) {
+		t.Errorf("expected a missing stream, got %v", err)
+	}
+}
+
+func TestKinesis_PartitionKeys(t *testing.T) {
+	ctx := context.Background()
+	fake := NewKinesis()
+
+	_, err := fake.CreateStream(ctx, &kinesis.CreateStreamInput{StreamName: aws.String("test-stream"), ShardCount: aws.Int32(4)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shards := map[string]string{}
+	used := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprint(i % 20)
+		output, err := fake.PutRecord(ctx, &kinesis.PutRecordInput{StreamName: aws.String("test-stream"), PartitionKey: aws.String(key), Data: []byte(key)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		shard := aws.ToString(output.ShardId)
+		if previous, ok := shards[key]; ok && previous != shard {
+			t.Errorf("partition key %s was put into shards %s and %s", key, previous, shard)
+		}
+		shards[key] = shard
+		used[shard] = true
+	}
+
+	if len(used) < 2 {
+		t.Errorf("expected the partition keys to spread over the shards, got %v", used)
+	}
+	if records := fake.Records("test-stream"); len(records) != 100 {
+		t.Errorf("expected 100 records, got %d", len(records))
+	}
+}
+
+func TestKinesis_Resharding(t *testing.T) {
+	ctx := context.Background()
+	fake := NewKinesis()
+	client := awsService.NewKinesisFromClient(fake)
+	if err := client.Create(ctx, "test-stream", awsService.WithShardCount(2)); err != nil {
+		t.Fatal(err)
+	}
+	openShards := func() []types.Shard {
+		t.Helper()
+		shards, err := client.OpenShards(ctx, "test-stream")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return shards
+	}
+
+	// Ensure scales the stream to the shard count of the options.
+	if drift, err := client.Drift(ctx, "test-stream", awsService.WithShardCount(4)); err != nil || drift.Action != awsService.ActionUpdate {
+		t.Fatalf("expected the shard count to drift, got %+v, %v", drift, err)
+	}
+	if err := client.Ensure(ctx, "test-stream", awsService.WithShardCount(4)); err != nil {
+		t.Fatal(err)
+	}
+	shards := openShards()
+	if len(shards) != 4 {
+		t.Fatalf("expected 4 open shards, got %d", len(shards))
+	}
+	for i, shard := range shards {
+		if parent := aws.ToString(shard.ParentShardId); parent != fmt.Sprintf("shardId-%012d", i/2) || shard.AdjacentParentShardId != nil {
+			t.Errorf("unexpected parents of %s: %s, %v", aws.ToString(shard.ShardId), parent, shard.AdjacentParentShardId)
+		}
+	}
+	if err := client.UpdateShardCount(ctx, "test-stream", 9); !errors.Is(err, awsService.ErrValidation) {
+		t.Errorf("expected more than double the shards to be invalid, got %v", err)
+	}
+
+	// Ensure scales beyond double and below half the open shards in several updates.
+	for _, count := range []int{9, 1, 4} {
+		if err := client.Ensure(ctx, "test-stream", awsService.WithShardCount(int32(count))); err != nil {
+			t.Fatalf("scaling to %d shards: %v", count, err)
+		}
+		if shards = openShards(); len(shards) != count {
+			t.Fatalf("expected %d open shards, got %d", count, len(shards))
+		}
+	}
+
+	// Splitting and merging the first shard keeps the open shards adjacent.
+	if err := client.SplitShard(ctx, "test-stream", aws.ToString(shards[0].ShardId)); err != nil {
+		t.Fatal(err)
+	}
+	shards = openShards()
+	if len(shards) != 5 {
+		t.Fatalf("expected 5 open shards, got %d", len(shards))
+	}
+	if err := client.MergeShards(ctx, "test-stream", aws.ToString(shards[0].ShardId), aws.ToString(shards[1].ShardId)); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.MergeShards(ctx, "test-stream", aws.ToString(shards[2].ShardId), aws.ToString(shards[4].ShardId)); err == nil {
+		t.Error("expected shards that are not adjacent not to be merged")
+	}
+	if drift, err := client.Drift(ctx, "test-stream", awsService.WithShardCount(4)); err != nil || drift.Action != awsService.ActionNone {
+		t.Errorf("expected 4 open shards, got %+v, %v", drift, err)
+	}
+}
+
+func TestKinesis_OnDemand(t *testing.T) {
+	ctx := context.Background()
+	fake := NewKinesis()
+	client := awsService.NewKinesisFromClient(fake)
+	if err := client.Ensure(ctx, "test-stream", awsService.WithOnDemand()); err != nil {
+		t.Fatal(err)
+	}
+
+	shards, err := client.OpenShards(ctx, "test-stream")
+	if err != nil || len(shards) != 4 {
+		t.Fatalf("expected 4 open shards, got %d, %v", len(shards), err)
+	}
+	if drift, err := client.Drift(ctx, "test-stream", awsService.WithOnDemand(), awsService.WithShardCount(2)); err != nil || drift.Action != awsService.ActionNone {
+		t.Errorf("expected the shard count of an on-demand stream to be ignored, got %+v, %v", drift, err)
+	}
+	if err := client.UpdateShardCount(ctx, "test-stream", 2); !errors.Is(err, awsService.ErrValidation) {
+		t.Errorf("expected an on-demand stream not to be scaled, got %v", err)
+	}
+}
+
+func TestKinesis_Settings(t *testing.T) {
+	ctx := context.Background()
+	fake := NewKinesis()
+	client := awsService.NewKinesisFromClient(fake)
+	week := 7 * 24 * time.Hour
+	// Ensure creates the missing stream, which applies the retention period and the
+	// encryption once the stream is active.
+	err := client.Ensure(ctx, "test-stream", awsService.WithShardCount(2), awsService.WithRetention(week), awsService.WithEncryption("alias/aws/kinesis"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := client.Describe(ctx, "test-stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stream.Mode != types.StreamModeProvisioned || stream.OpenShardCount != 2 || stream.Retention != week || stream.KMSKeyID != "alias/aws/kinesis" {
+		t.Errorf("unexpected created stream: %+v", stream)
+	}
+
+	// Switching to on-demand keeps the shards, and the retention period and the encryption
+	// are kept without their options.
+	if err := client.Ensure(ctx, "test-stream", awsService.WithOnDemand()); err != nil {
+		t.Fatal(err)
+	}
+	stream, err = client.Describe(ctx, "test-stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stream.Mode != types.StreamModeOnDemand || stream.OpenShardCount != 2 || stream.Retention != week || stream.KMSKeyID != "alias/aws/kinesis" {
+		t.Errorf("unexpected updated stream: %+v", stream)
+	}
+	if drift, err := client.Drift(ctx, "test-stream", awsService.WithOnDemand()); err != nil || drift.Action != awsService.ActionNone {
+		t.Errorf("expected no drift after the update, got %+v, %v", drift, err)
+	}
+
+	if err := client.Ensure(ctx, "test-stream", awsService.WithOnDemand(), awsService.WithRetention(24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.StopEncryption(ctx, "test-stream", "alias/aws/kinesis"); err != nil {
+		t.Fatal(err)
+	}
+	stream, err = client.Describe(ctx, "test-stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stream.Retention != 24*time.Hour || stream.KMSKeyID != "" {
+		t.Errorf("expected the retention period to be decreased and the encryption to be stopped, got %+v", stream)
+	}
+
+	if err := client.DecreaseRetention(ctx, "test-stream", 12*time.Hour); !errors.Is(err, awsService.ErrValidation) {
+		t.Errorf("expected a retention period below 24 hours to be rejected, got %v", err)
+	}
+	if err := client.StopEncryption(ctx, "test-stream", "alias/aws/kinesis"); !errors.Is(err, awsService.ErrValidation) {
+		t.Errorf("expected an unencrypted stream not to stop its encryption, got %v", err)
+	}
+}
+
+func TestKinesis_Producer(t *testing.T) {
+	ctx := context.Background()
+	fake := NewKinesis()
+	client := awsService.NewKinesisFromClient(fake)
+	if err := client.Create(ctx, "test-stream", awsService.WithShardCount(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	producer := client.NewProducer("test-stream", awsService.ProducerConfig{MaxRecords: 100})
+	shards := map[string]int{}
+	for i := 0; i < 250; i++ {
+		err := producer.Put(ctx, fmt.Sprint(i), []byte(fmt.Sprint(i)), func(d awsService.Delivery) {
+			if d.Err == nil {
+				shards[d.ShardID]++
+			}
+		})
+		if err != nil {
+			t.Fatalf("putting record %d: %v", i, err)
+		}
+	}
+	if err := producer.Close(ctx); err != nil {
+		t.Fatalf("closing the producer: %v", err)
+	}
+
+	if records := fake.Records("test-stream"); len(records) != 250 {
+		t.Errorf("expected 250 records, got %d", len(records))
+	}
+	if len(shards) != 2 || shards["shardId-000000000000"]+shards["shardId-000000000001"] != 250 {
+		t.Errorf("expected the records to be delivered to both shards, got %v", shards)
+	}
+}
+
+// testConsumerConfig reads and balances the shards quickly.
+var testConsumerConfig = awsService.ConsumerConfig{LeaseTable: "test-leases", LeaseDuration: 150 * time.Millisecond, PollInterval: 5 * time.Millisecond}
+
+// testLease is an item of the lease table of a consumer.
+type testLease struct {
+	ShardID    string `dynamodbav:"id"`
+	Owner      string `dynamodbav:"owner"`
+	Checkpoint string `dynamodbav:"checkpoint"`
+}
+
+// leases returns the leases of the test lease table by their shard.
+func leases(t *testing.T, fake *DynamoDB) map[string]testLease {
+	leases := map[string]testLease{}
+	for _, item := range fake.Items(testConsumerConfig.LeaseTable) {
+		var l testLease
+		if err := attributevalue.UnmarshalMap(item, &l); err != nil {
+			t.Fatal(err)
+		}
+		leases[l.ShardID] = l
+	}
+	return leases
+}
+
+// eventually fails the test if the condition does not hold within a few seconds.
+func eventually(t *testing.T, message string, condition func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !condition(); time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal(message)
+		}
+	}
+}
+
+// recorder records the partition keys of the handled records in the order they were
+// handled.
+type recorder struct {
+	mu      sync.Mutex
+	keys    []string
+	batches []string
+}
+
+func (r *recorder) handle(_ context.Context, batch awsService.Batch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.batches = append(r.batches, batch.ShardID)
+	for _, record := range batch.Records {
+		r.keys = append(r.keys, aws.ToString(record.PartitionKey))
+	}
+	return nil
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.keys)
+}
+
+// runConsumer runs the consumer until the returned function is called, which returns the
+// error of Run.
+func runConsumer(consumer *awsService.Consumer, handler func(context.Context, awsService.Batch) error) func() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- consumer.Run(ctx, handler) }()
+	return func() error {
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			return err
+		}
+		return nil
+	}
+}
+
+func TestKinesis_Consumer(t *testing.T) {
+	ctx := context.Background()
+	fake, dynamoDB := NewKinesis(), NewDynamoDB()
+	client := awsService.NewKinesisFromClient(fake)
+	if err := client.Create(ctx, "test-stream", awsService.WithShardCount(2)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		if err := client.PutRecord(ctx, "test-stream", fmt.Sprint(i), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	first := &recorder{}
+	stop := runConsumer(client.NewConsumer("test-stream", awsService.NewDynamoDBFromClient(dynamoDB), testConsumerConfig), first.handle)
+	eventually(t, "expected the records of both shards to be handled", func() bool { return first.count() == 20 })
+	last := map[string]string{}
+	for _, record := range fake.Records("test-stream") {
+		last[aws.ToString(record.PartitionKey)] = aws.ToString(record.SequenceNumber)
+	}
+	eventually(t, "expected the last records to be checkpointed", func() bool {
+		checkpoints := 0
+		for _, l := range leases(t, dynamoDB) {
+			for _, sequenceNumber := range last {
+				if l.Checkpoint == sequenceNumber {
+					checkpoints++
+				}
+			}
+		}
+		return checkpoints == 2
+	})
+	if err := stop(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for id, l := range leases(t, dynamoDB) {
+		if l.Owner != "" {
+			t.Errorf("expected the lease of shard %s to be released, got owner %s", id, l.Owner)
+		}
+	}
+
+	// A restarted consumer continues after the checkpoints.
+	if err := client.PutRecord(ctx, "test-stream", "20", nil); err != nil {
+		t.Fatal(err)
+	}
+	second := &recorder{}
+	stop = runConsumer(client.NewConsumer("test-stream", awsService.NewDynamoDBFromClient(dynamoDB), testConsumerConfig), second.handle)
+	eventually(t, "expected the new record to be handled", func() bool { return second.count() == 1 })
+	if err := stop(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if second.keys[0] != "20" {
+		t.Errorf("expected only the new record, got %v", second.keys)
+	}
+}
+
+func TestKinesis_Consumer_Resharding(t *testing.T) {
+	ctx := context.Background()
+	fake, dynamoDB := NewKinesis(), NewDynamoDB()
+	client := awsService.NewKinesisFromClient(fake)
+	if err := client.Create(ctx, "test-stream", awsService.WithShardCount(2)); err != nil {
+		t.Fatal(err)
+	}
+	put := func(from, to int) {
+		for i := from; i < to; i++ {
+			if err := client.PutRecord(ctx, "test-stream", fmt.Sprint(i), nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// The first shard is split and its children are merged again.
+	put(0, 20)
+	description, err := fake.DescribeStream(ctx, &kinesis.DescribeStreamInput{StreamName: aws.String("test-stream")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	end, _ := new(big.Int).SetString(aws.ToString(description.StreamDescription.Shards[0].HashKeyRange.EndingHashKey), 10)
+	if _, err := fake.SplitShard(ctx, &kinesis.SplitShardInput{
+		StreamName:         aws.String("test-stream"),
+		ShardToSplit:       aws.String("shardId-000000000000"),
+		NewStartingHashKey: aws.String(end.Rsh(end, 1).String()),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	put(20, 40)
+	if _, err := fake.MergeShards(ctx, &kinesis.MergeShardsInput{
+		StreamName:           aws.String("test-stream"),
+		ShardToMerge:         aws.String("shardId-000000000002"),
+		AdjacentShardToMerge: aws.String("shardId-000000000003"),
+	}); err != nil {
+		t.Fatal(err)
+	}
+	put(40, 60)
+
+	r := &recorder{}
+	stop := runConsumer(client.NewConsumer("test-stream", awsService.NewDynamoDBFromClient(dynamoDB), testConsumerConfig), r.handle)
+	eventually(t, "expected the records of every shard to be handled", func() bool { return r.count() == 60 })
+	eventually(t, "expected the closed shards to end", func() bool {
+		ended := 0
+		for _, l := range leases(t, dynamoDB) {
+			if l.Checkpoint == "SHARD_END" {
+				ended++
+			}
+		}
+		return ended == 3
+	})
+	if err := stop(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Every child is read after its parents.
+	order := map[string]int{}
+	for i, shard := range r.batches {
+		if _, ok := order[shard]; !ok {
+			order[shard] = i
+		}
+	}
+	for _, edge := range [][2]string{{"shardId-000000000000", "shardId-000000000002"}, {"shardId-000000000002", "shardId-000000000004"}, {"shardId-000000000003", "shardId-000000000004"}} {
+		parent, ok := order[edge[0]]
+		child, childOK := order[edge[1]]
+		if !ok || !childOK || parent > child {
+			t.Errorf("expected %s to be read before %s, got %v", edge[0], edge[1], r.batches)
+		}
+	}
+}
+
+func TestKinesis_Consumer_Workers(t *testing.T) {
+	ctx := context.Background()
+	fake, dynamoDB := NewKinesis(), NewDynamoDB()
+	client := awsService.NewKinesisFromClient(fake)
+	if err := client.Create(ctx, "test-stream", awsService.WithShardCount(4)); err != nil {
+		t.Fatal(err)
+	}
+	handler := func(context.Context, awsService.Batch) error { return nil }
+	owners := func() map[string]int {
+		owners := map[string]int{}
+		for _, l := range leases(t, dynamoDB) {
+			owners[l.Owner]++
+		}
+		return owners
+	}
+
+	first := client.NewConsumer("test-stream", awsService.NewDynamoDBFromClient(dynamoDB), testConsumerConfig)
+	stopFirst := runConsumer(first, handler)
+	defer stopFirst()
+	eventually(t, "expected the first worker to take every shard", func() bool { return owners()[first.WorkerID()] == 4 })
+
+	second := client.NewConsumer("test-stream", awsService.NewDynamoDBFromClient(dynamoDB), testConsumerConfig)
+	stopSecond := runConsumer(second, handler)
+	eventually(t, "expected the workers to share the shards", func() bool {
+		o := owners()
+		return o[first.WorkerID()] == 2 && o[second.WorkerID()] == 2
+	})
+
+	if err := stopSecond(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	eventually(t, "expected the first worker to take the released shards", func() bool { return owners()[first.WorkerID()] == 4 })
+}
+
+func TestKinesis_Consumer_HandlerFails(t *testing.T) {
+	ctx := context.Background()
+	fake, dynamoDB := NewKinesis(), NewDynamoDB()
+	client := awsService.NewKinesisFromClient(fake)
+	if err := client.Create(ctx, "test-stream"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.PutRecord(ctx, "test-stream", "1", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	failure := errors.New("failed")
+	consumer := client.NewConsumer("test-stream", awsService.NewDynamoDBFromClient(dynamoDB), testConsumerConfig)
+	err := consumer.Run(ctx, func(context.Context, awsService.Batch) error { return failure })
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected the error of the handler, got %v", err)
+	}
+	if l := leases(t, dynamoDB)["shardId-000000000000"]; l.Checkpoint != "" || l.Owner != "" {
+		t.Errorf("expected the failed batch not to be checkpointed and the lease to be released, got %+v", l)
+	}
+}
+
+func TestKinesis_Consumer_FanOut(t *testing.T) {
+	// Subscriptions end quickly, so the consumers renew them while they read.
+	duration := subscriptionDuration
+	subscriptionDuration = 20 * time.Millisecond
+	t.Cleanup(func() { subscriptionDuration = duration })
+
+	ctx := context.Background()
+	fake, dynamoDB := NewKinesis(), NewDynamoDB()
+	client := awsService.NewKinesisFromClient(fake)
+	if err := client.Create(ctx, "test-stream", awsService.WithShardCount(2)); err != nil {
+		t.Fatal(err)
+	}
+	put := func(from, to int) {
+		for i := from; i < to; i++ {
+			if err := client.PutRecord(ctx, "test-stream", fmt.Sprint(i), nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	put(0, 20)
+
+	// Every registered consumer reads every record.
+	recorders := map[string]*recorder{}
+	stops := map[string]func() error{}
+	for _, name := range []string{"broadcaster", "tap"} {
+		arn, err := client.EnsureConsumer(ctx, "test-stream", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again, err := client.EnsureConsumer(ctx, "test-stream", name); err != nil || again != arn {
+			t.Fatalf("expected the consumer to be registered once, got %s, %v", again, err)
+		}
+		if err := client.WaitUntilConsumerActive(ctx, "test-stream", name, time.Second); err != nil {
+			t.Fatal(err)
+		}
+
+		config := testConsumerConfig
+		config.LeaseTable = name + "-leases"
+		config.ConsumerARN = arn
+		recorders[name] = &recorder{}
+		stops[name] = runConsumer(client.NewConsumer("test-stream", awsService.NewDynamoDBFromClient(dynamoDB), config), recorders[name].handle)
+	}
+	for name, r := range recorders {
+		eventually(t, fmt.Sprintf("expected %s to handle the records", name), func() bool { return r.count() == 20 })
+	}
+
+	// The pushed records of a split shard are followed by the records of its children,
+	// also after the subscriptions were renewed.
+	time.Sleep(50 * time.Millisecond)
+	put(20, 30)
+	if err := client.SplitShard(ctx, "test-stream", "shardId-000000000000"); err != nil {
+		t.Fatal(err)
+	}
+	put(30, 50)
+	for name, r := range recorders {
+		eventually(t, fmt.Sprintf("expected %s to handle the records of the children", name), func() bool { return r.count() == 50 })
+	}
+
+	// A deregistered consumer fails once its subscriptions end.
+	if err := client.DeregisterConsumer(ctx, "test-stream", "tap"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := stops["tap"](); !awsService.IsNotFound(err) {
+		t.Errorf("expected the deregistered consumer to fail, got %v", err)
+	}
+	if err := stops["broadcaster"](); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	r := recorders["broadcaster"]
+	handled := map[string]int{}
+	for _, key := range r.keys {
+		handled[key]++
+	}
+	for i := 0; i < 50; i++ {
+		if handled[fmt.Sprint(i)] != 1 {
+			t.Errorf("expected record %d to be handled once, got %d", i, handled[fmt.Sprint(i)])
+		}
+	}
+	order := map[string]int{}
+	for i, shard := range r.batches {
+		if _, ok := order[shard]; !ok {
+			order[shard] = i
+		}
+	}
+	for _, child := range []string{"shardId-000000000002", "shardId-000000000003"} {
+		if parent, ok := order["shardId-000000000000"]; !ok || order[child] < parent {
+			t.Errorf("expected %s to be read after its parent, got %v", child, r.batches)
+		}
+	}
+}
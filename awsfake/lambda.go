@@ -0,0 +1,337 @@
+package awsfake
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// Lambda is an in-memory fake of the Lambda API, which stores the configuration of its
+// functions and event source mappings. It does not run the functions.
+type Lambda struct {
+	mu        sync.Mutex
+	code      *S3
+	functions map[string]*types.FunctionConfiguration
+	mappings  map[string]*types.EventSourceMappingConfiguration
+	tags      map[string]map[string]string
+	uuid      int
+}
+
+// NewLambda returns a fake of the Lambda API without functions. The code of functions
+// that is uploaded to S3 is read from the given fake, so the code hashes of the functions
+// match the hashes of the uploaded zip files. Without a fake, the hash of code in S3 is
+// the hash of its bucket and key.
+func NewLambda(code *S3) *Lambda {
+	return &Lambda{
+		code:      code,
+		functions: map[string]*types.FunctionConfiguration{},
+		mappings:  map[string]*types.EventSourceMappingConfiguration{},
+		tags:      map[string]map[string]string{},
+	}
+}
+
+func (f *Lambda) CreateFunction(_ context.Context, params *lambda.CreateFunctionInput, _ ...func(*lambda.Options)) (*lambda.CreateFunctionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.FunctionName)
+	if _, ok := f.functions[name]; ok {
+		return nil, operationError("CreateFunction", "ResourceConflictException", "function already exist: %s", name)
+	}
+	if params.Code == nil {
+		return nil, apiError("InvalidParameterValueException", "function %s has no code", name)
+	}
+	if aws.ToString(params.Role) == "" {
+		return nil, apiError("InvalidParameterValueException", "function %s has no role", name)
+	}
+	hash, err := f.codeSha256(params.Code.ZipFile, params.Code.S3Bucket, params.Code.S3Key)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &types.FunctionConfiguration{
+		FunctionName:     aws.String(name),
+		FunctionArn:      aws.String(arn("lambda", "function:"+name)),
+		Handler:          params.Handler,
+		Runtime:          params.Runtime,
+		Role:             params.Role,
+		Timeout:          aws.Int32(aws.ToInt32(params.Timeout)),
+		MemorySize:       aws.Int32(aws.ToInt32(params.MemorySize)),
+		Environment:      environment(params.Environment),
+		CodeSha256:       aws.String(hash),
+		State:            types.StateActive,
+		LastUpdateStatus: types.LastUpdateStatusSuccessful,
+		LastModified:     aws.String(time.Now().Format(time.RFC3339)),
+	}
+	if *config.Timeout == 0 {
+		config.Timeout = aws.Int32(3)
+	}
+	if *config.MemorySize == 0 {
+		config.MemorySize = aws.Int32(128)
+	}
+
+	f.functions[name] = config
+	f.tags[name] = params.Tags
+	return &lambda.CreateFunctionOutput{
+		FunctionName:     config.FunctionName,
+		FunctionArn:      config.FunctionArn,
+		CodeSha256:       config.CodeSha256,
+		State:            config.State,
+		LastUpdateStatus: config.LastUpdateStatus,
+	}, nil
+}
+
+func (f *Lambda) GetFunction(_ context.Context, params *lambda.GetFunctionInput, _ ...func(*lambda.Options)) (*lambda.GetFunctionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	config, err := f.function(aws.ToString(params.FunctionName))
+	if err != nil {
+		return nil, err
+	}
+
+	copied := *config
+	return &lambda.GetFunctionOutput{Configuration: &copied, Tags: f.tags[aws.ToString(config.FunctionName)]}, nil
+}
+
+func (f *Lambda) DeleteFunction(_ context.Context, params *lambda.DeleteFunctionInput, _ ...func(*lambda.Options)) (*lambda.DeleteFunctionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	config, err := f.function(aws.ToString(params.FunctionName))
+	if err != nil {
+		return nil, err
+	}
+
+	delete(f.functions, aws.ToString(config.FunctionName))
+	delete(f.tags, aws.ToString(config.FunctionName))
+	return &lambda.DeleteFunctionOutput{}, nil
+}
+
+func (f *Lambda) UpdateFunctionCode(_ context.Context, params *lambda.UpdateFunctionCodeInput, _ ...func(*lambda.Options)) (*lambda.UpdateFunctionCodeOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	config, err := f.function(aws.ToString(params.FunctionName))
+	if err != nil {
+		return nil, err
+	}
+	hash, err := f.codeSha256(params.ZipFile, params.S3Bucket, params.S3Key)
+	if err != nil {
+		return nil, err
+	}
+
+	config.CodeSha256 = aws.String(hash)
+	config.LastModified = aws.String(time.Now().Format(time.RFC3339))
+	return &lambda.UpdateFunctionCodeOutput{
+		FunctionName:     config.FunctionName,
+		FunctionArn:      config.FunctionArn,
+		CodeSha256:       config.CodeSha256,
+		State:            config.State,
+		LastUpdateStatus: config.LastUpdateStatus,
+	}, nil
+}
+
+// UpdateFunctionConfiguration updates the given settings of the function and keeps the
+// others.
+func (f *Lambda) UpdateFunctionConfiguration(_ context.Context, params *lambda.UpdateFunctionConfigurationInput, _ ...func(*lambda.Options)) (*lambda.UpdateFunctionConfigurationOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	config, err := f.function(aws.ToString(params.FunctionName))
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Handler != nil {
+		config.Handler = params.Handler
+	}
+	if params.Runtime != "" {
+		config.Runtime = params.Runtime
+	}
+	if params.Role != nil {
+		config.Role = params.Role
+	}
+	if params.Timeout != nil {
+		config.Timeout = params.Timeout
+	}
+	if params.MemorySize != nil {
+		config.MemorySize = params.MemorySize
+	}
+	if params.Environment != nil {
+		config.Environment = environment(params.Environment)
+	}
+	config.LastModified = aws.String(time.Now().Format(time.RFC3339))
+
+	return &lambda.UpdateFunctionConfigurationOutput{
+		FunctionName:     config.FunctionName,
+		FunctionArn:      config.FunctionArn,
+		CodeSha256:       config.CodeSha256,
+		State:            config.State,
+		LastUpdateStatus: config.LastUpdateStatus,
+	}, nil
+}
+
+func (f *Lambda) CreateEventSourceMapping(_ context.Context, params *lambda.CreateEventSourceMappingInput, _ ...func(*lambda.Options)) (*lambda.CreateEventSourceMappingOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	config, err := f.function(aws.ToString(params.FunctionName))
+	if err != nil {
+		return nil, err
+	}
+	for _, mapping := range f.mappings {
+		if aws.ToString(mapping.FunctionArn) == aws.ToString(config.FunctionArn) && aws.ToString(mapping.EventSourceArn) == aws.ToString(params.EventSourceArn) {
+			return nil, operationError("CreateEventSourceMapping", "ResourceConflictException", "event source mapping %s already exists", aws.ToString(mapping.UUID))
+		}
+	}
+
+	f.uuid++
+	mapping := &types.EventSourceMappingConfiguration{
+		UUID:             aws.String(fmt.Sprintf("00000000-0000-0000-0000-%012d", f.uuid)),
+		FunctionArn:      config.FunctionArn,
+		EventSourceArn:   params.EventSourceArn,
+		BatchSize:        params.BatchSize,
+		StartingPosition: params.StartingPosition,
+		State:            aws.String("Enabled"),
+		LastModified:     aws.Time(time.Now()),
+	}
+	f.mappings[*mapping.UUID] = mapping
+
+	return &lambda.CreateEventSourceMappingOutput{
+		UUID:           mapping.UUID,
+		FunctionArn:    mapping.FunctionArn,
+		EventSourceArn: mapping.EventSourceArn,
+		BatchSize:      mapping.BatchSize,
+		State:          mapping.State,
+	}, nil
+}
+
+// ListEventSourceMappings lists the mappings of the function and the event source in the
+// order they were created.
+func (f *Lambda) ListEventSourceMappings(_ context.Context, params *lambda.ListEventSourceMappingsInput, _ ...func(*lambda.Options)) (*lambda.ListEventSourceMappingsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	functionARN := aws.ToString(params.FunctionName)
+	if functionARN != "" && !strings.HasPrefix(functionARN, "arn:") {
+		functionARN = arn("lambda", "function:"+functionARN)
+	}
+
+	output := &lambda.ListEventSourceMappingsOutput{}
+	for _, uuid := range sortedKeys(f.mappings) {
+		mapping := f.mappings[uuid]
+		if functionARN != "" && aws.ToString(mapping.FunctionArn) != functionARN {
+			continue
+		}
+		if params.EventSourceArn != nil && aws.ToString(mapping.EventSourceArn) != aws.ToString(params.EventSourceArn) {
+			continue
+		}
+		output.EventSourceMappings = append(output.EventSourceMappings, *mapping)
+	}
+	return output, nil
+}
+
+func (f *Lambda) UpdateEventSourceMapping(_ context.Context, params *lambda.UpdateEventSourceMappingInput, _ ...func(*lambda.Options)) (*lambda.UpdateEventSourceMappingOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	mapping, err := f.mapping(aws.ToString(params.UUID))
+	if err != nil {
+		return nil, err
+	}
+
+	if params.BatchSize != nil {
+		mapping.BatchSize = params.BatchSize
+	}
+	if params.Enabled != nil {
+		mapping.State = aws.String("Enabled")
+		if !*params.Enabled {
+			mapping.State = aws.String("Disabled")
+		}
+	}
+	mapping.LastModified = aws.Time(time.Now())
+
+	return &lambda.UpdateEventSourceMappingOutput{
+		UUID:           mapping.UUID,
+		FunctionArn:    mapping.FunctionArn,
+		EventSourceArn: mapping.EventSourceArn,
+		BatchSize:      mapping.BatchSize,
+		State:          mapping.State,
+	}, nil
+}
+
+func (f *Lambda) DeleteEventSourceMapping(_ context.Context, params *lambda.DeleteEventSourceMappingInput, _ ...func(*lambda.Options)) (*lambda.DeleteEventSourceMappingOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	mapping, err := f.mapping(aws.ToString(params.UUID))
+	if err != nil {
+		return nil, err
+	}
+
+	delete(f.mappings, aws.ToString(params.UUID))
+	return &lambda.DeleteEventSourceMappingOutput{
+		UUID:           mapping.UUID,
+		FunctionArn:    mapping.FunctionArn,
+		EventSourceArn: mapping.EventSourceArn,
+		BatchSize:      mapping.BatchSize,
+		State:          aws.String("Deleting"),
+	}, nil
+}
+
+// function returns the function with the given name or ARN.
+func (f *Lambda) function(name string) (*types.FunctionConfiguration, error) {
+	if i := strings.LastIndex(name, ":function:"); i >= 0 {
+		name = name[i+len(":function:"):]
+	}
+	config, ok := f.functions[name]
+	if !ok {
+		return nil, apiError("ResourceNotFoundException", "function not found: %s", name)
+	}
+	return config, nil
+}
+
+// mapping returns the event source mapping with the given UUID.
+func (f *Lambda) mapping(uuid string) (*types.EventSourceMappingConfiguration, error) {
+	mapping, ok := f.mappings[uuid]
+	if !ok {
+		return nil, apiError("ResourceNotFoundException", "event source mapping not found: %s", uuid)
+	}
+	return mapping, nil
+}
+
+// codeSha256 returns the hash of the given zip file or the S3 object with the given bucket
+// and key in the format Lambda reports it.
+func (f *Lambda) codeSha256(zipFile []byte, bucket, key *string) (string, error) {
+	code := zipFile
+	if code == nil {
+		if f.code == nil {
+			code = []byte(aws.ToString(bucket) + "/" + aws.ToString(key))
+		} else {
+			var ok bool
+			if code, ok = f.code.Object(aws.ToString(bucket), aws.ToString(key)); !ok {
+				return "", apiError("InvalidParameterValueException", "error occurred while GetObject. S3 Error Code: NoSuchKey")
+			}
+		}
+	}
+
+	hash := sha256.Sum256(code)
+	return base64.StdEncoding.EncodeToString(hash[:]), nil
+}
+
+// environment returns the response of the given environment variables.
+func environment(env *types.Environment) *types.EnvironmentResponse {
+	if env == nil {
+		return nil
+	}
+	return &types.EnvironmentResponse{Variables: env.Variables}
+}
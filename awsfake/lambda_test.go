@@ -0,0 +1,68 @@
+package awsfake
+
+import (
+	"context"
+	"testing"
+
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+)
+
+func TestLambda(t *testing.T) {
+	ctx := context.Background()
+	code := NewS3()
+	s3 := awsService.NewS3FromClient(code)
+	client := awsService.NewLambdaFromClient(NewLambda(code))
+	config := awsService.FunctionConfig{
+		RoleARN:     "arn:aws:iam::000000000000:role/lambda-role",
+		Environment: map[string]string{"ENVIRONMENT": "test"},
+	}
+
+	if err := s3.CreateBucket(ctx, "code"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s3.PutObject(ctx, "code", "function.zip", []byte("v1")); err != nil {
+		t.Fatal(err)
+	}
+
+	arn, err := client.EnsureGo(ctx, "test-function", "code", "function.zip", []byte("v1"), config)
+	if err != nil {
+		t.Fatalf("ensuring the function: %v", err)
+	}
+	if err := client.WaitUntilActive(ctx, "test-function", 0); err != nil {
+		t.Fatalf("waiting for the function: %v", err)
+	}
+	if _, drift, err := client.DriftGo(ctx, "test-function", []byte("v1"), config); err != nil || drift.Action != awsService.ActionNone {
+		t.Errorf("expected no drift, got %+v, %v", drift, err)
+	}
+
+	// The code hash of the function is the hash of the uploaded object.
+	if err := s3.PutObject(ctx, "code", "function.zip", []byte("v2")); err != nil {
+		t.Fatal(err)
+	}
+	if _, action, err := client.PlanGo(ctx, "test-function", []byte("v2"), config); err != nil || action != awsService.ActionUpdate {
+		t.Errorf("expected to update the code, got %q, %v", action, err)
+	}
+	if _, err := client.EnsureGo(ctx, "test-function", "code", "function.zip", []byte("v2"), config); err != nil {
+		t.Fatalf("updating the function: %v", err)
+	}
+	if _, action, err := client.PlanGo(ctx, "test-function", []byte("v2"), config); err != nil || action != awsService.ActionNone {
+		t.Errorf("expected the updated code, got %q, %v", action, err)
+	}
+
+	streamARN := "arn:aws:kinesis:us-east-1:000000000000:stream/test-stream"
+	for i := 0; i < 2; i++ {
+		if err := client.EnsureBoundToService(ctx, "test-function", streamARN); err != nil {
+			t.Fatalf("binding the function: %v", err)
+		}
+	}
+	if n, err := client.UnbindFromService(ctx, arn, streamARN); err != nil || n != 1 {
+		t.Errorf("expected 1 deleted mapping, got %d, %v", n, err)
+	}
+
+	if err := client.Delete(ctx, "test-function"); err != nil {
+		t.Fatalf("deleting the function: %v", err)
+	}
+	if _, action, err := client.PlanGo(ctx, "test-function", nil, config); err != nil || action != awsService.ActionCreate {
+		t.Errorf("expected to create the deleted function, got %q, %v", action, err)
+	}
+}
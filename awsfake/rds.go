@@ -0,0 +1,214 @@
+package awsfake
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rds"
+	"github.com/aws/aws-sdk-go-v2/service/rds/types"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// RDS is an in-memory fake of the RDS API, which stores the descriptions of its database
+// clusters. The clusters are available as soon as they are created and the fake does not
+// run them, see RDSData for their databases.
+type RDS struct {
+	mu       sync.Mutex
+	clusters map[string]*types.DBCluster
+}
+
+// NewRDS returns a fake of the RDS API without database clusters.
+func NewRDS() *RDS {
+	return &RDS{clusters: map[string]*types.DBCluster{}}
+}
+
+func (f *RDS) CreateDBCluster(_ context.Context, params *rds.CreateDBClusterInput, _ ...func(*rds.Options)) (*rds.CreateDBClusterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	identifier := aws.ToString(params.DBClusterIdentifier)
+	if _, ok := f.clusters[identifier]; ok {
+		return nil, apiError("DBClusterAlreadyExistsFault", "db cluster %s already exists", identifier)
+	}
+	if aws.ToString(params.Engine) == "" {
+		return nil, apiError("InvalidParameterValue", "db cluster %s has no engine", identifier)
+	}
+
+	cluster := &types.DBCluster{
+		DBClusterIdentifier: aws.String(identifier),
+		DBClusterArn:        aws.String(arn("rds", "cluster:"+identifier)),
+		Engine:              params.Engine,
+		DatabaseName:        params.DatabaseName,
+		Endpoint:            aws.String(fmt.Sprintf("%s.cluster-fake.%s.rds.amazonaws.com", identifier, region)),
+		Status:              aws.String("available"),
+		ClusterCreateTime:   aws.Time(time.Now()),
+	}
+	f.clusters[identifier] = cluster
+
+	copied := *cluster
+	return &rds.CreateDBClusterOutput{DBCluster: &copied}, nil
+}
+
+// DescribeDBClusters describes the cluster with the identifier or every cluster in the
+// order of their identifiers if the input has no identifier.
+func (f *RDS) DescribeDBClusters(_ context.Context, params *rds.DescribeDBClustersInput, _ ...func(*rds.Options)) (*rds.DescribeDBClustersOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	output := &rds.DescribeDBClustersOutput{}
+	if identifier := aws.ToString(params.DBClusterIdentifier); identifier != "" {
+		cluster, err := f.cluster(identifier)
+		if err != nil {
+			return nil, err
+		}
+		output.DBClusters = append(output.DBClusters, *cluster)
+		return output, nil
+	}
+
+	for _, identifier := range sortedKeys(f.clusters) {
+		output.DBClusters = append(output.DBClusters, *f.clusters[identifier])
+	}
+	return output, nil
+}
+
+func (f *RDS) DeleteDBCluster(_ context.Context, params *rds.DeleteDBClusterInput, _ ...func(*rds.Options)) (*rds.DeleteDBClusterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cluster, err := f.cluster(aws.ToString(params.DBClusterIdentifier))
+	if err != nil {
+		return nil, err
+	}
+
+	delete(f.clusters, aws.ToString(params.DBClusterIdentifier))
+	copied := *cluster
+	copied.Status = aws.String("deleting")
+	return &rds.DeleteDBClusterOutput{DBCluster: &copied}, nil
+}
+
+// cluster returns the cluster with the given identifier.
+func (f *RDS) cluster(identifier string) (*types.DBCluster, error) {
+	cluster, ok := f.clusters[identifier]
+	if !ok {
+		return nil, apiError("DBClusterNotFoundFault", "db cluster %s not found", identifier)
+	}
+	return cluster, nil
+}
+
+// SecretsManager is an in-memory fake of the Secrets Manager API, which stores the string
+// values of its secrets. Secrets are identified by their name or ARN.
+type SecretsManager struct {
+	mu      sync.Mutex
+	secrets map[string]*secret
+}
+
+// secret is a secret and the version of its value.
+type secret struct {
+	arn     string
+	value   string
+	version int
+}
+
+// NewSecretsManager returns a fake of the Secrets Manager API without secrets.
+func NewSecretsManager() *SecretsManager {
+	return &SecretsManager{secrets: map[string]*secret{}}
+}
+
+func (f *SecretsManager) CreateSecret(_ context.Context, params *secretsmanager.CreateSecretInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.CreateSecretOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.Name)
+	if _, ok := f.secrets[name]; ok {
+		return nil, apiError("ResourceExistsException", "the secret %s already exists", name)
+	}
+
+	s := &secret{
+		arn:     arn("secretsmanager", "secret:"+name+"-fake"),
+		value:   aws.ToString(params.SecretString),
+		version: 1,
+	}
+	f.secrets[name] = s
+	return &secretsmanager.CreateSecretOutput{
+		Name:      aws.String(name),
+		ARN:       aws.String(s.arn),
+		VersionId: aws.String(s.versionID()),
+	}, nil
+}
+
+func (f *SecretsManager) GetSecretValue(_ context.Context, params *secretsmanager.GetSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.GetSecretValueOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name, s, err := f.secret(aws.ToString(params.SecretId))
+	if err != nil {
+		return nil, err
+	}
+
+	return &secretsmanager.GetSecretValueOutput{
+		Name:         aws.String(name),
+		ARN:          aws.String(s.arn),
+		SecretString: aws.String(s.value),
+		VersionId:    aws.String(s.versionID()),
+	}, nil
+}
+
+func (f *SecretsManager) PutSecretValue(_ context.Context, params *secretsmanager.PutSecretValueInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.PutSecretValueOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name, s, err := f.secret(aws.ToString(params.SecretId))
+	if err != nil {
+		return nil, err
+	}
+
+	s.value = aws.ToString(params.SecretString)
+	s.version++
+	return &secretsmanager.PutSecretValueOutput{
+		Name:      aws.String(name),
+		ARN:       aws.String(s.arn),
+		VersionId: aws.String(s.versionID()),
+	}, nil
+}
+
+// DeleteSecret deletes the secret immediately, regardless of the recovery window.
+func (f *SecretsManager) DeleteSecret(_ context.Context, params *secretsmanager.DeleteSecretInput, _ ...func(*secretsmanager.Options)) (*secretsmanager.DeleteSecretOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name, s, err := f.secret(aws.ToString(params.SecretId))
+	if err != nil {
+		return nil, err
+	}
+
+	delete(f.secrets, name)
+	return &secretsmanager.DeleteSecretOutput{
+		Name:         aws.String(name),
+		ARN:          aws.String(s.arn),
+		DeletionDate: aws.Time(time.Now()),
+	}, nil
+}
+
+// secret returns the name and the secret with the given name or ARN.
+func (f *SecretsManager) secret(id string) (string, *secret, error) {
+	if s, ok := f.secrets[id]; ok {
+		return id, s, nil
+	}
+	if strings.HasPrefix(id, "arn:") {
+		for name, s := range f.secrets {
+			if s.arn == id {
+				return name, s, nil
+			}
+		}
+	}
+	return "", nil, apiError("ResourceNotFoundException", "secrets manager can't find the specified secret %s", id)
+}
+
+// versionID returns the ID of the current version of the value of the secret.
+func (s *secret) versionID() string {
+	return fmt.Sprintf("00000000-0000-0000-0000-%012d", s.version)
+}
@@ -0,0 +1,164 @@
+package awsfake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+	"github.com/florianwoelki/uber-movement-speed/migrations"
+	"github.com/florianwoelki/uber-movement-speed/schema"
+)
+
+// newAurora returns an Aurora wrapper around fakes and the database of a created cluster.
+func newAurora(t *testing.T) (*awsService.Aurora, *migrations.DataAPI) {
+	ctx := context.Background()
+	data := NewRDSData()
+	t.Cleanup(func() {
+		if err := data.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	aurora := awsService.NewAuroraFromClients(NewRDS(), data, NewSecretsManager())
+	cluster, secretARN, err := aurora.EnsureDBCluster(ctx, "test-cluster", "test", "user", "password")
+	if err != nil {
+		t.Fatalf("ensuring the cluster: %v", err)
+	}
+	if err := aurora.WaitUntilAvailable(ctx, "test-cluster", 0); err != nil {
+		t.Fatalf("waiting for the cluster: %v", err)
+	}
+
+	return aurora, migrations.NewDataAPI(aurora, "test", aws.ToString(cluster.DBClusterArn), secretARN)
+}
+
+func TestAurora_Migrations(t *testing.T) {
+	ctx := context.Background()
+	_, db := newAurora(t)
+
+	all, err := migrations.All()
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := migrations.NewRunner(db, all)
+
+	if applied, err := runner.Up(ctx); err != nil || len(applied) != len(all) {
+		t.Fatalf("expected %d applied migrations, got %v, %v", len(all), applied, err)
+	}
+	if pending, err := runner.Pending(ctx); err != nil || len(pending) != 0 {
+		t.Fatalf("expected no pending migrations, got %v, %v", pending, err)
+	}
+
+	rows, err := db.Query(ctx, "SELECT COUNT(*) FROM "+schema.Table)
+	if err != nil {
+		t.Fatalf("expected the table of the migration, got %v", err)
+	}
+	if count, ok := rows[0][0].(*types.FieldMemberLongValue); !ok || count.Value != 0 {
+		t.Errorf("expected an empty table, got %v", rows)
+	}
+
+	if reverted, err := runner.Down(ctx, len(all)); err != nil || len(reverted) != len(all) {
+		t.Fatalf("expected %d reverted migrations, got %v, %v", len(all), reverted, err)
+	}
+	if _, err := db.Query(ctx, "SELECT COUNT(*) FROM "+schema.Table); err == nil {
+		t.Error("expected the table to be dropped")
+	}
+}
+
+func TestAurora_ExecuteTransaction(t *testing.T) {
+	ctx := context.Background()
+	_, db := newAurora(t)
+
+	if err := db.Exec(ctx, []string{"CREATE TABLE speeds (id SERIAL PRIMARY KEY, speed FLOAT)"}); err != nil {
+		t.Fatal(err)
+	}
+	err := db.Exec(ctx, []string{
+		"INSERT INTO speeds (speed) VALUES (1.5)",
+		"INSERT INTO missing (speed) VALUES (2.5)",
+	})
+	if err == nil {
+		t.Fatal("expected the transaction to fail")
+	}
+
+	rows, err := db.Query(ctx, "SELECT COUNT(*) FROM speeds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count := rows[0][0].(*types.FieldMemberLongValue); count.Value != 0 {
+		t.Errorf("expected the failed transaction to be rolled back, got %d rows", count.Value)
+	}
+}
+
+func TestRDSData_Parameters(t *testing.T) {
+	ctx := context.Background()
+	data := NewRDSData()
+	t.Cleanup(func() {
+		if err := data.Close(); err != nil {
+			t.Error(err)
+		}
+	})
+
+	execute := func(sql string, parameters ...types.SqlParameter) *rdsdata.ExecuteStatementOutput {
+		t.Helper()
+		output, err := data.ExecuteStatement(ctx, &rdsdata.ExecuteStatementInput{
+			ResourceArn:           aws.String("arn:aws:rds:us-east-1:000000000000:cluster:test"),
+			Database:              aws.String("test"),
+			Sql:                   aws.String(sql),
+			Parameters:            parameters,
+			IncludeResultMetadata: true,
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		return output
+	}
+
+	execute("CREATE TABLE segments (id BIGSERIAL PRIMARY KEY, name VARCHAR(100), speed FLOAT)")
+	output := execute("INSERT INTO segments (name, speed) VALUES (:name, :speed)",
+		types.SqlParameter{Name: aws.String("name"), Value: &types.FieldMemberStringValue{Value: "a"}},
+		types.SqlParameter{Name: aws.String("speed"), Value: &types.FieldMemberDoubleValue{Value: 12.5}},
+	)
+	if output.NumberOfRecordsUpdated != 1 {
+		t.Errorf("expected 1 updated record, got %d", output.NumberOfRecordsUpdated)
+	}
+
+	output = execute("SELECT id, name, speed FROM segments WHERE name = :name",
+		types.SqlParameter{Name: aws.String("name"), Value: &types.FieldMemberStringValue{Value: "a"}},
+	)
+	if len(output.Records) != 1 || len(output.ColumnMetadata) != 3 || aws.ToString(output.ColumnMetadata[1].Name) != "name" {
+		t.Fatalf("unexpected result %+v", output)
+	}
+	record := output.Records[0]
+	if id, ok := record[0].(*types.FieldMemberLongValue); !ok || id.Value != 1 {
+		t.Errorf("unexpected id %#v", record[0])
+	}
+	if name, ok := record[1].(*types.FieldMemberStringValue); !ok || name.Value != "a" {
+		t.Errorf("unexpected name %#v", record[1])
+	}
+	if speed, ok := record[2].(*types.FieldMemberDoubleValue); !ok || speed.Value != 12.5 {
+		t.Errorf("unexpected speed %#v", record[2])
+	}
+}
+
+func TestAurora_DeleteDBCluster(t *testing.T) {
+	ctx := context.Background()
+	aurora, _ := newAurora(t)
+
+	if arn, err := aurora.GetSecretARN(ctx, "user"); err != nil || arn == "" {
+		t.Errorf("expected the secret, got %q, %v", arn, err)
+	}
+	if err := aurora.DeleteDBCluster(ctx, "test-cluster"); err != nil {
+		t.Fatal(err)
+	}
+	if err := aurora.DeleteSecret(ctx, "user"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := aurora.GetDBCluster(ctx, "test-cluster"); !awsService.IsNotFound(err) {
+		t.Errorf("expected a missing cluster, got %v", err)
+	}
+	if _, err := aurora.GetSecretARN(ctx, "user"); !awsService.IsNotFound(err) {
+		t.Errorf("expected a missing secret, got %v", err)
+	}
+}
@@ -0,0 +1,317 @@
+package awsfake
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	_ "modernc.org/sqlite" // Registers the embedded SQL engine of RDSData.
+)
+
+// RDSData is a fake of the RDS Data API, which executes the statements on an in-memory
+// SQLite database per cluster ARN and database name. The databases are created on first
+// use and the PostgreSQL column types `SERIAL` and `BIGSERIAL` and the lookup of a table
+// of the current schema in `information_schema.tables` are translated, so the migrations
+// of the Aurora database run unchanged. Other PostgreSQL specifics are not supported. Parameters are bound by their name, e.g. `:id`.
+//
+// Every transaction keeps a connection to its database, so statements outside of it run
+// concurrently and fail if they conflict with it, like with a database that locks tables.
+// Close releases the databases.
+type RDSData struct {
+	mu           sync.Mutex
+	databases    map[string]*sql.DB
+	transactions map[string]*sql.Tx
+	transaction  int
+}
+
+// databaseID tells the shared in-memory databases of all fakes apart.
+var databaseID atomic.Int64
+
+// NewRDSData returns a fake of the RDS Data API without databases.
+func NewRDSData() *RDSData {
+	return &RDSData{
+		databases:    map[string]*sql.DB{},
+		transactions: map[string]*sql.Tx{},
+	}
+}
+
+// Close rolls back the open transactions and closes the databases.
+func (f *RDSData) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var errs []error
+	for id, tx := range f.transactions {
+		if err := tx.Rollback(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(f.transactions, id)
+	}
+	for key, db := range f.databases {
+		if err := db.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(f.databases, key)
+	}
+	return errors.Join(errs...)
+}
+
+// ExecuteStatement executes the statement in its transaction or on its own. Queries return
+// their rows, other statements the number of updated rows.
+func (f *RDSData) ExecuteStatement(ctx context.Context, params *rdsdata.ExecuteStatementInput, _ ...func(*rdsdata.Options)) (*rdsdata.ExecuteStatementOutput, error) {
+	var db interface {
+		ExecContext(context.Context, string, ...any) (sql.Result, error)
+		QueryContext(context.Context, string, ...any) (*sql.Rows, error)
+	}
+	if id := aws.ToString(params.TransactionId); id != "" {
+		tx, err := f.tx(id)
+		if err != nil {
+			return nil, err
+		}
+		db = tx
+	} else {
+		d, err := f.database(aws.ToString(params.ResourceArn), aws.ToString(params.Database))
+		if err != nil {
+			return nil, err
+		}
+		db = d
+	}
+
+	statement := translate(aws.ToString(params.Sql))
+	args := make([]any, 0, len(params.Parameters))
+	for _, parameter := range params.Parameters {
+		value, err := fieldValue(parameter.Value)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, sql.Named(aws.ToString(parameter.Name), value))
+	}
+
+	if !isQuery(statement) {
+		result, err := db.ExecContext(ctx, statement, args...)
+		if err != nil {
+			return nil, statementError(err)
+		}
+		updated, err := result.RowsAffected()
+		if err != nil {
+			return nil, statementError(err)
+		}
+		return &rdsdata.ExecuteStatementOutput{NumberOfRecordsUpdated: updated}, nil
+	}
+
+	rows, err := db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, statementError(err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, statementError(err)
+	}
+	output := &rdsdata.ExecuteStatementOutput{}
+	if params.IncludeResultMetadata {
+		for _, column := range columns {
+			output.ColumnMetadata = append(output.ColumnMetadata, types.ColumnMetadata{
+				Name:  aws.String(column),
+				Label: aws.String(column),
+			})
+		}
+	}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		pointers := make([]any, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, statementError(err)
+		}
+
+		record := make([]types.Field, len(values))
+		for i, value := range values {
+			record[i] = field(value)
+		}
+		output.Records = append(output.Records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, statementError(err)
+	}
+	return output, nil
+}
+
+func (f *RDSData) BeginTransaction(_ context.Context, params *rdsdata.BeginTransactionInput, _ ...func(*rdsdata.Options)) (*rdsdata.BeginTransactionOutput, error) {
+	db, err := f.database(aws.ToString(params.ResourceArn), aws.ToString(params.Database))
+	if err != nil {
+		return nil, err
+	}
+	// The transaction outlives the call, so it must not be rolled back with its context.
+	tx, err := db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return nil, statementError(err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.transaction++
+	id := fmt.Sprintf("transaction-%d", f.transaction)
+	f.transactions[id] = tx
+	return &rdsdata.BeginTransactionOutput{TransactionId: aws.String(id)}, nil
+}
+
+func (f *RDSData) CommitTransaction(_ context.Context, params *rdsdata.CommitTransactionInput, _ ...func(*rdsdata.Options)) (*rdsdata.CommitTransactionOutput, error) {
+	tx, err := f.endTx(aws.ToString(params.TransactionId))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, statementError(err)
+	}
+
+	return &rdsdata.CommitTransactionOutput{TransactionStatus: aws.String("Transaction Committed")}, nil
+}
+
+func (f *RDSData) RollbackTransaction(_ context.Context, params *rdsdata.RollbackTransactionInput, _ ...func(*rdsdata.Options)) (*rdsdata.RollbackTransactionOutput, error) {
+	tx, err := f.endTx(aws.ToString(params.TransactionId))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Rollback(); err != nil {
+		return nil, statementError(err)
+	}
+
+	return &rdsdata.RollbackTransactionOutput{TransactionStatus: aws.String("Rollback Complete")}, nil
+}
+
+// database returns the database of the cluster with the given ARN and the given name and
+// creates it if it does not exist yet.
+func (f *RDSData) database(clusterARN, name string) (*sql.DB, error) {
+	if clusterARN == "" {
+		return nil, apiError("BadRequestException", "the resource arn is empty")
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := clusterARN + "/" + name
+	if db, ok := f.databases[key]; ok {
+		return db, nil
+	}
+
+	// A shared in-memory database lives as long as one of its connections, so one
+	// connection is never closed.
+	dsn := fmt.Sprintf("file:awsfake-%d?mode=memory&cache=shared", databaseID.Add(1))
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxIdleTime(0)
+	db.SetConnMaxLifetime(0)
+
+	f.databases[key] = db
+	return db, nil
+}
+
+// tx returns the transaction with the given ID.
+func (f *RDSData) tx(id string) (*sql.Tx, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tx, ok := f.transactions[id]
+	if !ok {
+		return nil, apiError("NotFoundException", "transaction %s is not found", id)
+	}
+	return tx, nil
+}
+
+// endTx removes the transaction with the given ID and returns it.
+func (f *RDSData) endTx(id string) (*sql.Tx, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	tx, ok := f.transactions[id]
+	if !ok {
+		return nil, apiError("NotFoundException", "transaction %s is not found", id)
+	}
+	delete(f.transactions, id)
+	return tx, nil
+}
+
+// serialType matches the auto-incrementing PostgreSQL column types, which SQLite spells as
+// INTEGER primary keys.
+var serialType = regexp.MustCompile(`(?i)\b(BIG)?SERIAL\b`)
+
+// tableLookup matches the condition of a lookup of a table of the current schema in
+// `information_schema.tables`, which SQLite lists in `sqlite_master`.
+var tableLookup = regexp.MustCompile(`(?i)\binformation_schema\.tables\s+WHERE\s+table_schema\s*=\s*current_schema\(\)\s+AND\s+table_name\b`)
+
+// queryStatement matches the statements that return rows.
+var queryStatement = regexp.MustCompile(`(?is)^\s*(SELECT|WITH|VALUES|PRAGMA)\b|\bRETURNING\b`)
+
+// translate returns the given PostgreSQL statement in the dialect of SQLite.
+func translate(statement string) string {
+	statement = serialType.ReplaceAllString(statement, "INTEGER")
+	return tableLookup.ReplaceAllString(statement, "sqlite_master WHERE type = 'table' AND name")
+}
+
+// isQuery reports whether the given statement returns rows.
+func isQuery(statement string) bool {
+	return queryStatement.MatchString(statement)
+}
+
+// statementError returns the given error of the database as an error of the Data API.
+func statementError(err error) error {
+	return apiError("BadRequestException", "%s", strings.TrimSpace(err.Error()))
+}
+
+// fieldValue returns the value of the given field of a parameter.
+func fieldValue(value types.Field) (any, error) {
+	switch v := value.(type) {
+	case nil, *types.FieldMemberIsNull:
+		return nil, nil
+	case *types.FieldMemberStringValue:
+		return v.Value, nil
+	case *types.FieldMemberLongValue:
+		return v.Value, nil
+	case *types.FieldMemberDoubleValue:
+		return v.Value, nil
+	case *types.FieldMemberBooleanValue:
+		return v.Value, nil
+	case *types.FieldMemberBlobValue:
+		return v.Value, nil
+	}
+	return nil, apiError("BadRequestException", "unsupported parameter value %T", value)
+}
+
+// field returns the given value of a column as a field of a record.
+func field(value any) types.Field {
+	switch v := value.(type) {
+	case int64:
+		return &types.FieldMemberLongValue{Value: v}
+	case float64:
+		return &types.FieldMemberDoubleValue{Value: v}
+	case bool:
+		return &types.FieldMemberBooleanValue{Value: v}
+	case string:
+		return &types.FieldMemberStringValue{Value: v}
+	case time.Time:
+		// The Data API returns timestamps as strings.
+		return &types.FieldMemberStringValue{Value: v.UTC().Format("2006-01-02 15:04:05.999999")}
+	case []byte:
+		return &types.FieldMemberBlobValue{Value: v}
+	case nil:
+		return &types.FieldMemberIsNull{Value: true}
+	}
+	return &types.FieldMemberStringValue{Value: fmt.Sprint(value)}
+}
@@ -0,0 +1,244 @@
+package awsfake
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3 is an in-memory fake of the S3 API, which stores the objects of its buckets.
+type S3 struct {
+	mu      sync.Mutex
+	buckets map[string]map[string]object
+}
+
+// object is a stored S3 object.
+type object struct {
+	data         []byte
+	lastModified time.Time
+}
+
+// NewS3 returns a fake of the S3 API without buckets.
+func NewS3() *S3 {
+	return &S3{buckets: map[string]map[string]object{}}
+}
+
+// Object returns the content of the object with the given key in the given bucket and
+// whether it exists.
+func (f *S3) Object(bucket, key string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	o, ok := f.buckets[bucket][key]
+	return o.data, ok
+}
+
+// Keys returns the sorted keys of the objects in the given bucket.
+func (f *S3) Keys(bucket string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return sortedKeys(f.buckets[bucket])
+}
+
+func (f *S3) CreateBucket(_ context.Context, params *s3.CreateBucketInput, _ ...func(*s3.Options)) (*s3.CreateBucketOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.Bucket)
+	if name == "" {
+		return nil, apiError("InvalidBucketName", "the bucket name is empty")
+	}
+	if _, ok := f.buckets[name]; ok {
+		return nil, apiError("BucketAlreadyOwnedByYou", "bucket %s already exists", name)
+	}
+
+	f.buckets[name] = map[string]object{}
+	return &s3.CreateBucketOutput{Location: aws.String("/" + name)}, nil
+}
+
+func (f *S3) DeleteBucket(_ context.Context, params *s3.DeleteBucketInput, _ ...func(*s3.Options)) (*s3.DeleteBucketOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.ToString(params.Bucket)
+	objects, ok := f.buckets[name]
+	if !ok {
+		return nil, apiError("NoSuchBucket", "bucket %s does not exist", name)
+	}
+	if len(objects) > 0 {
+		return nil, apiError("BucketNotEmpty", "bucket %s is not empty", name)
+	}
+
+	delete(f.buckets, name)
+	return &s3.DeleteBucketOutput{}, nil
+}
+
+func (f *S3) HeadBucket(_ context.Context, params *s3.HeadBucketInput, _ ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if _, ok := f.buckets[aws.ToString(params.Bucket)]; !ok {
+		return nil, apiError("NotFound", "bucket %s does not exist", aws.ToString(params.Bucket))
+	}
+	return &s3.HeadBucketOutput{}, nil
+}
+
+func (f *S3) PutObject(_ context.Context, params *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	var data []byte
+	if params.Body != nil {
+		var err error
+		if data, err = io.ReadAll(params.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	objects, ok := f.buckets[aws.ToString(params.Bucket)]
+	if !ok {
+		return nil, apiError("NoSuchBucket", "bucket %s does not exist", aws.ToString(params.Bucket))
+	}
+
+	objects[aws.ToString(params.Key)] = object{data: data, lastModified: time.Now()}
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *S3) GetObject(_ context.Context, params *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	o, err := f.object(aws.ToString(params.Bucket), aws.ToString(params.Key), "NoSuchKey")
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3.GetObjectOutput{
+		Body:          io.NopCloser(bytes.NewReader(o.data)),
+		ContentLength: int64(len(o.data)),
+		LastModified:  aws.Time(o.lastModified),
+	}, nil
+}
+
+func (f *S3) HeadObject(_ context.Context, params *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	// HEAD responses have no body, so S3 returns the generic code of a missing object.
+	o, err := f.object(aws.ToString(params.Bucket), aws.ToString(params.Key), "NotFound")
+	if err != nil {
+		return nil, err
+	}
+
+	return &s3.HeadObjectOutput{
+		ContentLength: int64(len(o.data)),
+		LastModified:  aws.Time(o.lastModified),
+	}, nil
+}
+
+// object returns the object with the given key in the given bucket or an error with the
+// given code if it does not exist.
+func (f *S3) object(bucket, key, code string) (object, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	objects, ok := f.buckets[bucket]
+	if !ok {
+		return object{}, apiError("NoSuchBucket", "bucket %s does not exist", bucket)
+	}
+	o, ok := objects[key]
+	if !ok {
+		return object{}, apiError(code, "object %s does not exist in bucket %s", key, bucket)
+	}
+	return o, nil
+}
+
+// ListObjectsV2 lists the objects with the prefix in the order of their keys. The
+// continuation token is the last key of the page, so deleting the listed objects does not
+// skip objects of the next page.
+func (f *S3) ListObjectsV2(_ context.Context, params *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	objects, ok := f.buckets[aws.ToString(params.Bucket)]
+	if !ok {
+		return nil, apiError("NoSuchBucket", "bucket %s does not exist", aws.ToString(params.Bucket))
+	}
+
+	var keys []string
+	for _, key := range sortedKeys(objects) {
+		if strings.HasPrefix(key, aws.ToString(params.Prefix)) {
+			keys = append(keys, key)
+		}
+	}
+
+	start := 0
+	if token := aws.ToString(params.ContinuationToken); token != "" {
+		start = sort.SearchStrings(keys, token)
+		if start < len(keys) && keys[start] == token {
+			start++
+		}
+	}
+	maxKeys := 1000
+	if params.MaxKeys > 0 && params.MaxKeys < 1000 {
+		maxKeys = int(params.MaxKeys)
+	}
+	end := start + maxKeys
+	if end > len(keys) {
+		end = len(keys)
+	}
+
+	output := &s3.ListObjectsV2Output{
+		Name:     params.Bucket,
+		Prefix:   params.Prefix,
+		KeyCount: int32(end - start),
+		MaxKeys:  int32(maxKeys),
+	}
+	for _, key := range keys[start:end] {
+		output.Contents = append(output.Contents, types.Object{
+			Key:          aws.String(key),
+			Size:         int64(len(objects[key].data)),
+			LastModified: aws.Time(objects[key].lastModified),
+		})
+	}
+	if end < len(keys) {
+		output.IsTruncated = true
+		output.NextContinuationToken = aws.String(keys[end-1])
+	}
+	return output, nil
+}
+
+func (f *S3) DeleteObjects(_ context.Context, params *s3.DeleteObjectsInput, _ ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	objects, ok := f.buckets[aws.ToString(params.Bucket)]
+	if !ok {
+		return nil, apiError("NoSuchBucket", "bucket %s does not exist", aws.ToString(params.Bucket))
+	}
+
+	output := &s3.DeleteObjectsOutput{}
+	if params.Delete == nil {
+		return output, nil
+	}
+	for _, id := range params.Delete.Objects {
+		delete(objects, aws.ToString(id.Key))
+		if !params.Delete.Quiet {
+			output.Deleted = append(output.Deleted, types.DeletedObject{Key: id.Key})
+		}
+	}
+	return output, nil
+}
+
+// sortedKeys returns the sorted keys of the given map.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,92 @@
+package awsfake
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+)
+
+func TestS3(t *testing.T) {
+	ctx := context.Background()
+	fake := NewS3()
+	client := awsService.NewS3FromClient(fake)
+
+	if action, err := client.PlanBucket(ctx, "test-bucket"); err != nil || action != awsService.ActionCreate {
+		t.Fatalf("expected to create the bucket, got %q, %v", action, err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := client.EnsureBucket(ctx, "test-bucket"); err != nil {
+			t.Fatalf("ensuring the bucket: %v", err)
+		}
+	}
+	if err := client.PutObject(ctx, "test-bucket", "a/b.csv", []byte("data")); err != nil {
+		t.Fatalf("putting the object: %v", err)
+	}
+
+	if exists, err := client.ObjectExists(ctx, "test-bucket", "a/b.csv"); err != nil || !exists {
+		t.Errorf("expected the object to exist, got %v, %v", exists, err)
+	}
+	if exists, err := client.ObjectExists(ctx, "test-bucket", "missing"); err != nil || exists {
+		t.Errorf("expected the object not to exist, got %v, %v", exists, err)
+	}
+	if data, _ := fake.Object("test-bucket", "a/b.csv"); string(data) != "data" {
+		t.Errorf("unexpected object %q", data)
+	}
+
+	if err := client.DeleteBucket(ctx, "test-bucket"); err == nil {
+		t.Error("expected deleting a bucket with objects to fail")
+	}
+	if deleted, err := client.EmptyBucket(ctx, "test-bucket"); err != nil || deleted != 1 {
+		t.Errorf("expected 1 deleted object, got %d, %v", deleted, err)
+	}
+	if err := client.DeleteBucket(ctx, "test-bucket"); err != nil {
+		t.Errorf("deleting the bucket: %v", err)
+	}
+	if err := client.PutObject(ctx, "test-bucket", "a/b.csv", nil); !awsService.IsNotFound(err) {
+		t.Errorf("expected a missing bucket, got %v", err)
+	}
+}
+
+func TestS3_ListObjectsV2(t *testing.T) {
+	ctx := context.Background()
+	fake := NewS3()
+	client := awsService.NewS3FromClient(fake)
+
+	if err := client.CreateBucket(ctx, "test-bucket"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 4; i >= 0; i-- {
+		if err := client.PutObject(ctx, "test-bucket", fmt.Sprintf("key-%d", i), nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := client.PutObject(ctx, "test-bucket", "other", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []string
+	input := &s3.ListObjectsV2Input{Bucket: aws.String("test-bucket"), Prefix: aws.String("key-"), MaxKeys: 2}
+	for {
+		output, err := fake.ListObjectsV2(ctx, input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, object := range output.Contents {
+			keys = append(keys, aws.ToString(object.Key))
+		}
+		if !output.IsTruncated {
+			break
+		}
+		input.ContinuationToken = output.NextContinuationToken
+	}
+
+	expected := []string{"key-0", "key-1", "key-2", "key-3", "key-4"}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Errorf("expected keys %v, got %v", expected, keys)
+	}
+}
@@ -0,0 +1,53 @@
+// Package environment names the resources of an environment, so several environments,
+// e.g. dev, staging and per-developer stacks, can run side by side in one account.
+package environment
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// Variable is the environment variable that contains the name of the environment. It is
+// set for every Lambda function, so the services find the resources of their environment.
+const Variable = "ENVIRONMENT"
+
+// DefaultStage is the API Gateway stage of the default environment.
+const DefaultStage = "dev"
+
+// namePattern matches valid environment names. The names are short and lowercase, because
+// they prefix S3 buckets and Aurora clusters, which only allow lowercase names of limited
+// length.
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9-]{0,19}$`)
+
+// FromEnv returns the name of the environment from the Variable environment variable. An
+// empty name is the default environment.
+func FromEnv() string {
+	return os.Getenv(Variable)
+}
+
+// Validate checks that the given environment name can prefix the name of every resource.
+// The empty name of the default environment is valid.
+func Validate(env string) error {
+	if env != "" && !namePattern.MatchString(env) {
+		return fmt.Errorf("invalid environment %q, expected up to 20 lowercase letters, digits and hyphens starting with a letter", env)
+	}
+	return nil
+}
+
+// Name returns the name of the resource with the given name in the given environment.
+// Resources of the default environment are not prefixed.
+func Name(env, name string) string {
+	if env == "" {
+		return name
+	}
+	return env + "-" + name
+}
+
+// Stage returns the API Gateway stage of the given environment.
+func Stage(env string) string {
+	if env == "" {
+		return DefaultStage
+	}
+	return env
+}
@@ -0,0 +1,35 @@
+package environment
+
+import "testing"
+
+func TestName(t *testing.T) {
+	if name := Name("", "raw-data"); name != "raw-data" {
+		t.Errorf("unexpected name in default environment: %s", name)
+	}
+	if name := Name("staging", "raw-data"); name != "staging-raw-data" {
+		t.Errorf("unexpected name: %s", name)
+	}
+}
+
+func TestStage(t *testing.T) {
+	if stage := Stage(""); stage != DefaultStage {
+		t.Errorf("unexpected stage of default environment: %s", stage)
+	}
+	if stage := Stage("staging"); stage != "staging" {
+		t.Errorf("unexpected stage: %s", stage)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, env := range []string{"", "dev", "jane-doe", "pr-123"} {
+		if err := Validate(env); err != nil {
+			t.Errorf("unexpected error for %q: %v", env, err)
+		}
+	}
+
+	for _, env := range []string{"Dev", "1dev", "dev_1", "-dev", "a-very-long-environment-name"} {
+		if err := Validate(env); err == nil {
+			t.Errorf("expected error for %q", env)
+		}
+	}
+}
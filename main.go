@@ -2,384 +2,361 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+	"github.com/florianwoelki/uber-movement-speed/awsconfig"
+	"github.com/florianwoelki/uber-movement-speed/environment"
+	"github.com/florianwoelki/uber-movement-speed/manifest"
+	"github.com/florianwoelki/uber-movement-speed/migrations"
+	"github.com/florianwoelki/uber-movement-speed/outputs"
+	"github.com/florianwoelki/uber-movement-speed/provisioner"
+	"github.com/florianwoelki/uber-movement-speed/state"
 )
 
-type IAMRoles struct {
-	s3         *aws.CredentialsCache
-	kinesis    *aws.CredentialsCache
-	lambda     *aws.CredentialsCache
-	dynamodb   *aws.CredentialsCache
-	glue       *aws.CredentialsCache
-	aurora     *aws.CredentialsCache
-	apiGateway *aws.CredentialsCache
-}
-
-func createIAMRoles(iam *awsService.IAM) (*IAMRoles, error) {
-	iamRoles := &IAMRoles{}
+// Exit codes of the plan, drift and migrate status commands. Errors exit with `1` and invalid usage with
+// `2`.
+const (
+	exitNoChanges      = 0
+	exitChangesPending = 3
+)
 
-	s3Creds, err := iam.CreateRoleWithPolicy("s3-role", "s3")
-	if err != nil {
-		return nil, err
+func main() {
+	manifestPath := flag.String("manifest", "manifest.yaml", "path to the topology manifest")
+	env := flag.String("env", environment.FromEnv(), "`name` of the environment that prefixes the resource names, e.g. staging (defaults to $"+environment.Variable+")")
+	outputsPath := flag.String("outputs", "", "path of the outputs document that setup writes (default \"outputs.json\" or \"outputs.<env>.json\")")
+	parallelism := flag.Int("parallelism", 4, "maximum number of resources that setup creates at the same time")
+	waitTimeout := flag.Duration("wait-timeout", 10*time.Minute, "maximum time that setup waits for a single resource to become ready")
+	statePath := flag.String("state", "", "path of the checkpoint file from which a failed setup resumes (default \".setup-state.json\" or \".setup-state.<env>.json\")")
+	rollback := flag.Bool("rollback", false, "delete the resources that a failed setup created")
+	jsonOutput := flag.Bool("json", false, "print the report of the drift command as JSON")
+	maxAttempts := flag.Int("max-attempts", awsService.DefaultRetryPolicy.MaxAttempts, "maximum number of attempts of an AWS call that is throttled or fails transiently")
+	maxRetryDelay := flag.Duration("max-retry-delay", awsService.DefaultRetryPolicy.MaxDelay, "maximum delay between two attempts of an AWS call")
+	steps := flag.Int("steps", 1, "number of the latest applied migrations that migrate down reverts")
+	otlpEndpoint := flag.String("otlp-endpoint", "", "`url` of the OTLP endpoint to which setup configures the Lambda functions to export their spans, e.g. http://host.docker.internal:4318 for a local collector")
+	metricsAddr := flag.String("metrics-addr", "", "`address` on which the metrics of the AWS calls are served to Prometheus at /metrics while the command runs, e.g. :9090")
+	metricsNamespace := flag.String("metrics-namespace", "", "CloudWatch `namespace` into which the metrics of the AWS calls are published before the command exits, e.g. UberMovementSpeed/Setup")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [setup|destroy|plan [setup|destroy]|drift|migrate [status|up|down]]\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprintf(flag.CommandLine.Output(), "\nThe plan, drift and migrate status commands exit with %d if there are no changes and with %d if changes are pending.\n", exitNoChanges, exitChangesPending)
 	}
-	iamRoles.s3 = s3Creds
+	flag.Parse()
 
-	kinesisCreds, err := iam.CreateRoleWithPolicy("kinesis-role", "kinesis")
-	if err != nil {
-		return nil, err
+	command := "setup"
+	if flag.NArg() > 0 {
+		command = flag.Arg(0)
 	}
-	iamRoles.kinesis = kinesisCreds
 
-	lambdaCreds, err := iam.CreateRoleWithPolicy("lambda-role", "lambda")
+	m, err := manifest.Load(*manifestPath)
 	if err != nil {
-		return nil, err
+		fatal(err)
 	}
-	iamRoles.lambda = lambdaCreds
 
-	dynamodbCreds, err := iam.CreateRoleWithPolicy("dynamodb-role", "dynamodb")
+	m, err = m.WithEnvironment(*env)
 	if err != nil {
-		return nil, err
+		fatal(err)
 	}
-	iamRoles.dynamodb = dynamodbCreds
-
-	glueCreds, err := iam.CreateRoleWithPolicy("glue-role", "glue")
-	if err != nil {
-		return nil, err
+	if *outputsPath == "" {
+		*outputsPath = outputs.Path(*env)
 	}
-	iamRoles.glue = glueCreds
-
-	auroraCreds, err := iam.CreateRoleWithPolicy("rds-role", "rds")
-	if err != nil {
-		return nil, err
+	if *statePath == "" {
+		*statePath = state.Path(*env)
 	}
-	iamRoles.aurora = auroraCreds
 
-	apiGatewayCreds, err := iam.CreateRoleWithPolicy("apigatewayv2-role", "apigatewayv2")
+	// Interrupting a command cancels the calls to AWS that are in flight and stops setup
+	// from creating further resources.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cfg, err := awsconfig.LoadFromEnv(ctx)
 	if err != nil {
-		return nil, err
+		fatal(err)
 	}
-	iamRoles.apiGateway = apiGatewayCreds
-
-	return iamRoles, nil
-}
-
-func main() {
-	log.Println("Starting setup...")
-	defer log.Println("Finished setup")
-
-	customResolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
-		if service == s3.ServiceID {
-			return aws.Endpoint{
-				PartitionID:   "aws",
-				URL:           "http://s3.localhost.localstack.cloud:4566",
-				SigningRegion: "us-east-1",
-			}, nil
-		}
-
-		return aws.Endpoint{
-			PartitionID:   "aws",
-			URL:           "http://localhost.localstack.cloud:4566",
-			SigningRegion: "us-east-1",
-		}, nil
+	cfg = awsService.WithRetryPolicy(cfg, awsService.RetryPolicy{
+		MaxAttempts: *maxAttempts,
+		BaseDelay:   awsService.DefaultRetryPolicy.BaseDelay,
+		MaxDelay:    *maxRetryDelay,
 	})
-
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion("us-east-1"),
-		config.WithEndpointResolverWithOptions(customResolver),
-	)
-	if err != nil {
-		log.Fatal(err)
+	if *metricsAddr != "" || *metricsNamespace != "" {
+		metrics := awsService.NewMetrics()
+		if *metricsNamespace != "" {
+			// The calls that publish the metrics are not recorded themselves.
+			cloudWatch := awsService.NewCloudWatch(cfg)
+			beforeExit = func() { publishMetrics(metrics, cloudWatch, *metricsNamespace) }
+		}
+		cfg = awsService.WithMetrics(cfg, metrics)
+		if *metricsAddr != "" {
+			serveMetrics(*metricsAddr, metrics)
+		}
 	}
 
-	log.Println("Creating IAM roles...")
-	iam := awsService.NewIAM(cfg)
-	iamRoles, err := createIAMRoles(iam)
-	if err != nil {
-		log.Fatal(err)
-	}
-	log.Println("Created IAM roles")
-
-	cfg.Credentials = iamRoles.kinesis
-	kinesis := awsService.NewKinesis(cfg)
-	cfg.Credentials = iamRoles.lambda
-	lambda := awsService.NewLambda(cfg)
-	cfg.Credentials = iamRoles.s3
-	s3 := awsService.NewS3(cfg)
-	cfg.Credentials = iamRoles.dynamodb
-	dynamodb := awsService.NewDynamoDB(cfg)
-	cfg.Credentials = iamRoles.glue
-	glue := awsService.NewGlue(cfg)
-	cfg.Credentials = iamRoles.aurora
-	aurora := awsService.NewAurora(cfg)
-	cfg.Credentials = iamRoles.apiGateway
-	apiGateway := awsService.NewAPIGateway(cfg)
-
-	// Creates the lambda S3 bucket.
-	log.Println("Creating S3 bucket for lambda...")
-	err = s3.CreateBucket("lambda-bucket")
-	if err != nil {
-		log.Fatal(err)
-	}
-	log.Println("Created S3 bucket for lambda")
+	p := provisioner.New(cfg, m)
+	p.Parallelism = *parallelism
+	p.WaitTimeout = *waitTimeout
+	p.StatePath = *statePath
+	p.Rollback = *rollback
+	p.OTLPEndpoint = *otlpEndpoint
+	switch command {
+	case "setup":
+		log.Println("Starting setup...")
+		out, err := p.Apply(ctx)
+		if err != nil {
+			fatal(err)
+		}
 
-	// Creates the S3 bucket for the raw data that is being sent from the `preprocessing`
-	// service.
-	log.Println("Creating S3 bucket for raw data...")
-	err = s3.CreateBucket("raw-data")
-	if err != nil {
-		log.Fatal(err)
-	}
-	log.Println("Created S3 bucket for raw data")
+		if err := out.Write(*outputsPath); err != nil {
+			fatal(err)
+		}
+		log.Printf("Wrote outputs to `%s`", *outputsPath)
+		log.Println("Finished setup")
+	case "destroy":
+		log.Println("Starting teardown...")
+		removed, err := p.Destroy(ctx)
+		log.Printf("Removed %d resources", len(removed))
+		for _, resource := range removed {
+			log.Printf("  - %s", resource)
+		}
+		if err != nil {
+			fatal(err)
+		}
 
-	// Loads the python PySpark script.
-	log.Println("Uploading PySpark script to `raw-data` S3 bucket...")
-	file, err := os.Open("services/glue/raw_data_etl.py")
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer file.Close()
-	scriptFileBytes, err := io.ReadAll(file)
-	if err != nil {
-		log.Fatal(err)
-	}
+		// The checkpoint of a failed setup is stale once its resources are gone.
+		if err := state.Remove(*statePath); err != nil {
+			fatal(err)
+		}
+		log.Println("Finished teardown")
+	case "plan":
+		target := "setup"
+		if flag.NArg() > 1 {
+			target = flag.Arg(1)
+		}
 
-	// Uploads the python PySpark script to the S3 bucket.
-	err = s3.PutObject("raw-data", "scripts/raw_data_etl.py", scriptFileBytes)
-	if err != nil {
-		log.Fatal(err)
-	}
-	log.Println("Uploaded PySpark script to `raw-data` S3 bucket")
+		var changes []provisioner.Change
+		switch target {
+		case "setup":
+			changes, err = p.Plan(ctx)
+		case "destroy":
+			changes, err = p.PlanDestroy(ctx)
+		default:
+			flag.Usage()
+			exit(2)
+		}
+		if err != nil {
+			fatal(err)
+		}
 
-	// Creates the S3 bucket for the transformed data that is being sent from the glue job
-	log.Println("Creating S3 bucket for transformed data...")
-	err = s3.CreateBucket("transformed-data")
-	if err != nil {
-		log.Fatal(err)
-	}
-	log.Println("Created S3 bucket for transformed data")
+		exit(printPlan(os.Stdout, target, changes))
+	case "drift":
+		drifted, err := p.Drift(ctx)
+		if err != nil {
+			fatal(err)
+		}
 
-	// Creates the glue job.
-	log.Println("Creating glue job...")
-	err = glue.CreateJob("raw-data-etl", "s3://raw-data/scripts/raw_data_etl.py")
-	if err != nil {
-		log.Fatal(err)
-	}
-	log.Println("Created glue job")
+		if *jsonOutput {
+			err = printDriftJSON(os.Stdout, *env, drifted)
+		} else {
+			printDrift(os.Stdout, drifted)
+		}
+		if err != nil {
+			fatal(err)
+		}
 
-	// Loads the lambda zip file.
-	log.Println("Uploading `Preprocessing` lambda zip file to S3 bucket...")
-	file, err = os.Open("services/preprocessing/preprocessing.zip")
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer file.Close()
-	zipFileBytes, err := io.ReadAll(file)
-	if err != nil {
-		log.Fatal(err)
-	}
+		if len(drifted) > 0 {
+			exit(exitChangesPending)
+		}
+		exit(exitNoChanges)
+	case "migrate":
+		action := "status"
+		if flag.NArg() > 1 {
+			action = flag.Arg(1)
+		}
+		if action != "status" && action != "up" && action != "down" {
+			flag.Usage()
+			exit(2)
+		}
 
-	// Uploads the lambda zip file to the S3 bucket.
-	err = s3.PutObject("lambda-bucket", "preprocessing.zip", zipFileBytes)
-	if err != nil {
-		log.Fatal(err)
-	}
-	log.Println("Uploaded `Preprocessing` lambda zip file to S3 bucket")
+		migrators, err := p.Migrators(ctx)
+		if err != nil {
+			fatal(err)
+		}
 
-	// Creates the lambda function.
-	log.Println("Creating `Preprocessing` lambda function...")
-	_, err = lambda.CreateGo("Preprocessing", "lambda-bucket", "preprocessing.zip")
-	if err != nil {
-		log.Fatal(err)
-	}
-	// TODO: Wait for lambda function to be created.
-	log.Println("Created `Preprocessing` lambda function")
+		pending := false
+		for _, migrator := range migrators {
+			switch action {
+			case "status":
+				status, err := migrator.Status(ctx)
+				if err != nil {
+					fatal(err)
+				}
+				if printMigrations(migrator.Cluster, status) {
+					pending = true
+				}
+			case "up":
+				applied, err := migrator.Up(ctx)
+				log.Printf("Applied %d migrations to cluster `%s`", len(applied), migrator.Cluster)
+				for _, m := range applied {
+					log.Printf("  - %s", m)
+				}
+				if err != nil {
+					fatal(err)
+				}
+			case "down":
+				reverted, err := migrator.Down(ctx, *steps)
+				log.Printf("Reverted %d migrations of cluster `%s`", len(reverted), migrator.Cluster)
+				for _, m := range reverted {
+					log.Printf("  - %s", m)
+				}
+				if err != nil {
+					fatal(err)
+				}
+			}
+		}
 
-	// Loads the lambda zip file.
-	log.Println("Uploading `KinesisDataForwarder` lambda zip file to S3 bucket...")
-	file, err = os.Open("services/kinesis_data_forwarder/dist/kinesis_data_forwarder.zip")
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer file.Close()
-	zipFileBytes, err = io.ReadAll(file)
-	if err != nil {
-		log.Fatal(err)
+		if pending {
+			exit(exitChangesPending)
+		}
+		exit(exitNoChanges)
+	default:
+		flag.Usage()
+		exit(2)
 	}
 
-	// Uploads the lambda zip file to the S3 bucket.
-	err = s3.PutObject("lambda-bucket", "kinesis_data_forwarder.zip", zipFileBytes)
-	if err != nil {
-		log.Fatal(err)
-	}
-	log.Println("Uploaded `KinesisDataForwarder` lambda zip file to S3 bucket")
+	exit(0)
+}
 
-	// Creates the lambda function.
-	log.Println("Creating `KinesisDataForwarder` lambda function...")
-	kinesisDataForwarderARN, err := lambda.CreateNode("KinesisDataForwarder", "lambda-bucket", "kinesis_data_forwarder.zip")
-	if err != nil {
-		log.Fatal(err)
-	}
-	// TODO: Wait for lambda function to be created.
-	log.Println("Created `KinesisDataForwarder` lambda function")
+// beforeExit runs before the command exits, e.g. to publish the metrics of its AWS calls.
+var beforeExit = func() {}
 
-	// Loads the lambda zip file.
-	log.Println("Uploading `DynamoGetter` lambda zip file to S3 bucket...")
-	file, err = os.Open("services/dynamo_getter/dynamo_getter.zip")
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer file.Close()
-	zipFileBytes, err = io.ReadAll(file)
-	if err != nil {
-		log.Fatal(err)
-	}
+// exit runs beforeExit and exits with the given code.
+func exit(code int) {
+	beforeExit()
+	os.Exit(code)
+}
 
-	// Uploads the lambda zip file to the S3 bucket.
-	err = s3.PutObject("lambda-bucket", "dynamo_getter.zip", zipFileBytes)
-	if err != nil {
-		log.Fatal(err)
-	}
-	log.Println("Uploaded `DynamoGetter` lambda zip file to S3 bucket")
+// fatal logs the given error and exits with `1`.
+func fatal(err error) {
+	log.Print(err)
+	exit(1)
+}
 
-	// Creates the lambda function.
-	log.Println("Creating `DynamoGetter` lambda function...")
-	dynamoGetterARN, err := lambda.CreateGo("DynamoGetter", "lambda-bucket", "dynamo_getter.zip")
-	if err != nil {
-		log.Fatal(err)
-	}
-	// TODO: Wait for lambda function to be created.
-	log.Println("Created `DynamoGetter` lambda function")
+// printPlan prints the given changes of the given command with a summary to the given
+// writer and returns the exit code of the plan command.
+func printPlan(w io.Writer, command string, changes []provisioner.Change) int {
+	counts := map[awsService.Action]int{}
+	fmt.Fprintf(w, "Plan for %s:\n", command)
+	for _, change := range changes {
+		counts[change.Action]++
+		fmt.Fprintf(w, "  %s\n", change)
+		printDifferences(w, change.Differences)
+	}
+
+	fmt.Fprintf(w, "\n%d to create, %d to update, %d to delete, %d unchanged\n",
+		counts[awsService.ActionCreate],
+		counts[awsService.ActionUpdate],
+		counts[awsService.ActionDelete],
+		counts[awsService.ActionNone],
+	)
 
-	// Creates the websocket API Gateway.
-	log.Println("Creating websocket API Gateway...")
-	apiName := "my-kinesis-api"
-	websocketApiGatewayId, err := apiGateway.CreateWebSocketApi(apiName)
-	if err != nil {
-		log.Fatal(err)
+	if provisioner.HasChanges(changes) {
+		return exitChangesPending
 	}
-	log.Println("Created websocket API Gateway")
+	return exitNoChanges
+}
 
-	// Creates the HTTP API Gateway.
-	log.Println("Creating http API Gateway...")
-	apiName = "dynamo-getter"
-	httpApiGatewayId, err := apiGateway.CreateHTTPApi(apiName)
-	if err != nil {
-		log.Fatal(err)
-	}
-	log.Println("Created http API Gateway")
-
-	// Creates the websocket API Gateway endpoint for the kinesis data forwarder lambda function.
-	log.Println("Creating websocket API Gateway endpoint for `KinesisDataForwarder` lambda function...")
-	err = apiGateway.CreateWebSocket(websocketApiGatewayId, awsService.EndpointOptions{
-		Path:   "kinesis-data-forwarder",
-		Method: "POST",
-		Uri:    kinesisDataForwarderARN,
-	})
-	if err != nil {
-		log.Fatal(err)
+// printDrift prints the given drifted resources and their differing fields to the given
+// writer.
+func printDrift(w io.Writer, drifted []provisioner.Change) {
+	if len(drifted) == 0 {
+		fmt.Fprintln(w, "No drift: every resource matches the manifest.")
+		return
 	}
-	log.Println("Created websocket API Gateway endpoint for `KinesisDataForwarder` lambda function")
-
-	// Creates the REST API Gateway endpoint for the dynamo getter lambda function.
-	log.Println("Creating REST API Gateway endpoint for `DynamoGetter` lambda function...")
-	err = apiGateway.CreateEndpoint(httpApiGatewayId, awsService.EndpointOptions{
-		Path:   "/dynamo-getter",
-		Method: "GET",
-		Uri:    fmt.Sprintf("arn:aws:apigateway:us-east-1:lambda:path/2015-03-31/functions/%s/invocations", dynamoGetterARN),
-		RequestParameters: map[string]string{
-			"method.request.querystring.id": "true",
-		},
-	})
-	if err != nil {
-		log.Fatal(err)
-	}
-	log.Println("Created REST API Gateway endpoint for `DynamoGetter` lambda function")
 
-	log.Println("Deploying API Gateway...")
-	err = apiGateway.Deploy(websocketApiGatewayId)
-	if err != nil {
-		log.Fatal(err)
+	fmt.Fprintln(w, "Drift:")
+	for _, change := range drifted {
+		fmt.Fprintf(w, "  %s\n", change)
+		printDifferences(w, change.Differences)
 	}
-	err = apiGateway.Deploy(httpApiGatewayId)
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	log.Println("Deployed websocket API Gateway with ID:", websocketApiGatewayId)
-	log.Println("Deployed http API Gateway with ID:", httpApiGatewayId)
+	fmt.Fprintf(w, "\nDrifted resources: %d\n", len(drifted))
+}
 
-	// Creates the kinesis stream.
-	log.Println("Creating kinesis stream...")
-	streamName := "my-kinesis-stream"
-	err = kinesis.Create(streamName)
-	if err != nil {
-		log.Fatal(err)
+// printDifferences prints the given differing fields of a resource below the resource to
+// the given writer.
+func printDifferences(w io.Writer, differences []awsService.Difference) {
+	for _, difference := range differences {
+		fmt.Fprintf(w, "        %s: %q => %q\n", difference.Field, difference.Actual, difference.Expected)
 	}
-	log.Println("Created kinesis stream")
+}
 
-	// Gets the ARN from the kinesis stream.
-	log.Println("Binding lambda function to kinesis stream...")
-	streamARN, err := kinesis.GetARN(streamName)
-	if err != nil {
-		log.Fatal(err)
+// printMigrations prints the given migrations of the given cluster and whether they are
+// applied. It reports whether migrations are pending.
+func printMigrations(cluster string, status []migrations.Status) bool {
+	pending := 0
+	fmt.Printf("Migrations of cluster %s:\n", cluster)
+	for _, s := range status {
+		state := "applied"
+		if !s.Applied {
+			state = "pending"
+			pending++
+		}
+		fmt.Printf("  %-8s %s\n", state, s.Migration)
 	}
+	fmt.Printf("\n%d applied, %d pending\n", len(status)-pending, pending)
 
-	// Creates the lambda event source mapping.
-	err = lambda.BindToService("Preprocessing", streamARN)
-	if err != nil {
-		log.Fatal(err)
-	}
-	log.Println("Bound lambda function to kinesis stream")
+	return pending > 0
+}
 
-	// Create dynamodb table.
-	log.Println("Creating dynamodb table...")
-	err = dynamodb.CreateTable("street_segment_speeds")
-	if err != nil {
-		log.Fatal(err)
-	}
-	log.Println("Created dynamodb table")
+// driftReport is the JSON document that the drift command prints.
+type driftReport struct {
+	Environment string               `json:"environment,omitempty"`
+	Drifted     bool                 `json:"drifted"`
+	Resources   []provisioner.Change `json:"resources"`
+}
 
-	// Creates the Aurora DB Cluster.
-	log.Println("Creating Aurora DB Cluster...")
-	// Changing `dbpass`, `db1`, or `uber-data` requires a change in
-	// `services/glue/raw_data_etl.py` as well.
-	cluster, secret, err := aurora.CreateDBCluster("db1", "uber-data", "dbpass", "test")
-	if err != nil {
-		log.Fatal(err)
+// printDriftJSON prints the given drifted resources of the given environment as JSON to the
+// given writer.
+func printDriftJSON(w io.Writer, env string, drifted []provisioner.Change) error {
+	report := driftReport{
+		Environment: env,
+		Drifted:     len(drifted) > 0,
+		Resources:   drifted,
+	}
+	if report.Resources == nil {
+		report.Resources = []provisioner.Change{}
 	}
-	log.Println("Created Aurora DB Cluster")
 
-	clusterARN := aws.ToString(cluster.DBCluster.DBClusterArn)
-	secretARN := aws.ToString(secret.ARN)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(report)
+}
 
-	status := cluster.DBCluster.Status
-	for aws.ToString(status) != "available" {
-		log.Println("Waiting for Aurora DB Cluster to be available...")
-		c, err := aurora.GetDBCluster("db1")
-		if err != nil {
-			log.Fatal(err)
+// serveMetrics serves the given metrics at `/metrics` of the given address in the
+// background.
+func serveMetrics(addr string, metrics *awsService.Metrics) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics)
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			log.Printf("Serving metrics on `%s`: %v", addr, err)
 		}
+	}()
+}
 
-		status = c.Status
-		time.Sleep(2 * time.Second)
-	}
-	log.Println("Aurora DB Cluster is available")
+// publishMetrics publishes the given metrics into the given namespace of CloudWatch and
+// logs a failure, which does not change the exit code of the command.
+func publishMetrics(metrics *awsService.Metrics, cloudWatch *awsService.CloudWatch, namespace string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-	log.Println("Creating Aurora DB Cluster Endpoint...")
-	// Creates the table for the Aurora DB. Changing `street_segment_speeds` requires a change in
-	// `services/glue/raw_data_etl.py` as well.
-	_, err = aurora.ExecuteStatement("uber-data", clusterARN, secretARN, "CREATE TABLE street_segment_speeds (id SERIAL PRIMARY KEY, year INT, month INT, day INT, hour INT, utc_timestamp VARCHAR(100), start_junction_id VARCHAR(200), end_junction_id VARCHAR(200), osm_way_id BIGINT, osm_start_node_id BIGINT, osm_end_node_id BIGINT, speed_mph_mean FLOAT, speed_mph_stddev FLOAT)")
-	if err != nil {
-		log.Fatal(err)
+	if err := metrics.Publish(ctx, cloudWatch, namespace); err != nil {
+		log.Printf("Publishing metrics to `%s`: %v", namespace, err)
 	}
-	log.Println("Created Aurora DB Cluster Endpoint")
 }
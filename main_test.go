@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+	"github.com/florianwoelki/uber-movement-speed/provisioner"
+)
+
+func TestPrintPlan(t *testing.T) {
+	tests := []struct {
+		name     string
+		changes  []provisioner.Change
+		code     int
+		summary  string
+		expected []string
+	}{
+		{
+			name:    "no resources",
+			code:    exitNoChanges,
+			summary: "0 to create, 0 to update, 0 to delete, 0 unchanged",
+		},
+		{
+			name: "unchanged",
+			changes: []provisioner.Change{
+				{Action: awsService.ActionNone, Resource: "kinesis stream `speeds`"},
+				{Action: awsService.ActionNone, Resource: "dynamodb table `segment-speeds`"},
+			},
+			code:     exitNoChanges,
+			summary:  "0 to create, 0 to update, 0 to delete, 2 unchanged",
+			expected: []string{"no-op  kinesis stream `speeds`"},
+		},
+		{
+			name: "changes pending",
+			changes: []provisioner.Change{
+				{Action: awsService.ActionCreate, Resource: "kinesis stream `speeds`"},
+				{
+					Action:      awsService.ActionUpdate,
+					Resource:    "lambda function `Forwarder`",
+					Differences: []awsService.Difference{{Field: "memorySize", Expected: "256", Actual: "128"}},
+				},
+				{Action: awsService.ActionNone, Resource: "dynamodb table `segment-speeds`"},
+			},
+			code:    exitChangesPending,
+			summary: "1 to create, 1 to update, 0 to delete, 1 unchanged",
+			expected: []string{
+				"+ create kinesis stream `speeds`",
+				"~ update lambda function `Forwarder`",
+				`memorySize: "128" => "256"`,
+			},
+		},
+		{
+			name: "deletes pending",
+			changes: []provisioner.Change{
+				{Action: awsService.ActionDelete, Resource: "kinesis stream `speeds`"},
+			},
+			code:     exitChangesPending,
+			summary:  "0 to create, 0 to update, 1 to delete, 0 unchanged",
+			expected: []string{"- delete kinesis stream `speeds`"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if code := printPlan(&out, "setup", tt.changes); code != tt.code {
+				t.Errorf("unexpected exit code %d, expected %d", code, tt.code)
+			}
+
+			for _, expected := range append(tt.expected, "Plan for setup:", tt.summary) {
+				if !strings.Contains(out.String(), expected) {
+					t.Errorf("expected output to contain %q, got:\n%s", expected, out.String())
+				}
+			}
+		})
+	}
+}
+
+// testDrift is a drifted job and a missing stream.
+var testDrift = []provisioner.Change{
+	{
+		Action:   awsService.ActionUpdate,
+		Resource: "glue job `etl`",
+		Differences: []awsService.Difference{
+			{Field: "command.scriptLocation", Expected: "s3://code/etl.py", Actual: "s3://code/other.py"},
+		},
+	},
+	{Action: awsService.ActionCreate, Resource: "kinesis stream `speeds`"},
+}
+
+func TestPrintDrift(t *testing.T) {
+	var out bytes.Buffer
+	printDrift(&out, testDrift)
+
+	for _, expected := range []string{
+		"~ update glue job `etl`",
+		`command.scriptLocation: "s3://code/other.py" => "s3://code/etl.py"`,
+		"+ create kinesis stream `speeds`",
+		"Drifted resources: 2",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("expected output to contain %q, got:\n%s", expected, out.String())
+		}
+	}
+
+	out.Reset()
+	printDrift(&out, nil)
+	if !strings.Contains(out.String(), "No drift") {
+		t.Errorf("expected no drift, got:\n%s", out.String())
+	}
+}
+
+func TestPrintDriftJSON(t *testing.T) {
+	var out bytes.Buffer
+	if err := printDriftJSON(&out, "staging", testDrift); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var report driftReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out.String())
+	}
+	expected := driftReport{Environment: "staging", Drifted: true, Resources: testDrift}
+	if !reflect.DeepEqual(report, expected) {
+		t.Errorf("unexpected report:\n got: %+v\nwant: %+v", report, expected)
+	}
+	for _, field := range []string{`"action": "update"`, `"field": "command.scriptLocation"`, `"actual": "s3://code/other.py"`} {
+		if !strings.Contains(out.String(), field) {
+			t.Errorf("expected JSON to contain %s, got:\n%s", field, out.String())
+		}
+	}
+	if strings.Contains(out.String(), `"differences": null`) {
+		t.Errorf("expected no differences of the missing stream, got:\n%s", out.String())
+	}
+
+	out.Reset()
+	if err := printDriftJSON(&out, "", nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var empty map[string]any
+	if err := json.Unmarshal(out.Bytes(), &empty); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, out.String())
+	}
+	expectedEmpty := map[string]any{"drifted": false, "resources": []any{}}
+	if !reflect.DeepEqual(empty, expectedEmpty) {
+		t.Errorf("unexpected report without drift: %v", empty)
+	}
+}
@@ -0,0 +1,577 @@
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/florianwoelki/uber-movement-speed/environment"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the manifest version that is supported by this program.
+const Version = 1
+
+// consumerName matches the names Kinesis allows for stream consumers.
+var consumerName = regexp.MustCompile(`^[a-zA-Z0-9_.-]{1,128}$`)
+
+// Manifest describes the whole architecture that is provisioned by the setup program.
+type Manifest struct {
+	// Version is the version of the manifest format.
+	Version int `yaml:"version"`
+	// Roles are the IAM roles that are assumed by the wrapped AWS clients.
+	Roles []Role `yaml:"roles"`
+	// Buckets are the S3 buckets and the objects that are uploaded into them.
+	Buckets []Bucket `yaml:"buckets"`
+	// Streams are the Kinesis streams.
+	Streams []Stream `yaml:"streams"`
+	// Functions are the Lambda functions.
+	Functions []Function `yaml:"functions"`
+	// EventSourceMappings bind Lambda functions to Kinesis streams.
+	EventSourceMappings []EventSourceMapping `yaml:"eventSourceMappings"`
+	// Tables are the DynamoDB tables.
+	Tables []Table `yaml:"tables"`
+	// GlueJobs are the Glue jobs.
+	GlueJobs []GlueJob `yaml:"glueJobs"`
+	// Clusters are the Aurora database clusters.
+	Clusters []Cluster `yaml:"clusters"`
+	// APIs are the API Gateway v2 APIs.
+	APIs []API `yaml:"apis"`
+	// Tags are added to the streams, functions, tables and Glue jobs when they are
+	// created.
+	Tags map[string]string `yaml:"tags"`
+
+	// Environment is the environment whose name prefixes the names of the resources. It
+	// is set by WithEnvironment and empty for the default environment.
+	Environment string `yaml:"-"`
+}
+
+// Role is an IAM role with the policy of the given service attached to it.
+type Role struct {
+	// Name is the name of the role.
+	Name string `yaml:"name"`
+	// Service is the AWS service whose client assumes the role, e.g. `s3` or `kinesis`.
+	Service string `yaml:"service"`
+}
+
+// Bucket is a S3 bucket.
+type Bucket struct {
+	// Name is the name of the bucket.
+	Name string `yaml:"name"`
+	// Objects are local files that are uploaded into the bucket.
+	Objects []Object `yaml:"objects"`
+}
+
+// Object is a local file that is uploaded into a S3 bucket.
+type Object struct {
+	// Key is the key of the object in the bucket.
+	Key string `yaml:"key"`
+	// Source is the path of the local file.
+	Source string `yaml:"source"`
+}
+
+// Stream is a Kinesis stream.
+type Stream struct {
+	// Name is the name of the stream.
+	Name string `yaml:"name"`
+	// Mode is either `PROVISIONED`, the default, or `ON_DEMAND`.
+	Mode string `yaml:"mode"`
+	// ShardCount is the number of shards of a provisioned stream. It defaults to one.
+	ShardCount int32 `yaml:"shardCount"`
+	// RetentionHours is the number of hours the stream keeps its records. Zero means 24
+	// hours for a created stream and keeps the retention period of an existing stream.
+	RetentionHours int32 `yaml:"retentionHours"`
+	// KMSKeyID is the ID, ARN or alias of the KMS key that encrypts the records of the
+	// stream, e.g. `alias/aws/kinesis`. Empty means that a created stream is not encrypted
+	// and keeps the encryption of an existing stream, which setup does not stop.
+	KMSKeyID string `yaml:"kmsKeyId"`
+}
+
+// Function is a Lambda function whose code is uploaded to a S3 bucket.
+type Function struct {
+	// Name is the name of the function.
+	Name string `yaml:"name"`
+	// Runtime is either `go` or `node`.
+	Runtime string `yaml:"runtime"`
+	// Bucket is the name of the bucket the code is uploaded to.
+	Bucket string `yaml:"bucket"`
+	// Key is the key of the zipped code in the bucket. The hash of the code is added to
+	// the key, so unchanged code is not uploaded again.
+	Key string `yaml:"key"`
+	// Source is the path of a local zip file that was built beforehand.
+	Source string `yaml:"source"`
+	// Build is the directory of the code that setup builds and zips itself: a Go main
+	// package or a Node.js project with an `index.ts`. Either Source or Build is required.
+	Build string `yaml:"build"`
+	// MemorySize is the memory of the function in MB. Zero means 128 MB.
+	MemorySize int32 `yaml:"memorySize"`
+	// Timeout is the timeout of the function in seconds. Zero means 60 seconds.
+	Timeout int32 `yaml:"timeout"`
+}
+
+// EventSourceMapping binds a Lambda function to a Kinesis stream.
+type EventSourceMapping struct {
+	// Function is the name of the Lambda function.
+	Function string `yaml:"function"`
+	// Stream is the name of the Kinesis stream.
+	Stream string `yaml:"stream"`
+	// Consumer is the name of an enhanced fan-out consumer of the stream that is registered
+	// for the mapping, so the function reads the shards with a throughput of its own. Empty
+	// means that the function shares the read throughput of the stream.
+	Consumer string `yaml:"consumer"`
+}
+
+// Table is a DynamoDB table with a string hash key called `id`.
+type Table struct {
+	// Name is the name of the table.
+	Name string `yaml:"name"`
+	// BillingMode is either `PAY_PER_REQUEST`, the default, or `PROVISIONED`.
+	BillingMode string `yaml:"billingMode"`
+	// ReadCapacity and WriteCapacity are the capacity units of a provisioned table.
+	ReadCapacity  int64 `yaml:"readCapacity"`
+	WriteCapacity int64 `yaml:"writeCapacity"`
+}
+
+// GlueJob is a Glue job.
+type GlueJob struct {
+	// Name is the name of the job.
+	Name string `yaml:"name"`
+	// Script is the S3 location of the script, e.g. `s3://raw-data/scripts/etl.py`.
+	Script string `yaml:"script"`
+	// Command is the name of the command of the job. Empty means `pythonshell`.
+	Command string `yaml:"command"`
+	// GlueVersion is the Glue version of the job. Empty means the default of Glue.
+	GlueVersion string `yaml:"glueVersion"`
+	// MaxCapacity is the number of data processing units of the job. Zero means the
+	// default of Glue.
+	MaxCapacity float64 `yaml:"maxCapacity"`
+	// Buckets maps the names of job arguments to buckets of the manifest, e.g.
+	// `source_bucket: raw-data` passes the bucket as `--source_bucket` to every run.
+	Buckets map[string]string `yaml:"buckets"`
+	// Cluster is the identifier of a cluster of the manifest. Its identifier, the name of
+	// its secret and its database are passed as `--cluster`, `--secret` and `--database`
+	// to every run.
+	Cluster string `yaml:"cluster"`
+}
+
+// Cluster is an Aurora database cluster together with its secret.
+type Cluster struct {
+	// Identifier is the identifier of the cluster.
+	Identifier string `yaml:"identifier"`
+	// Database is the name of the database that is created in the cluster.
+	Database string `yaml:"database"`
+	// Username is the name of the secret that is created for the cluster.
+	Username string `yaml:"username"`
+	// Password is the value of the secret that is created for the cluster.
+	Password string `yaml:"password"`
+	// Statements are SQL statements that are executed once the cluster is available,
+	// before the migrations. They are executed on every run.
+	Statements []string `yaml:"statements"`
+	// Migrate applies the pending migrations of the `migrations` package to the database
+	// once the cluster is available.
+	Migrate bool `yaml:"migrate"`
+}
+
+// API is an API Gateway v2 API.
+type API struct {
+	// Name is the name of the API.
+	Name string `yaml:"name"`
+	// Protocol is either `websocket` or `http`.
+	Protocol string `yaml:"protocol"`
+	// Stage is the stage the API is deployed to. Empty means the stage of the environment.
+	Stage string `yaml:"stage"`
+	// Routes are the routes of the API that are integrated with Lambda functions.
+	Routes []Route `yaml:"routes"`
+}
+
+// Route is a route of an API that is integrated with a Lambda function.
+type Route struct {
+	// Path is the path of the route.
+	Path string `yaml:"path"`
+	// Method is the HTTP method of the route.
+	Method string `yaml:"method"`
+	// Function is the name of the Lambda function the route is integrated with.
+	Function string `yaml:"function"`
+	// RequestParameters are the request parameters of the integration.
+	RequestParameters map[string]string `yaml:"requestParameters"`
+}
+
+// Load reads the manifest from the given path and validates it. Both YAML and JSON
+// files are supported.
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("manifest %s: %w", path, err)
+	}
+
+	return m, nil
+}
+
+// Parse parses the given manifest data and validates it. Unknown fields are rejected.
+func Parse(data []byte) (*Manifest, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var m Manifest
+	if err := decoder.Decode(&m); err != nil {
+		return nil, err
+	}
+
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// Validate checks that the manifest has a supported version, that every resource has
+// a unique name and that every reference points to a resource in the manifest.
+func (m *Manifest) Validate() error {
+	var errs []error
+	fail := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if m.Version != Version {
+		fail("unsupported version %d, expected %d", m.Version, Version)
+	}
+
+	roles := map[string]bool{}
+	services := map[string]bool{}
+	for i, role := range m.Roles {
+		if role.Name == "" {
+			fail("roles[%d]: name is required", i)
+		} else if roles[role.Name] {
+			fail("roles[%d]: duplicate name %q", i, role.Name)
+		}
+		if role.Service == "" {
+			fail("roles[%d]: service is required", i)
+		} else if services[role.Service] {
+			fail("roles[%d]: duplicate service %q", i, role.Service)
+		}
+		roles[role.Name] = true
+		services[role.Service] = true
+	}
+
+	buckets := map[string]bool{}
+	for i, bucket := range m.Buckets {
+		if bucket.Name == "" {
+			fail("buckets[%d]: name is required", i)
+		} else if buckets[bucket.Name] {
+			fail("buckets[%d]: duplicate name %q", i, bucket.Name)
+		}
+		buckets[bucket.Name] = true
+
+		for j, object := range bucket.Objects {
+			if object.Key == "" || object.Source == "" {
+				fail("buckets[%d].objects[%d]: key and source are required", i, j)
+			}
+		}
+	}
+
+	streams := map[string]bool{}
+	for i, stream := range m.Streams {
+		if stream.Name == "" {
+			fail("streams[%d]: name is required", i)
+		} else if streams[stream.Name] {
+			fail("streams[%d]: duplicate name %q", i, stream.Name)
+		}
+		streams[stream.Name] = true
+
+		switch stream.Mode {
+		case "", "PROVISIONED":
+			if stream.ShardCount < 0 {
+				fail("streams[%d]: shardCount must not be negative, got %d", i, stream.ShardCount)
+			}
+		case "ON_DEMAND":
+			if stream.ShardCount != 0 {
+				fail("streams[%d]: shardCount requires the `PROVISIONED` mode", i)
+			}
+		default:
+			fail("streams[%d]: mode must be `PROVISIONED` or `ON_DEMAND`, got %q", i, stream.Mode)
+		}
+		if stream.RetentionHours != 0 && (stream.RetentionHours < 24 || stream.RetentionHours > 8760) {
+			fail("streams[%d]: retentionHours must be between 24 and 8760, got %d", i, stream.RetentionHours)
+		}
+	}
+
+	// Functions assume the role of Lambda, so they cannot be created without it.
+	if len(m.Functions) > 0 && !services["lambda"] {
+		fail("functions require a role of the `lambda` service")
+	}
+
+	functions := map[string]bool{}
+	for i, function := range m.Functions {
+		if function.Name == "" {
+			fail("functions[%d]: name is required", i)
+		} else if functions[function.Name] {
+			fail("functions[%d]: duplicate name %q", i, function.Name)
+		}
+		functions[function.Name] = true
+
+		if function.Runtime != "go" && function.Runtime != "node" {
+			fail("functions[%d]: runtime must be `go` or `node`, got %q", i, function.Runtime)
+		}
+		if !buckets[function.Bucket] {
+			fail("functions[%d]: unknown bucket %q", i, function.Bucket)
+		}
+		if function.Key == "" {
+			fail("functions[%d]: key is required", i)
+		}
+		if (function.Source == "") == (function.Build == "") {
+			fail("functions[%d]: either source or build is required", i)
+		}
+		if function.MemorySize != 0 && (function.MemorySize < 128 || function.MemorySize > 10240) {
+			fail("functions[%d]: memorySize must be between 128 and 10240, got %d", i, function.MemorySize)
+		}
+		if function.Timeout < 0 || function.Timeout > 900 {
+			fail("functions[%d]: timeout must be between 1 and 900, got %d", i, function.Timeout)
+		}
+	}
+
+	consumers := map[string]bool{}
+	for i, mapping := range m.EventSourceMappings {
+		if !functions[mapping.Function] {
+			fail("eventSourceMappings[%d]: unknown function %q", i, mapping.Function)
+		}
+		if !streams[mapping.Stream] {
+			fail("eventSourceMappings[%d]: unknown stream %q", i, mapping.Stream)
+		}
+		if mapping.Consumer == "" {
+			continue
+		}
+		if !consumerName.MatchString(mapping.Consumer) {
+			fail("eventSourceMappings[%d]: consumer must be 1 to 128 letters, digits, `_`, `.` or `-`, got %q", i, mapping.Consumer)
+		}
+		// A consumer has a single subscription to a shard at a time, so it cannot be shared.
+		key := mapping.Stream + "/" + mapping.Consumer
+		if consumers[key] {
+			fail("eventSourceMappings[%d]: duplicate consumer %q of stream %q", i, mapping.Consumer, mapping.Stream)
+		}
+		consumers[key] = true
+	}
+
+	tables := map[string]bool{}
+	for i, table := range m.Tables {
+		if table.Name == "" {
+			fail("tables[%d]: name is required", i)
+		} else if tables[table.Name] {
+			fail("tables[%d]: duplicate name %q", i, table.Name)
+		}
+		tables[table.Name] = true
+
+		switch table.BillingMode {
+		case "", "PAY_PER_REQUEST":
+			if table.ReadCapacity != 0 || table.WriteCapacity != 0 {
+				fail("tables[%d]: readCapacity and writeCapacity require the `PROVISIONED` billing mode", i)
+			}
+		case "PROVISIONED":
+			if table.ReadCapacity <= 0 || table.WriteCapacity <= 0 {
+				fail("tables[%d]: readCapacity and writeCapacity are required", i)
+			}
+		default:
+			fail("tables[%d]: billingMode must be `PAY_PER_REQUEST` or `PROVISIONED`, got %q", i, table.BillingMode)
+		}
+	}
+
+	jobs := map[string]bool{}
+	for i, job := range m.GlueJobs {
+		if job.Name == "" {
+			fail("glueJobs[%d]: name is required", i)
+		} else if jobs[job.Name] {
+			fail("glueJobs[%d]: duplicate name %q", i, job.Name)
+		}
+		jobs[job.Name] = true
+
+		if !strings.HasPrefix(job.Script, "s3://") {
+			fail("glueJobs[%d]: script must be a `s3://` location, got %q", i, job.Script)
+		}
+		if job.MaxCapacity < 0 {
+			fail("glueJobs[%d]: maxCapacity must not be negative, got %v", i, job.MaxCapacity)
+		}
+		arguments := make([]string, 0, len(job.Buckets))
+		for argument := range job.Buckets {
+			arguments = append(arguments, argument)
+		}
+		sort.Strings(arguments)
+		for _, argument := range arguments {
+			if !buckets[job.Buckets[argument]] {
+				fail("glueJobs[%d].buckets.%s: unknown bucket %q", i, argument, job.Buckets[argument])
+			}
+		}
+	}
+
+	clusters := map[string]bool{}
+	for i, cluster := range m.Clusters {
+		if cluster.Identifier == "" {
+			fail("clusters[%d]: identifier is required", i)
+		} else if clusters[cluster.Identifier] {
+			fail("clusters[%d]: duplicate identifier %q", i, cluster.Identifier)
+		}
+		clusters[cluster.Identifier] = true
+
+		if cluster.Database == "" || cluster.Username == "" {
+			fail("clusters[%d]: database and username are required", i)
+		}
+	}
+	for i, job := range m.GlueJobs {
+		if job.Cluster != "" && !clusters[job.Cluster] {
+			fail("glueJobs[%d]: unknown cluster %q", i, job.Cluster)
+		}
+	}
+
+	apis := map[string]bool{}
+	for i, api := range m.APIs {
+		if api.Name == "" {
+			fail("apis[%d]: name is required", i)
+		} else if apis[api.Name] {
+			fail("apis[%d]: duplicate name %q", i, api.Name)
+		}
+		apis[api.Name] = true
+
+		if api.Protocol != "websocket" && api.Protocol != "http" {
+			fail("apis[%d]: protocol must be `websocket` or `http`, got %q", i, api.Protocol)
+		}
+		for j, route := range api.Routes {
+			if route.Path == "" || route.Method == "" {
+				fail("apis[%d].routes[%d]: path and method are required", i, j)
+			}
+			if !functions[route.Function] {
+				fail("apis[%d].routes[%d]: unknown function %q", i, j, route.Function)
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// WithEnvironment returns a copy of the manifest in which the names of the resources and
+// every reference to them are prefixed with the name of the given environment. The names
+// of the default environment are not changed.
+func (m *Manifest) WithEnvironment(env string) (*Manifest, error) {
+	if err := environment.Validate(env); err != nil {
+		return nil, err
+	}
+
+	name := func(name string) string { return environment.Name(env, name) }
+	c := *m
+	c.Environment = env
+
+	c.Roles = make([]Role, len(m.Roles))
+	for i, role := range m.Roles {
+		role.Name = name(role.Name)
+		c.Roles[i] = role
+	}
+
+	buckets := map[string]bool{}
+	c.Buckets = make([]Bucket, len(m.Buckets))
+	for i, bucket := range m.Buckets {
+		buckets[bucket.Name] = true
+		bucket.Name = name(bucket.Name)
+		c.Buckets[i] = bucket
+	}
+
+	c.Streams = make([]Stream, len(m.Streams))
+	for i, stream := range m.Streams {
+		stream.Name = name(stream.Name)
+		c.Streams[i] = stream
+	}
+
+	c.Functions = make([]Function, len(m.Functions))
+	for i, function := range m.Functions {
+		function.Name = name(function.Name)
+		function.Bucket = name(function.Bucket)
+		c.Functions[i] = function
+	}
+
+	c.EventSourceMappings = make([]EventSourceMapping, len(m.EventSourceMappings))
+	for i, mapping := range m.EventSourceMappings {
+		mapping.Function = name(mapping.Function)
+		mapping.Stream = name(mapping.Stream)
+		c.EventSourceMappings[i] = mapping
+	}
+
+	c.Tables = make([]Table, len(m.Tables))
+	for i, table := range m.Tables {
+		table.Name = name(table.Name)
+		c.Tables[i] = table
+	}
+
+	c.GlueJobs = make([]GlueJob, len(m.GlueJobs))
+	for i, job := range m.GlueJobs {
+		// Only scripts in buckets of the manifest move to the buckets of the environment.
+		if bucket := job.ScriptBucket(); buckets[bucket] {
+			job.Script = "s3://" + name(bucket) + strings.TrimPrefix(job.Script, "s3://"+bucket)
+		}
+		job.Name = name(job.Name)
+		if job.Buckets != nil {
+			jobBuckets := make(map[string]string, len(job.Buckets))
+			for argument, bucket := range job.Buckets {
+				jobBuckets[argument] = name(bucket)
+			}
+			job.Buckets = jobBuckets
+		}
+		if job.Cluster != "" {
+			job.Cluster = name(job.Cluster)
+		}
+		c.GlueJobs[i] = job
+	}
+
+	c.Clusters = make([]Cluster, len(m.Clusters))
+	for i, cluster := range m.Clusters {
+		cluster.Identifier = name(cluster.Identifier)
+		cluster.Username = name(cluster.Username)
+		c.Clusters[i] = cluster
+	}
+
+	c.APIs = make([]API, len(m.APIs))
+	for i, api := range m.APIs {
+		routes := make([]Route, len(api.Routes))
+		for j, route := range api.Routes {
+			route.Function = name(route.Function)
+			routes[j] = route
+		}
+		api.Name = name(api.Name)
+		api.Routes = routes
+		c.APIs[i] = api
+	}
+
+	return &c, nil
+}
+
+// Role returns the role that is assumed by the client of the given service.
+func (m *Manifest) Role(service string) (Role, bool) {
+	for _, role := range m.Roles {
+		if role.Service == service {
+			return role, true
+		}
+	}
+
+	return Role{}, false
+}
+
+// Cluster returns the cluster with the given identifier.
+func (m *Manifest) Cluster(identifier string) (Cluster, bool) {
+	for _, cluster := range m.Clusters {
+		if cluster.Identifier == identifier {
+			return cluster, true
+		}
+	}
+
+	return Cluster{}, false
+}
+
+// ScriptBucket returns the name of the bucket that contains the script of the job.
+func (j GlueJob) ScriptBucket() string {
+	bucket, _, _ := strings.Cut(strings.TrimPrefix(j.Script, "s3://"), "/")
+	return bucket
+}
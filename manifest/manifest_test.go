@@ -0,0 +1,162 @@
+package manifest
+
+import (
+	"strings"
+	"testing"
+)
+
+const testManifest = `
+version: 1
+roles:
+  - name: s3-role
+    service: s3
+  - name: lambda-role
+    service: lambda
+buckets:
+  - name: lambda-bucket
+functions:
+  - name: Getter
+    runtime: go
+    bucket: lambda-bucket
+    key: getter.zip
+    source: getter.zip
+streams:
+  - name: test-stream
+eventSourceMappings:
+  - function: Getter
+    stream: test-stream
+apis:
+  - name: test-api
+    protocol: http
+    routes:
+      - path: /getter
+        method: GET
+        function: Getter
+`
+
+func TestLoad(t *testing.T) {
+	m, err := Load("../manifest.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(m.Functions) == 0 {
+		t.Errorf("expected functions in the manifest")
+	}
+}
+
+func TestParse(t *testing.T) {
+	m, err := Parse([]byte(testManifest))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if m.Functions[0].Name != "Getter" {
+		t.Errorf("unexpected function name: %s", m.Functions[0].Name)
+	}
+
+	role, ok := m.Role("s3")
+	if !ok || role.Name != "s3-role" {
+		t.Errorf("unexpected role: %v", role)
+	}
+}
+
+func TestParse_JSON(t *testing.T) {
+	_, err := Parse([]byte(`{"version": 1, "tables": [{"name": "test"}]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestParse_UnknownField(t *testing.T) {
+	_, err := Parse([]byte("version: 1\nqueues: []\n"))
+	if err == nil {
+		t.Fatalf("expected error for unknown field")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	m, err := Parse([]byte(testManifest))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m.Version = 2
+	m.Roles = m.Roles[:1]
+	m.Functions[0].Bucket = "missing-bucket"
+	m.Functions[0].Build = "getter"
+	m.EventSourceMappings[0].Stream = "missing-stream"
+	m.Streams = append(m.Streams, Stream{Name: "test-stream"}, Stream{Name: "on-demand", Mode: "ON_DEMAND", ShardCount: 2, RetentionHours: 12})
+	m.Functions[0].MemorySize = 64
+	m.Tables = []Table{{Name: "test", BillingMode: "PROVISIONED", ReadCapacity: 5}}
+	m.EventSourceMappings = append(m.EventSourceMappings, EventSourceMapping{Function: m.Functions[0].Name, Stream: "test-stream", Consumer: "tap/1"})
+	m.GlueJobs = []GlueJob{{Name: "etl", Script: "s3://lambda-bucket/etl.py", Buckets: map[string]string{"target_bucket": "missing-target"}, Cluster: "missing-cluster"}}
+
+	err = m.Validate()
+	if err == nil {
+		t.Fatalf("expected validation error")
+	}
+
+	for _, expected := range []string{"unsupported version", "unknown bucket", "either source or build", "unknown stream", "duplicate name", "memorySize must be", "readCapacity and writeCapacity are required", "shardCount requires", "retentionHours must be", "consumer must be", "unknown bucket \"missing-target\"", "unknown cluster", "role of the `lambda` service"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected error to contain %q, got: %v", expected, err)
+		}
+	}
+}
+
+func TestGlueJob_ScriptBucket(t *testing.T) {
+	job := GlueJob{Name: "etl", Script: "s3://raw-data/scripts/etl.py"}
+	if bucket := job.ScriptBucket(); bucket != "raw-data" {
+		t.Errorf("unexpected bucket: %s", bucket)
+	}
+}
+
+func TestWithEnvironment(t *testing.T) {
+	m, err := Parse([]byte(testManifest))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	m.GlueJobs = []GlueJob{{Name: "etl", Script: "s3://lambda-bucket/scripts/etl.py", Buckets: map[string]string{"source_bucket": "lambda-bucket"}, Cluster: "db1"}}
+	m.Clusters = []Cluster{{Identifier: "db1", Database: "uber-data", Username: "dbpass"}}
+
+	staging, err := m.WithEnvironment("staging")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := staging.Validate(); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+	if staging.Environment != "staging" {
+		t.Errorf("unexpected environment: %s", staging.Environment)
+	}
+	if name := staging.Functions[0].Name; name != "staging-Getter" {
+		t.Errorf("unexpected function name: %s", name)
+	}
+	if route := staging.APIs[0].Routes[0]; route.Function != "staging-Getter" {
+		t.Errorf("unexpected route function: %s", route.Function)
+	}
+	if script := staging.GlueJobs[0].Script; script != "s3://staging-lambda-bucket/scripts/etl.py" {
+		t.Errorf("unexpected script: %s", script)
+	}
+	if bucket := staging.GlueJobs[0].Buckets["source_bucket"]; bucket != "staging-lambda-bucket" {
+		t.Errorf("unexpected job bucket: %s", bucket)
+	}
+	if cluster, ok := staging.Cluster(staging.GlueJobs[0].Cluster); !ok || cluster.Username != "staging-dbpass" || cluster.Database != "uber-data" {
+		t.Errorf("unexpected job cluster: %v", cluster)
+	}
+
+	if name := m.Functions[0].Name; name != "Getter" {
+		t.Errorf("expected original manifest to be unchanged, got function name %s", name)
+	}
+	if route := m.APIs[0].Routes[0]; route.Function != "Getter" {
+		t.Errorf("expected original manifest to be unchanged, got route function %s", route.Function)
+	}
+	if bucket := m.GlueJobs[0].Buckets["source_bucket"]; bucket != "lambda-bucket" {
+		t.Errorf("expected original manifest to be unchanged, got job bucket %s", bucket)
+	}
+
+	if _, err := m.WithEnvironment("Staging"); err == nil {
+		t.Errorf("expected error for invalid environment")
+	}
+}
@@ -0,0 +1,141 @@
+// Package migrations evolves the schema of the Aurora database with versioned SQL
+// migrations. The migrations are embedded from the `sql` directory, where every migration
+// is a pair of files `<version>_<name>.up.sql` and `<version>_<name>.down.sql`, e.g.
+// `0002_add_speed_index.up.sql`. The runner applies them in the order of their versions
+// through the Data API and records the applied versions in the schema_migrations table.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// fileName matches the name of a migration file.
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a versioned change of the database schema.
+type Migration struct {
+	// Version orders the migrations. Every migration has a unique version.
+	Version int64
+	// Name describes the migration.
+	Name string
+	// Up are the statements that apply the migration.
+	Up []string
+	// Down are the statements that revert the migration. Empty means the migration cannot
+	// be reverted.
+	Down []string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// All returns the embedded migrations ordered by their version.
+func All() ([]Migration, error) {
+	sub, err := fs.Sub(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+
+	return Load(sub)
+}
+
+// Load returns the migrations of the `.sql` files in the root of the given file system
+// ordered by their version. Every migration needs an up file, the down file is optional.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	down := map[int64]bool{}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s: expected a name like 0001_create_table.up.sql", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: version must be a positive number", entry.Name())
+		}
+
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		statements := split(string(data))
+		if len(statements) == 0 {
+			return nil, fmt.Errorf("migration %s: no statements", entry.Name())
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %s: version %d is already used by %s", entry.Name(), version, m)
+		}
+
+		if match[3] == "up" {
+			m.Up = statements
+		} else {
+			m.Down = statements
+			down[version] = true
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if len(m.Up) == 0 {
+			return nil, fmt.Errorf("migration %s: missing %s.up.sql", m, m)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// split returns the statements of the given SQL file. Statements end with a semicolon at
+// the end of a line and lines starting with `--` are comments.
+func split(sql string) []string {
+	var statements []string
+	var current []string
+	flush := func() {
+		if statement := strings.TrimSpace(strings.Join(current, "\n")); statement != "" {
+			statements = append(statements, statement)
+		}
+		current = nil
+	}
+
+	for _, line := range strings.Split(sql, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		if strings.HasSuffix(trimmed, ";") {
+			current = append(current, strings.TrimSuffix(strings.TrimRight(line, " \t\r"), ";"))
+			flush()
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+
+	return statements
+}
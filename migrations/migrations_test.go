@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestAll(t *testing.T) {
+	migrations, err := All()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i, m := range migrations {
+		if i > 0 && m.Version <= migrations[i-1].Version {
+			t.Errorf("expected migration %s to be ordered after %s", m, migrations[i-1])
+		}
+		if len(m.Down) == 0 {
+			t.Errorf("expected migration %s to have a down migration", m)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0002_add_index.up.sql":      {Data: []byte("-- Speeds by way.\nCREATE INDEX a\n  ON t (b);\nCREATE INDEX c ON t (d);\n")},
+		"0001_create_table.up.sql":   {Data: []byte("CREATE TABLE t (b INT, d INT);")},
+		"0001_create_table.down.sql": {Data: []byte("DROP TABLE t;")},
+		"README.md":                  {Data: []byte("not a migration")},
+	}
+
+	migrations, err := Load(fsys)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []Migration{
+		{Version: 1, Name: "create_table", Up: []string{"CREATE TABLE t (b INT, d INT)"}, Down: []string{"DROP TABLE t"}},
+		{Version: 2, Name: "add_index", Up: []string{"CREATE INDEX a\n  ON t (b)", "CREATE INDEX c ON t (d)"}},
+	}
+	if !reflect.DeepEqual(migrations, expected) {
+		t.Errorf("expected %#v, got %#v", expected, migrations)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"bad name":          {"create_table.up.sql": {Data: []byte("SELECT 1;")}},
+		"duplicate version": {"0001_a.up.sql": {Data: []byte("SELECT 1;")}, "0001_b.up.sql": {Data: []byte("SELECT 1;")}},
+		"missing up":        {"0001_a.down.sql": {Data: []byte("SELECT 1;")}},
+		"empty":             {"0001_a.up.sql": {Data: []byte("-- Nothing yet.\n")}},
+	}
+
+	for name, fsys := range tests {
+		if _, err := Load(fsys); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
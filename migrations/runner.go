@@ -0,0 +1,219 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+)
+
+// Table is the table in which the runner records the versions of the applied migrations.
+const Table = "schema_migrations"
+
+// Database executes the statements of the runner.
+type Database interface {
+	// Query executes the given query and returns its rows.
+	Query(ctx context.Context, sql string) ([][]types.Field, error)
+	// Exec executes the given statements in a single transaction.
+	Exec(ctx context.Context, statements []string) error
+}
+
+// DataAPI is the database of an Aurora cluster that is accessed through the Data API.
+type DataAPI struct {
+	aurora     *awsService.Aurora
+	database   string
+	clusterARN string
+	secretARN  string
+}
+
+// NewDataAPI returns the given database of the Aurora cluster with the given ARN, which
+// is accessed with the secret with the given ARN.
+func NewDataAPI(aurora *awsService.Aurora, database, clusterARN, secretARN string) *DataAPI {
+	return &DataAPI{
+		aurora:     aurora,
+		database:   database,
+		clusterARN: clusterARN,
+		secretARN:  secretARN,
+	}
+}
+
+// Query executes the given query and returns its rows.
+func (d *DataAPI) Query(ctx context.Context, sql string) ([][]types.Field, error) {
+	output, err := d.aurora.ExecuteStatement(ctx, d.database, d.clusterARN, d.secretARN, sql)
+	if err != nil {
+		return nil, err
+	}
+
+	return output.Records, nil
+}
+
+// Exec executes the given statements in a single transaction.
+func (d *DataAPI) Exec(ctx context.Context, statements []string) error {
+	return d.aurora.ExecuteTransaction(ctx, d.database, d.clusterARN, d.secretARN, statements)
+}
+
+// Status is a migration together with whether it is applied.
+type Status struct {
+	Migration
+	Applied bool
+}
+
+// Runner applies and reverts migrations on a database. Every migration runs in its own
+// transaction together with the statement that records it, so a failed migration leaves
+// neither changes nor a record behind.
+type Runner struct {
+	db         Database
+	migrations []Migration
+}
+
+// NewRunner returns a runner of the given migrations, ordered by their version, on the
+// given database.
+func NewRunner(db Database, migrations []Migration) *Runner {
+	return &Runner{db: db, migrations: migrations}
+}
+
+// Status returns every migration and whether it is applied.
+func (r *Runner) Status(ctx context.Context) ([]Status, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]Status, 0, len(r.migrations))
+	for _, m := range r.migrations {
+		status = append(status, Status{Migration: m, Applied: applied[m.Version]})
+	}
+	return status, nil
+}
+
+// Pending returns the migrations that are not applied yet in the order they are applied.
+func (r *Runner) Pending(ctx context.Context) ([]Migration, error) {
+	status, err := r.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, s := range status {
+		if !s.Applied {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies the pending migrations in the order of their versions and creates the table
+// of the applied migrations before the first one. It returns the
+// migrations that were applied, which are the ones before the failed migration if a
+// migration fails.
+func (r *Runner) Up(ctx context.Context) ([]Migration, error) {
+	pending, err := r.Pending(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	create := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (version BIGINT PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)", Table)
+	if err := r.db.Exec(ctx, []string{create}); err != nil {
+		return nil, fmt.Errorf("creating %s: %w", Table, err)
+	}
+
+	var applied []Migration
+	for _, m := range pending {
+		record := fmt.Sprintf("INSERT INTO %s (version, name) VALUES (%d, '%s')", Table, m.Version, m.Name)
+		if err := r.db.Exec(ctx, append(append([]string(nil), m.Up...), record)); err != nil {
+			return applied, fmt.Errorf("applying migration %s: %w", m, err)
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// Down reverts the given number of applied migrations, starting with the latest. It
+// returns the migrations that were reverted, which are the ones before the failed
+// migration if a migration fails.
+func (r *Runner) Down(ctx context.Context, steps int) ([]Migration, error) {
+	status, err := r.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	for i := len(status) - 1; i >= 0 && len(reverted) < steps; i-- {
+		if !status[i].Applied {
+			continue
+		}
+
+		m := status[i].Migration
+		if len(m.Down) == 0 {
+			return reverted, fmt.Errorf("reverting migration %s: no down migration", m)
+		}
+
+		record := fmt.Sprintf("DELETE FROM %s WHERE version = %d", Table, m.Version)
+		if err := r.db.Exec(ctx, append(append([]string(nil), m.Down...), record)); err != nil {
+			return reverted, fmt.Errorf("reverting migration %s: %w", m, err)
+		}
+		reverted = append(reverted, m)
+	}
+	return reverted, nil
+}
+
+// applied returns the versions the table of the applied migrations records. Without the
+// table no migration is applied; it is only created by Up, so reading the versions never
+// changes the database. A version without a migration is an error, since the database was
+// migrated by a newer version of the migrations.
+func (r *Runner) applied(ctx context.Context) (map[int64]bool, error) {
+	exists, err := r.tableExists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return map[int64]bool{}, nil
+	}
+
+	rows, err := r.db.Query(ctx, fmt.Sprintf("SELECT version FROM %s ORDER BY version", Table))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", Table, err)
+	}
+
+	known := map[int64]bool{}
+	for _, m := range r.migrations {
+		known[m.Version] = true
+	}
+
+	applied := map[int64]bool{}
+	for _, row := range rows {
+		if len(row) == 0 {
+			return nil, fmt.Errorf("reading %s: empty row", Table)
+		}
+		version, ok := row[0].(*types.FieldMemberLongValue)
+		if !ok {
+			return nil, fmt.Errorf("reading %s: unexpected version %T", Table, row[0])
+		}
+		if !known[version.Value] {
+			return nil, fmt.Errorf("migration %d is applied but unknown, the database was migrated by a newer version", version.Value)
+		}
+		applied[version.Value] = true
+	}
+	return applied, nil
+}
+
+// tableExists reports whether the table of the applied migrations exists in the current
+// schema.
+func (r *Runner) tableExists(ctx context.Context) (bool, error) {
+	rows, err := r.db.Query(ctx, fmt.Sprintf("SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = '%s'", Table))
+	if err != nil {
+		return false, fmt.Errorf("looking up %s: %w", Table, err)
+	}
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return false, fmt.Errorf("looking up %s: empty result", Table)
+	}
+	count, ok := rows[0][0].(*types.FieldMemberLongValue)
+	if !ok {
+		return false, fmt.Errorf("looking up %s: unexpected count %T", Table, rows[0][0])
+	}
+	return count.Value > 0, nil
+}
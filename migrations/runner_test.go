@@ -0,0 +1,212 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
+)
+
+// fakeDatabase records the versions of the applied migrations like the schema_migrations
+// table, which does not exist while the versions are nil, and fails statements that
+// contain `fail`.
+type fakeDatabase struct {
+	versions map[int64]bool
+	executed []string
+}
+
+func (f *fakeDatabase) Query(ctx context.Context, sql string) ([][]types.Field, error) {
+	if strings.Contains(sql, "information_schema.tables") {
+		var count int64
+		if f.versions != nil {
+			count = 1
+		}
+		return [][]types.Field{{&types.FieldMemberLongValue{Value: count}}}, nil
+	}
+	if f.versions == nil {
+		return nil, errors.New("relation schema_migrations does not exist")
+	}
+
+	var versions []int64
+	for version := range f.versions {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+
+	var rows [][]types.Field
+	for _, version := range versions {
+		rows = append(rows, []types.Field{&types.FieldMemberLongValue{Value: version}})
+	}
+	return rows, nil
+}
+
+func (f *fakeDatabase) Exec(ctx context.Context, statements []string) error {
+	var versions map[int64]bool
+	if f.versions != nil {
+		versions = map[int64]bool{}
+		for version := range f.versions {
+			versions[version] = true
+		}
+	}
+
+	for _, statement := range statements {
+		if strings.Contains(statement, "fail") {
+			return errors.New("syntax error")
+		}
+		if strings.HasPrefix(statement, "CREATE TABLE IF NOT EXISTS schema_migrations") {
+			if versions == nil {
+				versions = map[int64]bool{}
+			}
+			continue
+		}
+		if strings.Contains(statement, "schema_migrations") && versions == nil {
+			return errors.New("relation schema_migrations does not exist")
+		}
+
+		var version int64
+		if _, err := fmt.Sscanf(statement, "INSERT INTO schema_migrations (version, name) VALUES (%d,", &version); err == nil {
+			versions[version] = true
+		}
+		if _, err := fmt.Sscanf(statement, "DELETE FROM schema_migrations WHERE version = %d", &version); err == nil {
+			delete(versions, version)
+		}
+	}
+
+	// Like a transaction, the statements only take effect if every statement succeeds.
+	f.versions = versions
+	f.executed = append(f.executed, statements...)
+	return nil
+}
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "create_table", Up: []string{"CREATE TABLE t (a INT)"}, Down: []string{"DROP TABLE t"}},
+	{Version: 2, Name: "add_column", Up: []string{"ALTER TABLE t ADD b INT"}, Down: []string{"ALTER TABLE t DROP b"}},
+	{Version: 3, Name: "add_index", Up: []string{"CREATE INDEX i ON t (b)"}},
+}
+
+func versions(migrations []Migration) []int64 {
+	var versions []int64
+	for _, m := range migrations {
+		versions = append(versions, m.Version)
+	}
+	return versions
+}
+
+func TestRunner_Up(t *testing.T) {
+	db := &fakeDatabase{versions: map[int64]bool{1: true}}
+	runner := NewRunner(db, testMigrations)
+
+	pending, err := runner.Pending(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := versions(pending); !reflect.DeepEqual(got, []int64{2, 3}) {
+		t.Errorf("expected pending migrations [2 3], got %v", got)
+	}
+
+	applied, err := runner.Up(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := versions(applied); !reflect.DeepEqual(got, []int64{2, 3}) {
+		t.Errorf("expected applied migrations [2 3], got %v", got)
+	}
+	if !reflect.DeepEqual(db.versions, map[int64]bool{1: true, 2: true, 3: true}) {
+		t.Errorf("expected every version to be recorded, got %v", db.versions)
+	}
+
+	// Running again applies nothing.
+	applied, err = runner.Up(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("expected no migrations to be applied, got %v", applied)
+	}
+}
+
+func TestRunner_Up_Failed(t *testing.T) {
+	migrations := append(append([]Migration(nil), testMigrations[:2]...), Migration{Version: 3, Name: "broken", Up: []string{"fail"}})
+	db := &fakeDatabase{versions: map[int64]bool{}}
+
+	applied, err := NewRunner(db, migrations).Up(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "0003_broken") {
+		t.Fatalf("expected the error to name the failed migration, got %v", err)
+	}
+	if got := versions(applied); !reflect.DeepEqual(got, []int64{1, 2}) {
+		t.Errorf("expected applied migrations [1 2], got %v", got)
+	}
+	if db.versions[3] {
+		t.Error("expected the failed migration not to be recorded")
+	}
+}
+
+func TestRunner_Down(t *testing.T) {
+	db := &fakeDatabase{versions: map[int64]bool{1: true, 2: true}}
+	runner := NewRunner(db, testMigrations)
+
+	reverted, err := runner.Down(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := versions(reverted); !reflect.DeepEqual(got, []int64{2}) {
+		t.Errorf("expected reverted migrations [2], got %v", got)
+	}
+	if !reflect.DeepEqual(db.versions, map[int64]bool{1: true}) {
+		t.Errorf("expected only version 1 to be recorded, got %v", db.versions)
+	}
+	if !strings.Contains(strings.Join(db.executed, ";"), "ALTER TABLE t DROP b") {
+		t.Errorf("expected the down migration to be executed, got %v", db.executed)
+	}
+}
+
+func TestRunner_Down_Irreversible(t *testing.T) {
+	db := &fakeDatabase{versions: map[int64]bool{1: true, 2: true, 3: true}}
+
+	if _, err := NewRunner(db, testMigrations).Down(context.Background(), 1); err == nil {
+		t.Fatal("expected an error for a migration without a down migration")
+	}
+	if len(db.versions) != 3 {
+		t.Errorf("expected every version to stay recorded, got %v", db.versions)
+	}
+}
+
+func TestRunner_Status_UnknownVersion(t *testing.T) {
+	db := &fakeDatabase{versions: map[int64]bool{1: true, 4: true}}
+
+	if _, err := NewRunner(db, testMigrations).Status(context.Background()); err == nil {
+		t.Fatal("expected an error for an applied version without a migration")
+	}
+}
+
+func TestRunner_MissingTable(t *testing.T) {
+	db := &fakeDatabase{}
+	runner := NewRunner(db, testMigrations)
+
+	pending, err := runner.Pending(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := versions(pending); !reflect.DeepEqual(got, []int64{1, 2, 3}) {
+		t.Errorf("expected pending migrations [1 2 3], got %v", got)
+	}
+	if _, err := runner.Status(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if db.versions != nil || len(db.executed) != 0 {
+		t.Errorf("expected reading the status not to change the database, got %v", db.executed)
+	}
+
+	applied, err := runner.Up(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := versions(applied); !reflect.DeepEqual(got, []int64{1, 2, 3}) {
+		t.Errorf("expected applied migrations [1 2 3], got %v", got)
+	}
+}
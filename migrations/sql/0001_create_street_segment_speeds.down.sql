@@ -0,0 +1 @@
+DROP TABLE IF EXISTS street_segment_speeds;
@@ -0,0 +1,5 @@
+-- Generated by `schema.CreateTableSQL` from the `SegmentSpeed` record in the `schema`
+-- package. The schema tests fail if this statement or the casts and the insert of
+-- `services/glue/raw_data_etl.py` differ from the record. The table may already exist if
+-- it was created by setup before migrations were introduced.
+CREATE TABLE IF NOT EXISTS street_segment_speeds (id SERIAL PRIMARY KEY, year INT, month INT, day INT, hour INT, utc_timestamp VARCHAR(100), start_junction_id VARCHAR(200), end_junction_id VARCHAR(200), osm_way_id BIGINT, osm_start_node_id BIGINT, osm_end_node_id BIGINT, speed_mph_mean FLOAT, speed_mph_stddev FLOAT);
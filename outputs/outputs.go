@@ -0,0 +1,145 @@
+// Package outputs reads and writes the outputs document that setup writes after every
+// successful run. The document contains the IDs, ARNs and names of the provisioned
+// resources, so tools and services do not need to look them up in the logs.
+package outputs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/florianwoelki/uber-movement-speed/environment"
+)
+
+// DefaultPath is the path of the outputs document that setup writes by default.
+const DefaultPath = "outputs.json"
+
+// Path returns the path of the outputs document that setup writes by default for the
+// given environment.
+func Path(env string) string {
+	if env == "" {
+		return DefaultPath
+	}
+	return fmt.Sprintf("outputs.%s.json", env)
+}
+
+// Outputs contains the provisioned resources by their name, which is prefixed with the
+// name of the environment.
+type Outputs struct {
+	// Environment is the environment the resources belong to. It is empty for the default
+	// environment.
+	Environment string `json:"environment,omitempty"`
+
+	APIs      map[string]API      `json:"apis"`
+	Streams   map[string]Stream   `json:"streams"`
+	Functions map[string]Function `json:"functions"`
+	Clusters  map[string]Cluster  `json:"clusters"`
+	Tables    []string            `json:"tables"`
+	Buckets   []string            `json:"buckets"`
+}
+
+// API is a deployed API Gateway.
+type API struct {
+	ID       string `json:"id"`
+	Protocol string `json:"protocol"`
+	// Stage is the stage the API Gateway is deployed to.
+	Stage string `json:"stage"`
+	// Endpoint is the base URL of the API Gateway without a stage.
+	Endpoint string `json:"endpoint"`
+	// InvokeURL is the URL of the stage the API Gateway is deployed to.
+	InvokeURL string `json:"invokeUrl"`
+}
+
+// Stream is a Kinesis stream.
+type Stream struct {
+	ARN string `json:"arn"`
+}
+
+// Function is a Lambda function.
+type Function struct {
+	ARN string `json:"arn"`
+}
+
+// Cluster is an Aurora DB cluster together with the secret that is used to access it.
+type Cluster struct {
+	ARN       string `json:"arn"`
+	SecretARN string `json:"secretArn"`
+	Database  string `json:"database"`
+}
+
+// New returns empty outputs.
+func New() *Outputs {
+	return &Outputs{
+		APIs:      map[string]API{},
+		Streams:   map[string]Stream{},
+		Functions: map[string]Function{},
+		Clusters:  map[string]Cluster{},
+	}
+}
+
+// Load reads the outputs document at the given path.
+func Load(path string) (*Outputs, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	o := New()
+	if err := json.Unmarshal(data, o); err != nil {
+		return nil, fmt.Errorf("outputs %s: %w", path, err)
+	}
+
+	return o, nil
+}
+
+// Write writes the outputs document to the given path.
+func (o *Outputs) Write(path string) error {
+	data, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// name returns the name of the resource with the given manifest name in the environment
+// of the outputs.
+func (o *Outputs) name(name string) string {
+	return environment.Name(o.Environment, name)
+}
+
+// API returns the API Gateway with the given name in the manifest.
+func (o *Outputs) API(name string) (API, error) {
+	api, ok := o.APIs[o.name(name)]
+	if !ok {
+		return API{}, fmt.Errorf("outputs: unknown api %q", name)
+	}
+	return api, nil
+}
+
+// Stream returns the Kinesis stream with the given name in the manifest.
+func (o *Outputs) Stream(name string) (Stream, error) {
+	stream, ok := o.Streams[o.name(name)]
+	if !ok {
+		return Stream{}, fmt.Errorf("outputs: unknown stream %q", name)
+	}
+	return stream, nil
+}
+
+// Function returns the Lambda function with the given name in the manifest.
+func (o *Outputs) Function(name string) (Function, error) {
+	function, ok := o.Functions[o.name(name)]
+	if !ok {
+		return Function{}, fmt.Errorf("outputs: unknown function %q", name)
+	}
+	return function, nil
+}
+
+// Cluster returns the Aurora DB cluster with the given identifier in the manifest.
+func (o *Outputs) Cluster(identifier string) (Cluster, error) {
+	cluster, ok := o.Clusters[o.name(identifier)]
+	if !ok {
+		return Cluster{}, fmt.Errorf("outputs: unknown cluster %q", identifier)
+	}
+	return cluster, nil
+}
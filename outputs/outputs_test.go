@@ -0,0 +1,70 @@
+package outputs
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteLoad(t *testing.T) {
+	o := New()
+	o.APIs["test-api"] = API{
+		ID:        "test-id",
+		Protocol:  "websocket",
+		Stage:     "dev",
+		Endpoint:  "ws://localhost:4510",
+		InvokeURL: "ws://localhost:4510/dev",
+	}
+	o.Streams["test-stream"] = Stream{ARN: "test-stream-arn"}
+	o.Functions["test-function"] = Function{ARN: "test-function-arn"}
+	o.Clusters["test-cluster"] = Cluster{ARN: "test-cluster-arn", SecretARN: "test-secret-arn", Database: "test-db"}
+	o.Tables = []string{"test-table"}
+	o.Buckets = []string{"test-bucket"}
+
+	path := filepath.Join(t.TempDir(), DefaultPath)
+	if err := o.Write(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(o, loaded) {
+		t.Errorf("unexpected outputs: %+v", loaded)
+	}
+
+	api, err := loaded.API("test-api")
+	if err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if api.InvokeURL != "ws://localhost:4510/dev" {
+		t.Errorf("unexpected invoke url: %s", api.InvokeURL)
+	}
+
+	if _, err := loaded.Stream("missing-stream"); err == nil {
+		t.Errorf("expected error for unknown stream")
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	o := New()
+	o.Environment = "staging"
+	o.APIs["staging-test-api"] = API{ID: "test-id"}
+
+	api, err := o.API("test-api")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if api.ID != "test-id" {
+		t.Errorf("unexpected api: %+v", api)
+	}
+
+	if path := Path("staging"); path != "outputs.staging.json" {
+		t.Errorf("unexpected path: %s", path)
+	}
+	if path := Path(""); path != DefaultPath {
+		t.Errorf("unexpected path of default environment: %s", path)
+	}
+}
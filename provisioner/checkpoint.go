@@ -0,0 +1,189 @@
+package provisioner
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+	"github.com/florianwoelki/uber-movement-speed/manifest"
+	"github.com/florianwoelki/uber-movement-speed/state"
+)
+
+// loadState reads the checkpoint file of the manifest. Without a checkpoint file or if
+// the file belongs to a different manifest, every step is run.
+func (p *Provisioner) loadState() (*state.State, error) {
+	fingerprint, err := state.Fingerprint(p.manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.StatePath == "" {
+		return state.New(fingerprint), nil
+	}
+
+	st, err := state.Load(p.StatePath, fingerprint)
+	if err != nil {
+		return nil, err
+	}
+	if len(st.Completed) > 0 {
+		log.Printf("Resuming setup from `%s`, %d steps were completed by a previous run", p.StatePath, len(st.Completed))
+	}
+
+	return st, nil
+}
+
+// loadResumedRoles stores the credentials of the IAM roles if the given state records a
+// completed role task. The skipped role tasks do not store the credentials of their role,
+// so without them the clients would use the default credentials.
+func (p *Provisioner) loadResumedRoles(ctx context.Context, st *state.State) {
+	for _, role := range p.manifest.Roles {
+		if st.Done(roleTask(role.Name)) {
+			p.loadRoles(ctx)
+			return
+		}
+	}
+}
+
+// rerun reports whether the task with the given name runs even if the state records it as
+// completed. The fingerprint of the state only covers the manifest, while the code of a
+// function may have changed since, so function tasks build it again and update the
+// function if it did.
+func rerun(name string) bool {
+	return strings.HasPrefix(name, functionTask(""))
+}
+
+// checkpoint makes every task of the given graph skip itself if the given state records
+// it as completed and record itself once it completes. Function tasks are never skipped,
+// see rerun. It returns the names of the tasks that were started, which is complete once
+// the graph ran.
+func (p *Provisioner) checkpoint(g *graph, st *state.State) map[string]bool {
+	started := map[string]bool{}
+	for _, name := range g.order {
+		t := g.tasks[name]
+		run := t.run
+		t.run = func(ctx context.Context) error {
+			if st.Done(t.name) && !rerun(t.name) {
+				log.Printf("Skipping %s, it was completed by a previous run", t.name)
+				return nil
+			}
+
+			p.mu.Lock()
+			started[t.name] = true
+			p.mu.Unlock()
+
+			if err := run(ctx); err != nil {
+				return err
+			}
+
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			st.Complete(t.name)
+			if p.StatePath == "" {
+				return nil
+			}
+			if err := st.Write(p.StatePath); err != nil {
+				return fmt.Errorf("writing state: %w", err)
+			}
+			return nil
+		}
+	}
+
+	return started
+}
+
+// missingResources returns the descriptions of the resources of the manifest that do not
+// exist yet, which are the resources Apply creates.
+func (p *Provisioner) missingResources(ctx context.Context) (map[string]bool, error) {
+	changes, err := p.plan(ctx, false)
+	if err != nil {
+		return nil, fmt.Errorf("inspecting resources before setup: %w", err)
+	}
+
+	missing := map[string]bool{}
+	for _, change := range changes {
+		if change.Action == awsService.ActionCreate {
+			missing[change.Resource] = true
+		}
+	}
+
+	return missing, nil
+}
+
+// rollback deletes the resources that were missing before Apply and whose task was
+// started, in the reverse order of Apply. The tasks of the deleted resources are removed
+// from the given state.
+func (p *Provisioner) rollback(ctx context.Context, st *state.State, started, missing map[string]bool) error {
+	created := &manifest.Manifest{}
+	var tasks []string
+	include := func(task, description string) bool {
+		if !started[task] || !missing[description] {
+			return false
+		}
+		tasks = append(tasks, task)
+		return true
+	}
+
+	for _, role := range p.manifest.Roles {
+		if include(roleTask(role.Name), fmt.Sprintf("IAM role `%s`", role.Name)) {
+			created.Roles = append(created.Roles, role)
+		}
+	}
+	for _, bucket := range p.manifest.Buckets {
+		if include(bucketTask(bucket.Name), fmt.Sprintf("S3 bucket `%s`", bucket.Name)) {
+			created.Buckets = append(created.Buckets, bucket)
+		}
+	}
+	for _, job := range p.manifest.GlueJobs {
+		if include(glueJobTask(job.Name), fmt.Sprintf("glue job `%s`", job.Name)) {
+			created.GlueJobs = append(created.GlueJobs, job)
+		}
+	}
+	for _, function := range p.manifest.Functions {
+		if include(functionTask(function.Name), fmt.Sprintf("lambda function `%s`", function.Name)) {
+			created.Functions = append(created.Functions, function)
+		}
+	}
+	for _, api := range p.manifest.APIs {
+		if include(apiTask(api.Name), fmt.Sprintf("%s API Gateway `%s`", api.Protocol, api.Name)) {
+			created.APIs = append(created.APIs, api)
+		}
+	}
+	for _, stream := range p.manifest.Streams {
+		if include(streamTask(stream.Name), fmt.Sprintf("kinesis stream `%s`", stream.Name)) {
+			created.Streams = append(created.Streams, stream)
+		}
+	}
+	for _, mapping := range p.manifest.EventSourceMappings {
+		if include(mappingTask(mapping), fmt.Sprintf("event source mapping `%s` -> `%s`", mapping.Stream, mapping.Function)) {
+			created.EventSourceMappings = append(created.EventSourceMappings, mapping)
+		}
+	}
+	for _, table := range p.manifest.Tables {
+		if include(tableTask(table.Name), fmt.Sprintf("dynamodb table `%s`", table.Name)) {
+			created.Tables = append(created.Tables, table)
+		}
+	}
+	for _, c := range p.manifest.Clusters {
+		if include(clusterTask(c.Identifier), fmt.Sprintf("aurora cluster `%s`", c.Identifier)) {
+			created.Clusters = append(created.Clusters, c)
+		}
+	}
+
+	log.Printf("Rolling back %d resources created by the failed setup...", len(tasks))
+	d := &destroyer{manifest: created}
+	if err := p.destroy(ctx, d); err != nil {
+		return fmt.Errorf("rolling back: %w", err)
+	}
+	log.Printf("Rolled back %d resources", len(d.removed))
+
+	st.Forget(tasks...)
+	if p.StatePath == "" {
+		return nil
+	}
+	if len(st.Completed) == 0 {
+		return state.Remove(p.StatePath)
+	}
+	return st.Write(p.StatePath)
+}
@@ -0,0 +1,202 @@
+package provisioner
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+	"github.com/florianwoelki/uber-movement-speed/state"
+)
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), state.DefaultPath)
+	p := &Provisioner{StatePath: path}
+
+	st := state.New("test-fingerprint")
+	st.Complete("a")
+
+	var ran []string
+	run := func(name string, err error) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			ran = append(ran, name)
+			return err
+		}
+	}
+
+	g := newGraph()
+	g.add("a", run("a", nil))
+	g.add("b", run("b", nil), "a")
+	g.add("c", run("c", errors.New("failed")), "b")
+	started := p.checkpoint(g, st)
+
+	if err := g.run(context.Background(), 1); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	if !reflect.DeepEqual(ran, []string{"b", "c"}) {
+		t.Errorf("unexpected tasks that ran: %v", ran)
+	}
+	if !reflect.DeepEqual(started, map[string]bool{"b": true, "c": true}) {
+		t.Errorf("unexpected started tasks: %v", started)
+	}
+
+	loaded, err := state.Load(path, "test-fingerprint")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Completed, []string{"a", "b"}) {
+		t.Errorf("unexpected completed steps: %v", loaded.Completed)
+	}
+}
+
+func TestCheckpoint_RerunsFunctions(t *testing.T) {
+	p := &Provisioner{}
+	st := state.New("test-fingerprint")
+	st.Complete(roleTask("lambda"))
+	st.Complete(functionTask("Preprocessing"))
+
+	var ran []string
+	g := newGraph()
+	g.add(roleTask("lambda"), func(ctx context.Context) error {
+		ran = append(ran, roleTask("lambda"))
+		return nil
+	})
+	g.add(functionTask("Preprocessing"), func(ctx context.Context) error {
+		ran = append(ran, functionTask("Preprocessing"))
+		return nil
+	}, roleTask("lambda"))
+	p.checkpoint(g, st)
+
+	if err := g.run(context.Background(), 1); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(ran, []string{functionTask("Preprocessing")}) {
+		t.Errorf("expected only the function task to run again, got %v", ran)
+	}
+}
+
+// captureLog returns the messages of the standard logger while the test runs, one per
+// line.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	flags := log.Flags()
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	t.Cleanup(func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(flags)
+	})
+	return &buf
+}
+
+// deletedResources returns the resources in the given log messages that were deleted,
+// in the order in which they were deleted.
+func deletedResources(messages string) []string {
+	var deleted []string
+	for _, message := range strings.Split(messages, "\n") {
+		if resource, ok := strings.CutPrefix(message, "Deleted "); ok && !strings.Contains(resource, " objects from ") {
+			deleted = append(deleted, resource)
+		}
+	}
+	return deleted
+}
+
+// newRollbackProvisioner returns a test provisioner that records checkpoints and rolls back
+// a failed setup, whose function task fails because the code of the function is missing.
+func newRollbackProvisioner(t *testing.T) (*Provisioner, Clients) {
+	t.Helper()
+
+	p, clients := newTestProvisioner(t)
+	p.StatePath = filepath.Join(t.TempDir(), state.DefaultPath)
+	p.Rollback = true
+	if err := os.Remove(p.manifest.Functions[0].Source); err != nil {
+		t.Fatal(err)
+	}
+	return p, clients
+}
+
+func TestApply_Rollback(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newRollbackProvisioner(t)
+	logs := captureLog(t)
+
+	if _, err := p.Apply(ctx); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the error of the missing code, got %v", err)
+	}
+
+	// Every resource the setup created before the failure is deleted in the reverse
+	// order of setup.
+	expected := []string{
+		"aurora cluster `db1`",
+		"secret `dbpass`",
+		"dynamodb table `segment-speeds`",
+		"kinesis stream `speeds`",
+		"glue job `etl`",
+		"S3 bucket `code`",
+		"IAM role `s3-role`",
+		"IAM role `lambda-role`",
+		"IAM role `kinesis-role`",
+		"IAM role `dynamodb-role`",
+		"IAM role `glue-role`",
+		"IAM role `rds-role`",
+		"IAM role `apigatewayv2-role`",
+	}
+	if deleted := deletedResources(logs.String()); !reflect.DeepEqual(deleted, expected) {
+		t.Errorf("unexpected deleted resources:\n got: %q\nwant: %q", deleted, expected)
+	}
+
+	changes, err := p.PlanDestroy(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("expected every resource to be deleted, got %v", changes)
+	}
+
+	if _, err := os.Stat(p.StatePath); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected the checkpoint to be removed, got %v", err)
+	}
+}
+
+func TestApply_RollbackKeepsExisting(t *testing.T) {
+	ctx := context.Background()
+	p, clients := newRollbackProvisioner(t)
+	if err := clients.S3.CreateBucket(ctx, "code"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := p.Apply(ctx); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	// The bucket existed before the setup, so it is kept together with its step.
+	changes, err := p.PlanDestroy(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	remaining := []Change{{Action: awsService.ActionDelete, Resource: "S3 bucket `code`"}}
+	if !reflect.DeepEqual(changes, remaining) {
+		t.Errorf("expected only the bucket to remain, got %v", changes)
+	}
+
+	fingerprint, err := state.Fingerprint(p.manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := state.Load(p.StatePath, fingerprint)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(st.Completed, []string{bucketTask("code")}) {
+		t.Errorf("unexpected completed steps: %v", st.Completed)
+	}
+}
@@ -0,0 +1,264 @@
+package provisioner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+	"github.com/florianwoelki/uber-movement-speed/manifest"
+)
+
+// Destroy deletes every resource of the manifest in the reverse order of Apply. Buckets
+// are emptied before they are deleted and resources that do not exist anymore are
+// skipped. It returns a description of every resource that was removed.
+func (p *Provisioner) Destroy(ctx context.Context) ([]string, error) {
+	p.loadRoles(ctx)
+	p.createClients()
+
+	d := &destroyer{manifest: p.manifest}
+	err := p.destroy(ctx, d)
+	return d.removed, err
+}
+
+// destroy deletes every resource of the manifest of the given destroyer in the reverse
+// order of Apply.
+func (p *Provisioner) destroy(ctx context.Context, d *destroyer) error {
+	steps := []func(context.Context, *destroyer) error{
+		p.destroyClusters,
+		p.destroyTables,
+		p.destroyEventSourceMappings,
+		p.destroyStreams,
+		p.destroyAPIs,
+		p.destroyFunctions,
+		p.destroyGlueJobs,
+		p.destroyBuckets,
+		p.destroyRoles,
+	}
+	for _, step := range steps {
+		if err := step(ctx, d); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadRoles stores the credentials of every existing IAM role of the manifest. Clients
+// of services whose role does not exist anymore use the default credentials.
+func (p *Provisioner) loadRoles(ctx context.Context) {
+	for _, role := range p.manifest.Roles {
+		creds, err := p.iam.RoleCredentials(ctx, role.Name)
+		if err != nil {
+			log.Printf("Using default credentials for %s: %v", role.Service, err)
+			continue
+		}
+		p.credentials[role.Service] = creds
+	}
+}
+
+// errMissing is returned by delete functions when a resource that is looked up by name
+// does not exist.
+var errMissing = errors.New("resource does not exist")
+
+// destroyer deletes the resources of a manifest and records the resources that were
+// removed.
+type destroyer struct {
+	manifest *manifest.Manifest
+	removed  []string
+}
+
+// delete deletes the resource with the given description by calling the given delete
+// function. A resource that does not exist anymore is skipped.
+func (d *destroyer) delete(description string, delete func() error) error {
+	log.Printf("Deleting %s...", description)
+	err := delete()
+	if errors.Is(err, errMissing) || awsService.IsNotFound(err) {
+		log.Printf("Skipped %s, it does not exist", description)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("deleting %s: %w", description, err)
+	}
+
+	log.Printf("Deleted %s", description)
+	d.removed = append(d.removed, description)
+	return nil
+}
+
+// destroyClusters deletes the Aurora DB clusters and their secrets.
+func (p *Provisioner) destroyClusters(ctx context.Context, d *destroyer) error {
+	for _, cluster := range d.manifest.Clusters {
+		err := d.delete(fmt.Sprintf("aurora cluster `%s`", cluster.Identifier), func() error {
+			return p.aurora.DeleteDBCluster(ctx, cluster.Identifier)
+		})
+		if err != nil {
+			return err
+		}
+
+		err = d.delete(fmt.Sprintf("secret `%s`", cluster.Username), func() error {
+			return p.aurora.DeleteSecret(ctx, cluster.Username)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// destroyTables deletes the DynamoDB tables.
+func (p *Provisioner) destroyTables(ctx context.Context, d *destroyer) error {
+	for _, table := range d.manifest.Tables {
+		err := d.delete(fmt.Sprintf("dynamodb table `%s`", table.Name), func() error {
+			return p.dynamodb.DeleteTable(ctx, table.Name)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// destroyEventSourceMappings deletes the bindings between the Lambda functions and the
+// Kinesis streams and deregisters their consumers.
+func (p *Provisioner) destroyEventSourceMappings(ctx context.Context, d *destroyer) error {
+	for _, mapping := range d.manifest.EventSourceMappings {
+		description := fmt.Sprintf("event source mapping `%s` -> `%s`", mapping.Stream, mapping.Function)
+		err := d.delete(description, func() error {
+			eventSourceARN, err := p.eventSourceARN(ctx, mapping)
+			if err != nil {
+				return err
+			}
+
+			deleted, err := p.lambda.UnbindFromService(ctx, mapping.Function, eventSourceARN)
+			if err == nil && deleted == 0 {
+				return errMissing
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		if mapping.Consumer == "" {
+			continue
+		}
+		description = fmt.Sprintf("kinesis consumer `%s` of stream `%s`", mapping.Consumer, mapping.Stream)
+		err = d.delete(description, func() error {
+			return p.kinesis.DeregisterConsumer(ctx, mapping.Stream, mapping.Consumer)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// destroyStreams deletes the Kinesis streams.
+func (p *Provisioner) destroyStreams(ctx context.Context, d *destroyer) error {
+	for _, stream := range d.manifest.Streams {
+		err := d.delete(fmt.Sprintf("kinesis stream `%s`", stream.Name), func() error {
+			return p.kinesis.Delete(ctx, stream.Name)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// destroyAPIs deletes the API Gateways.
+func (p *Provisioner) destroyAPIs(ctx context.Context, d *destroyer) error {
+	for _, api := range d.manifest.APIs {
+		description := fmt.Sprintf("%s API Gateway `%s`", api.Protocol, api.Name)
+		err := d.delete(description, func() error {
+			id, err := p.apiGateway.GetApiId(ctx, api.Name)
+			if err != nil {
+				return err
+			}
+
+			return p.apiGateway.Delete(ctx, id)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// destroyFunctions deletes the Lambda functions.
+func (p *Provisioner) destroyFunctions(ctx context.Context, d *destroyer) error {
+	for _, function := range d.manifest.Functions {
+		err := d.delete(fmt.Sprintf("lambda function `%s`", function.Name), func() error {
+			return p.lambda.Delete(ctx, function.Name)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// destroyGlueJobs deletes the Glue jobs.
+func (p *Provisioner) destroyGlueJobs(ctx context.Context, d *destroyer) error {
+	for _, job := range d.manifest.GlueJobs {
+		err := d.delete(fmt.Sprintf("glue job `%s`", job.Name), func() error {
+			// Deleting a job that does not exist succeeds, so it is looked up first.
+			exists, err := p.glue.JobExists(ctx, job.Name)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				return errMissing
+			}
+
+			return p.glue.DeleteJob(ctx, job.Name)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// destroyBuckets empties and deletes the S3 buckets.
+func (p *Provisioner) destroyBuckets(ctx context.Context, d *destroyer) error {
+	for _, bucket := range d.manifest.Buckets {
+		err := d.delete(fmt.Sprintf("S3 bucket `%s`", bucket.Name), func() error {
+			deleted, err := p.s3.EmptyBucket(ctx, bucket.Name)
+			if err != nil {
+				return err
+			}
+			log.Printf("Deleted %d objects from S3 bucket `%s`", deleted, bucket.Name)
+
+			return p.s3.DeleteBucket(ctx, bucket.Name)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// destroyRoles deletes the IAM roles and their policies.
+func (p *Provisioner) destroyRoles(ctx context.Context, d *destroyer) error {
+	for _, role := range d.manifest.Roles {
+		err := d.delete(fmt.Sprintf("IAM role `%s`", role.Name), func() error {
+			return p.iam.DeleteRoleWithPolicy(ctx, role.Name)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
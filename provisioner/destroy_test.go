@@ -0,0 +1,112 @@
+package provisioner
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+)
+
+// testTeardown are the resources of the test manifest in the order in which they are
+// deleted, which is the reverse order of setup.
+var testTeardown = []string{
+	"aurora cluster `db1`",
+	"secret `dbpass`",
+	"dynamodb table `segment-speeds`",
+	"event source mapping `speeds` -> `Forwarder`",
+	"kinesis stream `speeds`",
+	"http API Gateway `speeds-api`",
+	"lambda function `Forwarder`",
+	"glue job `etl`",
+	"S3 bucket `code`",
+	"IAM role `s3-role`",
+	"IAM role `lambda-role`",
+	"IAM role `kinesis-role`",
+	"IAM role `dynamodb-role`",
+	"IAM role `glue-role`",
+	"IAM role `rds-role`",
+	"IAM role `apigatewayv2-role`",
+}
+
+// expectNoResources fails the test if any resource of the manifest of the given
+// provisioner exists.
+func expectNoResources(t *testing.T, p *Provisioner) {
+	t.Helper()
+
+	changes, err := p.Plan(context.Background())
+	if err != nil {
+		t.Fatalf("planning: %v", err)
+	}
+	for _, change := range changes {
+		if change.Action != awsService.ActionCreate {
+			t.Errorf("expected %s to be deleted, got action %s", change.Resource, change.Action)
+		}
+	}
+}
+
+func TestDestroy(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newTestProvisioner(t)
+	if _, err := p.Apply(ctx); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	removed, err := p.Destroy(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(removed, testTeardown) {
+		t.Errorf("unexpected removed resources:\n got: %q\nwant: %q", removed, testTeardown)
+	}
+	expectNoResources(t, p)
+
+	removed, err = p.Destroy(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error of the second teardown: %v", err)
+	}
+	if len(removed) != 0 {
+		t.Errorf("expected the second teardown to remove nothing, got %q", removed)
+	}
+}
+
+func TestDestroy_AlreadyDeleted(t *testing.T) {
+	ctx := context.Background()
+	p, clients := newTestProvisioner(t)
+	if _, err := p.Apply(ctx); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	if err := clients.DynamoDB.DeleteTable(ctx, "segment-speeds"); err != nil {
+		t.Fatal(err)
+	}
+	if err := clients.Lambda.Delete(ctx, "Forwarder"); err != nil {
+		t.Fatal(err)
+	}
+	if err := clients.IAM.DeleteRoleWithPolicy(ctx, "glue-role"); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := p.Destroy(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Like Lambda, the fake keeps the event source mapping of a deleted function, so the
+	// mapping is still removed.
+	deleted := map[string]bool{
+		"dynamodb table `segment-speeds`": true,
+		"lambda function `Forwarder`":     true,
+		"IAM role `glue-role`":            true,
+	}
+	var expected []string
+	for _, resource := range testTeardown {
+		if !deleted[resource] {
+			expected = append(expected, resource)
+		}
+	}
+	if !reflect.DeepEqual(removed, expected) {
+		t.Errorf("unexpected removed resources:\n got: %q\nwant: %q", removed, expected)
+	}
+	expectNoResources(t, p)
+}
@@ -0,0 +1,118 @@
+package provisioner
+
+import (
+	"context"
+	"fmt"
+)
+
+// defaultParallelism is the number of resources that are created at the same time if no
+// parallelism is configured.
+const defaultParallelism = 4
+
+// task creates a single resource once the resources it depends on exist.
+type task struct {
+	name string
+	deps []string
+	run  func(ctx context.Context) error
+}
+
+// graph is a set of tasks that depend on each other.
+type graph struct {
+	tasks map[string]*task
+	// Names of the tasks in the order they were added, so independent tasks start in a
+	// predictable order.
+	order []string
+}
+
+// newGraph creates an empty graph.
+func newGraph() *graph {
+	return &graph{tasks: map[string]*task{}}
+}
+
+// add adds a task with the given name that runs after the tasks with the given names.
+func (g *graph) add(name string, run func(ctx context.Context) error, deps ...string) {
+	g.tasks[name] = &task{name: name, deps: deps, run: run}
+	g.order = append(g.order, name)
+}
+
+// run runs every task after its dependencies with at most the given number of tasks at
+// the same time. After the first error no new tasks are started and the context of the
+// running tasks is cancelled. It returns once every started task returned.
+func (g *graph) run(ctx context.Context, parallelism int) error {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	// Number of unfinished dependencies and the dependents of every task.
+	pending := map[string]int{}
+	dependents := map[string][]string{}
+	for _, name := range g.order {
+		for _, dep := range g.tasks[name].deps {
+			if _, ok := g.tasks[dep]; !ok {
+				return fmt.Errorf("task %s depends on unknown task %s", name, dep)
+			}
+			pending[name]++
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+
+	var ready []string
+	for _, name := range g.order {
+		if pending[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		name string
+		err  error
+	}
+	results := make(chan result)
+
+	var firstErr error
+	running, finished := 0, 0
+	for finished < len(g.order) {
+		if firstErr == nil && ctx.Err() != nil {
+			firstErr = ctx.Err()
+		}
+
+		for firstErr == nil && running < parallelism && len(ready) > 0 {
+			t := g.tasks[ready[0]]
+			ready = ready[1:]
+			running++
+			go func() {
+				results <- result{name: t.name, err: t.run(ctx)}
+			}()
+		}
+
+		if running == 0 {
+			if firstErr != nil {
+				return firstErr
+			}
+			return fmt.Errorf("dependency cycle between %d tasks", len(g.order)-finished)
+		}
+
+		r := <-results
+		running--
+		finished++
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = r.err
+				cancel()
+			}
+			continue
+		}
+
+		for _, name := range dependents[r.name] {
+			pending[name]--
+			if pending[name] == 0 {
+				ready = append(ready, name)
+			}
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,126 @@
+package provisioner
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGraph_RunsDependenciesFirst(t *testing.T) {
+	var mu sync.Mutex
+	var order []string
+	record := func(name string) func(context.Context) error {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			order = append(order, name)
+			return nil
+		}
+	}
+
+	g := newGraph()
+	g.add("mapping", record("mapping"), "stream", "function")
+	g.add("function", record("function"), "bucket", "role")
+	g.add("stream", record("stream"), "role")
+	g.add("bucket", record("bucket"), "role")
+	g.add("role", record("role"))
+
+	if err := g.run(context.Background(), 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	position := map[string]int{}
+	for i, name := range order {
+		position[name] = i
+	}
+	if len(position) != 5 {
+		t.Fatalf("unexpected tasks: %v", order)
+	}
+
+	for name, task := range g.tasks {
+		for _, dep := range task.deps {
+			if position[dep] > position[name] {
+				t.Errorf("task %s ran before its dependency %s: %v", name, dep, order)
+			}
+		}
+	}
+}
+
+func TestGraph_BoundsParallelism(t *testing.T) {
+	var running, maxRunning int32
+	run := func(ctx context.Context) error {
+		current := atomic.AddInt32(&running, 1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nil
+	}
+
+	g := newGraph()
+	for _, name := range []string{"a", "b", "c", "d", "e", "f"} {
+		g.add(name, run)
+	}
+
+	if err := g.run(context.Background(), 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if maxRunning != 2 {
+		t.Errorf("unexpected maximum number of running tasks: %d", maxRunning)
+	}
+}
+
+func TestGraph_StopsOnFirstError(t *testing.T) {
+	errTest := errors.New("test")
+	cancelled := false
+
+	g := newGraph()
+	g.add("failing", func(ctx context.Context) error {
+		return errTest
+	})
+	g.add("slow", func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			cancelled = true
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	})
+	g.add("dependent", func(ctx context.Context) error {
+		t.Errorf("dependent task of a failed task was started")
+		return nil
+	}, "failing")
+
+	err := g.run(context.Background(), 2)
+	if !errors.Is(err, errTest) {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if !cancelled {
+		t.Errorf("expected running task to be cancelled")
+	}
+}
+
+func TestGraph_InvalidDependencies(t *testing.T) {
+	noop := func(ctx context.Context) error { return nil }
+
+	g := newGraph()
+	g.add("a", noop, "missing")
+	if err := g.run(context.Background(), 1); err == nil {
+		t.Errorf("expected error for unknown dependency")
+	}
+
+	g = newGraph()
+	g.add("a", noop, "b")
+	g.add("b", noop, "a")
+	if err := g.run(context.Background(), 1); err == nil {
+		t.Errorf("expected error for dependency cycle")
+	}
+}
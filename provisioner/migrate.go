@@ -0,0 +1,91 @@
+package provisioner
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+	"github.com/florianwoelki/uber-movement-speed/manifest"
+	"github.com/florianwoelki/uber-movement-speed/migrations"
+)
+
+// Migrator is the migration runner of an Aurora DB cluster of the manifest.
+type Migrator struct {
+	// Cluster is the identifier of the cluster.
+	Cluster string
+	*migrations.Runner
+}
+
+// Migrators returns the migration runners of the Aurora DB clusters of the manifest that
+// are migrated. The clusters and their secrets must exist.
+func (p *Provisioner) Migrators(ctx context.Context) ([]Migrator, error) {
+	p.loadRoles(ctx)
+	p.createClients()
+
+	var migrators []Migrator
+	for _, c := range p.manifest.Clusters {
+		if !c.Migrate {
+			continue
+		}
+
+		runner, err := p.existingRunner(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		migrators = append(migrators, Migrator{Cluster: c.Identifier, Runner: runner})
+	}
+
+	return migrators, nil
+}
+
+// existingRunner returns the migration runner of the given existing cluster.
+func (p *Provisioner) existingRunner(ctx context.Context, c manifest.Cluster) (*migrations.Runner, error) {
+	cluster, err := p.aurora.GetDBCluster(ctx, c.Identifier)
+	if awsService.IsNotFound(err) {
+		return nil, fmt.Errorf("cluster %s does not exist, run setup first: %w", c.Identifier, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secretARN, err := p.aurora.GetSecretARN(ctx, c.Username)
+	if err != nil {
+		return nil, fmt.Errorf("secret %s of cluster %s: %w", c.Username, c.Identifier, err)
+	}
+
+	return p.runner(c, aws.ToString(cluster.DBClusterArn), secretARN)
+}
+
+// runner returns the migration runner of the given cluster with the given ARN and the
+// secret with the given ARN.
+func (p *Provisioner) runner(c manifest.Cluster, clusterARN, secretARN string) (*migrations.Runner, error) {
+	all, err := migrations.All()
+	if err != nil {
+		return nil, err
+	}
+
+	db := migrations.NewDataAPI(p.aurora, c.Database, clusterARN, secretARN)
+	return migrations.NewRunner(db, all), nil
+}
+
+// migrate applies the pending migrations to the database of the given cluster.
+func (p *Provisioner) migrate(ctx context.Context, c manifest.Cluster, clusterARN, secretARN string) error {
+	runner, err := p.runner(c, clusterARN, secretARN)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Migrating database `%s` of Aurora DB Cluster `%s`...", c.Database, c.Identifier)
+	applied, err := runner.Up(ctx)
+	for _, m := range applied {
+		log.Printf("  - applied migration %s", m)
+	}
+	if err != nil {
+		return fmt.Errorf("migrating cluster %s: %w", c.Identifier, err)
+	}
+	log.Printf("Migrated database `%s` of Aurora DB Cluster `%s`, %d migrations applied", c.Database, c.Identifier, len(applied))
+
+	return nil
+}
@@ -0,0 +1,335 @@
+package provisioner
+
+import (
+	"context"
+	"fmt"
+
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+)
+
+// Change is the action that setup or teardown would take for a single resource.
+type Change struct {
+	Action   awsService.Action `json:"action"`
+	Resource string            `json:"resource"`
+	// Differences are the fields of an existing resource that setup would update.
+	Differences []awsService.Difference `json:"differences,omitempty"`
+}
+
+// String returns the change in the format that is printed by the plan command.
+func (c Change) String() string {
+	symbol := " "
+	switch c.Action {
+	case awsService.ActionCreate:
+		symbol = "+"
+	case awsService.ActionUpdate:
+		symbol = "~"
+	case awsService.ActionDelete:
+		symbol = "-"
+	}
+
+	return fmt.Sprintf("%s %-6s %s", symbol, c.Action, c.Resource)
+}
+
+// HasChanges reports whether any of the given changes would modify a resource.
+func HasChanges(changes []Change) bool {
+	for _, change := range changes {
+		if change.Action != awsService.ActionNone {
+			return true
+		}
+	}
+	return false
+}
+
+// Plan inspects the live resources and returns the change that Apply would make to every
+// resource of the manifest. Nothing is created or modified.
+func (p *Provisioner) Plan(ctx context.Context) ([]Change, error) {
+	return p.plan(ctx, true)
+}
+
+// Drift inspects the live resources and returns the resources of the manifest whose live
+// configuration differs from the configuration Apply would produce, together with the
+// differing fields. Missing resources are drift as well. Nothing is created or modified.
+func (p *Provisioner) Drift(ctx context.Context) ([]Change, error) {
+	changes, err := p.plan(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+
+	var drifted []Change
+	for _, change := range changes {
+		if change.Action != awsService.ActionNone {
+			drifted = append(drifted, change)
+		}
+	}
+
+	return drifted, nil
+}
+
+// PlanDestroy inspects the live resources and returns the resources that Destroy would
+// delete in the order in which they are deleted. Nothing is deleted.
+func (p *Provisioner) PlanDestroy(ctx context.Context) ([]Change, error) {
+	changes, err := p.plan(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var deletes []Change
+	for i := len(changes) - 1; i >= 0; i-- {
+		if changes[i].Action == awsService.ActionCreate {
+			continue
+		}
+		deletes = append(deletes, Change{Action: awsService.ActionDelete, Resource: changes[i].Resource})
+	}
+
+	return deletes, nil
+}
+
+// planner records the changes of a plan.
+type planner struct {
+	changes []Change
+
+	// Whether the local code of the Lambda functions is compared with the deployed code.
+	compareCode bool
+}
+
+// add records the given action for the resource with the given description.
+func (pl *planner) add(action awsService.Action, description string) {
+	pl.changes = append(pl.changes, Change{Action: action, Resource: description})
+}
+
+// addDrift records the given drift of the resource with the given description.
+func (pl *planner) addDrift(drift awsService.Drift, description string) {
+	pl.changes = append(pl.changes, Change{Action: drift.Action, Resource: description, Differences: drift.Differences})
+}
+
+// plan returns the changes of Apply in the order in which Apply makes them.
+func (p *Provisioner) plan(ctx context.Context, compareCode bool) ([]Change, error) {
+	pl := &planner{compareCode: compareCode}
+	if err := p.planRoles(ctx, pl); err != nil {
+		return nil, err
+	}
+
+	p.loadRoles(ctx)
+	p.createClients()
+
+	if err := p.planBuckets(ctx, pl); err != nil {
+		return nil, err
+	}
+
+	if err := p.planGlueJobs(ctx, pl); err != nil {
+		return nil, err
+	}
+
+	functionARNs, err := p.planFunctions(ctx, pl)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.planAPIs(ctx, pl, functionARNs); err != nil {
+		return nil, err
+	}
+
+	if err := p.planStreams(ctx, pl); err != nil {
+		return nil, err
+	}
+
+	if err := p.planEventSourceMappings(ctx, pl); err != nil {
+		return nil, err
+	}
+
+	if err := p.planTables(ctx, pl); err != nil {
+		return nil, err
+	}
+
+	if err := p.planClusters(ctx, pl); err != nil {
+		return nil, err
+	}
+
+	return pl.changes, nil
+}
+
+// planRoles plans the IAM roles and their policies.
+func (p *Provisioner) planRoles(ctx context.Context, pl *planner) error {
+	for _, role := range p.manifest.Roles {
+		drift, err := p.iam.DriftRoleWithPolicy(ctx, role.Name, role.Service)
+		if err != nil {
+			return fmt.Errorf("planning role %s: %w", role.Name, err)
+		}
+		pl.addDrift(drift, fmt.Sprintf("IAM role `%s`", role.Name))
+	}
+
+	return nil
+}
+
+// planBuckets plans the S3 buckets.
+func (p *Provisioner) planBuckets(ctx context.Context, pl *planner) error {
+	for _, bucket := range p.manifest.Buckets {
+		action, err := p.s3.PlanBucket(ctx, bucket.Name)
+		if err != nil {
+			return fmt.Errorf("planning bucket %s: %w", bucket.Name, err)
+		}
+		pl.add(action, fmt.Sprintf("S3 bucket `%s`", bucket.Name))
+	}
+
+	return nil
+}
+
+// planGlueJobs plans the Glue jobs.
+func (p *Provisioner) planGlueJobs(ctx context.Context, pl *planner) error {
+	for _, job := range p.manifest.GlueJobs {
+		drift, err := p.glue.DriftJob(ctx, job.Name, job.Script, p.jobOptions(job)...)
+		if err != nil {
+			return fmt.Errorf("planning glue job %s: %w", job.Name, err)
+		}
+		pl.addDrift(drift, fmt.Sprintf("glue job `%s`", job.Name))
+	}
+
+	return nil
+}
+
+// planFunctions plans the Lambda functions. The code of a function is built and compared
+// with the deployed code if the planner compares code. It returns the ARNs of the existing functions
+// by their name.
+func (p *Provisioner) planFunctions(ctx context.Context, pl *planner) (map[string]string, error) {
+	config, err := p.functionConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	arns := map[string]string{}
+	for _, function := range p.manifest.Functions {
+		var code []byte
+		if pl.compareCode {
+			built, err := p.buildFunction(ctx, function)
+			if err != nil {
+				return nil, err
+			}
+			code = built.Data
+		}
+
+		plan := p.lambda.DriftGo
+		if function.Runtime == "node" {
+			plan = p.lambda.DriftNode
+		}
+
+		arn, drift, err := plan(ctx, function.Name, code, config, p.functionOptions(function)...)
+		if err != nil {
+			return nil, fmt.Errorf("planning function %s: %w", function.Name, err)
+		}
+		pl.addDrift(drift, fmt.Sprintf("lambda function `%s`", function.Name))
+
+		if arn != "" {
+			arns[function.Name] = arn
+		}
+	}
+
+	return arns, nil
+}
+
+// planAPIs plans the API Gateways and their routes. The routes of an API Gateway or a
+// Lambda function that does not exist yet are created.
+func (p *Provisioner) planAPIs(ctx context.Context, pl *planner, functionARNs map[string]string) error {
+	for _, api := range p.manifest.APIs {
+		plan := p.apiGateway.PlanHTTPApi
+		if api.Protocol == "websocket" {
+			plan = p.apiGateway.PlanWebSocketApi
+		}
+
+		id, action, err := plan(ctx, api.Name)
+		if err != nil {
+			return fmt.Errorf("planning api %s: %w", api.Name, err)
+		}
+		pl.add(action, fmt.Sprintf("%s API Gateway `%s`", api.Protocol, api.Name))
+
+		for _, route := range api.Routes {
+			description := fmt.Sprintf("route `%s` of API Gateway `%s`", route.Path, api.Name)
+			functionARN, ok := functionARNs[route.Function]
+			if id == "" || !ok {
+				pl.add(awsService.ActionCreate, description)
+				continue
+			}
+
+			drift, err := p.driftRoute(ctx, id, api.Protocol, route, functionARN)
+			if err != nil {
+				return fmt.Errorf("planning route %s of api %s: %w", route.Path, api.Name, err)
+			}
+			pl.addDrift(drift, description)
+		}
+	}
+
+	return nil
+}
+
+// planStreams plans the Kinesis streams.
+func (p *Provisioner) planStreams(ctx context.Context, pl *planner) error {
+	for _, stream := range p.manifest.Streams {
+		drift, err := p.kinesis.Drift(ctx, stream.Name, p.streamOptions(stream)...)
+		if err != nil {
+			return fmt.Errorf("planning stream %s: %w", stream.Name, err)
+		}
+		pl.addDrift(drift, fmt.Sprintf("kinesis stream `%s`", stream.Name))
+	}
+
+	return nil
+}
+
+// planEventSourceMappings plans the bindings between the Lambda functions and the Kinesis
+// streams and the consumers they read through.
+func (p *Provisioner) planEventSourceMappings(ctx context.Context, pl *planner) error {
+	for _, mapping := range p.manifest.EventSourceMappings {
+		description := fmt.Sprintf("event source mapping `%s` -> `%s`", mapping.Stream, mapping.Function)
+		if mapping.Consumer != "" {
+			action, err := p.kinesis.PlanConsumer(ctx, mapping.Stream, mapping.Consumer)
+			if err != nil {
+				return fmt.Errorf("planning consumer %s of stream %s: %w", mapping.Consumer, mapping.Stream, err)
+			}
+			pl.add(action, fmt.Sprintf("kinesis consumer `%s` of stream `%s`", mapping.Consumer, mapping.Stream))
+		}
+
+		eventSourceARN, err := p.eventSourceARN(ctx, mapping)
+		if awsService.IsNotFound(err) {
+			pl.add(awsService.ActionCreate, description)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("getting arn of event source of stream %s: %w", mapping.Stream, err)
+		}
+
+		drift, err := p.lambda.DriftBoundToService(ctx, mapping.Function, eventSourceARN)
+		if awsService.IsNotFound(err) {
+			drift, err = awsService.Drift{Action: awsService.ActionCreate}, nil
+		}
+		if err != nil {
+			return fmt.Errorf("planning binding of function %s to stream %s: %w", mapping.Function, mapping.Stream, err)
+		}
+		pl.addDrift(drift, description)
+	}
+
+	return nil
+}
+
+// planTables plans the DynamoDB tables.
+func (p *Provisioner) planTables(ctx context.Context, pl *planner) error {
+	for _, table := range p.manifest.Tables {
+		drift, err := p.dynamodb.DriftTable(ctx, table.Name, p.tableOptions(table)...)
+		if err != nil {
+			return fmt.Errorf("planning table %s: %w", table.Name, err)
+		}
+		pl.addDrift(drift, fmt.Sprintf("dynamodb table `%s`", table.Name))
+	}
+
+	return nil
+}
+
+// planClusters plans the Aurora DB clusters and their secrets.
+func (p *Provisioner) planClusters(ctx context.Context, pl *planner) error {
+	for _, c := range p.manifest.Clusters {
+		drift, err := p.aurora.DriftDBCluster(ctx, c.Identifier, c.Username, c.Password)
+		if err != nil {
+			return fmt.Errorf("planning cluster %s: %w", c.Identifier, err)
+		}
+		pl.addDrift(drift, fmt.Sprintf("aurora cluster `%s`", c.Identifier))
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package provisioner
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+)
+
+// actions returns the actions of the given changes by their resource.
+func actions(changes []Change) map[string]awsService.Action {
+	actions := map[string]awsService.Action{}
+	for _, change := range changes {
+		actions[change.Resource] = change.Action
+	}
+	return actions
+}
+
+func TestPlan(t *testing.T) {
+	ctx := context.Background()
+	p, clients := newTestProvisioner(t)
+
+	changes, err := p.Plan(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) == 0 {
+		t.Fatalf("expected changes")
+	}
+	for _, change := range changes {
+		if change.Action != awsService.ActionCreate {
+			t.Errorf("expected %s to be created, got action %s", change.Resource, change.Action)
+		}
+	}
+	if !HasChanges(changes) {
+		t.Errorf("expected changes to be pending before setup")
+	}
+
+	if _, err := p.Apply(ctx); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	changes, err = p.Plan(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, change := range changes {
+		if change.Action != awsService.ActionNone {
+			t.Errorf("expected %s to be unchanged, got action %s", change.Resource, change.Action)
+		}
+	}
+	if HasChanges(changes) {
+		t.Errorf("expected no changes to be pending after setup")
+	}
+
+	// The table is deleted outside of setup and the memory of the function changes.
+	if err := clients.DynamoDB.DeleteTable(ctx, "segment-speeds"); err != nil {
+		t.Fatal(err)
+	}
+	p.manifest.Functions[0].MemorySize = 256
+
+	changes, err = p.Plan(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for resource, action := range actions(changes) {
+		expected := awsService.ActionNone
+		switch resource {
+		case "dynamodb table `segment-speeds`":
+			expected = awsService.ActionCreate
+		case "lambda function `Forwarder`":
+			expected = awsService.ActionUpdate
+		}
+		if action != expected {
+			t.Errorf("unexpected action of %s: %s, expected %s", resource, action, expected)
+		}
+	}
+	if !HasChanges(changes) {
+		t.Errorf("expected changes to be pending")
+	}
+}
+
+func TestPlanDestroy(t *testing.T) {
+	ctx := context.Background()
+	p, clients := newTestProvisioner(t)
+
+	changes, err := p.PlanDestroy(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(changes) != 0 || HasChanges(changes) {
+		t.Errorf("expected nothing to delete before setup, got %v", changes)
+	}
+
+	if _, err := p.Apply(ctx); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+	if err := clients.DynamoDB.DeleteTable(ctx, "segment-speeds"); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := p.Plan(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	changes, err = p.PlanDestroy(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Every existing resource is deleted in the reverse order of setup.
+	var expected []Change
+	for i := len(plan) - 1; i >= 0; i-- {
+		if plan[i].Resource != "dynamodb table `segment-speeds`" {
+			expected = append(expected, Change{Action: awsService.ActionDelete, Resource: plan[i].Resource})
+		}
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("unexpected changes:\n got: %v\nwant: %v", changes, expected)
+	}
+	if !HasChanges(changes) {
+		t.Errorf("expected deletes to be pending")
+	}
+
+	// Planning changes nothing.
+	if _, err := clients.Kinesis.GetARN(ctx, "speeds"); err != nil {
+		t.Errorf("expected the stream to exist after planning: %v", err)
+	}
+}
+
+func TestDrift(t *testing.T) {
+	ctx := context.Background()
+	p, clients := newTestProvisioner(t)
+	if _, err := p.Apply(ctx); err != nil {
+		t.Fatalf("setup: %v", err)
+	}
+
+	drifted, err := p.Drift(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(drifted) != 0 {
+		t.Errorf("expected no drift after setup, got %v", drifted)
+	}
+
+	// The job runs another script and the stream is deleted outside of setup.
+	if err := clients.Glue.EnsureJob(ctx, "etl", "s3://code/other.py"); err != nil {
+		t.Fatal(err)
+	}
+	if err := clients.Kinesis.Delete(ctx, "speeds"); err != nil {
+		t.Fatal(err)
+	}
+
+	drifted, err = p.Drift(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []Change{
+		{
+			Action:   awsService.ActionUpdate,
+			Resource: "glue job `etl`",
+			Differences: []awsService.Difference{
+				{Field: "command.scriptLocation", Expected: "s3://code/etl.py", Actual: "s3://code/other.py"},
+			},
+		},
+		{Action: awsService.ActionCreate, Resource: "kinesis stream `speeds`"},
+		// The mapping of the stream is gone with the stream.
+		{Action: awsService.ActionCreate, Resource: "event source mapping `speeds` -> `Forwarder`"},
+	}
+	if !reflect.DeepEqual(drifted, expected) {
+		t.Errorf("unexpected drift:\n got: %+v\nwant: %+v", drifted, expected)
+	}
+}
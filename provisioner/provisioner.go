@@ -0,0 +1,857 @@
+package provisioner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/florianwoelki/uber-movement-speed/artifact"
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+	"github.com/florianwoelki/uber-movement-speed/environment"
+	"github.com/florianwoelki/uber-movement-speed/manifest"
+	"github.com/florianwoelki/uber-movement-speed/outputs"
+	"github.com/florianwoelki/uber-movement-speed/state"
+	"github.com/florianwoelki/uber-movement-speed/tracing"
+)
+
+// defaultWaitTimeout is the maximum time to wait for a single resource to become ready if
+// no timeout is configured.
+const defaultWaitTimeout = 10 * time.Minute
+
+// services are the services that have a wrapped client.
+var services = []string{"s3", "kinesis", "lambda", "dynamodb", "glue", "rds", "apigatewayv2"}
+
+// Provisioner creates the resources that are described in a manifest. Resources that
+// already exist are reused and updated if they differ from the manifest, so the
+// provisioner can be run again after a partial failure or a change of the manifest.
+type Provisioner struct {
+	manifest *manifest.Manifest
+	config   aws.Config
+
+	// Parallelism is the maximum number of resources that Apply creates at the same time.
+	// Zero means the default of 4.
+	Parallelism int
+	// WaitTimeout is the maximum time Apply waits for a single resource to become ready.
+	// Zero means the default of 10 minutes.
+	WaitTimeout time.Duration
+	// StatePath is the path of the checkpoint file in which Apply records its completed
+	// steps, so a failed run can be resumed. Empty means no checkpoints.
+	StatePath string
+	// Rollback makes a failed Apply delete the resources it created.
+	Rollback bool
+	// OTLPEndpoint is the URL of the OTLP endpoint, e.g. of a local collector, to which the
+	// Lambda functions export their spans. Empty means the functions export no spans.
+	OTLPEndpoint string
+
+	// mu guards the credentials and the outputs while resources are created concurrently.
+	mu sync.Mutex
+	// Credentials of the IAM roles by the service that assumes them.
+	credentials map[string]*aws.CredentialsCache
+	// fixedClients keeps the clients of NewFromClients instead of creating clients that
+	// assume the roles of the manifest.
+	fixedClients bool
+
+	iam        *awsService.IAM
+	s3         *awsService.S3
+	kinesis    *awsService.Kinesis
+	lambda     *awsService.Lambda
+	dynamodb   *awsService.DynamoDB
+	glue       *awsService.Glue
+	aurora     *awsService.Aurora
+	apiGateway *awsService.APIGateway
+}
+
+// New creates a new provisioner for the given manifest. The given configuration is
+// used to create the IAM roles, every other client assumes the role of its service.
+func New(config aws.Config, m *manifest.Manifest) *Provisioner {
+	return &Provisioner{
+		manifest:    m,
+		config:      config,
+		credentials: map[string]*aws.CredentialsCache{},
+		iam:         awsService.NewIAM(config),
+	}
+}
+
+// Clients are the wrapped clients of every service of a provisioner.
+type Clients struct {
+	IAM        *awsService.IAM
+	S3         *awsService.S3
+	Kinesis    *awsService.Kinesis
+	Lambda     *awsService.Lambda
+	DynamoDB   *awsService.DynamoDB
+	Glue       *awsService.Glue
+	Aurora     *awsService.Aurora
+	APIGateway *awsService.APIGateway
+}
+
+// NewFromClients creates a new provisioner for the given manifest that uses the given
+// clients, e.g. wrappers of the fakes of the `awsfake` package. Unlike the clients of New,
+// they keep their credentials once the IAM roles exist. The region of the given
+// configuration is the region of the resources.
+func NewFromClients(config aws.Config, m *manifest.Manifest, clients Clients) *Provisioner {
+	return &Provisioner{
+		manifest:     m,
+		config:       config,
+		credentials:  map[string]*aws.CredentialsCache{},
+		fixedClients: true,
+		iam:          clients.IAM,
+		s3:           clients.S3,
+		kinesis:      clients.Kinesis,
+		lambda:       clients.Lambda,
+		dynamodb:     clients.DynamoDB,
+		glue:         clients.Glue,
+		aurora:       clients.Aurora,
+		apiGateway:   clients.APIGateway,
+	}
+}
+
+// Apply creates or updates every resource of the manifest. Resources are created as soon
+// as the resources they depend on exist, with at most Parallelism resources at the same
+// time. After the first error no further resources are created and the context of the
+// resources that are being created is cancelled. If StatePath is set, the steps that a
+// previous failed run completed are skipped. If Rollback is set, the resources that a
+// failed run created are deleted. It returns the IDs, ARNs and names of the provisioned
+// resources.
+func (p *Provisioner) Apply(ctx context.Context) (*outputs.Outputs, error) {
+	st, err := p.loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	var missing map[string]bool
+	if p.Rollback {
+		if missing, err = p.missingResources(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	p.loadResumedRoles(ctx, st)
+	p.createClients()
+
+	out := st.Outputs
+	out.Environment = p.manifest.Environment
+	g := newGraph()
+
+	for _, role := range p.manifest.Roles {
+		role := role
+		g.add(roleTask(role.Name), func(ctx context.Context) error {
+			return p.createRole(ctx, role)
+		})
+	}
+
+	for _, bucket := range p.manifest.Buckets {
+		bucket := bucket
+		g.add(bucketTask(bucket.Name), func(ctx context.Context) error {
+			return p.createBucket(ctx, bucket)
+		}, p.roleTasks("s3")...)
+	}
+
+	for _, job := range p.manifest.GlueJobs {
+		job := job
+		deps := p.roleTasks("glue")
+		if bucket := job.ScriptBucket(); p.hasBucket(bucket) {
+			deps = append(deps, bucketTask(bucket))
+		}
+		g.add(glueJobTask(job.Name), func(ctx context.Context) error {
+			return p.createGlueJob(ctx, job)
+		}, deps...)
+	}
+
+	for _, function := range p.manifest.Functions {
+		function := function
+		deps := append(p.roleTasks("lambda"), bucketTask(function.Bucket))
+		g.add(functionTask(function.Name), func(ctx context.Context) error {
+			arn, err := p.createFunction(ctx, function)
+			if err != nil {
+				return err
+			}
+
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			out.Functions[function.Name] = outputs.Function{ARN: arn}
+			return nil
+		}, deps...)
+	}
+
+	for _, api := range p.manifest.APIs {
+		api := api
+		deps := p.roleTasks("apigatewayv2")
+		for _, route := range api.Routes {
+			deps = append(deps, functionTask(route.Function))
+		}
+		g.add(apiTask(api.Name), func(ctx context.Context) error {
+			p.mu.Lock()
+			functionARNs := map[string]string{}
+			for name, function := range out.Functions {
+				functionARNs[name] = function.ARN
+			}
+			p.mu.Unlock()
+
+			created, err := p.createAPI(ctx, api, functionARNs)
+			if err != nil {
+				return err
+			}
+
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			out.APIs[api.Name] = created
+			return nil
+		}, deps...)
+	}
+
+	for _, stream := range p.manifest.Streams {
+		stream := stream
+		g.add(streamTask(stream.Name), func(ctx context.Context) error {
+			arn, err := p.createStream(ctx, stream)
+			if err != nil {
+				return err
+			}
+
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			out.Streams[stream.Name] = outputs.Stream{ARN: arn}
+			return nil
+		}, p.roleTasks("kinesis")...)
+	}
+
+	for _, mapping := range p.manifest.EventSourceMappings {
+		mapping := mapping
+		g.add(mappingTask(mapping), func(ctx context.Context) error {
+			return p.createEventSourceMapping(ctx, mapping)
+		}, streamTask(mapping.Stream), functionTask(mapping.Function))
+	}
+
+	for _, table := range p.manifest.Tables {
+		table := table
+		g.add(tableTask(table.Name), func(ctx context.Context) error {
+			return p.createTable(ctx, table)
+		}, p.roleTasks("dynamodb")...)
+	}
+
+	for _, c := range p.manifest.Clusters {
+		c := c
+		g.add(clusterTask(c.Identifier), func(ctx context.Context) error {
+			cluster, err := p.createCluster(ctx, c)
+			if err != nil {
+				return err
+			}
+
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			out.Clusters[c.Identifier] = cluster
+			return nil
+		}, p.roleTasks("rds")...)
+	}
+
+	started := p.checkpoint(g, st)
+
+	parallelism := p.Parallelism
+	if parallelism == 0 {
+		parallelism = defaultParallelism
+	}
+	if err := g.run(ctx, parallelism); err != nil {
+		if p.Rollback {
+			// The rollback must also run if the setup was interrupted, so it does not use
+			// the cancelled context of the setup.
+			if rollbackErr := p.rollback(context.Background(), st, started, missing); rollbackErr != nil {
+				return nil, errors.Join(err, rollbackErr)
+			}
+		}
+		return nil, err
+	}
+
+	if p.StatePath != "" {
+		if err := state.Remove(p.StatePath); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, bucket := range p.manifest.Buckets {
+		out.Buckets = append(out.Buckets, bucket.Name)
+	}
+	for _, table := range p.manifest.Tables {
+		out.Tables = append(out.Tables, table.Name)
+	}
+
+	return out, nil
+}
+
+// Names of the tasks that create the resources. They are the steps of the checkpoint
+// file.
+func roleTask(name string) string     { return "role/" + name }
+func bucketTask(name string) string   { return "bucket/" + name }
+func glueJobTask(name string) string  { return "glue-job/" + name }
+func functionTask(name string) string { return "function/" + name }
+func apiTask(name string) string      { return "api/" + name }
+func streamTask(name string) string   { return "stream/" + name }
+func tableTask(name string) string    { return "table/" + name }
+func clusterTask(name string) string  { return "cluster/" + name }
+func mappingTask(mapping manifest.EventSourceMapping) string {
+	return fmt.Sprintf("event-source-mapping/%s/%s", mapping.Stream, mapping.Function)
+}
+
+// roleTasks returns the names of the tasks that create the roles of the given service.
+func (p *Provisioner) roleTasks(service string) []string {
+	var tasks []string
+	for _, role := range p.manifest.Roles {
+		if role.Service == service {
+			tasks = append(tasks, roleTask(role.Name))
+		}
+	}
+	return tasks
+}
+
+// hasBucket reports whether the manifest contains a bucket with the given name.
+func (p *Provisioner) hasBucket(name string) bool {
+	for _, bucket := range p.manifest.Buckets {
+		if bucket.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// createRole creates the IAM role, stores its credentials and recreates the client of its
+// service, so the client assumes the role.
+func (p *Provisioner) createRole(ctx context.Context, role manifest.Role) error {
+	log.Printf("Creating IAM role `%s`...", role.Name)
+	creds, err := p.iam.EnsureRoleWithPolicy(ctx, role.Name, role.Service)
+	if err != nil {
+		return fmt.Errorf("creating role %s: %w", role.Name, err)
+	}
+	log.Printf("Created IAM role `%s`", role.Name)
+
+	p.mu.Lock()
+	p.credentials[role.Service] = creds
+	p.mu.Unlock()
+
+	p.createClient(role.Service)
+	return nil
+}
+
+// clientConfig returns a copy of the configuration that uses the credentials of the
+// role for the given service. If there is no such role, the default credentials are
+// used.
+func (p *Provisioner) clientConfig(service string) aws.Config {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	cfg := p.config.Copy()
+	if creds, ok := p.credentials[service]; ok {
+		cfg.Credentials = creds
+	}
+	return cfg
+}
+
+// createClients creates the wrapped clients for every service.
+func (p *Provisioner) createClients() {
+	for _, service := range services {
+		p.createClient(service)
+	}
+}
+
+// createClient creates the wrapped client for the given service. The clients of
+// NewFromClients are kept.
+func (p *Provisioner) createClient(service string) {
+	if p.fixedClients {
+		return
+	}
+
+	cfg := p.clientConfig(service)
+	switch service {
+	case "s3":
+		p.s3 = awsService.NewS3(cfg)
+	case "kinesis":
+		p.kinesis = awsService.NewKinesis(cfg)
+	case "lambda":
+		p.lambda = awsService.NewLambda(cfg)
+	case "dynamodb":
+		p.dynamodb = awsService.NewDynamoDB(cfg)
+	case "glue":
+		p.glue = awsService.NewGlue(cfg)
+	case "rds":
+		p.aurora = awsService.NewAurora(cfg)
+	case "apigatewayv2":
+		p.apiGateway = awsService.NewAPIGateway(cfg)
+	}
+}
+
+// createBucket creates the S3 bucket and uploads its objects.
+func (p *Provisioner) createBucket(ctx context.Context, bucket manifest.Bucket) error {
+	log.Printf("Creating S3 bucket `%s`...", bucket.Name)
+	if err := p.s3.EnsureBucket(ctx, bucket.Name); err != nil {
+		return fmt.Errorf("creating bucket %s: %w", bucket.Name, err)
+	}
+	log.Printf("Created S3 bucket `%s`", bucket.Name)
+
+	for _, object := range bucket.Objects {
+		if err := p.upload(ctx, bucket.Name, object.Key, object.Source); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// upload uploads the local file at the given source path into the given bucket.
+func (p *Provisioner) upload(ctx context.Context, bucket, key, source string) error {
+	log.Printf("Uploading `%s` to `%s` S3 bucket...", source, bucket)
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return err
+	}
+
+	if err := p.s3.PutObject(ctx, bucket, key, data); err != nil {
+		return fmt.Errorf("uploading %s to bucket %s: %w", key, bucket, err)
+	}
+	log.Printf("Uploaded `%s` to `%s` S3 bucket", source, bucket)
+
+	return nil
+}
+
+// createGlueJob creates the Glue job.
+func (p *Provisioner) createGlueJob(ctx context.Context, job manifest.GlueJob) error {
+	log.Printf("Creating glue job `%s`...", job.Name)
+	if err := p.glue.EnsureJob(ctx, job.Name, job.Script, p.jobOptions(job)...); err != nil {
+		return fmt.Errorf("creating glue job %s: %w", job.Name, err)
+	}
+	log.Printf("Created glue job `%s`", job.Name)
+
+	return nil
+}
+
+// createFunction builds and uploads the code of the Lambda function, creates it and waits
+// for it to be active. Code that was uploaded before is not uploaded again and the code of
+// an existing function is only updated if it changed. It returns the ARN of the function.
+func (p *Provisioner) createFunction(ctx context.Context, function manifest.Function) (string, error) {
+	code, err := p.buildFunction(ctx, function)
+	if err != nil {
+		return "", err
+	}
+
+	key := code.Key(function.Key)
+	if err := p.uploadFunction(ctx, function, key, code); err != nil {
+		return "", err
+	}
+
+	log.Printf("Creating `%s` lambda function...", function.Name)
+	create := p.lambda.EnsureGo
+	if function.Runtime == "node" {
+		create = p.lambda.EnsureNode
+	}
+
+	config, err := p.functionConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	arn, err := create(ctx, function.Name, function.Bucket, key, code.Data, config, p.functionOptions(function)...)
+	if err != nil {
+		return "", fmt.Errorf("creating function %s: %w", function.Name, err)
+	}
+	log.Printf("Created `%s` lambda function", function.Name)
+
+	log.Printf("Waiting for `%s` lambda function to be active...", function.Name)
+	if err := p.lambda.WaitUntilActive(ctx, function.Name, p.waitTimeout()); err != nil {
+		return "", err
+	}
+	log.Printf("Lambda function `%s` is active", function.Name)
+
+	return arn, nil
+}
+
+// buildFunction returns the zipped code of the Lambda function. The code is built from its
+// directory or read from the zip that was built beforehand.
+func (p *Provisioner) buildFunction(ctx context.Context, function manifest.Function) (*artifact.Artifact, error) {
+	if function.Source != "" {
+		return artifact.Read(function.Source)
+	}
+
+	log.Printf("Building `%s` lambda function from `%s`...", function.Name, function.Build)
+	build := artifact.BuildGo
+	if function.Runtime == "node" {
+		build = artifact.BuildNode
+	}
+
+	code, err := build(ctx, function.Build)
+	if err != nil {
+		return nil, fmt.Errorf("building function %s: %w", function.Name, err)
+	}
+	log.Printf("Built `%s` lambda function with hash %s", function.Name, code.Hash)
+
+	return code, nil
+}
+
+// uploadFunction uploads the zipped code of the Lambda function to the given key of its
+// bucket unless the key already exists. The key contains the hash of the code, so an
+// existing key has the same code.
+func (p *Provisioner) uploadFunction(ctx context.Context, function manifest.Function, key string, code *artifact.Artifact) error {
+	exists, err := p.s3.ObjectExists(ctx, function.Bucket, key)
+	if err != nil {
+		return fmt.Errorf("looking up %s in bucket %s: %w", key, function.Bucket, err)
+	}
+	if exists {
+		log.Printf("Skipped uploading `%s` to `%s` S3 bucket, the code did not change", key, function.Bucket)
+		return nil
+	}
+
+	log.Printf("Uploading `%s` to `%s` S3 bucket...", key, function.Bucket)
+	if err := p.s3.PutObject(ctx, function.Bucket, key, code.Data); err != nil {
+		return fmt.Errorf("uploading %s to bucket %s: %w", key, function.Bucket, err)
+	}
+	log.Printf("Uploaded `%s` to `%s` S3 bucket", key, function.Bucket)
+
+	return nil
+}
+
+// createAPI creates the API Gateway, integrates its routes with the Lambda functions with
+// the given ARNs, deploys it and waits for the deployment to finish.
+func (p *Provisioner) createAPI(ctx context.Context, api manifest.API, functionARNs map[string]string) (outputs.API, error) {
+	log.Printf("Creating %s API Gateway `%s`...", api.Protocol, api.Name)
+	create := p.apiGateway.EnsureHTTPApi
+	if api.Protocol == "websocket" {
+		create = p.apiGateway.EnsureWebSocketApi
+	}
+
+	id, err := create(ctx, api.Name)
+	if err != nil {
+		return outputs.API{}, fmt.Errorf("creating api %s: %w", api.Name, err)
+	}
+	log.Printf("Created %s API Gateway `%s`", api.Protocol, api.Name)
+
+	for _, route := range api.Routes {
+		log.Printf("Creating API Gateway endpoint for `%s` lambda function...", route.Function)
+		err := p.createRoute(ctx, id, api.Protocol, route, functionARNs[route.Function])
+		if err != nil {
+			return outputs.API{}, fmt.Errorf("creating route %s of api %s: %w", route.Path, api.Name, err)
+		}
+		log.Printf("Created API Gateway endpoint for `%s` lambda function", route.Function)
+	}
+
+	stage := p.stage(api)
+	deploymentID, err := p.apiGateway.Deploy(ctx, id, stage)
+	if err != nil {
+		return outputs.API{}, fmt.Errorf("deploying api %s: %w", api.Name, err)
+	}
+	if err := p.apiGateway.WaitUntilDeployed(ctx, id, deploymentID, p.waitTimeout()); err != nil {
+		return outputs.API{}, err
+	}
+	log.Printf("Deployed %s API Gateway with ID %s to stage `%s`", api.Protocol, id, stage)
+
+	endpoint, invokeURL, err := p.apiGateway.GetEndpoints(ctx, api.Name, stage)
+	if err != nil {
+		return outputs.API{}, fmt.Errorf("getting endpoints of api %s: %w", api.Name, err)
+	}
+
+	return outputs.API{
+		ID:        id,
+		Protocol:  api.Protocol,
+		Stage:     stage,
+		Endpoint:  endpoint,
+		InvokeURL: invokeURL,
+	}, nil
+}
+
+// stage returns the stage the given API is deployed to.
+func (p *Provisioner) stage(api manifest.API) string {
+	if api.Stage != "" {
+		return api.Stage
+	}
+	return environment.Stage(p.manifest.Environment)
+}
+
+// createRoute integrates the given route of the API with the given ID with the Lambda
+// function with the given ARN.
+func (p *Provisioner) createRoute(ctx context.Context, id, protocol string, route manifest.Route, functionARN string) error {
+	if protocol == "websocket" {
+		return p.apiGateway.EnsureWebSocket(ctx, id, p.routeOptions(protocol, route, functionARN))
+	}
+
+	return p.apiGateway.EnsureEndpoint(ctx, id, p.routeOptions(protocol, route, functionARN))
+}
+
+// driftRoute compares the given route with the route createRoute would produce.
+func (p *Provisioner) driftRoute(ctx context.Context, id, protocol string, route manifest.Route, functionARN string) (awsService.Drift, error) {
+	if protocol == "websocket" {
+		return p.apiGateway.DriftWebSocket(ctx, id, p.routeOptions(protocol, route, functionARN))
+	}
+
+	return p.apiGateway.DriftEndpoint(ctx, id, p.routeOptions(protocol, route, functionARN))
+}
+
+// routeOptions returns the endpoint options that integrate the given route with the
+// Lambda function with the given ARN.
+func (p *Provisioner) routeOptions(protocol string, route manifest.Route, functionARN string) awsService.EndpointOptions {
+	uri := functionARN
+	if protocol != "websocket" {
+		uri = fmt.Sprintf("arn:aws:apigateway:%s:lambda:path/2015-03-31/functions/%s/invocations", p.config.Region, functionARN)
+	}
+
+	return awsService.EndpointOptions{
+		Path:              route.Path,
+		Method:            route.Method,
+		Uri:               uri,
+		RequestParameters: route.RequestParameters,
+	}
+}
+
+// functionOptions returns the options of the Lambda function of the manifest.
+func (p *Provisioner) functionOptions(function manifest.Function) []awsService.FunctionOption {
+	opts := []awsService.FunctionOption{awsService.WithTags(p.manifest.Tags), awsService.WithWaitTimeout(p.waitTimeout())}
+	if function.MemorySize != 0 {
+		opts = append(opts, awsService.WithMemory(function.MemorySize))
+	}
+	if function.Timeout != 0 {
+		opts = append(opts, awsService.WithTimeout(time.Duration(function.Timeout)*time.Second))
+	}
+	return opts
+}
+
+// streamOptions returns the options of the Kinesis stream of the manifest.
+func (p *Provisioner) streamOptions(stream manifest.Stream) []awsService.StreamOption {
+	opts := []awsService.StreamOption{awsService.WithTags(p.manifest.Tags), awsService.WithWaitTimeout(p.waitTimeout())}
+	if stream.Mode == "ON_DEMAND" {
+		opts = append(opts, awsService.WithOnDemand())
+	}
+	if stream.ShardCount != 0 {
+		opts = append(opts, awsService.WithShardCount(stream.ShardCount))
+	}
+	if stream.RetentionHours != 0 {
+		opts = append(opts, awsService.WithRetention(time.Duration(stream.RetentionHours)*time.Hour))
+	}
+	if stream.KMSKeyID != "" {
+		opts = append(opts, awsService.WithEncryption(stream.KMSKeyID))
+	}
+	return opts
+}
+
+// tableOptions returns the options of the DynamoDB table of the manifest.
+func (p *Provisioner) tableOptions(table manifest.Table) []awsService.TableOption {
+	opts := []awsService.TableOption{awsService.WithTags(p.manifest.Tags)}
+	if table.BillingMode == "PROVISIONED" {
+		opts = append(opts, awsService.WithProvisionedThroughput(table.ReadCapacity, table.WriteCapacity))
+	}
+	return opts
+}
+
+// jobOptions returns the options of the Glue job of the manifest.
+func (p *Provisioner) jobOptions(job manifest.GlueJob) []awsService.JobOption {
+	opts := []awsService.JobOption{awsService.WithTags(p.manifest.Tags)}
+	if job.Command != "" {
+		opts = append(opts, awsService.WithJobCommand(job.Command))
+	}
+	if job.GlueVersion != "" {
+		opts = append(opts, awsService.WithGlueVersion(job.GlueVersion))
+	}
+	if job.MaxCapacity != 0 {
+		opts = append(opts, awsService.WithMaxCapacity(job.MaxCapacity))
+	}
+	if arguments := p.jobArguments(job); len(arguments) > 0 {
+		opts = append(opts, awsService.WithDefaultArguments(arguments))
+	}
+	return opts
+}
+
+// jobArguments returns the default arguments of the Glue job. They pass the names of the
+// buckets and of the cluster of the job, so the script uses the resources of the
+// environment.
+func (p *Provisioner) jobArguments(job manifest.GlueJob) map[string]string {
+	arguments := map[string]string{}
+	for argument, bucket := range job.Buckets {
+		arguments["--"+argument] = bucket
+	}
+	if cluster, ok := p.manifest.Cluster(job.Cluster); ok {
+		arguments["--cluster"] = cluster.Identifier
+		arguments["--secret"] = cluster.Username
+		arguments["--database"] = cluster.Database
+	}
+	return arguments
+}
+
+// functionConfig returns the configuration of the Lambda functions. The functions assume
+// the role of Lambda, know the environment they belong to and where to export their spans.
+// The ARN of the role is empty if the role does not exist yet, which only happens while
+// planning.
+func (p *Provisioner) functionConfig(ctx context.Context) (awsService.FunctionConfig, error) {
+	var config awsService.FunctionConfig
+	if role, ok := p.manifest.Role("lambda"); ok {
+		arn, err := p.iam.RoleARN(ctx, role.Name)
+		if err != nil && !errors.Is(err, awsService.ErrNotFound) {
+			return config, fmt.Errorf("getting role %s: %w", role.Name, err)
+		}
+		config.RoleARN = arn
+	}
+
+	variables := map[string]string{}
+	if p.manifest.Environment != "" {
+		variables[environment.Variable] = p.manifest.Environment
+	}
+	if p.OTLPEndpoint != "" {
+		variables[tracing.EndpointVariable] = p.OTLPEndpoint
+	}
+	if len(variables) > 0 {
+		config.Environment = variables
+	}
+	return config, nil
+}
+
+// createStream creates the Kinesis stream, waits for it to be active and returns its ARN.
+func (p *Provisioner) createStream(ctx context.Context, stream manifest.Stream) (string, error) {
+	log.Printf("Creating kinesis stream `%s`...", stream.Name)
+	if err := p.kinesis.Ensure(ctx, stream.Name, p.streamOptions(stream)...); err != nil {
+		return "", fmt.Errorf("creating stream %s: %w", stream.Name, err)
+	}
+	log.Printf("Created kinesis stream `%s`", stream.Name)
+
+	log.Printf("Waiting for kinesis stream `%s` to be active...", stream.Name)
+	if err := p.kinesis.WaitUntilActive(ctx, stream.Name, p.waitTimeout()); err != nil {
+		return "", err
+	}
+	log.Printf("Kinesis stream `%s` is active", stream.Name)
+
+	arn, err := p.kinesis.GetARN(ctx, stream.Name)
+	if err != nil {
+		return "", fmt.Errorf("getting arn of stream %s: %w", stream.Name, err)
+	}
+
+	return arn, nil
+}
+
+// createEventSourceMapping binds the Lambda function to the Kinesis stream, through the
+// consumer of the mapping if it has one.
+func (p *Provisioner) createEventSourceMapping(ctx context.Context, mapping manifest.EventSourceMapping) error {
+	if mapping.Consumer != "" {
+		consumerARN, err := p.createConsumer(ctx, mapping)
+		if err != nil {
+			return err
+		}
+		return p.bindFunction(ctx, mapping, consumerARN)
+	}
+
+	streamARN, err := p.kinesis.GetARN(ctx, mapping.Stream)
+	if err != nil {
+		return fmt.Errorf("getting arn of stream %s: %w", mapping.Stream, err)
+	}
+	return p.bindFunction(ctx, mapping, streamARN)
+}
+
+// bindFunction binds the Lambda function of the event source mapping to the event source
+// with the given ARN, the stream or its consumer.
+func (p *Provisioner) bindFunction(ctx context.Context, mapping manifest.EventSourceMapping, eventSourceARN string) error {
+	log.Printf("Binding `%s` lambda function to kinesis stream `%s`...", mapping.Function, mapping.Stream)
+
+	if err := p.lambda.EnsureBoundToService(ctx, mapping.Function, eventSourceARN); err != nil {
+		return fmt.Errorf("binding function %s to stream %s: %w", mapping.Function, mapping.Stream, err)
+	}
+	log.Printf("Bound `%s` lambda function to kinesis stream `%s`", mapping.Function, mapping.Stream)
+
+	return nil
+}
+
+// createConsumer registers the enhanced fan-out consumer of the event source mapping,
+// waits for it to be active and returns its ARN.
+func (p *Provisioner) createConsumer(ctx context.Context, mapping manifest.EventSourceMapping) (string, error) {
+	log.Printf("Registering kinesis consumer `%s` of stream `%s`...", mapping.Consumer, mapping.Stream)
+	arn, err := p.kinesis.EnsureConsumer(ctx, mapping.Stream, mapping.Consumer)
+	if err != nil {
+		return "", fmt.Errorf("registering consumer %s of stream %s: %w", mapping.Consumer, mapping.Stream, err)
+	}
+	log.Printf("Registered kinesis consumer `%s` of stream `%s`", mapping.Consumer, mapping.Stream)
+
+	log.Printf("Waiting for kinesis consumer `%s` to be active...", mapping.Consumer)
+	if err := p.kinesis.WaitUntilConsumerActive(ctx, mapping.Stream, mapping.Consumer, p.waitTimeout()); err != nil {
+		return "", err
+	}
+	log.Printf("Kinesis consumer `%s` is active", mapping.Consumer)
+
+	return arn, nil
+}
+
+// eventSourceARN returns the ARN of the event source of the event source mapping, which is
+// its consumer if it has one and its stream otherwise.
+func (p *Provisioner) eventSourceARN(ctx context.Context, mapping manifest.EventSourceMapping) (string, error) {
+	if mapping.Consumer != "" {
+		return p.kinesis.GetConsumerARN(ctx, mapping.Stream, mapping.Consumer)
+	}
+
+	return p.kinesis.GetARN(ctx, mapping.Stream)
+}
+
+// createTable creates the DynamoDB table and waits for it to be active.
+func (p *Provisioner) createTable(ctx context.Context, table manifest.Table) error {
+	log.Printf("Creating dynamodb table `%s`...", table.Name)
+	if err := p.dynamodb.EnsureTable(ctx, table.Name, p.tableOptions(table)...); err != nil {
+		return fmt.Errorf("creating table %s: %w", table.Name, err)
+	}
+	log.Printf("Created dynamodb table `%s`", table.Name)
+
+	log.Printf("Waiting for dynamodb table `%s` to be active...", table.Name)
+	if err := p.dynamodb.WaitUntilActive(ctx, table.Name, p.waitTimeout()); err != nil {
+		return err
+	}
+	log.Printf("Dynamodb table `%s` is active", table.Name)
+
+	return nil
+}
+
+// createCluster creates the Aurora DB cluster, waits for it to be available, executes its
+// statements and applies its pending migrations.
+func (p *Provisioner) createCluster(ctx context.Context, c manifest.Cluster) (outputs.Cluster, error) {
+	log.Printf("Creating Aurora DB Cluster `%s`...", c.Identifier)
+	cluster, secretARN, err := p.aurora.EnsureDBCluster(ctx, c.Identifier, c.Database, c.Username, c.Password)
+	if err != nil {
+		return outputs.Cluster{}, fmt.Errorf("creating cluster %s: %w", c.Identifier, err)
+	}
+	log.Printf("Created Aurora DB Cluster `%s`", c.Identifier)
+
+	clusterARN := aws.ToString(cluster.DBClusterArn)
+
+	log.Printf("Waiting for Aurora DB Cluster `%s` to be available...", c.Identifier)
+	if err := p.aurora.WaitUntilAvailable(ctx, c.Identifier, p.waitTimeout()); err != nil {
+		return outputs.Cluster{}, err
+	}
+	log.Printf("Aurora DB Cluster `%s` is available", c.Identifier)
+
+	for _, statement := range c.Statements {
+		log.Printf("Executing statement `%s`...", truncate(statement, 40))
+		_, err := p.aurora.ExecuteStatement(ctx, c.Database, clusterARN, secretARN, statement)
+		if err != nil {
+			return outputs.Cluster{}, fmt.Errorf("executing statement on cluster %s: %w", c.Identifier, err)
+		}
+	}
+
+	if c.Migrate {
+		if err := p.migrate(ctx, c, clusterARN, secretARN); err != nil {
+			return outputs.Cluster{}, err
+		}
+	}
+
+	return outputs.Cluster{
+		ARN:       clusterARN,
+		SecretARN: secretARN,
+		Database:  c.Database,
+	}, nil
+}
+
+// waitTimeout returns the maximum time to wait for a single resource to become ready.
+func (p *Provisioner) waitTimeout() time.Duration {
+	if p.WaitTimeout == 0 {
+		return defaultWaitTimeout
+	}
+	return p.WaitTimeout
+}
+
+// truncate shortens the given string to the given length for logging.
+func truncate(s string, length int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) <= length {
+		return s
+	}
+	return s[:length] + "..."
+}
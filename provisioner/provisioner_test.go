@@ -0,0 +1,123 @@
+package provisioner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+	"github.com/florianwoelki/uber-movement-speed/awsfake"
+	"github.com/florianwoelki/uber-movement-speed/manifest"
+)
+
+// testManifest describes a resource of every kind that the fakes support. The `%s` is the
+// path of the code of the function.
+const testManifest = `
+version: 1
+roles:
+  - name: s3-role
+    service: s3
+  - name: lambda-role
+    service: lambda
+  - name: kinesis-role
+    service: kinesis
+  - name: dynamodb-role
+    service: dynamodb
+  - name: glue-role
+    service: glue
+  - name: rds-role
+    service: rds
+  - name: apigatewayv2-role
+    service: apigatewayv2
+buckets:
+  - name: code
+glueJobs:
+  - name: etl
+    script: s3://code/etl.py
+functions:
+  - name: Forwarder
+    runtime: go
+    bucket: code
+    key: forwarder.zip
+    source: %s
+streams:
+  - name: speeds
+eventSourceMappings:
+  - function: Forwarder
+    stream: speeds
+tables:
+  - name: segment-speeds
+apis:
+  - name: speeds-api
+    protocol: http
+    routes:
+      - path: /speeds
+        method: POST
+        function: Forwarder
+clusters:
+  - identifier: db1
+    database: speeds
+    username: dbpass
+    password: test
+`
+
+// newTestProvisioner returns a provisioner of the test manifest whose clients use fakes of
+// the `awsfake` package. Setup creates one resource at a time, so the order of the
+// created and deleted resources does not change between runs.
+func newTestProvisioner(t *testing.T) (*Provisioner, Clients) {
+	t.Helper()
+
+	source := filepath.Join(t.TempDir(), "forwarder.zip")
+	if err := os.WriteFile(source, []byte("forwarder"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := manifest.Parse([]byte(fmt.Sprintf(testManifest, source)))
+	if err != nil {
+		t.Fatalf("parsing the manifest: %v", err)
+	}
+
+	iam := awsfake.NewIAM()
+	s3 := awsfake.NewS3()
+	clients := Clients{
+		IAM:        awsService.NewIAMFromClients(iam, iam),
+		S3:         awsService.NewS3FromClient(s3),
+		Kinesis:    awsService.NewKinesisFromClient(awsfake.NewKinesis()),
+		Lambda:     awsService.NewLambdaFromClient(awsfake.NewLambda(s3)),
+		DynamoDB:   awsService.NewDynamoDBFromClient(awsfake.NewDynamoDB()),
+		Glue:       awsService.NewGlueFromClient(awsfake.NewGlue()),
+		Aurora:     awsService.NewAuroraFromClients(awsfake.NewRDS(), awsfake.NewRDSData(), awsfake.NewSecretsManager()),
+		APIGateway: awsService.NewAPIGatewayFromClient(awsfake.NewAPIGateway()),
+	}
+
+	p := NewFromClients(aws.Config{Region: "us-east-1"}, m, clients)
+	p.Parallelism = 1
+	return p, clients
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	p, _ := newTestProvisioner(t)
+
+	out, err := p.Apply(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.Functions["Forwarder"].ARN == "" {
+		t.Errorf("expected the ARN of the function in the outputs, got %v", out.Functions)
+	}
+	if out.Streams["speeds"].ARN == "" {
+		t.Errorf("expected the ARN of the stream in the outputs, got %v", out.Streams)
+	}
+
+	changes, err := p.Plan(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if HasChanges(changes) {
+		t.Errorf("expected no changes after setup, got %v", changes)
+	}
+}
@@ -0,0 +1,21 @@
+// Command gen writes the JSON Schema of the SegmentSpeed record. It is run by
+// `go generate ./schema`.
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/florianwoelki/uber-movement-speed/schema"
+)
+
+func main() {
+	data, err := schema.JSONSchema()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile(schema.JSONSchemaFile, data, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,68 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// JSONSchemaFile is the name of the generated JSON Schema of the SegmentSpeed record.
+const JSONSchemaFile = "segment_speed.schema.json"
+
+// traceContextProperties are the optional properties of the W3C Trace Context that the
+// producer of a record may add to its payload.
+var traceContextProperties = []string{"traceparent", "tracestate"}
+
+// jsonSchema is a JSON Schema of an object whose properties are all required.
+type jsonSchema struct {
+	Schema               string                    `json:"$schema"`
+	Title                string                    `json:"title"`
+	Type                 string                    `json:"type"`
+	Properties           map[string]jsonSchemaType `json:"properties"`
+	Required             []string                  `json:"required"`
+	AdditionalProperties bool                      `json:"additionalProperties"`
+}
+
+// jsonSchemaType is the JSON Schema of a property.
+type jsonSchemaType struct {
+	Type string `json:"type"`
+}
+
+// JSONSchema returns the JSON Schema of the SegmentSpeed record as it is sent to Kinesis,
+// optionally with the trace context of its producer.
+func JSONSchema() ([]byte, error) {
+	s := jsonSchema{
+		Schema:     "https://json-schema.org/draft/2020-12/schema",
+		Title:      "SegmentSpeed",
+		Type:       "object",
+		Properties: map[string]jsonSchemaType{},
+	}
+
+	for _, column := range columns {
+		var t string
+		switch column.kind {
+		case reflect.String:
+			t = "string"
+		case reflect.Int, reflect.Int64:
+			t = "integer"
+		case reflect.Float32:
+			t = "number"
+		default:
+			return nil, fmt.Errorf("schema: unsupported kind %s of column %s", column.kind, column.Name)
+		}
+
+		s.Properties[column.Name] = jsonSchemaType{Type: t}
+		s.Required = append(s.Required, column.Name)
+	}
+
+	// The trace context of the record travels inside its payload, see the tracing package.
+	for _, name := range traceContextProperties {
+		s.Properties[name] = jsonSchemaType{Type: "string"}
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,231 @@
+// Package schema defines the SegmentSpeed record that flows from the simulation through
+// Kinesis, DynamoDB, the raw data bucket and the Glue job into Aurora. The record is
+// defined once by the SegmentSpeed struct; the Aurora DDL and insert statement, the CSV
+// layout and the JSON Schema are generated from its struct tags.
+package schema
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+//go:generate go run ./gen
+
+// Table is the name of the DynamoDB table and the Aurora table of the records.
+const Table = "street_segment_speeds"
+
+// SegmentSpeed is the speed on a street segment during an hour. Every field is a column:
+//
+//   - `json` is the name of the column in JSON, CSV, DynamoDB and Aurora.
+//   - `sql` is the type of the column in Aurora. The option `generated` marks a column
+//     whose value Aurora generates, so it is not inserted.
+type SegmentSpeed struct {
+	Id              string  `json:"id" dynamodbav:"id" sql:"SERIAL PRIMARY KEY,generated"`
+	Year            int     `json:"year" dynamodbav:"year" sql:"INT"`
+	Month           int     `json:"month" dynamodbav:"month" sql:"INT"`
+	Day             int     `json:"day" dynamodbav:"day" sql:"INT"`
+	Hour            int     `json:"hour" dynamodbav:"hour" sql:"INT"`
+	UtcTimestamp    string  `json:"utc_timestamp" dynamodbav:"utc_timestamp" sql:"VARCHAR(100)"`
+	StartJunctionId string  `json:"start_junction_id" dynamodbav:"start_junction_id" sql:"VARCHAR(200)"`
+	EndJunctionId   string  `json:"end_junction_id" dynamodbav:"end_junction_id" sql:"VARCHAR(200)"`
+	OsmWayId        int64   `json:"osm_way_id" dynamodbav:"osm_way_id" sql:"BIGINT"`
+	OsmStartNodeId  int64   `json:"osm_start_node_id" dynamodbav:"osm_start_node_id" sql:"BIGINT"`
+	OsmEndNodeId    int64   `json:"osm_end_node_id" dynamodbav:"osm_end_node_id" sql:"BIGINT"`
+	SpeedMphMean    float32 `json:"speed_mph_mean" dynamodbav:"speed_mph_mean" sql:"FLOAT"`
+	SpeedMphStddev  float32 `json:"speed_mph_stddev" dynamodbav:"speed_mph_stddev" sql:"FLOAT"`
+}
+
+// PartitionKey returns the Kinesis partition key of the record, which identifies its
+// street segment, so the speeds of a segment stay in order on one shard while the
+// segments spread over every shard. A segment is identified by its start and end
+// junction, by its OSM way and nodes if the junctions are unknown, or by the ID of the
+// record if neither is known.
+func (s SegmentSpeed) PartitionKey() string {
+	switch {
+	case s.StartJunctionId != "" && s.EndJunctionId != "":
+		return s.StartJunctionId + "-" + s.EndJunctionId
+	case s.OsmWayId != 0:
+		return fmt.Sprintf("%d-%d-%d", s.OsmWayId, s.OsmStartNodeId, s.OsmEndNodeId)
+	default:
+		return s.Id
+	}
+}
+
+// Column is a column of the SegmentSpeed record.
+type Column struct {
+	// Name is the name of the column.
+	Name string
+	// SQLType is the type of the column in Aurora.
+	SQLType string
+	// Generated reports whether Aurora generates the value of the column.
+	Generated bool
+
+	// index is the index of the field of the column in SegmentSpeed.
+	index int
+	// kind is the kind of the field of the column.
+	kind reflect.Kind
+}
+
+// columns are the columns of SegmentSpeed in the order of its fields.
+var columns = parseColumns(reflect.TypeOf(SegmentSpeed{}))
+
+// parseColumns returns the columns of the fields of the given struct type.
+func parseColumns(t reflect.Type) []Column {
+	cols := make([]Column, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		sqlType, options, _ := strings.Cut(field.Tag.Get("sql"), ",")
+		if name == "" || sqlType == "" {
+			panic(fmt.Sprintf("schema: field %s needs a json and a sql tag", field.Name))
+		}
+
+		cols = append(cols, Column{
+			Name:      name,
+			SQLType:   sqlType,
+			Generated: options == "generated",
+			index:     i,
+			kind:      field.Type.Kind(),
+		})
+	}
+	return cols
+}
+
+// Columns returns the columns of the SegmentSpeed record in the order of the CSV layout.
+func Columns() []Column {
+	return append([]Column(nil), columns...)
+}
+
+// CreateTableSQL returns the statement that creates the Aurora table of the records if it
+// does not exist yet.
+func CreateTableSQL() string {
+	definitions := make([]string, 0, len(columns))
+	for _, column := range columns {
+		definitions = append(definitions, column.Name+" "+column.SQLType)
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", Table, strings.Join(definitions, ", "))
+}
+
+// InsertSQL returns the statement that inserts a record into the Aurora table. Every
+// column that is not generated is a named parameter with the name of the column.
+func InsertSQL() string {
+	var names, parameters []string
+	for _, column := range columns {
+		if column.Generated {
+			continue
+		}
+		names = append(names, column.Name)
+		parameters = append(parameters, ":"+column.Name)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", Table, strings.Join(names, ", "), strings.Join(parameters, ", "))
+}
+
+// Header returns the header of the CSV layout of the records.
+func Header() []string {
+	header := make([]string, 0, len(columns))
+	for _, column := range columns {
+		header = append(header, column.Name)
+	}
+	return header
+}
+
+// Record returns the CSV row of the record in the order of Header.
+func (s SegmentSpeed) Record() []string {
+	value := reflect.ValueOf(s)
+	record := make([]string, 0, len(columns))
+	for _, column := range columns {
+		field := value.Field(column.index)
+		switch column.kind {
+		case reflect.String:
+			record = append(record, field.String())
+		case reflect.Int, reflect.Int64:
+			record = append(record, strconv.FormatInt(field.Int(), 10))
+		case reflect.Float32:
+			record = append(record, strconv.FormatFloat(field.Float(), 'g', -1, 32))
+		default:
+			panic(fmt.Sprintf("schema: unsupported kind %s of column %s", column.kind, column.Name))
+		}
+	}
+	return record
+}
+
+// ParseRecord returns the record of the given CSV row in the order of Header.
+func ParseRecord(record []string) (SegmentSpeed, error) {
+	if len(record) != len(columns) {
+		return SegmentSpeed{}, fmt.Errorf("schema: expected %d fields, got %d", len(columns), len(record))
+	}
+
+	var s SegmentSpeed
+	value := reflect.ValueOf(&s).Elem()
+	for i, column := range columns {
+		field := value.Field(column.index)
+		switch column.kind {
+		case reflect.String:
+			field.SetString(record[i])
+		case reflect.Int, reflect.Int64:
+			n, err := strconv.ParseInt(record[i], 10, field.Type().Bits())
+			if err != nil {
+				return SegmentSpeed{}, fmt.Errorf("schema: column %s: %w", column.Name, err)
+			}
+			field.SetInt(n)
+		case reflect.Float32:
+			f, err := strconv.ParseFloat(record[i], 32)
+			if err != nil {
+				return SegmentSpeed{}, fmt.Errorf("schema: column %s: %w", column.Name, err)
+			}
+			field.SetFloat(f)
+		default:
+			panic(fmt.Sprintf("schema: unsupported kind %s of column %s", column.kind, column.Name))
+		}
+	}
+	return s, nil
+}
+
+// WriteCSV writes the header and the given records as CSV to the given writer.
+func WriteCSV(w io.Writer, speeds []SegmentSpeed) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(Header()); err != nil {
+		return err
+	}
+	for _, s := range speeds {
+		if err := writer.Write(s.Record()); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// ReadCSV reads the records from the given CSV with a header.
+func ReadCSV(r io.Reader) ([]SegmentSpeed, error) {
+	reader := csv.NewReader(r)
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	if !reflect.DeepEqual(header, Header()) {
+		return nil, fmt.Errorf("schema: unexpected header %v", header)
+	}
+
+	var speeds []SegmentSpeed
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return speeds, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		s, err := ParseRecord(record)
+		if err != nil {
+			return nil, err
+		}
+		speeds = append(speeds, s)
+	}
+}
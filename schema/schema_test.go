@@ -0,0 +1,186 @@
+package schema
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/florianwoelki/uber-movement-speed/migrations"
+)
+
+var testSpeed = SegmentSpeed{
+	Id:              "test-id",
+	Year:            2020,
+	Month:           1,
+	Day:             2,
+	Hour:            3,
+	UtcTimestamp:    "2020-01-02T03:00:00.000Z",
+	StartJunctionId: "start",
+	EndJunctionId:   "end",
+	OsmWayId:        40722998,
+	OsmStartNodeId:  62385707,
+	OsmEndNodeId:    4927951349,
+	SpeedMphMean:    26.636,
+	SpeedMphStddev:  4.483,
+}
+
+func TestCreateTableSQL(t *testing.T) {
+	expected := "CREATE TABLE IF NOT EXISTS street_segment_speeds (id SERIAL PRIMARY KEY, year INT, month INT, day INT, hour INT, " +
+		"utc_timestamp VARCHAR(100), start_junction_id VARCHAR(200), end_junction_id VARCHAR(200), osm_way_id BIGINT, " +
+		"osm_start_node_id BIGINT, osm_end_node_id BIGINT, speed_mph_mean FLOAT, speed_mph_stddev FLOAT)"
+	if sql := CreateTableSQL(); sql != expected {
+		t.Errorf("unexpected statement: %s", sql)
+	}
+}
+
+// TestCreateTableSQL_Migration checks the migration that creates the table. The table is
+// only created by a single migration so far, a later migration that alters the table needs
+// a comparison with the schema after every migration instead.
+func TestCreateTableSQL_Migration(t *testing.T) {
+	all, err := migrations.All()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, m := range all {
+		for _, statement := range m.Up {
+			if strings.Contains(statement, "TABLE IF NOT EXISTS "+Table) {
+				if statement != CreateTableSQL() {
+					t.Errorf("statement of migration %s differs from the schema:\n%s\nexpected:\n%s", m, statement, CreateTableSQL())
+				}
+				return
+			}
+		}
+	}
+	t.Errorf("expected a migration to create the table %s", Table)
+}
+
+func TestGlueScript(t *testing.T) {
+	data, err := os.ReadFile("../services/glue/raw_data_etl.py")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	script := regexp.MustCompile(`\s+`).ReplaceAllString(string(data), "")
+
+	if !strings.Contains(string(data), InsertSQL()) {
+		t.Errorf("glue script does not contain the insert statement:\n%s", InsertSQL())
+	}
+
+	// Spark types of the casts and Data API values of the parameters by SQL type.
+	types := map[string][2]string{
+		"INT":    {".cast(IntegerType())", "longValue"},
+		"BIGINT": {".cast(LongType())", "longValue"},
+		"FLOAT":  {".cast(FloatType())", "doubleValue"},
+	}
+	for _, column := range Columns() {
+		cast, value := "", "stringValue"
+		if t, ok := types[column.SQLType]; ok {
+			cast, value = t[0], t[1]
+		}
+
+		expected := `.withColumn("` + column.Name + `",col("` + column.Name + `")` + cast + `)`
+		if !strings.Contains(script, expected) {
+			t.Errorf("glue script does not transform column %s with %s", column.Name, expected)
+		}
+
+		if column.Generated {
+			continue
+		}
+		expected = `{"name":"` + column.Name + `","value":{"` + value + `":row["` + column.Name + `"]}`
+		if !strings.Contains(script, expected) {
+			t.Errorf("glue script does not insert column %s with %s", column.Name, expected)
+		}
+	}
+}
+
+func TestInsertSQL(t *testing.T) {
+	sql := InsertSQL()
+	if strings.Contains(sql, ":id") {
+		t.Errorf("unexpected generated column in statement: %s", sql)
+	}
+	if !strings.HasPrefix(sql, "INSERT INTO street_segment_speeds (year, month,") {
+		t.Errorf("unexpected statement: %s", sql)
+	}
+}
+
+func TestColumns_DynamoDB(t *testing.T) {
+	typ := reflect.TypeOf(SegmentSpeed{})
+	for _, column := range Columns() {
+		if tag := typ.Field(column.index).Tag.Get("dynamodbav"); tag != column.Name {
+			t.Errorf("dynamodb attribute %s differs from column %s", tag, column.Name)
+		}
+	}
+}
+
+func TestCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, []SegmentSpeed{testSpeed}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if lines[0] != strings.Join(Header(), ",") {
+		t.Errorf("unexpected header: %s", lines[0])
+	}
+	if lines[1] != "test-id,2020,1,2,3,2020-01-02T03:00:00.000Z,start,end,40722998,62385707,4927951349,26.636,4.483" {
+		t.Errorf("unexpected row: %s", lines[1])
+	}
+
+	speeds, err := ReadCSV(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(speeds, []SegmentSpeed{testSpeed}) {
+		t.Errorf("unexpected records: %+v", speeds)
+	}
+}
+
+func TestParseRecord_Invalid(t *testing.T) {
+	record := testSpeed.Record()
+	record[1] = "not-a-year"
+	if _, err := ParseRecord(record); err == nil {
+		t.Errorf("expected error for invalid year")
+	}
+
+	if _, err := ParseRecord(record[:3]); err == nil {
+		t.Errorf("expected error for missing fields")
+	}
+}
+
+func TestJSONSchema_Generated(t *testing.T) {
+	generated, err := os.ReadFile(JSONSchemaFile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected, err := JSONSchema()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(generated, expected) {
+		t.Errorf("%s is outdated, run `go generate ./schema`", JSONSchemaFile)
+	}
+}
+
+func TestSegmentSpeed_PartitionKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		speed    SegmentSpeed
+		expected string
+	}{
+		{name: "junctions", speed: testSpeed, expected: "start-end"},
+		{name: "osm way", speed: SegmentSpeed{Id: "test-id", StartJunctionId: "start", OsmWayId: 1, OsmStartNodeId: 2, OsmEndNodeId: 3}, expected: "1-2-3"},
+		{name: "id", speed: SegmentSpeed{Id: "test-id"}, expected: "test-id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if key := tt.speed.PartitionKey(); key != tt.expected {
+				t.Errorf("expected partition key %s, got %s", tt.expected, key)
+			}
+		})
+	}
+}
@@ -1,15 +1,26 @@
 #!/bin/bash
 
 # This script checks the data in the Aurora MySQL database.
-# Gets the database cluster.
-CLUSTER=$(aws --endpoint-url=http://localhost:4566 rds describe-db-clusters --db-cluster-identifier db1)
-# Gets the ARN of the database cluster.
-CLUSTER_ARN=$(echo $CLUSTER | jq -r '.DBClusters[0].DBClusterArn')
+# The names of the resources are prefixed with the environment, e.g. `staging-db1`.
+PREFIX=${ENVIRONMENT:+$ENVIRONMENT-}
+DEFAULT_OUTPUTS=outputs${ENVIRONMENT:+.$ENVIRONMENT}.json
+OUTPUTS=${OUTPUTS_PATH:-$DEFAULT_OUTPUTS}
 
-# Gets the secret of the database cluster.
-SECRET=$(aws --endpoint-url=http://localhost:4566 secretsmanager describe-secret --secret-id dbpass)
-# Gets the ARN of the secret.
-SECRET_ARN=$(echo $SECRET | jq -r '.ARN')
+if [ -f "$OUTPUTS" ]; then
+  # Gets the ARNs of the database cluster and its secret from the outputs of the setup.
+  CLUSTER_ARN=$(jq -r --arg cluster "${PREFIX}db1" '.clusters[$cluster].arn' "$OUTPUTS")
+  SECRET_ARN=$(jq -r --arg cluster "${PREFIX}db1" '.clusters[$cluster].secretArn' "$OUTPUTS")
+else
+  # Gets the database cluster.
+  CLUSTER=$(aws --endpoint-url=http://localhost:4566 rds describe-db-clusters --db-cluster-identifier ${PREFIX}db1)
+  # Gets the ARN of the database cluster.
+  CLUSTER_ARN=$(echo $CLUSTER | jq -r '.DBClusters[0].DBClusterArn')
+
+  # Gets the secret of the database cluster.
+  SECRET=$(aws --endpoint-url=http://localhost:4566 secretsmanager describe-secret --secret-id ${PREFIX}dbpass)
+  # Gets the ARN of the secret.
+  SECRET_ARN=$(echo $SECRET | jq -r '.ARN')
+fi
 
 # Selects all the data in the table `street_segment_speeds` in the database.
 aws --endpoint-url=http://localhost:4566 rds-data execute-statement \
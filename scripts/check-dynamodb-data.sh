@@ -1,5 +1,8 @@
 #!/bin/bash
 
 # This script checks the data in the Aurora MySQL database.
+# The name of the table is prefixed with the environment, e.g. `staging-street_segment_speeds`.
+TABLE=${ENVIRONMENT:+$ENVIRONMENT-}street_segment_speeds
+
 aws --endpoint-url=http://localhost:4566 dynamodb execute-statement \
-    --statement "SELECT * FROM street_segment_speeds"
+    --statement "SELECT * FROM \"$TABLE\""
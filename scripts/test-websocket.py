@@ -1,9 +1,24 @@
 import json
+import os
 import asyncio
 import websockets
 
 
-url = "ws://localhost:4510"
+def get_websocket_url() -> str:
+    """
+    Returns the endpoint of the websocket API Gateway from the outputs document that is
+    written by the setup. Falls back to the default localstack endpoint if the document
+    does not exist.
+    """
+    path = os.environ.get("OUTPUTS_PATH", "outputs.json")
+    try:
+        with open(path) as outputs:
+            return json.load(outputs)["apis"]["my-kinesis-api"]["endpoint"]
+    except (OSError, KeyError):
+        return "ws://localhost:4510"
+
+
+url = get_websocket_url()
 
 
 def main():
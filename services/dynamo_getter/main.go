@@ -2,16 +2,21 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"os"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+	"github.com/florianwoelki/uber-movement-speed/awsconfig"
+	"github.com/florianwoelki/uber-movement-speed/environment"
+	"github.com/florianwoelki/uber-movement-speed/schema"
+	"github.com/florianwoelki/uber-movement-speed/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
@@ -23,47 +28,58 @@ var (
 	dynamodbClient *awsService.DynamoDB
 )
 
+// tracerProvider exports the spans of the function after every invocation.
+var tracerProvider *tracing.Provider
+
 type DynamoGetterResponse struct {
 	Item map[string]types.AttributeValue `json:"item"`
 }
 
 func init() {
-	tableName = "street_segment_speeds"
-
-	customResolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
-		return aws.Endpoint{
-			PartitionID:   "aws",
-			URL:           fmt.Sprintf("http://%s:4566", os.Getenv("LOCALSTACK_HOSTNAME")),
-			SigningRegion: "us-east-1",
-		}, nil
-	})
-
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion("us-east-1"),
-		config.WithEndpointResolverWithOptions(customResolver),
-	)
+	// The name of the table is prefixed with the environment of the function.
+	tableName = environment.Name(environment.FromEnv(), schema.Table)
+
+	var err error
+	tracerProvider, err = tracing.Setup(context.TODO(), "dynamo-getter")
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	cfg, err := awsconfig.LoadFromEnv(context.TODO())
+	if err != nil {
+		log.Fatal(err)
+	}
+	cfg = awsService.WithTracing(cfg, otel.GetTracerProvider())
+
 	dynamodbClient = awsService.NewDynamoDB(cfg)
 }
 
 func handleRequest(ctx context.Context, event events.APIGatewayProxyRequest) (DynamoGetterResponse, error) {
+	defer func() {
+		if err := tracerProvider.Flush(ctx); err != nil {
+			log.Printf("Failed to export spans: %v", err)
+		}
+	}()
+
+	// The span continues the trace of the caller if the request has a trace context.
+	ctx, span := tracing.Tracer().Start(tracing.ExtractHeaders(ctx, event.Headers), "dynamo-getter", trace.WithSpanKind(trace.SpanKindServer))
+	defer span.End()
+
 	id := event.QueryStringParameters["id"]
+	span.SetAttributes(attribute.String("segment_speed.id", id))
 	if id == "" {
 		return DynamoGetterResponse{}, fmt.Errorf("id is required")
 	}
 
-	item, err := dynamodbClient.GetItemById(tableName, id)
+	// A missing table is a deployment error and is reported as is, unlike a missing item.
+	item, err := dynamodbClient.GetItemById(ctx, tableName, id)
+	if errors.Is(err, awsService.ErrNotFound) {
+		return DynamoGetterResponse{}, fmt.Errorf("item with id %s not found", id)
+	}
 	if err != nil {
 		return DynamoGetterResponse{}, err
 	}
 
-	if item == nil {
-		return DynamoGetterResponse{}, fmt.Errorf("item with id %s not found", id)
-	}
-
 	return DynamoGetterResponse{
 		Item: item,
 	}, nil
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+	"github.com/florianwoelki/uber-movement-speed/awsfake"
+	"github.com/florianwoelki/uber-movement-speed/schema"
+)
+
+func TestHandleRequest(t *testing.T) {
+	ctx := context.Background()
+	dynamodbClient = awsService.NewDynamoDBFromClient(awsfake.NewDynamoDB())
+	if err := dynamodbClient.CreateTable(ctx, tableName); err != nil {
+		t.Fatal(err)
+	}
+
+	speed := schema.SegmentSpeed{Id: "1", Year: 2023, OsmWayId: 42, SpeedMphMean: 25.5}
+	item, err := attributevalue.MarshalMap(speed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dynamodbClient.PutItem(ctx, tableName, item); err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := handleRequest(ctx, events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"id": "1"}})
+	if err != nil {
+		t.Fatalf("handling the request: %v", err)
+	}
+	var got schema.SegmentSpeed
+	if err := attributevalue.UnmarshalMap(response.Item, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got != speed {
+		t.Errorf("expected %+v, got %+v", speed, got)
+	}
+
+	if _, err := handleRequest(ctx, events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"id": "2"}}); err == nil || err.Error() != "item with id 2 not found" {
+		t.Errorf("expected a missing item, got %v", err)
+	}
+	if _, err := handleRequest(ctx, events.APIGatewayProxyRequest{}); err == nil {
+		t.Error("expected a request without an id to fail")
+	}
+
+	dynamodbClient = awsService.NewDynamoDBFromClient(awsfake.NewDynamoDB())
+	if _, err := handleRequest(ctx, events.APIGatewayProxyRequest{QueryStringParameters: map[string]string{"id": "1"}}); !errors.Is(err, awsService.ErrTableNotFound) || errors.Is(err, awsService.ErrNotFound) {
+		t.Errorf("expected a missing table to fail the request, got %v", err)
+	}
+}
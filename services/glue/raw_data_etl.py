@@ -119,7 +119,12 @@ def main():
     glue_context = GlueContext(sc)
     job = Job(glue_context)
 
-    args = getResolvedOptions(sys.argv, ["JOB_NAME"])
+    # The names of the buckets and of the cluster are passed by the provisioner, so they
+    # carry the prefix of the environment.
+    args = getResolvedOptions(
+        sys.argv,
+        ["JOB_NAME", "source_bucket", "target_bucket", "cluster", "secret", "database"],
+    )
 
     LOG_GROUP_NAME = "/aws/glue/jobs"
     LOG_STREAM_NAME = get_running_job_id(args["JOB_NAME"])
@@ -132,7 +137,7 @@ def main():
     month = now.strftime("%m")
     day = now.strftime("%d")
 
-    source_bucket = "raw-data"
+    source_bucket = args["source_bucket"]
     source_key = f"year={year}/month={month}/day={day}"
     source_path = f"s3://{source_bucket}/{source_key}"
     df = glue_context.create_dynamic_frame.from_options(
@@ -149,6 +154,8 @@ def main():
         f"Read {df.count()} rows from {source_path}.",
     )
 
+    # The casts and the insert below follow the `SegmentSpeed` record of the `schema` Go
+    # package. Its tests fail if they differ from the record.
     df_transformed = (
         df.withColumn("id", col("id"))
         .withColumn("year", col("year").cast(IntegerType()))
@@ -174,7 +181,7 @@ def main():
 
     df_transformed = DynamicFrame.fromDF(df_transformed, glue_context, "df_transformed")
 
-    target_bucket = "transformed-data"
+    target_bucket = args["target_bucket"]
     target_key = f"year={year}/month={month}/day={day}/"
     target_path = f"s3://{target_bucket}/{target_key}"
     glue_context.write_dynamic_frame.from_options(
@@ -193,7 +200,7 @@ def main():
     )
 
     # Gets the ARNs for the RDS Aurora database and the secret.
-    cluster_arn, secret_arn = get_db_and_secret_arns("db1", "dbpass")
+    cluster_arn, secret_arn = get_db_and_secret_arns(args["cluster"], args["secret"])
 
     # Saves data to the RDS Aurora database.
     aurora_client = boto3.client("rds-data", endpoint_url=endpoint_url)
@@ -229,7 +236,7 @@ def main():
         aurora_client.execute_statement(
             resourceArn=cluster_arn,
             secretArn=secret_arn,
-            database="uber-data",
+            database=args["database"],
             sql="INSERT INTO street_segment_speeds (year, month, day, hour, utc_timestamp, start_junction_id, end_junction_id, osm_way_id, osm_start_node_id, osm_end_node_id, speed_mph_mean, speed_mph_stddev) VALUES (:year, :month, :day, :hour, :utc_timestamp, :start_junction_id, :end_junction_id, :osm_way_id, :osm_start_node_id, :osm_end_node_id, :speed_mph_mean, :speed_mph_stddev)",
             parameters=parameters,
         )
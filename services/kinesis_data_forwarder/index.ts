@@ -1,19 +1,126 @@
 import { v4 as uuidv4 } from 'uuid';
-import { KinesisClient, PutRecordCommand } from '@aws-sdk/client-kinesis';
+import {
+  KinesisClient,
+  KinesisClientConfig,
+  PutRecordCommand,
+} from '@aws-sdk/client-kinesis';
+import {
+  context,
+  propagation,
+  trace,
+  SpanKind,
+  SpanStatusCode,
+} from '@opentelemetry/api';
+import { W3CTraceContextPropagator } from '@opentelemetry/core';
+import { OTLPTraceExporter } from '@opentelemetry/exporter-trace-otlp-http';
+import { Resource } from '@opentelemetry/resources';
+import {
+  BasicTracerProvider,
+  BatchSpanProcessor,
+} from '@opentelemetry/sdk-trace-base';
+import { SemanticResourceAttributes } from '@opentelemetry/semantic-conventions';
 import {
   APIGatewayProxyCallbackV2,
   APIGatewayProxyEventV2,
   Context,
 } from 'aws-lambda';
 
+// The name of the stream is prefixed with the environment of the function.
+const environment = process.env.ENVIRONMENT;
+const streamName = environment
+  ? `${environment}-my-kinesis-stream`
+  : 'my-kinesis-stream';
+
+// Spans are exported with OTLP over HTTP if an endpoint is configured, e.g. of a local
+// collector. The trace context of a span travels inside the payload of its Kinesis record
+// as the `traceparent` and `tracestate` members, so the preprocessing service continues
+// the trace.
+const otlpEndpoint =
+  process.env.OTEL_EXPORTER_OTLP_TRACES_ENDPOINT ||
+  process.env.OTEL_EXPORTER_OTLP_ENDPOINT;
+const tracerProvider = new BasicTracerProvider({
+  resource: new Resource({
+    [SemanticResourceAttributes.SERVICE_NAME]: 'kinesis-data-forwarder',
+  }),
+});
+if (otlpEndpoint) {
+  tracerProvider.addSpanProcessor(
+    new BatchSpanProcessor(new OTLPTraceExporter()),
+  );
+}
+tracerProvider.register({ propagator: new W3CTraceContextPropagator() });
+const tracer = trace.getTracer('kinesis-data-forwarder');
+
+// kinesisConfig returns the configuration of the Kinesis client. It reads the same
+// environment variables as `FromEnv` of the awsconfig package: `AWS_ENDPOINT_URL` selects
+// a custom endpoint, `AWS_TARGET` either `aws` or `localstack` and `LOCALSTACK_HOSTNAME`
+// the host of LocalStack. Without them, LocalStack is used unless the function runs
+// outside of LocalStack. Credentials are read by the default credential chain and dummy
+// credentials are only used for a stand-in without any credentials.
+const kinesisConfig = (): KinesisClientConfig => {
+  const env = process.env;
+  const config: KinesisClientConfig = {
+    region: env.AWS_REGION || env.AWS_DEFAULT_REGION || 'us-east-1',
+  };
+
+  const target = env.AWS_TARGET;
+  if (env.AWS_ENDPOINT_URL) {
+    config.endpoint = env.AWS_ENDPOINT_URL;
+  } else if (target && target !== 'aws' && target !== 'localstack') {
+    throw new Error(
+      `unsupported AWS_TARGET "${target}", expected "aws" or "localstack"`,
+    );
+  } else if (
+    target === 'aws' ||
+    (!target && !env.LOCALSTACK_HOSTNAME && env.AWS_LAMBDA_FUNCTION_NAME)
+  ) {
+    return config;
+  } else {
+    const host = env.LOCALSTACK_HOSTNAME || 'localhost.localstack.cloud';
+    config.endpoint = `http://${host}:4566`;
+  }
+
+  if (!env.AWS_PROFILE && !env.AWS_ACCESS_KEY_ID) {
+    config.credentials = { accessKeyId: 'test', secretAccessKey: 'test' };
+  }
+  return config;
+};
+
+// The client is shared by the invocations of the same environment of the function.
+const client = new KinesisClient(kinesisConfig());
+
 interface Event {
   action: 'kinesis-data-forwarder';
   data: {
     id: string;
     title: string;
+    start_junction_id?: string;
+    end_junction_id?: string;
+    osm_way_id?: number;
+    osm_start_node_id?: number;
+    osm_end_node_id?: number;
   };
+  // Optional trace context of the sender of the message.
+  traceparent?: string;
+  tracestate?: string;
 }
 
+// partitionKey returns the partition key of the street segment of the data, so the speeds
+// of a segment stay in order on one shard while the segments spread over every shard. It
+// follows `SegmentSpeed.PartitionKey` of the schema package: the start and end junction,
+// the OSM way and nodes if the junctions are unknown, or the ID of the record.
+const partitionKey = (data: Event['data'], id: string): string => {
+  if (data.start_junction_id && data.end_junction_id) {
+    return `${data.start_junction_id}-${data.end_junction_id}`;
+  }
+  if (data.osm_way_id) {
+    return `${data.osm_way_id}-${data.osm_start_node_id ?? 0}-${
+      data.osm_end_node_id ?? 0
+    }`;
+  }
+  return id;
+};
+
 export const handler = async (
   event: APIGatewayProxyEventV2,
   _: Context,
@@ -30,28 +137,30 @@ export const handler = async (
   // Tries to parse the event body as a valid Event.
   const parsedEvent = JSON.parse(event.body) as Event;
   if (parsedEvent.action === 'kinesis-data-forwarder') {
-    // Setup Kinesis client.
-    const client = new KinesisClient({
-      signingRegion: 'us-east-1',
-      endpoint: 'http://kinesis.localhost.localstack.cloud:4566',
-      credentials: {
-        accessKeyId: 'na',
-        secretAccessKey: 'na',
+    // The span continues the trace of the sender if the message has a trace context.
+    const parentContext = propagation.extract(context.active(), parsedEvent);
+    const span = tracer.startSpan(
+      'kinesis-data-forwarder',
+      {
+        kind: SpanKind.PRODUCER,
+        attributes: {
+          'messaging.system': 'aws_kinesis',
+          'messaging.destination.name': streamName,
+        },
       },
-      region: 'us-east-1',
-    });
+      parentContext,
+    );
 
-    // Transform data to a base64 string and add an id.
-    const data = {
-      ...parsedEvent.data,
-      id: uuidv4(),
-    };
+    // Transform data to a base64 string and add an id and the trace context of the span.
+    const id = uuidv4();
+    const data: Record<string, unknown> = { ...parsedEvent.data, id };
+    propagation.inject(trace.setSpan(parentContext, span), data);
     const base64Data = Buffer.from(JSON.stringify(data));
 
     // Tries to send the event to Kinesis.
     const command = new PutRecordCommand({
-      StreamName: 'my-kinesis-stream',
-      PartitionKey: '1',
+      StreamName: streamName,
+      PartitionKey: partitionKey(parsedEvent.data, id),
       Data: base64Data,
     });
     try {
@@ -65,6 +174,8 @@ export const handler = async (
       });
     } catch (error) {
       console.error(error);
+      span.recordException(error as Error);
+      span.setStatus({ code: SpanStatusCode.ERROR });
       callback(new Error('Error sending event to Kinesis'), {
         statusCode: 500,
         body: JSON.stringify({
@@ -72,6 +183,10 @@ export const handler = async (
           data: error,
         }),
       });
+    } finally {
+      // The environment of the function may be frozen until the next invocation.
+      span.end();
+      await tracerProvider.forceFlush();
     }
 
     return;
@@ -3,46 +3,35 @@ package main
 import (
 	"bytes"
 	"context"
-	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"log"
-	"os"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/aws/aws-lambda-go/lambda"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+	"github.com/florianwoelki/uber-movement-speed/awsconfig"
+	"github.com/florianwoelki/uber-movement-speed/environment"
+	"github.com/florianwoelki/uber-movement-speed/schema"
+	"github.com/florianwoelki/uber-movement-speed/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
-type SegmentSpeed struct {
-	Id              string  `json:"id" dynamodbav:"id"`
-	Year            int     `json:"year" dynamodbav:"year"`
-	Month           int     `json:"month" dynamodbav:"month"`
-	Day             int     `json:"day" dynamodbav:"day"`
-	Hour            int     `json:"hour" dynamodbav:"hour"`
-	UtcTimestamp    string  `json:"utc_timestamp" dynamodbav:"utc_timestamp"`
-	StartJunctionId string  `json:"start_junction_id" dynamodbav:"start_junction_id"`
-	EndJunctionId   string  `json:"end_junction_id" dynamodbav:"end_junction_id"`
-	OsmWayId        int64   `json:"osm_way_id" dynamodbav:"osm_way_id"`
-	OsmStartNodeId  int64   `json:"osm_start_node_id" dynamodbav:"osm_start_node_id"`
-	OsmEndNodeId    int64   `json:"osm_end_node_id" dynamodbav:"osm_end_node_id"`
-	SpeedMphMean    float32 `json:"speed_mph_mean" dynamodbav:"speed_mph_mean"`
-	SpeedMphStddev  float32 `json:"speed_mph_stddev" dynamodbav:"speed_mph_stddev"`
-}
-
 var (
 	tableName    string
 	s3BucketName string
 )
 
 var (
-	dataBatch []SegmentSpeed
-	batchSize = 1000
+	dataBatch []schema.SegmentSpeed
+	// batchLinks link the upload of the batch to the spans of its records.
+	batchLinks []trace.Link
+	batchSize  = 1000
 )
 
 // Used clients for the AWS services.
@@ -51,94 +40,122 @@ var (
 	s3Client       *awsService.S3
 )
 
+// tracerProvider exports the spans of the function after every invocation.
+var tracerProvider *tracing.Provider
+
 func init() {
-	tableName = "street_segment_speeds"
-	s3BucketName = "raw-data"
-
-	customResolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
-		if service == s3.ServiceID {
-			return aws.Endpoint{
-				PartitionID:   "aws",
-				URL:           "http://s3.localhost.localstack.cloud:4566",
-				SigningRegion: "us-east-1",
-			}, nil
-		}
+	// The names of the resources are prefixed with the environment of the function.
+	env := environment.FromEnv()
+	tableName = environment.Name(env, schema.Table)
+	s3BucketName = environment.Name(env, "raw-data")
 
-		return aws.Endpoint{
-			PartitionID:   "aws",
-			URL:           fmt.Sprintf("http://%s:4566", os.Getenv("LOCALSTACK_HOSTNAME")),
-			SigningRegion: "us-east-1",
-		}, nil
-	})
+	var err error
+	tracerProvider, err = tracing.Setup(context.TODO(), "preprocessing")
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion("us-east-1"),
-		config.WithEndpointResolverWithOptions(customResolver),
-	)
+	cfg, err := awsconfig.LoadFromEnv(context.TODO())
 	if err != nil {
 		log.Fatal(err)
 	}
+	cfg = awsService.WithTracing(cfg, otel.GetTracerProvider())
 
 	dynamodbClient = awsService.NewDynamoDB(cfg)
 	s3Client = awsService.NewS3(cfg)
 }
 
 func handleRequest(ctx context.Context, event events.KinesisEvent) error {
-	for _, record := range event.Records {
-		log.Printf("Received message from kinesis. partition key: %s\n", record.Kinesis.PartitionKey)
-		log.Printf("Storing information to dynamodb table: %s\n", tableName)
+	defer func() {
+		if err := tracerProvider.Flush(ctx); err != nil {
+			log.Printf("Failed to export spans: %v", err)
+		}
+	}()
 
-		kinesisRecord := record.Kinesis
-		dataBytes := kinesisRecord.Data
+	ctx, span := tracing.Tracer().Start(ctx, "preprocessing", trace.WithAttributes(attribute.Int("messaging.batch.message_count", len(event.Records))))
+	defer span.End()
 
-		var segmentSpeed SegmentSpeed
-		err := json.Unmarshal(dataBytes, &segmentSpeed)
-		if err != nil {
+	for _, record := range event.Records {
+		if err := processRecord(ctx, record.Kinesis); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
 			return err
 		}
+	}
 
-		// Prepare the item to be stored in the DynamoDB table.
-		item, err := attributevalue.MarshalMap(segmentSpeed)
-		if err != nil {
-			return err
-		}
+	flushBatch(ctx)
+	return nil
+}
 
-		// Stores the item in the DynamoDB table.
-		err = dynamodbClient.PutItem(tableName, item)
+// processRecord stores the speed of the given record in the DynamoDB table and adds it to
+// the batch of the S3 bucket. Its span continues the trace of the producer of the record.
+func processRecord(ctx context.Context, kinesisRecord events.KinesisRecord) (err error) {
+	ctx, span := tracing.StartRecordSpan(ctx, "process record", kinesisRecord.Data,
+		attribute.String("messaging.system", "aws_kinesis"),
+		attribute.String("messaging.kinesis.partition_key", kinesisRecord.PartitionKey),
+		attribute.String("messaging.kinesis.sequence_number", kinesisRecord.SequenceNumber),
+	)
+	defer func() {
 		if err != nil {
-			return err
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
 		}
+		span.End()
+	}()
 
-		log.Println("Successfully put item into dynamodb table")
+	log.Printf("Received message from kinesis. partition key: %s\n", kinesisRecord.PartitionKey)
+	log.Printf("Storing information to dynamodb table: %s\n", tableName)
 
-		// Accumulate data for batch upload to S3.
-		dataBatch = append(dataBatch, segmentSpeed)
+	var segmentSpeed schema.SegmentSpeed
+	err = json.Unmarshal(kinesisRecord.Data, &segmentSpeed)
+	if err != nil {
+		return err
+	}
 
-		if len(dataBatch) > batchSize {
-			if err := uploadToS3(dataBatch); err != nil {
-				return fmt.Errorf("failed to upload data to S3: %v", err)
-			}
-			dataBatch = nil
-		}
+	// Prepare the item to be stored in the DynamoDB table.
+	item, err := attributevalue.MarshalMap(segmentSpeed)
+	if err != nil {
+		return err
+	}
+
+	// Stores the item in the DynamoDB table.
+	err = dynamodbClient.PutItem(ctx, tableName, item)
+	if err != nil {
+		return err
 	}
 
-	flushBatch()
+	log.Println("Successfully put item into dynamodb table")
+
+	// Accumulate data for batch upload to S3.
+	dataBatch = append(dataBatch, segmentSpeed)
+	batchLinks = append(batchLinks, trace.Link{SpanContext: span.SpanContext()})
+
+	if len(dataBatch) > batchSize {
+		if err := uploadToS3(ctx, dataBatch); err != nil {
+			return fmt.Errorf("failed to upload data to S3: %v", err)
+		}
+		dataBatch, batchLinks = nil, nil
+	}
 	return nil
 }
 
 // flushBatch flushes the current batch to the S3 bucket.
-func flushBatch() {
+func flushBatch(ctx context.Context) {
 	if len(dataBatch) > 0 {
-		if err := uploadToS3(dataBatch); err != nil {
+		if err := uploadToS3(ctx, dataBatch); err != nil {
 			log.Printf("Failed to upload data to S3: %v", err)
 		}
-		dataBatch = nil
+		dataBatch, batchLinks = nil, nil
 	}
 }
 
 // uploadToS3 uploads the given data to the S3 bucket as a CSV file and partitions it by
 // the current time.
-func uploadToS3(data []SegmentSpeed) error {
+func uploadToS3(ctx context.Context, data []schema.SegmentSpeed) error {
+	// The upload links to the spans of its records, since it belongs to all of their traces.
+	ctx, span := tracing.Tracer().Start(ctx, "upload batch", trace.WithLinks(batchLinks...), trace.WithAttributes(attribute.Int("batch.size", len(data))))
+	defer span.End()
+
 	// Gets the current time to construct the partition path.
 	currentTime := time.Now()
 	year := currentTime.Format("2006")
@@ -151,40 +168,15 @@ func uploadToS3(data []SegmentSpeed) error {
 
 	log.Println("Uploading data to S3")
 	csvBuffer := new(bytes.Buffer)
-	writer := csv.NewWriter(csvBuffer)
 
-	// Write headers to CSV file.
-	headers := []string{"id", "year", "month", "day", "hour", "utc_timestamp", "start_junction_id", "end_junction_id", "osm_way_id", "osm_start_node_id", "osm_end_node_id", "speed_mph_mean", "speed_mph_stddev"}
-	if err := writer.Write(headers); err != nil {
+	// Write the header and the rows in the layout of the schema.
+	if err := schema.WriteCSV(csvBuffer, data); err != nil {
 		return err
 	}
 
-	for _, segmentSpeed := range data {
-		if err := writer.Write(
-			[]string{
-				segmentSpeed.Id,
-				fmt.Sprint(segmentSpeed.Year),
-				fmt.Sprint(segmentSpeed.Month),
-				fmt.Sprint(segmentSpeed.Day),
-				fmt.Sprint(segmentSpeed.Hour),
-				segmentSpeed.UtcTimestamp,
-				segmentSpeed.StartJunctionId,
-				segmentSpeed.EndJunctionId,
-				fmt.Sprint(segmentSpeed.OsmWayId),
-				fmt.Sprint(segmentSpeed.OsmStartNodeId),
-				fmt.Sprint(segmentSpeed.OsmEndNodeId),
-				fmt.Sprint(segmentSpeed.SpeedMphMean),
-				fmt.Sprint(segmentSpeed.SpeedMphStddev),
-			},
-		); err != nil {
-			return err
-		}
-	}
-	writer.Flush()
-
 	key := fmt.Sprintf("batch-from-%s-to-%s.csv", data[0].Id, data[len(data)-1].Id)
 	keyWithPartition := partitionPath + key
-	err := s3Client.PutObject(s3BucketName, keyWithPartition, csvBuffer.Bytes())
+	err := s3Client.PutObject(ctx, s3BucketName, keyWithPartition, csvBuffer.Bytes())
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	awsService "github.com/florianwoelki/uber-movement-speed/aws"
+	"github.com/florianwoelki/uber-movement-speed/awsfake"
+	"github.com/florianwoelki/uber-movement-speed/schema"
+	"github.com/florianwoelki/uber-movement-speed/tracing"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// useFakes replaces the clients with clients of in-memory fakes that have the table and
+// the bucket of the function.
+func useFakes(t *testing.T) (*awsfake.DynamoDB, *awsfake.S3) {
+	ctx := context.Background()
+	dynamodb, s3 := awsfake.NewDynamoDB(), awsfake.NewS3()
+	dynamodbClient = awsService.NewDynamoDBFromClient(dynamodb)
+	s3Client = awsService.NewS3FromClient(s3)
+	dataBatch, batchLinks = nil, nil
+
+	if err := dynamodbClient.CreateTable(ctx, tableName); err != nil {
+		t.Fatal(err)
+	}
+	if err := s3Client.CreateBucket(ctx, s3BucketName); err != nil {
+		t.Fatal(err)
+	}
+	return dynamodb, s3
+}
+
+// kinesisEvent returns an event with a record of every given speed.
+func kinesisEvent(t *testing.T, speeds ...schema.SegmentSpeed) events.KinesisEvent {
+	var event events.KinesisEvent
+	for _, speed := range speeds {
+		data, err := json.Marshal(speed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		event.Records = append(event.Records, events.KinesisEventRecord{
+			Kinesis: events.KinesisRecord{PartitionKey: speed.Id, Data: data},
+		})
+	}
+	return event
+}
+
+func TestHandleRequest(t *testing.T) {
+	dynamodb, s3 := useFakes(t)
+	speeds := []schema.SegmentSpeed{
+		{Id: "1", Year: 2023, Month: 5, Day: 1, Hour: 8, OsmWayId: 42, SpeedMphMean: 25.5},
+		{Id: "2", Year: 2023, Month: 5, Day: 1, Hour: 9, OsmWayId: 43, SpeedMphMean: 30},
+	}
+
+	if err := handleRequest(context.Background(), kinesisEvent(t, speeds...)); err != nil {
+		t.Fatalf("handling the event: %v", err)
+	}
+
+	items := dynamodb.Items(tableName)
+	if len(items) != len(speeds) {
+		t.Fatalf("expected %d items, got %d", len(speeds), len(items))
+	}
+	for i, item := range items {
+		var stored schema.SegmentSpeed
+		if err := attributevalue.UnmarshalMap(item, &stored); err != nil {
+			t.Fatal(err)
+		}
+		if stored != speeds[i] {
+			t.Errorf("expected item %+v, got %+v", speeds[i], stored)
+		}
+	}
+
+	keys := s3.Keys(s3BucketName)
+	if len(keys) != 1 {
+		t.Fatalf("expected 1 batch in the bucket, got %v", keys)
+	}
+	data, _ := s3.Object(s3BucketName, keys[0])
+	uploaded, err := schema.ReadCSV(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("reading the batch: %v", err)
+	}
+	if !reflect.DeepEqual(uploaded, speeds) {
+		t.Errorf("expected the batch %+v, got %+v", speeds, uploaded)
+	}
+}
+
+func TestHandleRequest_InvalidRecord(t *testing.T) {
+	dynamodb, _ := useFakes(t)
+	event := events.KinesisEvent{Records: []events.KinesisEventRecord{
+		{Kinesis: events.KinesisRecord{Data: []byte("not json")}},
+	}}
+
+	if err := handleRequest(context.Background(), event); err == nil {
+		t.Fatal("expected an invalid record to fail")
+	}
+	if items := dynamodb.Items(tableName); len(items) != 0 {
+		t.Errorf("expected no items, got %d", len(items))
+	}
+}
+
+func TestHandleRequest_Tracing(t *testing.T) {
+	useFakes(t)
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	// The forwarder injects the trace context of its span into the payload.
+	producerCtx, producer := tracing.Tracer().Start(context.Background(), "kinesis-data-forwarder")
+	producer.End()
+	data, err := json.Marshal(schema.SegmentSpeed{Id: "1", Year: 2023})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = tracing.Inject(producerCtx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := events.KinesisEvent{Records: []events.KinesisEventRecord{
+		{Kinesis: events.KinesisRecord{PartitionKey: "1", Data: data}},
+	}}
+
+	if err := handleRequest(context.Background(), event); err != nil {
+		t.Fatalf("handling the event: %v", err)
+	}
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	record, upload := spans["process record"], spans["upload batch"]
+	if record == nil || upload == nil || spans["preprocessing"] == nil {
+		t.Fatalf("expected the spans of the invocation, the record and the upload, got %v", spans)
+	}
+	if record.SpanContext().TraceID() != producer.SpanContext().TraceID() || record.Parent().SpanID() != producer.SpanContext().SpanID() {
+		t.Errorf("expected the record span to continue the trace of the forwarder")
+	}
+	if links := upload.Links(); len(links) != 1 || links[0].SpanContext.SpanID() != record.SpanContext().SpanID() {
+		t.Errorf("expected the upload to link to the record span, got %v", links)
+	}
+}
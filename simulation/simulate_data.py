@@ -1,3 +1,4 @@
+import os
 import sys
 from datetime import datetime, timedelta
 import random
@@ -6,7 +7,27 @@ import json
 import asyncio
 import websockets
 
-url = "ws://localhost:4510"
+
+def get_websocket_url() -> str:
+    """
+    Returns the endpoint of the websocket API Gateway from the outputs document that is
+    written by the setup. Falls back to the default localstack endpoint if the document
+    does not exist.
+    """
+    environment = os.environ.get("ENVIRONMENT", "")
+    default_path = f"outputs.{environment}.json" if environment else "outputs.json"
+    path = os.environ.get("OUTPUTS_PATH", default_path)
+    try:
+        with open(path) as outputs:
+            document = json.load(outputs)
+            # The names of the resources are prefixed with their environment.
+            prefix = f"{document['environment']}-" if document.get("environment") else ""
+            return document["apis"][f"{prefix}my-kinesis-api"]["endpoint"]
+    except (OSError, KeyError):
+        return "ws://localhost:4510"
+
+
+url = get_websocket_url()
 
 # Sample data for street segment speeds.
 segment_speeds = {
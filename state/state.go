@@ -0,0 +1,139 @@
+// Package state reads and writes the checkpoint file of setup. The file records the steps
+// of a run that completed, together with the outputs they produced, so a run that failed
+// halfway can be resumed from the failed step instead of starting over.
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/florianwoelki/uber-movement-speed/manifest"
+	"github.com/florianwoelki/uber-movement-speed/outputs"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPath is the path of the checkpoint file that setup writes by default.
+const DefaultPath = ".setup-state.json"
+
+// Path returns the path of the checkpoint file that setup writes by default for the
+// given environment.
+func Path(env string) string {
+	if env == "" {
+		return DefaultPath
+	}
+	return fmt.Sprintf(".setup-state.%s.json", env)
+}
+
+// State is the progress of a setup run.
+type State struct {
+	// Fingerprint identifies the manifest the steps were completed for. A state of a
+	// different manifest must not be resumed.
+	Fingerprint string `json:"fingerprint"`
+	// Completed are the names of the completed steps.
+	Completed []string `json:"completed"`
+	// Outputs are the outputs of the completed steps.
+	Outputs *outputs.Outputs `json:"outputs"`
+}
+
+// New returns an empty state for the manifest with the given fingerprint.
+func New(fingerprint string) *State {
+	return &State{
+		Fingerprint: fingerprint,
+		Outputs:     outputs.New(),
+	}
+}
+
+// Fingerprint returns a hash of the given manifest that changes whenever the manifest
+// changes. The code of the Lambda functions is not part of the hash, it is only known once
+// the functions are built.
+func Fingerprint(m *manifest.Manifest) (string, error) {
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// Load reads the state at the given path. A missing file is an empty state of the manifest
+// with the given fingerprint and so is a state of a different manifest.
+func Load(path, fingerprint string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return New(fingerprint), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s := New(fingerprint)
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("state %s: %w", path, err)
+	}
+
+	if s.Fingerprint != fingerprint {
+		return New(fingerprint), nil
+	}
+
+	return s, nil
+}
+
+// Write writes the state to the given path.
+func (s *State) Write(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Remove removes the state at the given path. A missing file is not an error.
+func Remove(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Done reports whether the step with the given name is completed.
+func (s *State) Done(step string) bool {
+	for _, completed := range s.Completed {
+		if completed == step {
+			return true
+		}
+	}
+	return false
+}
+
+// Complete records the step with the given name as completed.
+func (s *State) Complete(step string) {
+	if s.Done(step) {
+		return
+	}
+	s.Completed = append(s.Completed, step)
+	sort.Strings(s.Completed)
+}
+
+// Forget records the steps with the given names as not completed, e.g. after their
+// resources were deleted.
+func (s *State) Forget(steps ...string) {
+	forget := map[string]bool{}
+	for _, step := range steps {
+		forget[step] = true
+	}
+
+	var completed []string
+	for _, step := range s.Completed {
+		if !forget[step] {
+			completed = append(completed, step)
+		}
+	}
+	s.Completed = completed
+}
@@ -0,0 +1,85 @@
+package state
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/florianwoelki/uber-movement-speed/manifest"
+	"github.com/florianwoelki/uber-movement-speed/outputs"
+)
+
+func TestWriteLoad(t *testing.T) {
+	s := New("test-fingerprint")
+	s.Complete("role/test-role")
+	s.Complete("bucket/test-bucket")
+	s.Outputs.Functions["test-function"] = outputs.Function{ARN: "test-function-arn"}
+
+	path := filepath.Join(t.TempDir(), DefaultPath)
+	if err := s.Write(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	loaded, err := Load(path, "test-fingerprint")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(s, loaded) {
+		t.Errorf("unexpected state: %+v", loaded)
+	}
+
+	loaded, err = Load(path, "other-fingerprint")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(loaded.Completed) != 0 {
+		t.Errorf("unexpected completed steps of a different manifest: %v", loaded.Completed)
+	}
+
+	if err := Remove(path); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Remove(path); err != nil {
+		t.Errorf("unexpected error for missing state: %v", err)
+	}
+
+	loaded, err = Load(path, "test-fingerprint")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(loaded.Completed) != 0 {
+		t.Errorf("unexpected completed steps of missing state: %v", loaded.Completed)
+	}
+}
+
+func TestCompleteForget(t *testing.T) {
+	s := New("test-fingerprint")
+	s.Complete("b")
+	s.Complete("a")
+	s.Complete("b")
+	if !reflect.DeepEqual(s.Completed, []string{"a", "b"}) {
+		t.Errorf("unexpected completed steps: %v", s.Completed)
+	}
+
+	s.Forget("a", "c")
+	if s.Done("a") || !s.Done("b") {
+		t.Errorf("unexpected completed steps: %v", s.Completed)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	m := &manifest.Manifest{Version: manifest.Version, Tables: []manifest.Table{{Name: "test-table"}}}
+	before, err := Fingerprint(m)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	m.Tables[0].Name = "other-table"
+	after, err := Fingerprint(m)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if before == after {
+		t.Errorf("expected a different fingerprint for a changed manifest")
+	}
+}
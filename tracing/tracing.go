@@ -0,0 +1,157 @@
+// Package tracing traces the speed updates with OpenTelemetry from the websocket ingest
+// through Kinesis to DynamoDB and S3. The spans are exported with OTLP over HTTP, e.g. to
+// a local collector, and the trace context of a Kinesis record travels inside its JSON
+// payload as the members `traceparent` and `tracestate` of the W3C Trace Context, so the
+// span that processes a record continues the trace of the span that produced it.
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// EndpointVariable is the environment variable with the URL of the OTLP endpoint, e.g.
+// `http://localhost:4318` for a local collector. Spans are only exported if it or
+// `OTEL_EXPORTER_OTLP_TRACES_ENDPOINT` is set.
+const EndpointVariable = "OTEL_EXPORTER_OTLP_ENDPOINT"
+
+// tracesEndpointVariable is the environment variable with the URL of the OTLP endpoint of
+// the traces, which overrides EndpointVariable.
+const tracesEndpointVariable = "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"
+
+// instrumentationName is the name of the tracer of the spans of this module.
+const instrumentationName = "github.com/florianwoelki/uber-movement-speed"
+
+// propagator reads and writes the trace context of the payloads. It is fixed to the W3C
+// Trace Context, so the payloads do not depend on the configured propagator.
+var propagator = propagation.TraceContext{}
+
+// Provider exports the spans of a program. The zero Provider exports nothing.
+type Provider struct {
+	provider *sdktrace.TracerProvider
+}
+
+// Setup installs the global tracer provider and propagator of the given service. If an
+// OTLP endpoint is configured in the environment, see EndpointVariable, the spans are
+// exported in batches to it, otherwise they are dropped.
+func Setup(ctx context.Context, service string) (*Provider, error) {
+	otel.SetTextMapPropagator(propagator)
+	if os.Getenv(EndpointVariable) == "" && os.Getenv(tracesEndpointVariable) == "" {
+		return &Provider{}, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", service)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return &Provider{provider: provider}, nil
+}
+
+// Flush exports the ended spans that are not exported yet. Lambda functions flush after
+// every invocation, since their environment may be frozen until the next one.
+func (p *Provider) Flush(ctx context.Context) error {
+	if p == nil || p.provider == nil {
+		return nil
+	}
+	return p.provider.ForceFlush(ctx)
+}
+
+// Shutdown exports the remaining spans and stops the provider.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p == nil || p.provider == nil {
+		return nil
+	}
+	return p.provider.Shutdown(ctx)
+}
+
+// Tracer returns the tracer of the global tracer provider for the spans of this module.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Inject returns the given JSON object with the trace context of the given context as its
+// members `traceparent` and `tracestate`. The payload is returned as is if the context has
+// no span.
+func Inject(ctx context.Context, payload []byte) ([]byte, error) {
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return payload, nil
+	}
+
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &object); err != nil {
+		return nil, err
+	}
+	for key, value := range carrier {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		object[key] = encoded
+	}
+	return json.Marshal(object)
+}
+
+// Extract returns the given context with the remote span of the trace context in the
+// given JSON payload. The context is returned as is if the payload has no trace context.
+func Extract(ctx context.Context, payload []byte) context.Context {
+	var fields struct {
+		TraceParent string `json:"traceparent"`
+		TraceState  string `json:"tracestate"`
+	}
+	if err := json.Unmarshal(payload, &fields); err != nil || fields.TraceParent == "" {
+		return ctx
+	}
+
+	carrier := propagation.MapCarrier{"traceparent": fields.TraceParent}
+	if fields.TraceState != "" {
+		carrier["tracestate"] = fields.TraceState
+	}
+	return propagator.Extract(ctx, carrier)
+}
+
+// ExtractHeaders returns the given context with the remote span of the trace context in
+// the given HTTP headers, whose names may have any case.
+func ExtractHeaders(ctx context.Context, headers map[string]string) context.Context {
+	carrier := propagation.MapCarrier{}
+	for name, value := range headers {
+		carrier[strings.ToLower(name)] = value
+	}
+	return propagator.Extract(ctx, carrier)
+}
+
+// StartRecordSpan starts a consumer span of the record with the given JSON payload. The
+// span continues the trace of the producer of the record and links to the span of the
+// given context, e.g. the span of the invocation that received a batch of records.
+// Without a trace context in the payload, the span is a child of the span of the given
+// context.
+func StartRecordSpan(ctx context.Context, name string, payload []byte, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	opts := []trace.SpanStartOption{
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attributes...),
+	}
+
+	producerCtx := Extract(ctx, payload)
+	if invocation := trace.SpanContextFromContext(ctx); invocation.IsValid() && !invocation.Equal(trace.SpanContextFromContext(producerCtx)) {
+		opts = append(opts, trace.WithLinks(trace.Link{SpanContext: invocation}))
+	}
+	return Tracer().Start(producerCtx, name, opts...)
+}
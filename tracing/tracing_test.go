@@ -0,0 +1,119 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// useRecorder installs a global tracer provider that records the spans.
+func useRecorder(t *testing.T) *tracetest.SpanRecorder {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+}
+
+func TestInjectExtract(t *testing.T) {
+	useRecorder(t)
+	ctx, span := Tracer().Start(context.Background(), "producer")
+	defer span.End()
+
+	payload, err := Inject(ctx, []byte(`{"id":"1","speed_mph_mean":25.5}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(payload, &object); err != nil {
+		t.Fatal(err)
+	}
+	if object["id"] != "1" || object["speed_mph_mean"] != 25.5 || object["traceparent"] == nil {
+		t.Errorf("expected the payload with a traceparent, got %s", payload)
+	}
+
+	extracted := trace.SpanContextFromContext(Extract(context.Background(), payload))
+	if extracted.TraceID() != span.SpanContext().TraceID() || extracted.SpanID() != span.SpanContext().SpanID() || !extracted.IsRemote() {
+		t.Errorf("expected the remote span of the producer, got %v", extracted)
+	}
+}
+
+func TestInject_WithoutSpan(t *testing.T) {
+	payload := []byte(`{"id":"1"}`)
+	injected, err := Inject(context.Background(), payload)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(injected) != string(payload) {
+		t.Errorf("expected the payload as is, got %s", injected)
+	}
+}
+
+func TestExtract_WithoutTraceContext(t *testing.T) {
+	ctx := context.Background()
+	for _, payload := range []string{`{"id":"1"}`, `not json`, `{"traceparent":"invalid"}`} {
+		if trace.SpanContextFromContext(Extract(ctx, []byte(payload))).IsValid() {
+			t.Errorf("%s: unexpected span", payload)
+		}
+	}
+}
+
+func TestStartRecordSpan(t *testing.T) {
+	recorder := useRecorder(t)
+	producerCtx, producer := Tracer().Start(context.Background(), "producer")
+	producer.End()
+	payload, err := Inject(producerCtx, []byte(`{"id":"1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, invocation := Tracer().Start(context.Background(), "invocation")
+	_, record := StartRecordSpan(ctx, "record", payload)
+	record.End()
+	invocation.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+	span := spans[1]
+	if span.Parent().SpanID() != producer.SpanContext().SpanID() || span.SpanContext().TraceID() != producer.SpanContext().TraceID() {
+		t.Errorf("expected the record span to continue the trace of the producer")
+	}
+	if span.SpanKind() != trace.SpanKindConsumer {
+		t.Errorf("expected a consumer span, got %s", span.SpanKind())
+	}
+	if links := span.Links(); len(links) != 1 || links[0].SpanContext.SpanID() != invocation.SpanContext().SpanID() {
+		t.Errorf("expected a link to the invocation span, got %v", links)
+	}
+}
+
+func TestStartRecordSpan_WithoutTraceContext(t *testing.T) {
+	recorder := useRecorder(t)
+	ctx, invocation := Tracer().Start(context.Background(), "invocation")
+	_, record := StartRecordSpan(ctx, "record", []byte(`{"id":"1"}`))
+	record.End()
+	invocation.End()
+
+	span := recorder.Ended()[0]
+	if span.Parent().SpanID() != invocation.SpanContext().SpanID() || len(span.Links()) != 0 {
+		t.Errorf("expected the record span to be a child of the invocation span without links")
+	}
+}
+
+func TestProvider_Zero(t *testing.T) {
+	var p *Provider
+	if err := p.Flush(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := (&Provider{}).Shutdown(context.Background()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}